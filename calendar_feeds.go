@@ -0,0 +1,384 @@
+package servermanager
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/heindl/caldav-go/icalendar"
+	"github.com/heindl/caldav-go/icalendar/components"
+	"github.com/sirupsen/logrus"
+)
+
+// CalendarFeedKind is the type of thing a calendar feed is for.
+type CalendarFeedKind string
+
+const (
+	CalendarFeedDriver       CalendarFeedKind = "driver"
+	CalendarFeedChampionship CalendarFeedKind = "championship"
+	CalendarFeedRaceWeekend  CalendarFeedKind = "race-weekend"
+)
+
+var ErrInvalidCalendarFeedSignature = errors.New("servermanager: invalid calendar feed signature")
+
+const calendarFeedSecretMetaKey = "calendar-feed-secret"
+
+var (
+	// calendarFeedSecret is the key that calendar feeds are signed with. It is generated randomly the first time
+	// Server Manager starts, and stored in the meta of the store. Feeds can't be signed or served without it.
+	calendarFeedSecret      string
+	calendarFeedSecretMutex sync.RWMutex
+)
+
+// InitCalendarFeeds loads the calendar feed secret from the store, generating and saving a new one if there isn't one.
+func InitCalendarFeeds(store Store) error {
+	var secret string
+
+	err := store.GetMeta(calendarFeedSecretMetaKey, &secret)
+
+	if err != nil && err != ErrValueNotSet {
+		return err
+	}
+
+	if secret == "" {
+		secret, err = generateCalendarFeedSecret()
+
+		if err != nil {
+			return err
+		}
+
+		if err := store.SetMeta(calendarFeedSecretMetaKey, secret); err != nil {
+			return err
+		}
+	}
+
+	calendarFeedSecretMutex.Lock()
+	defer calendarFeedSecretMutex.Unlock()
+
+	calendarFeedSecret = secret
+
+	return nil
+}
+
+func generateCalendarFeedSecret() (string, error) {
+	secret := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// calendarFeedSignature signs a feed so that its URL can't be guessed from a driver GUID or championship ID. An empty
+// signature is returned if there is no calendar feed secret.
+func calendarFeedSignature(kind CalendarFeedKind, id string) string {
+	calendarFeedSecretMutex.RLock()
+	secret := calendarFeedSecret
+	calendarFeedSecretMutex.RUnlock()
+
+	if secret == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(string(kind) + ":" + id))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validCalendarFeedSignature(kind CalendarFeedKind, id, signature string) bool {
+	expected := calendarFeedSignature(kind, id)
+
+	if expected == "" || signature == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// CalendarFeedURL is the signed URL of a calendar feed, or an empty string if feeds can't be signed.
+func CalendarFeedURL(kind CalendarFeedKind, id string) string {
+	signature := calendarFeedSignature(kind, id)
+
+	if signature == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/calendar/%s/%s/%s.ics", config.HTTP.BaseURL, kind, url.PathEscape(id), signature)
+}
+
+func (c *Championship) CalendarFeedURL() string {
+	return CalendarFeedURL(CalendarFeedChampionship, c.ID.String())
+}
+
+func (rw *RaceWeekend) CalendarFeedURL() string {
+	return CalendarFeedURL(CalendarFeedRaceWeekend, rw.ID.String())
+}
+
+// calendarFeedPassword is the password needed to join an event.
+func calendarFeedPassword(event ScheduledEvent, serverOpts *GlobalServerConfig) string {
+	switch e := event.(type) {
+	case *CustomRace:
+		if e.OverrideServerPassword() {
+			return e.ReplacementServerPassword()
+		}
+	case *ChampionshipEvent:
+		if e.championship != nil && e.championship.OverridePassword {
+			return e.championship.ReplacementPassword
+		}
+	case *RaceWeekendSession:
+		if e.OverridePassword {
+			return e.ReplacementPassword
+		}
+	}
+
+	return serverOpts.Password
+}
+
+// calendarAlarms builds VALARM components for the reminder timers (in minutes before the event). The iCal library
+// doesn't support alarms, so they are added to the marshalled events.
+func calendarAlarms(reminders []int, title string) string {
+	var alarms []string
+
+	for _, reminder := range reminders {
+		alarms = append(alarms, strings.Join([]string{
+			"BEGIN:VALARM",
+			"ACTION:DISPLAY",
+			"DESCRIPTION:" + strings.NewReplacer(",", "\\,", ";", "\\;").Replace(title),
+			fmt.Sprintf("TRIGGER:-PT%dM", reminder),
+			"END:VALARM",
+		}, icalendar.Newline))
+	}
+
+	return strings.Join(alarms, icalendar.Newline)
+}
+
+// buildCalendarFeed writes a calendar feed of events, with the server join link, password (if includePassword is set)
+// and reminders from the server options. Feeds are built from the current schedule, so rescheduled events are updated
+// when calendar apps next refresh the feed.
+func (srm *ScheduledRacesManager) buildCalendarFeed(w io.Writer, events []ScheduledEvent, includePassword bool) error {
+	serverOpts, err := srm.store.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	var joinLink string
+
+	if serverOpts.ShowContentManagerJoinLink == 1 {
+		link, err := getContentManagerJoinLink(*serverOpts)
+
+		if err != nil {
+			logrus.WithError(err).Errorf("could not get CM join link for calendar feed")
+		} else {
+			joinLink = link.String()
+		}
+	}
+
+	cal := components.NewCalendar()
+
+	for _, event := range events {
+		var icalEvents []*components.Event
+
+		if customRace, ok := event.(*CustomRace); ok && customRace.HasRecurrenceRule() {
+			icalEvents, err = BuildRecurringICalEvents(customRace)
+
+			if err != nil {
+				return err
+			}
+		} else {
+			icalEvents = append(icalEvents, BuildICalEvent(event))
+		}
+
+		for _, icalEvent := range icalEvents {
+			icalEvent.Description += fmt.Sprintf("\n\nServer: %s", serverOpts.Name)
+
+			if joinLink != "" {
+				icalEvent.Description += fmt.Sprintf("\nContent Manager join link: %s", joinLink)
+			}
+
+			if includePassword {
+				if password := calendarFeedPassword(event, serverOpts); password != "" {
+					icalEvent.Description += fmt.Sprintf("\nPassword: %s", password)
+				} else {
+					icalEvent.Description += "\nNo password"
+				}
+			}
+		}
+
+		cal.Events = append(cal.Events, icalEvents...)
+	}
+
+	str, err := icalendar.Marshal(cal)
+
+	if err != nil {
+		return err
+	}
+
+	if reminders := parseReminderTimers(serverOpts.CalendarFeedReminderTimers); len(reminders) > 0 {
+		alarms := calendarAlarms(reminders, fmt.Sprintf("Event starting soon on %s", serverOpts.Name))
+
+		str = strings.Replace(str, "END:VEVENT", alarms+icalendar.Newline+"END:VEVENT", -1)
+	}
+
+	_, err = fmt.Fprint(w, str)
+
+	return err
+}
+
+// scheduledChampionshipEvents lists the scheduled events of a championship.
+func scheduledChampionshipEvents(championship *Championship) []ScheduledEvent {
+	var events []ScheduledEvent
+
+	for _, event := range championship.Events {
+		if event.GetScheduledTime().IsZero() {
+			continue
+		}
+
+		event.championship = championship
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// scheduledRaceWeekendSessions lists the scheduled sessions of a race weekend.
+func scheduledRaceWeekendSessions(raceWeekend *RaceWeekend) []ScheduledEvent {
+	var events []ScheduledEvent
+
+	for _, session := range raceWeekend.Sessions {
+		if session.GetScheduledTime().IsZero() {
+			continue
+		}
+
+		session.raceWeekend = raceWeekend
+		events = append(events, session)
+	}
+
+	return events
+}
+
+// BuildDriverCalendarFeed writes a feed of the scheduled events in championships and race weekends the driver is
+// entered in.
+func (srm *ScheduledRacesManager) BuildDriverCalendarFeed(guid string, w io.Writer) error {
+	var events []ScheduledEvent
+
+	championships, err := srm.store.ListChampionships()
+
+	if err != nil {
+		return err
+	}
+
+	championshipsByID := make(map[uuid.UUID]*Championship)
+
+	for _, championship := range championships {
+		championshipsByID[championship.ID] = championship
+
+		if _, entrant := findEntrantInChampionship(championship, guid); entrant != nil {
+			events = append(events, scheduledChampionshipEvents(championship)...)
+		}
+	}
+
+	raceWeekends, err := srm.store.ListRaceWeekends()
+
+	if err != nil {
+		return err
+	}
+
+	for _, raceWeekend := range raceWeekends {
+		if raceWeekend.HasLinkedChampionship() {
+			// the entrants of a race weekend in a championship are the championship's entrants.
+			raceWeekend.Championship = championshipsByID[raceWeekend.ChampionshipID]
+
+			if raceWeekend.Championship == nil {
+				continue
+			}
+		}
+
+		for _, entrant := range raceWeekend.GetEntryList() {
+			if entrant.GUID == guid {
+				events = append(events, scheduledRaceWeekendSessions(raceWeekend)...)
+				break
+			}
+		}
+	}
+
+	serverOpts, err := srm.store.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	return srm.buildCalendarFeed(w, events, serverOpts.ShowPasswordInCalendarFeeds == 1)
+}
+
+// BuildChampionshipCalendarFeed writes a feed of the scheduled events of a championship. Championship feeds are
+// shared with anyone, so they never contain the server password.
+func (srm *ScheduledRacesManager) BuildChampionshipCalendarFeed(championshipID string, w io.Writer) error {
+	championship, err := srm.store.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	return srm.buildCalendarFeed(w, scheduledChampionshipEvents(championship), false)
+}
+
+// BuildRaceWeekendCalendarFeed writes a feed of the scheduled sessions of a race weekend.
+func (srm *ScheduledRacesManager) BuildRaceWeekendCalendarFeed(raceWeekendID string, w io.Writer) error {
+	raceWeekend, err := srm.store.LoadRaceWeekend(raceWeekendID)
+
+	if err != nil {
+		return err
+	}
+
+	return srm.buildCalendarFeed(w, scheduledRaceWeekendSessions(raceWeekend), false)
+}
+
+// calendarFeed serves a signed calendar feed. Feeds are public so that calendar apps can subscribe to them, the
+// signature in the URL stops feeds from being guessed.
+func (rs *ScheduledRacesHandler) calendarFeed(w http.ResponseWriter, r *http.Request) {
+	kind := CalendarFeedKind(chi.URLParam(r, "kind"))
+	id := chi.URLParam(r, "id")
+
+	if !validCalendarFeedSignature(kind, id, strings.TrimSuffix(chi.URLParam(r, "signature"), ".ics")) {
+		logrus.WithError(ErrInvalidCalendarFeedSignature).Warnf("Invalid calendar feed request for %s: %s", kind, id)
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Add("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("inline; filename=%s.ics", kind))
+
+	var err error
+
+	switch kind {
+	case CalendarFeedDriver:
+		err = rs.scheduledRacesManager.BuildDriverCalendarFeed(id, w)
+	case CalendarFeedChampionship:
+		err = rs.scheduledRacesManager.BuildChampionshipCalendarFeed(id, w)
+	case CalendarFeedRaceWeekend:
+		err = rs.scheduledRacesManager.BuildRaceWeekendCalendarFeed(id, w)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("could not build %s calendar feed", kind)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package servermanager
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarFeedSignature(t *testing.T) {
+	calendarFeedSecret = ""
+
+	if CalendarFeedURL(CalendarFeedDriver, "76561198000000000") != "" {
+		t.Log("Expected no calendar feed URL without a calendar feed secret")
+		t.Fail()
+	}
+
+	if validCalendarFeedSignature(CalendarFeedDriver, "76561198000000000", "") {
+		t.Log("Expected an empty signature to be invalid without a calendar feed secret")
+		t.Fail()
+	}
+
+	store := NewJSONStore(filepath.Join(os.TempDir(), "asm-calendar-store"), filepath.Join(os.TempDir(), "asm-calendar-store-shared"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-calendar-store"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-calendar-store-shared"))
+
+	if err := InitCalendarFeeds(store); err != nil {
+		t.Error(err)
+		return
+	}
+
+	firstSecret := calendarFeedSecret
+	signature := calendarFeedSignature(CalendarFeedDriver, "76561198000000000")
+
+	if firstSecret == "" || !validCalendarFeedSignature(CalendarFeedDriver, "76561198000000000", signature) {
+		t.Log("Expected a calendar feed secret to be generated and used to sign feeds")
+		t.Fail()
+	}
+
+	if validCalendarFeedSignature(CalendarFeedDriver, "76561198000000001", signature) {
+		t.Log("Expected a signature to only be valid for the feed it was made for")
+		t.Fail()
+	}
+
+	calendarFeedSecret = ""
+
+	if err := InitCalendarFeeds(store); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if calendarFeedSecret != firstSecret {
+		t.Log("Expected the calendar feed secret to be loaded from the store")
+		t.Fail()
+	}
+}
+
+func TestScheduledRacesManager_BuildDriverCalendarFeed(t *testing.T) {
+	store := NewJSONStore(filepath.Join(os.TempDir(), "asm-calendar-store"), filepath.Join(os.TempDir(), "asm-calendar-store-shared"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-calendar-store"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-calendar-store-shared"))
+
+	championship := NewChampionship("Calendar Championship")
+	class := NewChampionshipClass("GT3")
+	championship.AddClass(class)
+
+	entrant := NewEntrant()
+	entrant.Name = "Championship Driver"
+	entrant.GUID = "76561198000000000"
+	entrant.Model = "ks_audi_r8_lms"
+	class.Entrants.AddToBackOfGrid(entrant)
+
+	// the race weekend's own entry list is not used once it is linked to a championship.
+	raceWeekend := NewRaceWeekend()
+	raceWeekend.ChampionshipID = championship.ID
+	raceWeekend.EntryList = make(EntryList)
+
+	session := NewRaceWeekendSession()
+	session.RaceConfig.Sessions = Sessions{SessionTypeRace: &SessionConfig{Name: "Race", Time: 20}}
+	session.ScheduledTime = time.Now().Add(time.Hour * 24)
+	raceWeekend.AddSession(session, nil)
+
+	event := NewChampionshipEvent()
+	event.RaceWeekendID = raceWeekend.ID
+	championship.Events = append(championship.Events, event)
+
+	if err := store.UpsertChampionship(championship); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+		t.Error(err)
+		return
+	}
+
+	srm := NewScheduledRacesManager(store)
+
+	for guid, expected := range map[string]bool{"76561198000000000": true, "76561198000000001": false} {
+		buf := new(bytes.Buffer)
+
+		if err := srm.BuildDriverCalendarFeed(guid, buf); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if strings.Contains(buf.String(), session.ID.String()) != expected {
+			t.Logf("Expected the race weekend session to be in the feed of %s: %t, got: %s", guid, expected, buf.String())
+			t.Fail()
+		}
+	}
+}
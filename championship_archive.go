@@ -0,0 +1,465 @@
+package servermanager
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	championshipArchiveVersion      = 1
+	championshipArchiveManifestName = "championship.json"
+	championshipArchiveResultsDir   = "results"
+)
+
+var ErrInvalidChampionshipArchive = errors.New("servermanager: invalid championship archive")
+
+// ChampionshipArchiveManifest is the manifest file of a Championship archive. Linked RaceWeekends and the results
+// of each session are included in the Championship. The raw result files are stored alongside the manifest in
+// the results directory of the archive.
+type ChampionshipArchiveManifest struct {
+	Version  int
+	Exported time.Time
+
+	Championship *Championship
+
+	// ResultFiles are the names of the result files included in the archive.
+	ResultFiles []string
+
+	// Tracks and Cars are the content required to run the Championship.
+	Tracks []string
+	Cars   []string
+}
+
+// ChampionshipArchiveImportReport describes the outcome of importing a Championship archive.
+type ChampionshipArchiveImportReport struct {
+	ChampionshipID uuid.UUID
+
+	ResultFilesImported int
+	ResultFilesSkipped  int
+
+	MissingTracks []string
+	MissingCars   []string
+}
+
+// HasMissingContent indicates that the Championship uses tracks or cars which are not installed on this server.
+func (r *ChampionshipArchiveImportReport) HasMissingContent() bool {
+	return len(r.MissingTracks) > 0 || len(r.MissingCars) > 0
+}
+
+// championshipContent returns the tracks (as "track/layout") and cars used by all events, race weekend sessions
+// and classes in the Championship.
+func championshipContent(championship *Championship) (tracks []string, cars []string) {
+	trackMap := make(map[string]bool)
+	carMap := make(map[string]bool)
+
+	addRaceSetup := func(raceSetup CurrentRaceConfig) {
+		if raceSetup.Track != "" {
+			trackMap[path.Join(raceSetup.Track, raceSetup.TrackLayout)] = true
+		}
+
+		for _, car := range strings.Split(raceSetup.Cars, ";") {
+			if car != "" {
+				carMap[car] = true
+			}
+		}
+	}
+
+	for _, event := range championship.Events {
+		addRaceSetup(event.RaceSetup)
+
+		if event.IsRaceWeekend() && event.RaceWeekend != nil {
+			for _, session := range event.RaceWeekend.Sessions {
+				addRaceSetup(session.RaceConfig)
+			}
+		}
+	}
+
+	for _, car := range championship.ValidCarIDs() {
+		if car != "" && car != AnyCarModel {
+			carMap[car] = true
+		}
+	}
+
+	for track := range trackMap {
+		tracks = append(tracks, track)
+	}
+
+	for car := range carMap {
+		cars = append(cars, car)
+	}
+
+	sort.Strings(tracks)
+	sort.Strings(cars)
+
+	return tracks, cars
+}
+
+// championshipResultFiles returns the names of all result files attached to the Championship.
+func championshipResultFiles(championship *Championship) []string {
+	files := make(map[string]bool)
+
+	for _, event := range championship.Events {
+		for _, session := range event.Sessions {
+			if session.Results != nil && session.Results.SessionFile != "" {
+				files[session.Results.SessionFile+".json"] = true
+			}
+		}
+
+		if event.IsRaceWeekend() && event.RaceWeekend != nil {
+			for _, session := range event.RaceWeekend.Sessions {
+				if session.Results != nil && session.Results.SessionFile != "" {
+					files[session.Results.SessionFile+".json"] = true
+				}
+			}
+		}
+	}
+
+	var out []string
+
+	for file := range files {
+		out = append(out, file)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// ExportChampionshipArchive writes a zip archive of the Championship, its linked RaceWeekends and all of
+// its result files to w.
+func (cm *ChampionshipManager) ExportChampionshipArchive(championshipID string, w io.Writer) error {
+	championship, err := cm.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	manifest := &ChampionshipArchiveManifest{
+		Version:      championshipArchiveVersion,
+		Exported:     time.Now(),
+		Championship: championship,
+	}
+
+	manifest.Tracks, manifest.Cars = championshipContent(championship)
+
+	z := zip.NewWriter(w)
+
+	for _, resultFile := range championshipResultFiles(championship) {
+		data, err := ioutil.ReadFile(filepath.Join(ServerInstallPath, "results", resultFile))
+
+		if os.IsNotExist(err) {
+			// the results are still stored in the championship, so the file isn't required for the import.
+			logrus.Warnf("Result file: %s for championship: %s does not exist, skipping", resultFile, championship.Name)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		f, err := z.Create(path.Join(championshipArchiveResultsDir, resultFile))
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+
+		manifest.ResultFiles = append(manifest.ResultFiles, resultFile)
+	}
+
+	f, err := z.Create(championshipArchiveManifestName)
+
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	return z.Close()
+}
+
+// championshipIDRemapper assigns new IDs to an imported Championship so that it can't collide with an existing
+// Championship (or a previous import of the same archive).
+type championshipIDRemapper struct {
+	championshipID uuid.UUID
+	ids            map[uuid.UUID]uuid.UUID
+}
+
+func newChampionshipIDRemapper() *championshipIDRemapper {
+	return &championshipIDRemapper{
+		ids: make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+// remap returns the new ID for a given ID, creating one if necessary. uuid.Nil is never remapped.
+func (r *championshipIDRemapper) remap(id uuid.UUID) uuid.UUID {
+	if id == uuid.Nil {
+		return id
+	}
+
+	if newID, ok := r.ids[id]; ok {
+		return newID
+	}
+
+	newID := uuid.New()
+	r.ids[id] = newID
+
+	return newID
+}
+
+// remapString remaps an ID in string form. Strings which are not IDs are returned unchanged.
+func (r *championshipIDRemapper) remapString(id string) string {
+	parsed, err := uuid.Parse(id)
+
+	if err != nil {
+		return id
+	}
+
+	return r.remap(parsed).String()
+}
+
+func (r *championshipIDRemapper) remapResults(results *SessionResults) {
+	if results == nil {
+		return
+	}
+
+	if results.ChampionshipID != "" {
+		results.ChampionshipID = r.remapString(results.ChampionshipID)
+	}
+
+	if results.RaceWeekendID != "" {
+		results.RaceWeekendID = r.remapString(results.RaceWeekendID)
+	}
+
+	// drivers are matched to the classes of the Championship by ClassID, so class IDs in the results are remapped
+	// the same way as the classes themselves.
+	for _, car := range results.Cars {
+		car.Driver.ClassID = r.remap(car.Driver.ClassID)
+	}
+
+	for _, event := range results.Events {
+		if event.Driver != nil {
+			event.Driver.ClassID = r.remap(event.Driver.ClassID)
+		}
+
+		if event.OtherDriver != nil {
+			event.OtherDriver.ClassID = r.remap(event.OtherDriver.ClassID)
+		}
+	}
+
+	for _, lap := range results.Laps {
+		lap.ClassID = r.remap(lap.ClassID)
+	}
+
+	for _, result := range results.Result {
+		result.ClassID = r.remap(result.ClassID)
+	}
+}
+
+func (r *championshipIDRemapper) remapChampionship(championship *Championship) {
+	championship.ID = r.remap(championship.ID)
+
+	for _, class := range championship.Classes {
+		class.ID = r.remap(class.ID)
+	}
+
+	for _, event := range championship.Events {
+		event.ID = r.remap(event.ID)
+		event.RaceWeekendID = r.remap(event.RaceWeekendID)
+
+		for _, session := range event.Sessions {
+			r.remapResults(session.Results)
+		}
+
+		if event.RaceWeekend != nil {
+			r.remapRaceWeekend(event.RaceWeekend)
+		}
+	}
+}
+
+func (r *championshipIDRemapper) remapRaceWeekend(raceWeekend *RaceWeekend) {
+	raceWeekend.ID = r.remap(raceWeekend.ID)
+	raceWeekend.ChampionshipID = r.remap(raceWeekend.ChampionshipID)
+
+	for _, session := range raceWeekend.Sessions {
+		session.ID = r.remap(session.ID)
+
+		for i, parentID := range session.ParentIDs {
+			session.ParentIDs[i] = r.remap(parentID)
+		}
+
+		points := make(map[uuid.UUID]*ChampionshipPoints)
+
+		for classID, classPoints := range session.Points {
+			points[r.remap(classID)] = classPoints
+		}
+
+		session.Points = points
+
+		r.remapResults(session.Results)
+	}
+
+	filters := make(map[string]map[string]*RaceWeekendSessionToSessionFilter)
+
+	for parentID, children := range raceWeekend.Filters {
+		remappedChildren := make(map[string]*RaceWeekendSessionToSessionFilter)
+
+		for childID, filter := range children {
+			remappedChildren[r.remapString(childID)] = filter
+		}
+
+		filters[r.remapString(parentID)] = remappedChildren
+	}
+
+	raceWeekend.Filters = filters
+}
+
+// ImportChampionshipArchive imports a Championship archive created by ExportChampionshipArchive. All IDs in the
+// Championship are replaced, and result files which do not already exist on this server are copied into the
+// results directory. Content used by the Championship which is not installed is listed in the report, but does
+// not stop the import.
+func (cm *ChampionshipManager) ImportChampionshipArchive(r io.ReaderAt, size int64) (*ChampionshipArchiveImportReport, error) {
+	z, err := zip.NewReader(r, size)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest *ChampionshipArchiveManifest
+	resultFiles := make(map[string]*zip.File)
+
+	for _, f := range z.File {
+		switch {
+		case f.Name == championshipArchiveManifestName:
+			rc, err := f.Open()
+
+			if err != nil {
+				return nil, err
+			}
+
+			err = json.NewDecoder(rc).Decode(&manifest)
+			rc.Close()
+
+			if err != nil {
+				return nil, err
+			}
+		case path.Dir(f.Name) == championshipArchiveResultsDir && path.Ext(f.Name) == ".json":
+			resultFiles[path.Base(f.Name)] = f
+		}
+	}
+
+	if manifest == nil || manifest.Championship == nil {
+		return nil, ErrInvalidChampionshipArchive
+	}
+
+	championship := manifest.Championship
+
+	remapper := newChampionshipIDRemapper()
+	remapper.remapChampionship(championship)
+
+	report := &ChampionshipArchiveImportReport{
+		ChampionshipID: championship.ID,
+	}
+
+	for _, resultFile := range manifest.ResultFiles {
+		f, ok := resultFiles[resultFile]
+
+		if !ok {
+			logrus.Warnf("Result file: %s is listed in the championship archive but is not present", resultFile)
+			report.ResultFilesSkipped++
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(ServerInstallPath, "results", resultFile)); err == nil {
+			// don't overwrite results that already exist on this server.
+			report.ResultFilesSkipped++
+			continue
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		rc, err := f.Open()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var results *SessionResults
+
+		err = json.NewDecoder(rc).Decode(&results)
+		rc.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		remapper.remapResults(results)
+
+		if err := saveResults(resultFile, results); err != nil {
+			return nil, err
+		}
+
+		report.ResultFilesImported++
+	}
+
+	report.MissingTracks, report.MissingCars, err = findMissingContent(championshipContent(championship))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range championship.Events {
+		if event.IsRaceWeekend() && event.RaceWeekend != nil {
+			if err := cm.store.UpsertRaceWeekend(event.RaceWeekend); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, cm.UpsertChampionship(championship)
+}
+
+// findMissingContent returns the tracks (in "track/layout" form) and cars which are not installed on this server.
+func findMissingContent(tracks []string, cars []string) (missingTracks []string, missingCars []string, err error) {
+	for _, track := range tracks {
+		if _, err := os.Stat(filepath.Join(ServerInstallPath, "content", "tracks", filepath.FromSlash(track))); os.IsNotExist(err) {
+			missingTracks = append(missingTracks, track)
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	installedCars, err := ListCars()
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	installed := installedCars.AsMap()
+
+	for _, car := range cars {
+		if _, ok := installed[car]; !ok {
+			missingCars = append(missingCars, car)
+		}
+	}
+
+	return missingTracks, missingCars, nil
+}
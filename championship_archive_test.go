@@ -0,0 +1,147 @@
+package servermanager
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChampionshipManager_ExportImportChampionshipArchive(t *testing.T) {
+	ServerInstallPath = filepath.Join("cmd", "server-manager", "assetto")
+
+	champ := NewChampionship("Archived Championship")
+
+	gt3 := NewChampionshipClass("GT3")
+	gt4 := NewChampionshipClass("GT4")
+	champ.AddClass(gt3)
+	champ.AddClass(gt4)
+
+	drivers := []struct {
+		guid, name, car string
+		class           *ChampionshipClass
+	}{
+		{"76561198000000001", "Driver One", "ks_audi_r8_lms", gt3},
+		{"76561198000000002", "Driver Two", "ks_audi_r8_lms", gt3},
+		{"76561198000000003", "Driver Three", "ks_maserati_gt_mc_gt4", gt4},
+		{"76561198000000004", "Driver Four", "ks_maserati_gt_mc_gt4", gt4},
+	}
+
+	results := &SessionResults{
+		Type:           SessionTypeRace,
+		ChampionshipID: champ.ID.String(),
+	}
+
+	for i, driver := range drivers {
+		results.Cars = append(results.Cars, &SessionCar{
+			CarID:  i,
+			Model:  driver.car,
+			Driver: SessionDriver{GUID: driver.guid, Name: driver.name, ClassID: driver.class.ID},
+		})
+
+		results.Laps = append(results.Laps, &SessionLap{
+			CarID:      i,
+			CarModel:   driver.car,
+			DriverGUID: driver.guid,
+			DriverName: driver.name,
+			LapTime:    100000 + i*1000,
+			Timestamp:  100000 + i*1000,
+			ClassID:    driver.class.ID,
+		})
+
+		results.Result = append(results.Result, &SessionResult{
+			CarID:      i,
+			CarModel:   driver.car,
+			DriverGUID: driver.guid,
+			DriverName: driver.name,
+			BestLap:    100000 + i*1000,
+			TotalTime:  1000000 + i*1000,
+			ClassID:    driver.class.ID,
+		})
+	}
+
+	event := NewChampionshipEvent()
+	event.RaceSetup = ConfigIniDefault().CurrentRaceConfig
+	event.CompletedTime = time.Now()
+	event.Sessions[SessionTypeRace] = &ChampionshipSession{
+		StartedTime:   time.Now().Add(-time.Hour),
+		CompletedTime: time.Now(),
+		Results:       results,
+	}
+
+	champ.Events = append(champ.Events, event)
+
+	if err := championshipManager.UpsertChampionship(champ); err != nil {
+		t.Error(err)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := championshipManager.ExportChampionshipArchive(champ.ID.String(), buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	report, err := championshipManager.ImportChampionshipArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if report.ChampionshipID == champ.ID {
+		t.Log("Expected the imported championship to have a new ID")
+		t.Fail()
+	}
+
+	imported, err := championshipManager.LoadChampionship(report.ChampionshipID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(imported.Classes) != len(champ.Classes) {
+		t.Logf("Expected %d classes in the imported championship, got: %d", len(champ.Classes), len(imported.Classes))
+		t.Fail()
+		return
+	}
+
+	for i, class := range champ.Classes {
+		importedClass := imported.Classes[i]
+
+		if importedClass.ID == class.ID {
+			t.Logf("Expected class: %s to have a new ID", class.Name)
+			t.Fail()
+		}
+
+		standings := class.Standings(champ, champ.Events)
+		importedStandings := importedClass.Standings(imported, imported.Events)
+
+		if len(standings) == 0 {
+			t.Logf("Expected class: %s to have standings", class.Name)
+			t.Fail()
+		}
+
+		if len(importedStandings) != len(standings) {
+			t.Logf("Expected class: %s to have %d standings after import, got: %d", class.Name, len(standings), len(importedStandings))
+			t.Fail()
+			continue
+		}
+
+		for j, standing := range standings {
+			importedStanding := importedStandings[j]
+
+			if importedStanding.Car.Driver.GUID != standing.Car.Driver.GUID || importedStanding.Points != standing.Points {
+				t.Logf("Expected standing %d of class: %s to be %s with %.1f points, got: %s with %.1f points", j+1, class.Name, standing.Car.Driver.GUID, standing.Points, importedStanding.Car.Driver.GUID, importedStanding.Points)
+				t.Fail()
+			}
+
+			if importedStanding.Car.Driver.ClassID != importedClass.ID {
+				t.Logf("Expected the car of %s to be in the imported class: %s", importedStanding.Car.Driver.GUID, importedClass.ID)
+				t.Fail()
+			}
+		}
+	}
+}
@@ -29,8 +29,9 @@ import (
 type ChampionshipManager struct {
 	*RaceManager
 
-	activeChampionship *ActiveChampionship
-	mutex              sync.Mutex
+	activeChampionship  *ActiveChampionship
+	mutex               sync.Mutex
+	notificationManager NotificationDispatcher
 
 	championshipEventStartTimers    map[string]*time.Timer
 	championshipEventReminderTimers map[string]*time.Timer
@@ -55,7 +56,12 @@ func (cm *ChampionshipManager) applyConfigAndStart(config CurrentRaceConfig, ent
 }
 
 func (cm *ChampionshipManager) LoadChampionship(id string) (*Championship, error) {
-	championship, err := cm.store.LoadChampionship(id)
+	return loadChampionship(cm.store, id)
+}
+
+// loadChampionship loads a Championship from the store, along with its linked RaceWeekends and Teams.
+func loadChampionship(store Store, id string) (*Championship, error) {
+	championship, err := store.LoadChampionship(id)
 
 	if err != nil {
 		return nil, err
@@ -63,7 +69,7 @@ func (cm *ChampionshipManager) LoadChampionship(id string) (*Championship, error
 
 	for _, event := range championship.Events {
 		if event.IsRaceWeekend() {
-			event.RaceWeekend, err = cm.store.LoadRaceWeekend(event.RaceWeekendID.String())
+			event.RaceWeekend, err = store.LoadRaceWeekend(event.RaceWeekendID.String())
 
 			if err != nil {
 				return nil, err
@@ -71,6 +77,12 @@ func (cm *ChampionshipManager) LoadChampionship(id string) (*Championship, error
 		}
 	}
 
+	championship.teams, err = TeamsForChampionship(store, championship.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return championship, nil
 }
 
@@ -203,6 +215,7 @@ func (cm *ChampionshipManager) HandleCreateChampionship(r *http.Request) (champi
 	championship.Name = r.FormValue("ChampionshipName")
 	championship.OpenEntrants = r.FormValue("ChampionshipOpenEntrants") == "on" || r.FormValue("ChampionshipOpenEntrants") == "1"
 	championship.PersistOpenEntrants = r.FormValue("ChampionshipPersistOpenEntrants") == "on" || r.FormValue("ChampionshipPersistOpenEntrants") == "1"
+	championship.TeamPointsMaxDrivers = formValueAsInt(r.FormValue("TeamPointsMaxDrivers"))
 	championship.SignUpForm.Enabled = r.FormValue("Championship.SignUpForm.Enabled") == "on" || r.FormValue("Championship.SignUpForm.Enabled") == "1"
 	championship.SignUpForm.AskForEmail = r.FormValue("Championship.SignUpForm.AskForEmail") == "on" || r.FormValue("Championship.SignUpForm.AskForEmail") == "1"
 	championship.SignUpForm.AskForTeam = r.FormValue("Championship.SignUpForm.AskForTeam") == "on" || r.FormValue("Championship.SignUpForm.AskForTeam") == "1"
@@ -287,6 +300,13 @@ func (cm *ChampionshipManager) HandleCreateChampionship(r *http.Request) (champi
 				continue
 			}
 
+			if team := championship.FindTeamForDriver(entrant.GUID, time.Time{}); team != nil {
+				// the team of a driver in a Team roster comes from the roster, not the results files. their
+				// points are moved by transferring them to another Team on the Teams page.
+				logrus.Warnf("Entrant: %s (%s) is in the roster of team: %s, points must be transferred from the Teams page", entrant.Name, entrant.GUID, team.Name)
+				continue
+			}
+
 			logrus.Infof("Renaming team for entrant: %s (%s)", entrant.Name, entrant.GUID)
 
 			for _, event := range championship.Events {
@@ -526,6 +546,11 @@ func (cm *ChampionshipManager) StartEvent(championshipID string, eventID string,
 		}
 	}
 
+	if !isPreChampionshipPracticeEvent {
+		entryList = event.removeWithdrawnEntrants(entryList)
+		entryList = event.applyReserves(championship, entryList)
+	}
+
 	event.RaceSetup.LoopMode = 1
 
 	if event.RaceSetup.HasSession(SessionTypeBooking) {
@@ -732,7 +757,11 @@ func (cm *ChampionshipManager) handleSessionChanges(message udp.Message, champio
 
 		}
 
-		if championship.OpenEntrants && championship.PersistOpenEntrants && a.Event() == udp.EventNewConnection {
+		isReserve := championship.Events[currentEventIndex].ReserveAssignment(string(a.DriverGUID)) != nil
+
+		// reserve drivers are already in the entry list in place of the entrant they are replacing,
+		// so they must not be given a slot of their own.
+		if championship.OpenEntrants && championship.PersistOpenEntrants && a.Event() == udp.EventNewConnection && !isReserve {
 			// a person joined, check to see if they need adding to the championship
 			foundSlot, classForCar, err := cm.AddEntrantFromSessionData(championship, sessionEntrantWrapper(a), false, false)
 
@@ -1324,22 +1353,46 @@ func (cm *ChampionshipManager) HandleChampionshipSignUp(r *http.Request) (respon
 		}
 	}
 
+	if err := validateChampionshipSignUp(championship, signUpResponse); err != nil {
+		return signUpResponse, false, err
+	}
+
+	foundSlot, err = cm.SignUp(championship, signUpResponse, championship.SignUpForm.HideCarChoice)
+
+	return signUpResponse, foundSlot, err
+}
+
+// validateChampionshipSignUp checks the GUID and email address of a sign up response. It is used by every way of
+// signing up to a Championship.
+func validateChampionshipSignUp(championship *Championship, signUpResponse *ChampionshipSignUpResponse) error {
 	if !steamGUIDRegex.MatchString(signUpResponse.GUID) {
-		return signUpResponse, false, ValidationError("Please enter a valid SteamID64.")
+		return ValidationError("Please enter a valid SteamID64.")
 	}
 
-	for _, entrant := range championship.SignUpForm.Responses {
-		if championship.SignUpForm.AskForEmail && entrant.Email == signUpResponse.Email && entrant.GUID != signUpResponse.GUID {
-			return signUpResponse, false, ValidationError("Someone has already registered with this email address.")
+	if championship.SignUpForm.AskForEmail {
+		if signUpResponse.Email == "" {
+			return ValidationError("Please enter your email address.")
+		}
+
+		for _, entrant := range championship.SignUpForm.Responses {
+			if entrant.Email == signUpResponse.Email && entrant.GUID != signUpResponse.GUID {
+				return ValidationError("Someone has already registered with this email address.")
+			}
 		}
 	}
 
+	return nil
+}
+
+// SignUp adds a sign up response to a Championship. If the Championship does not require approval, the entrant is
+// added to a free slot in their chosen car, or the first free slot if takeFirstFreeSlot is set.
+func (cm *ChampionshipManager) SignUp(championship *Championship, signUpResponse *ChampionshipSignUpResponse, takeFirstFreeSlot bool) (foundSlot bool, err error) {
 	if !championship.SignUpForm.RequiresApproval {
 		// check to see if there is room in the entrylist for the user in their specific car
-		foundSlot, _, err = cm.AddEntrantFromSessionData(championship, signUpResponse, true, championship.SignUpForm.HideCarChoice)
+		foundSlot, _, err = cm.AddEntrantFromSessionData(championship, signUpResponse, true, takeFirstFreeSlot)
 
 		if err != nil {
-			return signUpResponse, foundSlot, err
+			return foundSlot, err
 		}
 
 		if foundSlot {
@@ -1363,7 +1416,20 @@ func (cm *ChampionshipManager) HandleChampionshipSignUp(r *http.Request) (respon
 		championship.SignUpForm.Responses = append(championship.SignUpForm.Responses, signUpResponse)
 	}
 
-	return signUpResponse, foundSlot, cm.UpsertChampionship(championship)
+	return foundSlot, cm.UpsertChampionship(championship)
+}
+
+// notifySignUpApproved sends a direct message to a driver whose sign up has been accepted by an admin.
+func (cm *ChampionshipManager) notifySignUpApproved(championship *Championship, guid string) {
+	if cm.notificationManager == nil {
+		return
+	}
+
+	go func() {
+		if err := cm.notificationManager.SendSignUpApprovedMessage(championship, guid); err != nil {
+			logrus.WithError(err).Errorf("Could not send sign up approved notification to driver: %s", guid)
+		}
+	}()
 }
 
 func (cm *ChampionshipManager) InitScheduledChampionships() error {
@@ -166,6 +166,22 @@ func (d dummyNotificationManager) SendChampionshipReminderMessage(championship *
 	return nil
 }
 
+func (d dummyNotificationManager) SendResultsPostedMessage(summary *ResultsSummary) error {
+	return nil
+}
+
+func (d dummyNotificationManager) SendDriverMessage(guid string, eventType NotificationEventType, title, msg string) error {
+	return nil
+}
+
+func (d dummyNotificationManager) SendSignUpApprovedMessage(championship *Championship, guid string) error {
+	return nil
+}
+
+func (d dummyNotificationManager) SendPenaltyMessage(guid string, sessionType, track string, penalty time.Duration, reason string) error {
+	return nil
+}
+
 func (d dummyNotificationManager) SaveServerOptions(oldServerOpts *GlobalServerConfig, newServerOpts *GlobalServerConfig) error {
 	return nil
 }
@@ -502,3 +518,37 @@ func TestChampionshipManager_ChampionshipEventCallbackOpenChampionshipExample(t
 		eventNum++
 	}
 }
+
+func TestValidateChampionshipSignUp(t *testing.T) {
+	champ := NewChampionship("Sign Up Championship")
+	champ.SignUpForm.Enabled = true
+	champ.SignUpForm.AskForEmail = true
+	champ.SignUpForm.Responses = []*ChampionshipSignUpResponse{
+		{GUID: "76561198000000001", Email: "driver1@example.com"},
+	}
+
+	for _, testCase := range []struct {
+		name, guid, email string
+		valid             bool
+	}{
+		{"Valid sign up", "76561198000000002", "driver2@example.com", true},
+		{"Invalid GUID", "not-a-guid", "driver2@example.com", false},
+		{"Missing email", "76561198000000002", "", false},
+		{"Email used by another driver", "76561198000000002", "driver1@example.com", false},
+		{"Driver updating their own sign up", "76561198000000001", "driver1@example.com", true},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validateChampionshipSignUp(champ, &ChampionshipSignUpResponse{GUID: testCase.guid, Email: testCase.email})
+
+			if testCase.valid && err != nil {
+				t.Logf("Expected sign up to be valid, got: %s", err)
+				t.Fail()
+			} else if !testCase.valid {
+				if _, ok := err.(ValidationError); !ok {
+					t.Logf("Expected a validation error, got: %v", err)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package servermanager
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+// ReservePointsRule decides who is awarded the points scored by a reserve driver.
+type ReservePointsRule int
+
+const (
+	// ReservePointsToTeam awards the points to the Team of the seat the reserve drove in. The reserve does not
+	// appear in the driver standings.
+	ReservePointsToTeam ReservePointsRule = iota
+	// ReservePointsToReserve awards the points to the reserve driver only. The Team of the seat is not awarded points.
+	ReservePointsToReserve
+	// ReservePointsToBoth awards the points to both the reserve driver and the Team of the seat.
+	ReservePointsToBoth
+)
+
+func (r ReservePointsRule) String() string {
+	switch r {
+	case ReservePointsToReserve:
+		return "Reserve Driver Only"
+	case ReservePointsToBoth:
+		return "Reserve Driver and Team"
+	default:
+		return "Team Only"
+	}
+}
+
+func (r ReservePointsRule) AwardsTeam() bool {
+	return r == ReservePointsToTeam || r == ReservePointsToBoth
+}
+
+func (r ReservePointsRule) AwardsDriver() bool {
+	return r == ReservePointsToReserve || r == ReservePointsToBoth
+}
+
+var ReservePointsRules = []ReservePointsRule{
+	ReservePointsToTeam,
+	ReservePointsToReserve,
+	ReservePointsToBoth,
+}
+
+var (
+	ErrReserveDriverNotFound   = errors.New("servermanager: reserve driver not found")
+	ErrReserveSeatNotFound     = errors.New("servermanager: seat for reserve driver not found")
+	ErrReserveDriverIsEntrant  = errors.New("servermanager: reserve driver is already an entrant in the championship")
+	ErrReserveDriverSeatInUse  = errors.New("servermanager: seat already has a reserve driver for this event")
+	ErrReserveDriverOccupied   = errors.New("servermanager: reserve driver is already driving in this event")
+	ErrReserveDriverEventStart = errors.New("servermanager: reserve drivers can't be changed once an event has started")
+)
+
+// A ReserveDriver can stand in for an Entrant of a Championship at individual events.
+type ReserveDriver struct {
+	GUID  string
+	Name  string
+	Added time.Time
+}
+
+// A ReserveAssignment places a ReserveDriver into the seat of a Championship Entrant for a single ChampionshipEvent.
+type ReserveAssignment struct {
+	ReserveGUID string
+	// SeatGUID is the GUID of the Entrant whose car the reserve drives.
+	SeatGUID string
+}
+
+// ReserveDriver returns the ReserveDriver with the given GUID.
+func (c *Championship) ReserveDriver(guid string) *ReserveDriver {
+	for _, reserve := range c.ReserveDrivers {
+		if reserve.GUID == guid {
+			return reserve
+		}
+	}
+
+	return nil
+}
+
+// SeatName returns the name of the Entrant with the given GUID, for displaying which seat a reserve is driving.
+func (c *Championship) SeatName(seatGUID string) string {
+	if _, entrant := findEntrantInChampionship(c, seatGUID); entrant != nil {
+		return entrant.Name
+	}
+
+	return seatGUID
+}
+
+func (c *Championship) AddReserveDriver(guid, name string) error {
+	if _, entrant := findEntrantInChampionship(c, guid); entrant != nil {
+		return ErrReserveDriverIsEntrant
+	}
+
+	if reserve := c.ReserveDriver(guid); reserve != nil {
+		reserve.Name = name
+		return nil
+	}
+
+	c.ReserveDrivers = append(c.ReserveDrivers, &ReserveDriver{
+		GUID:  guid,
+		Name:  name,
+		Added: time.Now(),
+	})
+
+	return nil
+}
+
+// RemoveReserveDriver removes a ReserveDriver from the Championship, along with any assignments to events which
+// have not yet been completed.
+func (c *Championship) RemoveReserveDriver(guid string) {
+	for i, reserve := range c.ReserveDrivers {
+		if reserve.GUID == guid {
+			c.ReserveDrivers = append(c.ReserveDrivers[:i], c.ReserveDrivers[i+1:]...)
+			break
+		}
+	}
+
+	for _, event := range c.Events {
+		if !event.Completed() {
+			event.UnassignReserve(guid)
+		}
+	}
+}
+
+// AssignReserve places the reserve with reserveGUID in the seat of the Entrant with seatGUID for this event.
+func (cr *ChampionshipEvent) AssignReserve(championship *Championship, reserveGUID, seatGUID string) error {
+	if !cr.StartedTime.IsZero() {
+		return ErrReserveDriverEventStart
+	}
+
+	if championship.ReserveDriver(reserveGUID) == nil {
+		return ErrReserveDriverNotFound
+	}
+
+	if _, entrant := findEntrantInChampionship(championship, seatGUID); entrant == nil {
+		return ErrReserveSeatNotFound
+	}
+
+	for _, assignment := range cr.Reserves {
+		if assignment.SeatGUID == seatGUID {
+			return ErrReserveDriverSeatInUse
+		}
+
+		if assignment.ReserveGUID == reserveGUID {
+			return ErrReserveDriverOccupied
+		}
+	}
+
+	cr.Reserves = append(cr.Reserves, &ReserveAssignment{
+		ReserveGUID: reserveGUID,
+		SeatGUID:    seatGUID,
+	})
+
+	return nil
+}
+
+func (cr *ChampionshipEvent) UnassignReserve(reserveGUID string) {
+	for i, assignment := range cr.Reserves {
+		if assignment.ReserveGUID == reserveGUID {
+			cr.Reserves = append(cr.Reserves[:i], cr.Reserves[i+1:]...)
+			return
+		}
+	}
+}
+
+// ReserveAssignment returns the assignment for the reserve driver with the given GUID in this event, or nil if
+// the GUID is not a reserve in this event.
+func (cr *ChampionshipEvent) ReserveAssignment(reserveGUID string) *ReserveAssignment {
+	for _, assignment := range cr.Reserves {
+		if assignment.ReserveGUID == reserveGUID {
+			return assignment
+		}
+	}
+
+	return nil
+}
+
+// ReserveForSeat returns the assignment for the seat of the Entrant with the given GUID, or nil if no reserve
+// is driving in their place.
+func (cr *ChampionshipEvent) ReserveForSeat(seatGUID string) *ReserveAssignment {
+	for _, assignment := range cr.Reserves {
+		if assignment.SeatGUID == seatGUID {
+			return assignment
+		}
+	}
+
+	return nil
+}
+
+// applyReserves replaces the Entrants in the entryList who have a reserve driver for this event. The Entrant's
+// car, skin and team are kept so that the reserve drives the seat in the same livery.
+func (cr *ChampionshipEvent) applyReserves(championship *Championship, entryList EntryList) EntryList {
+	if len(cr.Reserves) == 0 {
+		return entryList
+	}
+
+	out := make(EntryList)
+
+	for key, entrant := range entryList {
+		if assignment := cr.ReserveForSeat(entrant.GUID); entrant.GUID != "" && assignment != nil {
+			if reserve := championship.ReserveDriver(assignment.ReserveGUID); reserve != nil {
+				reserveEntrant := *entrant
+				reserveEntrant.GUID = reserve.GUID
+				reserveEntrant.Name = reserve.Name
+
+				logrus.Infof("Reserve driver: %s (%s) is driving in place of %s (%s)", reserve.Name, reserve.GUID, entrant.Name, entrant.GUID)
+
+				out[key] = &reserveEntrant
+				continue
+			}
+		}
+
+		out[key] = entrant
+	}
+
+	return out
+}
+
+func (cm *ChampionshipManager) AddReserveDriver(championshipID string, r *http.Request) error {
+	championship, err := cm.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	guid := NormaliseEntrantGUID(strings.TrimSpace(r.FormValue("GUID")))
+	name := strings.TrimSpace(r.FormValue("Name"))
+
+	if guid == "" || name == "" {
+		return ValidationError("Please enter a name and GUID for the reserve driver.")
+	}
+
+	if err := championship.AddReserveDriver(guid, name); err != nil {
+		return err
+	}
+
+	return cm.UpsertChampionship(championship)
+}
+
+func (cm *ChampionshipManager) RemoveReserveDriver(championshipID, guid string) error {
+	championship, err := cm.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	championship.RemoveReserveDriver(guid)
+
+	return cm.UpsertChampionship(championship)
+}
+
+func (cm *ChampionshipManager) UpdateReservePointsRule(championshipID string, rule ReservePointsRule) error {
+	championship, err := cm.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	championship.ReservePointsRule = rule
+
+	return cm.UpsertChampionship(championship)
+}
+
+func (cm *ChampionshipManager) AssignReserveDriver(championshipID, eventID, reserveGUID, seatGUID string) error {
+	championship, event, err := cm.GetChampionshipAndEvent(championshipID, eventID)
+
+	if err != nil {
+		return err
+	}
+
+	if err := event.AssignReserve(championship, reserveGUID, seatGUID); err != nil {
+		return err
+	}
+
+	return cm.UpsertChampionship(championship)
+}
+
+func (cm *ChampionshipManager) UnassignReserveDriver(championshipID, eventID, reserveGUID string) error {
+	championship, event, err := cm.GetChampionshipAndEvent(championshipID, eventID)
+
+	if err != nil {
+		return err
+	}
+
+	if !event.StartedTime.IsZero() {
+		return ErrReserveDriverEventStart
+	}
+
+	event.UnassignReserve(reserveGUID)
+
+	return cm.UpsertChampionship(championship)
+}
+
+type championshipReservesTemplateVars struct {
+	BaseTemplateVars
+
+	Championship *Championship
+	PointsRules  []ReservePointsRule
+}
+
+func (ch *ChampionshipsHandler) reserves(w http.ResponseWriter, r *http.Request) {
+	championship, err := ch.championshipManager.LoadChampionship(chi.URLParam(r, "championshipID"))
+
+	if err == ErrChampionshipNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't load championship")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	ch.viewRenderer.MustLoadTemplate(w, r, "championships/reserves.html", &championshipReservesTemplateVars{
+		Championship: championship,
+		PointsRules:  ReservePointsRules,
+	})
+}
+
+func (ch *ChampionshipsHandler) addReserve(w http.ResponseWriter, r *http.Request) {
+	err := ch.championshipManager.AddReserveDriver(chi.URLParam(r, "championshipID"), r)
+
+	if validationError, ok := err.(ValidationError); ok {
+		AddErrorFlash(w, r, string(validationError))
+	} else if err == ErrReserveDriverIsEntrant {
+		AddErrorFlash(w, r, "That driver is already an entrant in this championship.")
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't add reserve driver")
+		AddErrorFlash(w, r, "Couldn't add reserve driver")
+	} else {
+		AddFlash(w, r, "Reserve driver successfully added")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (ch *ChampionshipsHandler) removeReserve(w http.ResponseWriter, r *http.Request) {
+	err := ch.championshipManager.RemoveReserveDriver(chi.URLParam(r, "championshipID"), chi.URLParam(r, "driverGUID"))
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't remove reserve driver")
+		AddErrorFlash(w, r, "Couldn't remove reserve driver")
+	} else {
+		AddFlash(w, r, "Reserve driver successfully removed")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (ch *ChampionshipsHandler) reservePointsRule(w http.ResponseWriter, r *http.Request) {
+	err := ch.championshipManager.UpdateReservePointsRule(chi.URLParam(r, "championshipID"), ReservePointsRule(formValueAsInt(r.FormValue("ReservePointsRule"))))
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't update reserve points rule")
+		AddErrorFlash(w, r, "Couldn't update reserve points rule")
+	} else {
+		AddFlash(w, r, "Reserve points rule successfully updated")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (ch *ChampionshipsHandler) assignReserve(w http.ResponseWriter, r *http.Request) {
+	err := ch.championshipManager.AssignReserveDriver(
+		chi.URLParam(r, "championshipID"),
+		chi.URLParam(r, "eventID"),
+		r.FormValue("ReserveGUID"),
+		r.FormValue("SeatGUID"),
+	)
+
+	switch err {
+	case nil:
+		AddFlash(w, r, "Reserve driver successfully assigned to the event")
+	case ErrReserveDriverSeatInUse:
+		AddErrorFlash(w, r, "That seat already has a reserve driver for this event.")
+	case ErrReserveDriverOccupied:
+		AddErrorFlash(w, r, "That reserve driver is already driving in this event.")
+	case ErrReserveDriverEventStart:
+		AddErrorFlash(w, r, "Reserve drivers can't be changed once an event has started.")
+	default:
+		logrus.WithError(err).Error("couldn't assign reserve driver")
+		AddErrorFlash(w, r, "Couldn't assign reserve driver")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (ch *ChampionshipsHandler) unassignReserve(w http.ResponseWriter, r *http.Request) {
+	err := ch.championshipManager.UnassignReserveDriver(chi.URLParam(r, "championshipID"), chi.URLParam(r, "eventID"), chi.URLParam(r, "driverGUID"))
+
+	if err == ErrReserveDriverEventStart {
+		AddErrorFlash(w, r, "Reserve drivers can't be changed once an event has started.")
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't unassign reserve driver")
+		AddErrorFlash(w, r, "Couldn't unassign reserve driver")
+	} else {
+		AddFlash(w, r, "Reserve driver successfully removed from the event")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
@@ -118,9 +118,37 @@ type Championship struct {
 	Classes []*ChampionshipClass
 	Events  []*ChampionshipEvent
 
+	// TeamPointsMaxDrivers is the maximum number of drivers per Team whose points count towards the Team
+	// Standings in each event. The highest scoring drivers are counted. 0 means all drivers count.
+	TeamPointsMaxDrivers int
+
+	// ReserveDrivers can stand in for Entrants at individual events. ReservePointsRule decides who is
+	// awarded the points they score.
+	ReserveDrivers    []*ReserveDriver
+	ReservePointsRule ReservePointsRule
+
+	// teams are the Teams which have a roster in this Championship. They are populated on load.
+	teams []*Team
+
 	entryListMutex sync.Mutex
 }
 
+// Teams returns the Teams which have a roster in this Championship.
+func (c *Championship) Teams() []*Team {
+	return c.teams
+}
+
+// FindTeamForDriver finds the Team that a driver was in at a given time. A zero time finds the driver's current Team.
+func (c *Championship) FindTeamForDriver(driverGUID string, at time.Time) *Team {
+	for _, team := range c.teams {
+		if team.HasDriverAt(c.ID, driverGUID, at) {
+			return team
+		}
+	}
+
+	return nil
+}
+
 type ChampionshipSignUpForm struct {
 	Enabled          bool
 	AskForEmail      bool
@@ -255,8 +283,8 @@ func (c *Championship) GetPlayerSummary(guid string) string {
 		return ""
 	}
 
-	standings := class.Standings(c.Events)
-	teamStandings := class.TeamStandings(c.Events)
+	standings := class.Standings(c, c.Events)
+	teamStandings := class.TeamStandings(c, c.Events)
 
 	var driverPos, teamPos int
 	var driverPoints, teamPoints float64
@@ -526,7 +554,7 @@ func (c *Championship) EntrantAttendance(guid string) int {
 	for _, event := range c.Events {
 		if event.Completed() {
 			for _, class := range c.Classes {
-				standings := class.StandingsForEvent(event)
+				standings := class.StandingsForEvent(c, event)
 
 				for _, standing := range standings {
 					if standing.Car.GetGUID() == guid {
@@ -757,6 +785,14 @@ func (c *Championship) EnhanceResults(results *SessionResults) {
 			}
 		}
 	}
+
+	// attach the Team that each driver was in at the time of the session
+	for _, car := range results.Cars {
+		if team := c.FindTeamForDriver(car.Driver.GUID, results.Date); team != nil {
+			car.Driver.TeamID = team.ID
+			car.Driver.Team = team.Name
+		}
+	}
 }
 
 func NewChampionshipStanding(car *SessionCar) *ChampionshipStanding {
@@ -970,8 +1006,9 @@ var championshipStandingSessionOrder = []SessionType{
 	SessionTypeBooking,
 }
 
-// Standings returns the current Driver Standings for the Championship.
-func (c *ChampionshipClass) Standings(inEvents []*ChampionshipEvent) []*ChampionshipStanding {
+// Standings returns the current Driver Standings for the Championship. Points scored by reserve drivers are
+// only included if the Championship's ReservePointsRule awards them to the reserve.
+func (c *ChampionshipClass) Standings(championship *Championship, inEvents []*ChampionshipEvent) []*ChampionshipStanding {
 	var out []*ChampionshipStanding
 
 	// make a copy of events so we do not persist race weekend sessions
@@ -1013,6 +1050,10 @@ func (c *ChampionshipClass) Standings(inEvents []*ChampionshipEvent) []*Champion
 	standings := make(map[string]*ChampionshipStanding)
 
 	c.standings(events, func(event *ChampionshipEvent, driverGUID string, points float64) {
+		if event.ReserveAssignment(driverGUID) != nil && !championship.ReservePointsRule.AwardsDriver() {
+			return
+		}
+
 		var car *SessionCar
 
 		for _, sessionType := range championshipStandingSessionOrder {
@@ -1063,8 +1104,8 @@ func (c *ChampionshipClass) Standings(inEvents []*ChampionshipEvent) []*Champion
 	return out
 }
 
-func (c *ChampionshipClass) StandingsForEvent(event *ChampionshipEvent) []*ChampionshipStanding {
-	return c.Standings([]*ChampionshipEvent{event})
+func (c *ChampionshipClass) StandingsForEvent(championship *Championship, event *ChampionshipEvent) []*ChampionshipStanding {
+	return c.Standings(championship, []*ChampionshipEvent{event})
 }
 
 // extractRaceWeekendSessionsIntoIndividualEvents looks for race weekend events, and makes each indiivdual session of that
@@ -1083,6 +1124,7 @@ func ExtractRaceWeekendSessionsIntoIndividualEvents(inEvents []*ChampionshipEven
 				e.RaceSetup = session.RaceConfig
 				e.CompletedTime = session.CompletedTime
 				e.StartedTime = session.StartedTime
+				e.Reserves = event.Reserves
 
 				e.Sessions[session.SessionType()] = &ChampionshipSession{
 					StartedTime:        session.StartedTime,
@@ -1101,54 +1143,126 @@ func ExtractRaceWeekendSessionsIntoIndividualEvents(inEvents []*ChampionshipEven
 
 // TeamStanding is the current number of Points a Team has.
 type TeamStanding struct {
+	// TeamID is the ID of the Team. It is uuid.Nil if the Team is only known by its name in the results files.
+	TeamID uuid.UUID
 	Team   string
 	Points float64
+
+	team *Team
 }
 
-// TeamStandings returns the current position of Teams in the Championship.
-func (c *ChampionshipClass) TeamStandings(inEvents []*ChampionshipEvent) []*TeamStanding {
-	teams := make(map[string]float64)
+// Key is used to identify the Team for penalties. Teams which exist in the Store are keyed by ID,
+// otherwise the Team name is used.
+func (ts *TeamStanding) Key() string {
+	if ts.TeamID != uuid.Nil {
+		return ts.TeamID.String()
+	}
+
+	return ts.Team
+}
+
+// Details returns the full Team, if the TeamStanding refers to a Team in the Store.
+func (ts *TeamStanding) Details() *Team {
+	return ts.team
+}
+
+// TeamStandings returns the current position of Teams in the Championship. Drivers are matched to Teams using the
+// Team rosters in the Championship, falling back to the team name found in the results files for drivers who are not
+// in a roster.
+func (c *ChampionshipClass) TeamStandings(championship *Championship, inEvents []*ChampionshipEvent) []*TeamStanding {
+	teams := make(map[string]*TeamStanding)
+
+	// eventPoints is a map of event ID -> team key -> driver GUID -> points
+	eventPoints := make(map[uuid.UUID]map[string]map[string]float64)
 
 	// make a copy of events so we do not persist race weekend sessions
 	events := ExtractRaceWeekendSessionsIntoIndividualEvents(inEvents)
 
 	c.standings(events, func(event *ChampionshipEvent, driverGUID string, points float64) {
-		var team string
+		var standing *TeamStanding
 
-		// find the team the driver was in for this race.
-		for _, session := range event.Sessions {
-			if session.Results != nil {
-				for _, car := range session.Results.Cars {
-					if car.Driver.GUID == driverGUID {
-						team = car.Driver.Team
-						break
+		teamDriverGUID := driverGUID
+
+		if assignment := event.ReserveAssignment(driverGUID); assignment != nil {
+			if !championship.ReservePointsRule.AwardsTeam() {
+				return
+			}
+
+			// reserve drivers score for the team of the seat they are driving in
+			teamDriverGUID = assignment.SeatGUID
+		}
+
+		if team := championship.FindTeamForDriver(teamDriverGUID, event.CompletedTime); team != nil {
+			standing = &TeamStanding{TeamID: team.ID, Team: team.Name, team: team}
+		} else {
+			var teamName string
+
+			// find the team the driver was in for this race.
+			for _, session := range event.Sessions {
+				if session.Results != nil {
+					for _, car := range session.Results.Cars {
+						if car.Driver.GUID == driverGUID {
+							teamName = car.Driver.Team
+							break
+						}
 					}
+					break
 				}
-				break
 			}
+
+			standing = &TeamStanding{Team: teamName}
 		}
 
-		if _, ok := teams[team]; !ok {
-			teams[team] = points
-		} else {
-			teams[team] += points
+		key := standing.Key()
+
+		if _, ok := teams[key]; !ok {
+			teams[key] = standing
+		}
+
+		if _, ok := eventPoints[event.ID]; !ok {
+			eventPoints[event.ID] = make(map[string]map[string]float64)
+		}
+
+		if _, ok := eventPoints[event.ID][key]; !ok {
+			eventPoints[event.ID][key] = make(map[string]float64)
 		}
+
+		eventPoints[event.ID][key][driverGUID] += points
 	})
 
+	for _, eventTeams := range eventPoints {
+		for key, drivers := range eventTeams {
+			var driverPoints []float64
+
+			for _, points := range drivers {
+				driverPoints = append(driverPoints, points)
+			}
+
+			sort.Sort(sort.Reverse(sort.Float64Slice(driverPoints)))
+
+			if championship.TeamPointsMaxDrivers > 0 && len(driverPoints) > championship.TeamPointsMaxDrivers {
+				driverPoints = driverPoints[:championship.TeamPointsMaxDrivers]
+			}
+
+			for _, points := range driverPoints {
+				teams[key].Points += points
+			}
+		}
+	}
+
 	var out []*TeamStanding
 
-	for name, pts := range teams {
-		out = append(out, &TeamStanding{
-			Team:   name,
-			Points: pts - float64(c.PenaltyForTeam(name)),
-		})
+	for key, standing := range teams {
+		standing.Points -= float64(c.PenaltyForTeam(key))
+
+		out = append(out, standing)
 	}
 
 	sort.Slice(out, func(i, j int) bool {
 		return out[i].Team < out[j].Team
 	})
 
-	sort.Slice(out, func(i, j int) bool {
+	sort.SliceStable(out, func(i, j int) bool {
 		return out[i].Points > out[j].Points
 	})
 
@@ -1192,9 +1306,52 @@ type ChampionshipEvent struct {
 	StartedTime   time.Time
 	CompletedTime time.Time
 
+	// Withdrawals is a map of driver GUID to the time at which they withdrew from this event.
+	// Withdrawn drivers are removed from the entry list when the event is started.
+	Withdrawals map[string]time.Time
+
+	// Reserves are the reserve drivers who are driving in place of an Entrant in this event.
+	Reserves []*ReserveAssignment
+
 	championship *Championship
 }
 
+// IsWithdrawn indicates whether the driver with the given GUID has withdrawn from the event.
+func (cr *ChampionshipEvent) IsWithdrawn(guid string) bool {
+	_, withdrawn := cr.Withdrawals[guid]
+
+	return withdrawn
+}
+
+// removeWithdrawnEntrants returns the entryList without the drivers who have withdrawn from the event.
+func (cr *ChampionshipEvent) removeWithdrawnEntrants(entryList EntryList) EntryList {
+	if len(cr.Withdrawals) == 0 {
+		return entryList
+	}
+
+	filteredEntryList := make(EntryList)
+
+	for _, entrant := range entryList {
+		if entrant.GUID == "" || !cr.IsWithdrawn(entrant.GUID) {
+			filteredEntryList.Add(entrant)
+		}
+	}
+
+	return filteredEntryList
+}
+
+func (cr *ChampionshipEvent) Withdraw(guid string) {
+	if cr.Withdrawals == nil {
+		cr.Withdrawals = make(map[string]time.Time)
+	}
+
+	cr.Withdrawals[guid] = time.Now()
+}
+
+func (cr *ChampionshipEvent) Rejoin(guid string) {
+	delete(cr.Withdrawals, guid)
+}
+
 func (cr *ChampionshipEvent) IsRaceWeekend() bool {
 	return cr.RaceWeekendID != uuid.Nil
 }
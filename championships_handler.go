@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -179,6 +180,69 @@ func (ch *ChampionshipsHandler) importChampionship(w http.ResponseWriter, r *htt
 	ch.viewRenderer.MustLoadTemplate(w, r, "championships/import-championship.html", nil)
 }
 
+// exportArchive downloads the Championship, its linked Race Weekends and its result files as a zip archive.
+func (ch *ChampionshipsHandler) exportArchive(w http.ResponseWriter, r *http.Request) {
+	championshipID := chi.URLParam(r, "championshipID")
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="championship_%s.zip"`, championshipID))
+
+	if err := ch.championshipManager.ExportChampionshipArchive(championshipID, w); err != nil {
+		logrus.WithError(err).Errorf("couldn't export championship archive")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+const championshipArchiveUploadSizeLimit = 100 << 20
+
+// importArchive reads a Championship archive created by exportArchive.
+func (ch *ChampionshipsHandler) importArchive(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse championship archive upload")
+		AddErrorFlash(w, r, "Sorry, we couldn't read that upload!")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	}
+
+	file, header, err := r.FormFile("archive")
+
+	if err != nil {
+		AddErrorFlash(w, r, "Please choose a championship archive to import.")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	}
+
+	defer file.Close()
+
+	if header.Size > championshipArchiveUploadSizeLimit {
+		AddErrorFlash(w, r, "Sorry, that championship archive is too large to import.")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	}
+
+	report, err := ch.championshipManager.ImportChampionshipArchive(file, header.Size)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't import championship archive")
+		AddErrorFlash(w, r, "Sorry, we couldn't import that championship archive! Make sure it was exported from Server Manager.")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	}
+
+	AddFlash(w, r, fmt.Sprintf("Championship successfully imported! %d result files were imported, %d were skipped.", report.ResultFilesImported, report.ResultFilesSkipped))
+
+	if len(report.MissingTracks) > 0 {
+		AddErrorFlash(w, r, "The following tracks are not installed on this server: "+strings.Join(report.MissingTracks, ", "))
+	}
+
+	if len(report.MissingCars) > 0 {
+		AddErrorFlash(w, r, "The following cars are not installed on this server: "+strings.Join(report.MissingCars, ", "))
+	}
+
+	http.Redirect(w, r, "/championship/"+report.ChampionshipID.String(), http.StatusFound)
+}
+
 type championshipResultsCollection struct {
 	Name    string                `json:"name"`
 	Results []championshipResults `json:"results"`
@@ -391,6 +455,34 @@ func (ch *ChampionshipsHandler) scheduleEvent(w http.ResponseWriter, r *http.Req
 		panic(err) // @TODO
 	}
 
+	err = ch.scheduler.SetMissedEventPolicy(event, missedEventPolicyFromForm(r))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't set championship event missed event policy")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	conflicts, err := ch.scheduler.ResolveScheduleConflicts(event, date, ScheduleConflictAction(r.FormValue("schedule-conflict-action")))
+
+	switch err {
+	case nil:
+	case ErrScheduleConflict, ErrNoFreeServer:
+		message := "The Championship Event was not scheduled, it conflicts with other events on the server: "
+
+		if err == ErrNoFreeServer {
+			message = "The Championship Event was not scheduled, no server is free at that time: "
+		}
+
+		AddErrorFlash(w, r, message+scheduleConflictsDescription(conflicts))
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	default:
+		logrus.WithError(err).Errorf("couldn't check championship event for schedule conflicts")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
 	err = ch.scheduler.Schedule(event, date)
 
 	if err != nil {
@@ -399,6 +491,10 @@ func (ch *ChampionshipsHandler) scheduleEvent(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if len(conflicts) > 0 {
+		AddErrorFlash(w, r, "Warning, this event conflicts with other events on the server: "+scheduleConflictsDescription(conflicts))
+	}
+
 	AddFlash(w, r, fmt.Sprintf("We have scheduled the Championship Event to begin at %s", date.Format(time.RFC1123)))
 	http.Redirect(w, r, r.Referer(), http.StatusFound)
 }
@@ -731,6 +827,7 @@ func (ch *ChampionshipsHandler) modifyEntrantStatus(w http.ResponseWriter, r *ht
 	}
 
 	entrantGUID := chi.URLParam(r, "entrantGUID")
+	accepted := false
 
 	for index, entrant := range championship.SignUpForm.Responses {
 		if entrant.GUID != entrantGUID {
@@ -755,6 +852,7 @@ func (ch *ChampionshipsHandler) modifyEntrantStatus(w http.ResponseWriter, r *ht
 
 			if foundSlot {
 				entrant.Status = ChampionshipEntrantAccepted
+				accepted = true
 
 				AddFlash(w, r, "The entrant was successfully accepted!")
 			} else {
@@ -779,5 +877,9 @@ func (ch *ChampionshipsHandler) modifyEntrantStatus(w http.ResponseWriter, r *ht
 		return
 	}
 
+	if accepted {
+		ch.championshipManager.notifySignUpApproved(championship, entrantGUID)
+	}
+
 	http.Redirect(w, r, r.Referer(), http.StatusFound)
 }
@@ -3,6 +3,8 @@ package servermanager
 import (
 	"math/rand"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 type lastSessionTest struct {
@@ -440,3 +442,112 @@ func TestChampionship_AddEntrantFromSession(t *testing.T) {
 		}
 	})
 }
+
+func TestChampionshipEvent_AssignReserve(t *testing.T) {
+	class := NewChampionshipClass("FXX K")
+	class.Entrants.AddToBackOfGrid(&Entrant{
+		Name:  "Driver 1",
+		GUID:  "78987656782716273",
+		Team:  "Team Name",
+		Model: "ferrari_fxx_k",
+		Skin:  "skin_01",
+	})
+
+	champ := &Championship{}
+	champ.AddClass(class)
+
+	if err := champ.AddReserveDriver("78987656782716273", "Driver 1"); err != ErrReserveDriverIsEntrant {
+		t.Log("Expected an entrant to not be able to become a reserve driver")
+		t.Fail()
+	}
+
+	if err := champ.AddReserveDriver("12345678912345678", "Reserve"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	event := NewChampionshipEvent()
+
+	if err := event.AssignReserve(champ, "12345678912345678", "78987656782716273"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	entryList := event.applyReserves(champ, class.Entrants)
+
+	for _, entrant := range entryList {
+		if entrant.GUID != "12345678912345678" || entrant.Name != "Reserve" || entrant.Team != "Team Name" || entrant.Skin != "skin_01" {
+			t.Logf("Expected reserve driver to take the entrant's seat, got: %s (%s)", entrant.Name, entrant.GUID)
+			t.Fail()
+		}
+	}
+
+	for _, entrant := range class.Entrants {
+		if entrant.GUID != "78987656782716273" {
+			t.Log("Expected championship entrant to be unchanged by reserve")
+			t.Fail()
+		}
+	}
+}
+
+func TestRaceWeekend_GetEntryListReservesAndWithdrawals(t *testing.T) {
+	class := NewChampionshipClass("FXX K")
+	class.AvailableCars = []string{"ferrari_fxx_k"}
+	class.Entrants.AddToBackOfGrid(&Entrant{
+		Name:  "Driver 1",
+		GUID:  "78987656782716273",
+		Team:  "Team Name",
+		Model: "ferrari_fxx_k",
+	})
+	class.Entrants.AddToBackOfGrid(&Entrant{
+		Name:  "Driver 2",
+		GUID:  "78987656782716274",
+		Model: "ferrari_fxx_k",
+	})
+
+	champ := NewChampionship("Reserves")
+	champ.AddClass(class)
+
+	if err := champ.AddReserveDriver("12345678912345678", "Reserve"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	raceWeekend := NewRaceWeekend()
+	raceWeekend.ChampionshipID = champ.ID
+	raceWeekend.Championship = champ
+
+	session := NewRaceWeekendSession()
+	session.RaceConfig.Sessions = Sessions{SessionTypeQualifying: &SessionConfig{Name: "Qualifying", Time: 10}}
+	session.ParentIDs = []uuid.UUID{raceWeekend.ID}
+	raceWeekend.AddSession(session, nil)
+
+	event := NewChampionshipEvent()
+	event.RaceWeekendID = raceWeekend.ID
+	champ.Events = append(champ.Events, event)
+
+	if err := event.AssignReserve(champ, "12345678912345678", "78987656782716273"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	event.Withdraw("78987656782716274")
+
+	grid, err := session.GetRaceWeekendEntryList(raceWeekend, nil, "")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(grid) != 1 {
+		t.Logf("Expected the withdrawn driver to be left out of the race weekend, got %d entrants", len(grid))
+		t.Fail()
+		return
+	}
+
+	if grid[0].Car.Driver.GUID != "12345678912345678" || grid[0].Car.Driver.Team != "Team Name" {
+		t.Log("Expected the reserve driver to take the entrant's seat in the race weekend")
+		t.Fail()
+	}
+}
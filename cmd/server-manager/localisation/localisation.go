@@ -0,0 +1,32 @@
+package localisation
+
+import (
+	"path"
+	"strings"
+)
+
+// Pack the message files into this package
+//go:generate esc -o static_embed.go -pkg=localisation -include=\.yml$ .
+
+// MessageFileLoader loads the message files which are embedded in this package.
+type MessageFileLoader struct{}
+
+func (m *MessageFileLoader) MessageFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	for filename, data := range _escData {
+		if data.IsDir() || !strings.HasSuffix(filename, ".yml") {
+			continue
+		}
+
+		contents, err := _escFSByte(false, filename)
+
+		if err != nil {
+			return nil, err
+		}
+
+		files[path.Base(filename)] = contents
+	}
+
+	return files, nil
+}
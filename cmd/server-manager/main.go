@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/cj123/assetto-server-manager"
+	"github.com/cj123/assetto-server-manager/cmd/server-manager/localisation"
 	"github.com/cj123/assetto-server-manager/cmd/server-manager/static"
 	"github.com/cj123/assetto-server-manager/cmd/server-manager/views"
 	"github.com/cj123/assetto-server-manager/pkg/udp"
@@ -50,16 +51,23 @@ func main() {
 	}
 
 	var templateLoader servermanager.TemplateLoader
+	var localisationLoader servermanager.LocalisationLoader
 	var filesystem http.FileSystem
 
 	if os.Getenv("FILESYSTEM_HTML") == "true" {
 		templateLoader = servermanager.NewFilesystemTemplateLoader("views")
+		localisationLoader = servermanager.NewFilesystemLocalisationLoader("localisation")
 		filesystem = http.Dir("static")
 	} else {
 		templateLoader = &views.TemplateLoader{}
+		localisationLoader = &localisation.MessageFileLoader{}
 		filesystem = static.FS(false)
 	}
 
+	if err := servermanager.InitLocalisation(localisationLoader); err != nil {
+		logrus.WithError(err).Errorf("Could not load localisation files, notifications will be sent in English")
+	}
+
 	resolver, err := servermanager.NewResolver(templateLoader, os.Getenv("FILESYSTEM_HTML") == "true", store)
 
 	if err != nil {
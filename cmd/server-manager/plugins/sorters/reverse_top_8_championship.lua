@@ -0,0 +1,64 @@
+json = require "json"
+utils = require "utils"
+
+-- this is an example Race Weekend entry list sorter, for help please view lua_readme.md!
+-- every .lua file in the plugins/sorters folder is shown in the Race Weekend sort dropdown.
+
+-- the top 8 drivers in the championship standings start in reverse order, then everyone else starts
+-- in the order they finished the previous session.
+local numToReverse = 8
+
+-- called when the entrants of a Race Weekend session are sorted (including grid previews)
+function sortEntrants(encodedEntrants, encodedResults, encodedStandings, encodedSession)
+    -- Decode block, you probably shouldn't touch these!
+    local entrants = json.decode(encodedEntrants)
+    local results = json.decode(encodedResults)
+    local standings = json.decode(encodedStandings)
+    local session = json.decode(encodedSession)
+
+    -- Uncomment these lines and preview a grid to print out the structure of each object.
+    --print("Entrants:", utils.dump(entrants))
+    --print("Results:", utils.dump(results))
+    --print("Standings:", utils.dump(standings))
+    --print("Session:", utils.dump(session))
+
+    -- Function block NOTE: this hook BLOCKS, make sure your functions don't loop forever!
+    local order = {}
+    local added = {}
+
+    local inRace = {}
+
+    for _, entrant in ipairs(entrants) do
+        inRace[entrant.Car.Driver.Guid] = true
+    end
+
+    local top = {}
+
+    for _, standing in ipairs(standings) do
+        if #top >= numToReverse then
+            break
+        end
+
+        if inRace[standing.GUID] then
+            table.insert(top, standing.GUID)
+        end
+    end
+
+    for i = #top, 1, -1 do
+        table.insert(order, top[i])
+        added[top[i]] = true
+    end
+
+    -- entrants are already in the order they finished the previous session
+    for _, entrant in ipairs(entrants) do
+        local guid = entrant.Car.Driver.Guid
+
+        if not added[guid] then
+            table.insert(order, guid)
+            added[guid] = true
+        end
+    end
+
+    -- Encode block, you probably shouldn't touch this either!
+    return json.encode(order)
+end
@@ -779,6 +779,7 @@ class LiveTimings implements WebsocketHandler {
             <td class="gap"></td>
             <td class="num-laps"></td>
             <td class="top-speed"></td>
+            <td class="pit-stops"></td>
             <td class="events"></td>
         </tr>
     `;
@@ -892,6 +893,20 @@ class LiveTimings implements WebsocketHandler {
 
         $tr.find(".top-speed").text(topSpeed ? topSpeed.toFixed(2) + speedUnits : "");
 
+        if (addingDriverToConnectedTable) {
+            // pit stops
+            const $tdPitStops = $tr.find(".pit-stops");
+
+            $tdPitStops.text(driver.PitStops.length);
+            $tdPitStops.attr("title", driver.PitStops.map((pitStop) => {
+                return "Lap " + pitStop.Lap + ": stationary for " + msToTime(pitStop.StationaryTime / 1000000);
+            }).join("\n"));
+
+            if (driver.InPitLane) {
+                $tdPitStops.append($("<span/>").attr({'class': 'badge badge-info live-badge ml-1'}).text("In Pit Lane"));
+            }
+        }
+
         if (addingDriverToConnectedTable) {
             // events
             const $tdEvents = $tr.find(".events");
@@ -461,10 +461,19 @@ export namespace RaceWeekend {
         ClassColor: string;
     }
 
+    interface GridChange {
+        Name: string;
+        ComputedPosition: number;
+        FinalPosition: number;
+        Reasons: string[];
+    }
+
     interface GridPreview {
         Grid: Map<number, SessionPreviewEntrant>;
         Results: Map<number, SessionPreviewEntrant>;
         Classes: Map<string, string>;
+        ComputedGrid: Map<number, SessionPreviewEntrant>;
+        GridChanges: GridChange[] | null;
     }
 
     /**
@@ -492,11 +501,16 @@ export namespace RaceWeekend {
                 type: "GET",
             }).then((response: GridPreview) => {
                 let grid: SessionPreviewEntrant[] = [];
+                let computedGrid: SessionPreviewEntrant[] = [];
 
                 for (const [key, value] of Object.entries(response.Grid)) {
                     grid.push(value);
                 }
 
+                for (const [key, value] of Object.entries(response.ComputedGrid)) {
+                    computedGrid.push(value);
+                }
+
                 let $table = $("table#entrylist-preview");
                 $table.find("tr:not(:first-child)").remove();
                 this.buildClassKey(response.Classes);
@@ -510,10 +524,37 @@ export namespace RaceWeekend {
                     }
 
                     $row.append($pos);
+
+                    if (i < computedGrid.length) {
+                        $row.append(this.buildTableDataForEntrant(computedGrid[i]));
+                    } else {
+                        $row.append($("<td>"));
+                    }
+
                     $row.append(this.buildTableDataForEntrant(grid[i]));
 
                     $table.append($row);
                 }
+
+                let $changesTable = $("table#grid-changes-table");
+                $changesTable.find("tr:not(:first-child)").remove();
+
+                if (response.GridChanges && response.GridChanges.length > 0) {
+                    for (const change of response.GridChanges) {
+                        let $row = $("<tr>");
+
+                        $row.append($("<td>").text(change.Name));
+                        $row.append($("<td>").text(change.ComputedPosition));
+                        $row.append($("<td>").text(change.FinalPosition));
+                        $row.append($("<td>").text(change.Reasons.join(", ")));
+
+                        $changesTable.append($row);
+                    }
+
+                    $("#grid-changes").show();
+                } else {
+                    $("#grid-changes").hide();
+                }
             });
         }
 
@@ -260,6 +260,40 @@ class RaceControlDriverMapRaceControlDriverCollision {
     }
 }
 
+// struct2ts:github.com/JustaPenguin/assetto-server-manager.RaceControlDriverMapRaceControlDriverPitStop
+class RaceControlDriverMapRaceControlDriverPitStop {
+    CarID: number;
+    DriverGUID: string;
+    DriverName: string;
+    CarModel: string;
+    Lap: number;
+    EntryTime: Date;
+    ExitTime: Date;
+    StationaryTime: number;
+
+    constructor(data?: any) {
+        const d: any = (data && typeof data === 'object') ? ToObject(data) : {};
+        this.CarID = ('CarID' in d) ? d.CarID as number : 0;
+        this.DriverGUID = ('DriverGUID' in d) ? d.DriverGUID as string : '';
+        this.DriverName = ('DriverName' in d) ? d.DriverName as string : '';
+        this.CarModel = ('CarModel' in d) ? d.CarModel as string : '';
+        this.Lap = ('Lap' in d) ? d.Lap as number : 0;
+        this.EntryTime = ('EntryTime' in d) ? ParseDate(d.EntryTime) : new Date();
+        this.ExitTime = ('ExitTime' in d) ? ParseDate(d.ExitTime) : new Date();
+        this.StationaryTime = ('StationaryTime' in d) ? d.StationaryTime as number : 0;
+    }
+
+    toObject(): any {
+        const cfg: any = {};
+        cfg.CarID = 'number';
+        cfg.Lap = 'number';
+        cfg.EntryTime = 'string';
+        cfg.ExitTime = 'string';
+        cfg.StationaryTime = 'number';
+        return ToObject(this, cfg);
+    }
+}
+
 // struct2ts:github.com/JustaPenguin/assetto-server-manager.RaceControlDriverMapRaceControlDriverRaceControlCarLapInfo
 class RaceControlDriverMapRaceControlDriverRaceControlCarLapInfo {
     TopSpeedThisLap: number;
@@ -307,6 +341,8 @@ class RaceControlDriverMapRaceControlDriver {
     LastSeen: Date;
     LastPos: RaceControlDriverMapRaceControlDriverVec;
     Collisions: RaceControlDriverMapRaceControlDriverCollision[];
+    InPitLane: boolean;
+    PitStops: RaceControlDriverMapRaceControlDriverPitStop[];
     Cars: { [key: string]: RaceControlDriverMapRaceControlDriverRaceControlCarLapInfo };
 
     constructor(data?: any) {
@@ -320,6 +356,8 @@ class RaceControlDriverMapRaceControlDriver {
         this.LastSeen = ('LastSeen' in d) ? ParseDate(d.LastSeen) : new Date();
         this.LastPos = new RaceControlDriverMapRaceControlDriverVec(d.LastPos);
         this.Collisions = Array.isArray(d.Collisions) ? d.Collisions.map((v: any) => new RaceControlDriverMapRaceControlDriverCollision(v)) : [];
+        this.InPitLane = ('InPitLane' in d) ? d.InPitLane as boolean : false;
+        this.PitStops = Array.isArray(d.PitStops) ? d.PitStops.map((v: any) => new RaceControlDriverMapRaceControlDriverPitStop(v)) : [];
         this.Cars = ('Cars' in d) ? d.Cars as { [key: string]: RaceControlDriverMapRaceControlDriverRaceControlCarLapInfo } : {};
     }
 
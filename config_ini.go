@@ -209,14 +209,41 @@ type GlobalServerConfig struct {
 	DiscordRoleID      string      `ini:"-" help:"If set, this role will be mentioned in all Discord notifications.  Any users with this role and access to the channel will be pinged.  To find the role ID, enable Developer mode (see above)), then Server Settings, Roles, right click on the role and Copy ID."`
 	DiscordRoleCommand string      `ini:"-" help:"If the Discord Role ID is set, you can optionally specify a command string here, like \"notify\" (no ! prefix), which if run as a ! command by a user (on a line by itself) in Discord will cause this server to attempt to add the configured role to the user.  If you run multiple servers with Discord enabled, only set this on one of them.  In order for this to work your bot must have the \"Manage Roles\" permission."`
 
+	DiscordCommandRoleID string               `ini:"-" help:"If set, only members with this role (or the admin role below) can use the Discord bot commands, such as !standings, !results latest, !signup, !whoison and !nextrace. Type !help in Discord to see all of the commands."`
+	DiscordAdminRoleID   string               `ini:"-" help:"Members with this role can use the admin commands !start, !stop, !restart and !kick. If empty, the admin commands are turned off."`
+	DiscordSlashCommands formulate.BoolNumber `ini:"-" help:"Register the Discord bot commands as slash commands, e.g. /standings. Slash commands can take up to an hour to appear in Discord. The ! commands keep working either way."`
+
+	DiscordNotificationEvents NotificationEventSelection `ini:"-" help:"Choose which notifications are sent to the Discord channel."`
+
 	NotificationReminderTimer   int                  `ini:"-"  show:"-" min:"0" max:"65535" help:"This setting has been deprecated and will be removed in the next release. Use Notification Reminder Timers instead."`
 	NotificationReminderTimers  string               `ini:"-" help:"If Discord is enabled, a reminder will be sent this many minutes prior to race start.  If 0 or empty, only race start messages will be sent.  You may schedule multiple reminders by using a comma separated list like 120,15."`
 	ShowPasswordInNotifications formulate.BoolNumber `ini:"-" help:"Show the server password in race start notifications."`
 	NotifyWhenScheduled         formulate.BoolNumber `ini:"-" help:"Send a notification when a race is scheduled (or cancelled)."`
 
+	// Notification Channels
+	NotificationChannels  FormHeading                `ini:"-" json:"-"`
+	WebhookNotifications  WebhookNotificationConfig  `ini:"-" help:"Send notifications as JSON to a webhook."`
+	SlackNotifications    SlackNotificationConfig    `ini:"-" help:"Send notifications to a Slack or Mattermost channel."`
+	EmailNotifications    EmailNotificationConfig    `ini:"-" help:"Send notifications by email."`
+	TelegramNotifications TelegramNotificationConfig `ini:"-" help:"Send notifications to a Telegram chat."`
+
+	// Results Notifications
+	ResultsNotifications              FormHeading `ini:"-" json:"-"`
+	ResultsNotificationStandingsCount int         `ini:"-" min:"0" max:"100" help:"The number of drivers in each class shown in the championship standings of results notifications. If 0, the top 5 are shown."`
+	ResultsNotificationTemplate       string      `ini:"-" show:"-" help:"This setting has been deprecated and will be removed in the next release. Use the Notification Templates page instead."`
+
+	// Calendar Feeds
+	CalendarFeeds               FormHeading          `ini:"-" json:"-"`
+	CalendarFeedReminderTimers  string               `ini:"-" help:"Reminders added to driver, championship and race weekend calendar feeds, in minutes before the event starts. You may add multiple reminders by using a comma separated list like 60,15. If empty, no reminders are added."`
+	ShowPasswordInCalendarFeeds formulate.BoolNumber `ini:"-" help:"Show the server password in driver calendar feeds. Driver feeds only contain events the driver is entered in, so their links should not be shared. Championship and race weekend feeds never show the password."`
+
 	// Messages
 	ContentManagerWelcomeMessage string `ini:"-" show:"-"`
 	ServerJoinMessage            string `ini:"-" show:"-"`
+
+	// Notification Templates
+	NotificationLanguage  string                `ini:"-" show:"-"`
+	NotificationTemplates NotificationTemplates `ini:"-" show:"-"`
 }
 
 func (gsc GlobalServerConfig) GetName() string {
@@ -297,6 +324,10 @@ type CurrentRaceConfig struct {
 	DriverSwapMinimumNumberOfSwaps  int `ini:"-" help:"Minimum number of swaps required."`
 	DriverSwapNotEnoughSwapsPenalty int `ini:"-" help:"Penalty to be applied if the minimum number of swaps is not met. Applied once per each swap not taken. (Seconds)"`
 
+	MandatoryPitStopEnabled           int `ini:"-" help:"Require every car to make a pit stop during the race, within the race pit window if one is set"`
+	MandatoryPitStopMinStationaryTime int `ini:"-" help:"Minimum time in seconds a car must be stationary in the pit lane for its pit stop to count"`
+	MandatoryPitStopPenalty           int `ini:"-" help:"Penalty in seconds for cars that do not make a valid mandatory pit stop. If set to 0, the driver is disqualified"`
+
 	MaxClients   int       `ini:"MAX_CLIENTS" help:"max number of clients (must be <= track's number of pits)"`
 	RaceOverTime int       `ini:"RACE_OVER_TIME" help:"time remaining in seconds to finish the race from the moment the first one passes on the finish line"`
 	StartRule    StartRule `ini:"START_RULE" min:"0" max:"2" help:"0 is car locked until start;   1 is teleport   ; 2 is drive-through (if race has 3 or less laps then the Teleport penalty is enabled)"`
@@ -458,6 +458,7 @@ func (tm *TrackManager) UpdateTrackMetadata(name string, r *http.Request) error
 type TrackDataGateway interface {
 	TrackInfo(name, layout string) (*TrackInfo, error)
 	TrackMap(name, layout string) (*TrackMapData, error)
+	TrackPitLane(name, layout string) (*TrackPitLane, error)
 }
 
 type filesystemTrackData struct{}
@@ -466,6 +467,10 @@ func (filesystemTrackData) TrackMap(name, layout string) (*TrackMapData, error)
 	return LoadTrackMapData(name, layout)
 }
 
+func (filesystemTrackData) TrackPitLane(name, layout string) (*TrackPitLane, error) {
+	return LoadTrackPitLane(name, layout)
+}
+
 func (filesystemTrackData) TrackInfo(name, layout string) (*TrackInfo, error) {
 	trackInfo, err := GetTrackInfo(name, layout)
 
@@ -0,0 +1,127 @@
+package servermanager
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/JustaPenguin/assetto-server-manager/pkg/udp"
+)
+
+// pitLaneDistanceTolerance is the distance in metres from the pit lane spline that a car can be while still being
+// considered to be in the pit lane.
+const pitLaneDistanceTolerance = 4.0
+
+var ErrTrackHasNoPitLane = errors.New("servermanager: track has no pit lane spline")
+
+// TrackPitLane is the pit lane geometry of a track, taken from the AI splines in the track's ai folder.
+type TrackPitLane struct {
+	// PitLane is the AI line through the pit lane.
+	PitLane []udp.Vec
+	// RacingLine is the AI line around the track. Points of the pit lane which are also close to the racing line
+	// (e.g. pit entry and exit) are not considered to be in the pit lane.
+	RacingLine []udp.Vec
+}
+
+func LoadTrackPitLane(track, trackLayout string) (*TrackPitLane, error) {
+	p := filepath.Join(ServerInstallPath, "content", "tracks", track)
+
+	if trackLayout != "" {
+		p = filepath.Join(p, trackLayout)
+	}
+
+	pitLane, err := loadAISpline(filepath.Join(p, "ai", "pit_lane.ai"))
+
+	if os.IsNotExist(err) {
+		return nil, ErrTrackHasNoPitLane
+	} else if err != nil {
+		return nil, err
+	}
+
+	racingLine, err := loadAISpline(filepath.Join(p, "ai", "fast_lane.ai"))
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &TrackPitLane{
+		PitLane:    pitLane,
+		RacingLine: racingLine,
+	}, nil
+}
+
+// loadAISpline reads the points of an Assetto Corsa AI spline. The file is a header of four int32s (version,
+// number of points, lap time, sample count) followed by each point as three float32 coordinates, a float32
+// distance along the spline and an int32 id.
+func loadAISpline(filename string) ([]udp.Vec, error) {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var header struct {
+		Version     int32
+		NumPoints   int32
+		LapTime     int32
+		SampleCount int32
+	}
+
+	if err := binary.Read(f, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.NumPoints < 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	points := make([]udp.Vec, 0, header.NumPoints)
+
+	for i := int32(0); i < header.NumPoints; i++ {
+		var point struct {
+			Position udp.Vec
+			Length   float32
+			ID       int32
+		}
+
+		if err := binary.Read(f, binary.LittleEndian, &point); err != nil {
+			return nil, err
+		}
+
+		points = append(points, point.Position)
+	}
+
+	return points, nil
+}
+
+func distanceToSpline(spline []udp.Vec, pos udp.Vec) float64 {
+	closest := math.MaxFloat64
+
+	for _, point := range spline {
+		dx, dy, dz := float64(point.X-pos.X), float64(point.Y-pos.Y), float64(point.Z-pos.Z)
+
+		if distance := dx*dx + dy*dy + dz*dz; distance < closest {
+			closest = distance
+		}
+	}
+
+	return math.Sqrt(closest)
+}
+
+// Contains determines if a position is within the pit lane.
+func (tpl *TrackPitLane) Contains(pos udp.Vec) bool {
+	if tpl == nil || len(tpl.PitLane) == 0 {
+		return false
+	}
+
+	if distanceToSpline(tpl.PitLane, pos) > pitLaneDistanceTolerance {
+		return false
+	}
+
+	return len(tpl.RacingLine) == 0 || distanceToSpline(tpl.RacingLine, pos) > pitLaneDistanceTolerance
+}
@@ -9,7 +9,6 @@ import (
 
 	embed "github.com/Clinet/discordgo-embed"
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,6 +17,13 @@ type DiscordManager struct {
 	discord               *discordgo.Session
 	scheduledRacesManager *ScheduledRacesManager
 	enabled               bool
+
+	commands      *DiscordCommandRegistry
+	applicationID string
+
+	// multiServerManager is set once the servers have been created, as the servers depend on the DiscordManager
+	// for notifications.
+	multiServerManager *MultiServerManager
 }
 
 // NewDiscordManager instantiates the DiscordManager type.  On error, it will log the error and return the type
@@ -28,8 +34,11 @@ func NewDiscordManager(store Store, scheduledRacesManager *ScheduledRacesManager
 		scheduledRacesManager: scheduledRacesManager,
 		discord:               nil,
 		enabled:               false,
+		commands:              NewDiscordCommandRegistry(),
 	}
 
+	discordManager.registerCommands()
+
 	opts, err := store.LoadServerOptions()
 
 	if err != nil {
@@ -60,7 +69,7 @@ func NewDiscordManager(store Store, scheduledRacesManager *ScheduledRacesManager
 	discordManager.enabled = true
 	discordManager.discord = session
 
-	session.AddHandler(discordManager.CommandHandler)
+	discordManager.addHandlers(session)
 
 	return discordManager, nil
 }
@@ -87,19 +96,29 @@ func (dm *DiscordManager) SaveServerOptions(oldServerOpts *GlobalServerConfig, n
 		dm.discord = session
 		dm.enabled = true
 
-		session.AddHandler(dm.CommandHandler)
+		dm.addHandlers(session)
 
 		logrus.Infof("Discord notification bot reconnected")
 	} else if newServerOpts.DiscordAPIToken == "" && oldServerOpts.DiscordAPIToken != "" {
 		// token removed, so close session (also sets enabled to false)
 		_ = dm.Stop()
 		logrus.Infof("Discord notification bot stopped")
+	} else if dm.enabled && dm.applicationID != "" && (oldServerOpts.DiscordSlashCommands != newServerOpts.DiscordSlashCommands || oldServerOpts.DiscordRoleCommand != newServerOpts.DiscordRoleCommand) {
+		// the bot is already connected, so its slash commands need updating now rather than when it next connects.
+		return dm.RegisterSlashCommands(dm.applicationID)
 	}
 
 	return nil
 }
 
-// CommandSessions outputs a full list of all scheduled sessions (P, Q & R), using buildCalendar as a base
+func (dm *DiscordManager) addHandlers(session *discordgo.Session) {
+	session.AddHandler(dm.CommandHandler)
+	session.AddHandler(dm.InteractionHandler)
+	session.AddHandler(dm.onReady)
+}
+
+// CommandSessions outputs a full list of all scheduled sessions (P, Q & R), using buildCalendar as a base. The reply
+// is written with the Discord !sessions notification template.
 func (dm *DiscordManager) CommandSessions() (string, error) {
 	serverOpts, err := dm.store.LoadServerOptions()
 
@@ -116,18 +135,21 @@ func (dm *DiscordManager) CommandSessions() (string, error) {
 		return "A server error occurred, please try again later", err
 	}
 
-	msg := fmt.Sprintf("Upcoming sessions on server %s\n", serverOpts.Name)
+	data := &NotificationTemplateData{}
 
 	for _, event := range calendar {
-		msg += event.Start.Format("Mon, 02 Jan 2006 15:04:05 MST") + "\n"
-		msg += event.Title + "\n"
-		msg += event.Description + "\n\n"
+		data.ScheduledEvents = append(data.ScheduledEvents, NotificationTemplateScheduledEvent{
+			Date:        event.Start,
+			Title:       event.Title,
+			Description: event.Description,
+		})
 	}
 
-	return msg, nil
+	return discordCommandReply(serverOpts, NotificationDiscordSessions, data)
 }
 
-// CommandSchedule outputs an abbreviated list of all scheduled events
+// CommandSchedule outputs an abbreviated list of all scheduled events. The reply is written with the Discord
+// !schedule notification template.
 func (dm *DiscordManager) CommandSchedule() (string, error) {
 	serverOpts, err := dm.store.LoadServerOptions()
 
@@ -143,83 +165,62 @@ func (dm *DiscordManager) CommandSchedule() (string, error) {
 		return "A server error occurred, please try again later", err
 	}
 
-	var recurring []ScheduledEvent
-
-	for _, scheduledEvent := range scheduled {
-		if scheduledEvent.HasRecurrenceRule() {
-			customRace, ok := scheduledEvent.(*CustomRace)
-
-			if !ok {
-				continue
-			}
+	scheduled, err = expandRecurringEvents(scheduled, start, end)
 
-			rule, err := customRace.GetRecurrenceRule()
-
-			if err != nil {
-				continue
-			}
+	if err != nil {
+		return "A server error occurred, please try again later", err
+	}
 
-			for _, startTime := range rule.Between(start, end, true) {
-				newEvent := *customRace
-				newEvent.Scheduled = startTime
-				newEvent.UUID = uuid.New()
+	data := &NotificationTemplateData{}
 
-				if customRace.GetScheduledTime() == newEvent.GetScheduledTime() {
-					continue
-				}
+	for _, scheduledEvent := range scheduled {
+		raceSetup := scheduledEvent.GetRaceSetup()
 
-				recurring = append(recurring, &newEvent)
-			}
-		}
+		data.ScheduledEvents = append(data.ScheduledEvents, NotificationTemplateScheduledEvent{
+			Date:      scheduledEvent.GetScheduledTime(),
+			TrackInfo: trackSummary(raceSetup.Track, raceSetup.TrackLayout),
+			CarList:   carList(raceSetup.Cars),
+		})
 	}
 
-	scheduled = append(scheduled, recurring...)
+	return discordCommandReply(serverOpts, NotificationDiscordSchedule, data)
+}
 
-	msg := fmt.Sprintf("\nUpcoming events on server %s\n\n", serverOpts.Name)
+// discordCommandReply writes the reply to a Discord command using its notification template, with the title of the
+// notification in bold.
+func discordCommandReply(serverOpts *GlobalServerConfig, eventType NotificationEventType, data *NotificationTemplateData) (string, error) {
+	title, msg, err := renderNotification(serverOpts, eventType, data)
 
-	for _, scheduledEvent := range scheduled {
-		raceSetup := scheduledEvent.GetRaceSetup()
-		cars := carList(scheduledEvent.GetRaceSetup().Cars)
-		msg += fmt.Sprintf("Date: %s\n", scheduledEvent.GetScheduledTime().Format("Mon, 02 Jan 2006 15:04:05 MST"))
-		msg += fmt.Sprintf("Track: %s\n", trackSummary(raceSetup.Track, raceSetup.TrackLayout))
-		msg += fmt.Sprintf("Cars: %s\n", cars)
-		msg += "\n\n"
+	if err != nil {
+		return "A server error occurred, please try again later", err
 	}
 
-	return msg, nil
+	return fmt.Sprintf("**%s**\n%s", title, msg), nil
 }
 
 // CommandNotify attempts to add a role ID (if configured) to the user issuing the !notify command
 // The role will be added as a mention on all Discord notifications
-func (dm *DiscordManager) CommandNotify(s *discordgo.Session, m *discordgo.MessageCreate) (string, error) {
-	serverOpts, err := dm.store.LoadServerOptions()
-
-	if err != nil {
-		logrus.WithError(err).Infof("couldn't get server options")
-		return "A server error occurred, try again later", err
-	}
+func (dm *DiscordManager) CommandNotify(ctx *DiscordCommandContext, args string) (string, error) {
+	s := ctx.Session
+	serverOpts := ctx.ServerOpts
 
 	if serverOpts.DiscordRoleID == "" || serverOpts.DiscordRoleCommand == "" {
 		return "", nil
 	}
 
-	// get the member
-	member, err := s.State.Member(m.GuildID, m.Author.ID)
+	l := getLocaliser()
+	lang := serverOpts.NotificationLanguage
 
-	if err != nil {
-		// if it's not in the state, punt to asking the server
-		if err == discordgo.ErrStateNotFound {
-			member, err = s.GuildMember(m.GuildID, m.Author.ID)
-		}
+	// get the member's roles
+	memberRoles, err := dm.memberRoles(ctx)
 
-		if err != nil {
-			return "You don't seem to exist, so I can't assign you that role.  Try again later.", err
-		}
+	if err != nil {
+		return l.Localise(lang, "DiscordRoleMemberNotFound", nil), err
 	}
 
 	// get the role name from ID, for use in user feedback
-	roleName := "notification"
-	roles, err := s.GuildRoles(m.GuildID)
+	roleName := l.Localise(lang, "DiscordRoleDefaultName", nil)
+	roles, err := s.GuildRoles(ctx.GuildID)
 
 	if err != nil {
 		// meh, just log it and carry on
@@ -233,71 +234,34 @@ func (dm *DiscordManager) CommandNotify(s *discordgo.Session, m *discordgo.Messa
 		}
 	}
 
-	for _, roleID := range member.Roles {
+	roleData := map[string]string{"RoleName": roleName}
+
+	for _, roleID := range memberRoles {
 		if roleID == serverOpts.DiscordRoleID {
 			// they have the role, so remove it
-			err = s.GuildMemberRoleRemove(m.GuildID, m.Author.ID, serverOpts.DiscordRoleID)
+			err = s.GuildMemberRoleRemove(ctx.GuildID, ctx.UserID, serverOpts.DiscordRoleID)
 
 			if err != nil {
 				// meh, log the error here, and just return some feedback to the user
 				logrus.WithError(err).Infof("failed to remove Discord role (make sure you have set the bot permissions to manage roles)")
-				return fmt.Sprintf("You already have the %s role, and an error occurred trying to remove it.  Try again later.", roleName), nil
+				return l.Localise(lang, "DiscordRoleRemoveFailed", roleData), nil
 			}
 
-			return fmt.Sprintf("You already had the %s role, it has now been removed.  Type the command again to add it back.", roleName), nil
+			return l.Localise(lang, "DiscordRoleRemoved", roleData), nil
 		}
 	}
 
 	// they didn't have the role, so add it
-	err = s.GuildMemberRoleAdd(m.GuildID, m.Author.ID, serverOpts.DiscordRoleID)
+	err = s.GuildMemberRoleAdd(ctx.GuildID, ctx.UserID, serverOpts.DiscordRoleID)
 
 	if err != nil {
 		// meh, log the error here and return feedback to the user
 		logrus.WithError(err).Infof("failed to set Discord role (make sure you have set the bot permissions to manage roles)")
-		return fmt.Sprintf("A server error occurred trying to assign you the %s role, try again later", roleName), nil
+		return l.Localise(lang, "DiscordRoleAddFailed", roleData), nil
 	}
 
 	// w00t!
-	return fmt.Sprintf("The %s role has been assigned, you will now get pinged with notifications.  Type the command again to remove it.", roleName), nil
-}
-
-func (dm *DiscordManager) CommandHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
-	serverOpts, err := dm.store.LoadServerOptions()
-
-	if err != nil {
-		logrus.WithError(err).Errorf("couldn't load server opts")
-		return
-	}
-
-	if m.Author.ID == s.State.User.ID {
-		return
-	}
-
-	msg := ""
-
-	switch m.Content {
-	case "!schedule":
-		msg, err = dm.CommandSchedule()
-	case "!sessions":
-		msg, err = dm.CommandSessions()
-	case "!" + serverOpts.DiscordRoleCommand:
-		msg, err = dm.CommandNotify(s, m)
-	default:
-		return
-	}
-
-	// if error, log it, but continue with message sending, handler may have put user feedback in msg
-	if err != nil {
-		logrus.WithError(err).Errorf("Error during handling of Discord command")
-	}
-
-	if msg != "" {
-		_, err = s.ChannelMessageSend(m.ChannelID, msg)
-
-		if err != nil {
-			logrus.WithError(err).Errorf("couldn't send Discord msg")
-		}
-	}
+	return l.Localise(lang, "DiscordRoleAdded", roleData), nil
 }
 
 func (dm *DiscordManager) Stop() error {
@@ -348,6 +312,23 @@ func (dm *DiscordManager) SendMessage(title string, msg string) error {
 	return nil
 }
 
+// SendDirectMessage sends a private message to a single Discord user
+func (dm *DiscordManager) SendDirectMessage(userID string, title string, msg string) error {
+	if !dm.enabled {
+		return nil
+	}
+
+	channel, err := dm.discord.UserChannelCreate(userID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = dm.discord.ChannelMessageSendEmbed(channel.ID, embed.NewGenericEmbed(title, msg))
+
+	return err
+}
+
 // SendMessage sends a message to the configured channel and logs any errors
 func (dm *DiscordManager) SendMessageWithLink(title string, msg string, linkText string, link *url.URL) error {
 	if !dm.enabled {
@@ -0,0 +1,1063 @@
+package servermanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hako/durafmt"
+	"github.com/sirupsen/logrus"
+
+	"github.com/JustaPenguin/assetto-server-manager/pkg/udp"
+)
+
+const (
+	discordCommandPrefix = "!"
+
+	// discordMessageLimit is the maximum length of a Discord message.
+	discordMessageLimit = 2000
+
+	// discordStandingsCount is the number of drivers in each class shown by the standings command.
+	discordStandingsCount = 10
+)
+
+// discordInteractionsAPI is the version of the Discord API which supports slash commands.
+var discordInteractionsAPI = "https://discord.com/api/v8/"
+
+var (
+	ErrDiscordChampionshipNotFound = errors.New("servermanager: discord command championship not found")
+	ErrDiscordServerNotFound       = errors.New("servermanager: discord command server not found")
+	ErrDiscordNoScheduledEvents    = errors.New("servermanager: no scheduled events")
+)
+
+// DiscordCommandContext describes where a command was sent from and who sent it.
+type DiscordCommandContext struct {
+	Session    *discordgo.Session
+	GuildID    string
+	ChannelID  string
+	UserID     string
+	UserName   string
+	ServerOpts *GlobalServerConfig
+
+	// Roles are the member's roles in the guild. They are loaded from Discord when the command does not include them.
+	Roles []string
+}
+
+// DiscordCommandHandler runs a command. args is the text after the command name, or the values of the slash command
+// options joined by spaces. The returned message is sent back to the channel, even if there is an error.
+type DiscordCommandHandler func(ctx *DiscordCommandContext, args string) (string, error)
+
+// DiscordCommandOption is an argument to a command. Options are shown in the command usage and are registered as
+// string options of slash commands.
+type DiscordCommandOption struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+type DiscordCommand struct {
+	Name        string
+	Description string
+	Options     []DiscordCommandOption
+	AdminOnly   bool
+	Handler     DiscordCommandHandler
+}
+
+// Usage shows how to use the command, e.g. "!standings <championship>".
+func (c *DiscordCommand) Usage() string {
+	usage := discordCommandPrefix + c.Name
+
+	for _, option := range c.Options {
+		if option.Required {
+			usage += " <" + option.Name + ">"
+		} else {
+			usage += " [" + option.Name + "]"
+		}
+	}
+
+	return usage
+}
+
+// DiscordCommandRegistry holds the commands that the Discord bot understands.
+type DiscordCommandRegistry struct {
+	mutex    sync.RWMutex
+	commands map[string]*DiscordCommand
+}
+
+func NewDiscordCommandRegistry() *DiscordCommandRegistry {
+	return &DiscordCommandRegistry{
+		commands: make(map[string]*DiscordCommand),
+	}
+}
+
+// Register adds a command to the registry, replacing any command with the same name.
+func (r *DiscordCommandRegistry) Register(command *DiscordCommand) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.commands[strings.ToLower(command.Name)] = command
+}
+
+func (r *DiscordCommandRegistry) Find(name string) (*DiscordCommand, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	command, ok := r.commands[strings.ToLower(name)]
+
+	return command, ok
+}
+
+// List returns the registered commands, sorted by name.
+func (r *DiscordCommandRegistry) List() []*DiscordCommand {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	commands := make([]*DiscordCommand, 0, len(r.commands))
+
+	for _, command := range r.commands {
+		commands = append(commands, command)
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+
+	return commands
+}
+
+// registerCommands adds the built in commands to the DiscordManager's registry.
+func (dm *DiscordManager) registerCommands() {
+	dm.commands.Register(&DiscordCommand{
+		Name:        "help",
+		Description: "List the commands you can use",
+		Handler:     dm.CommandHelp,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "schedule",
+		Description: "List the events scheduled in the next week",
+		Handler: func(ctx *DiscordCommandContext, args string) (string, error) {
+			return dm.CommandSchedule()
+		},
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "sessions",
+		Description: "List every session scheduled in the next week",
+		Handler: func(ctx *DiscordCommandContext, args string) (string, error) {
+			return dm.CommandSessions()
+		},
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "standings",
+		Description: "Show the standings of a championship",
+		Options:     []DiscordCommandOption{{Name: "championship", Description: "The name of the championship", Required: true}},
+		Handler:     dm.CommandStandings,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "results",
+		Description: "Show the latest session results",
+		Options:     []DiscordCommandOption{{Name: "latest", Description: "Which results to show, only 'latest' is supported"}},
+		Handler:     dm.CommandResults,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "signup",
+		Description: "Sign up to a championship with the Steam account you have linked using !link",
+		Options: []DiscordCommandOption{
+			{Name: "championship", Description: "The name of the championship", Required: true},
+		},
+		Handler: dm.CommandSignUp,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "link",
+		Description: "Get a code to link your Discord account in the Driver Portal for direct messages",
+		Handler:     dm.CommandLink,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "whoison",
+		Description: "List the drivers connected to each server",
+		Handler:     dm.CommandWhoIsOn,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "nextrace",
+		Description: "Show the next scheduled event",
+		Handler:     dm.CommandNextRace,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "start",
+		Description: "Start the next scheduled event now",
+		AdminOnly:   true,
+		Handler:     dm.CommandStart,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "stop",
+		Description: "Stop the event running on a server",
+		Options:     []DiscordCommandOption{{Name: "server", Description: "The name of the server, if more than one is running"}},
+		AdminOnly:   true,
+		Handler:     dm.CommandStop,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "restart",
+		Description: "Restart the event running on a server",
+		Options:     []DiscordCommandOption{{Name: "server", Description: "The name of the server, if more than one is running"}},
+		AdminOnly:   true,
+		Handler:     dm.CommandRestart,
+	})
+
+	dm.commands.Register(&DiscordCommand{
+		Name:        "kick",
+		Description: "Kick a driver from the server they are connected to",
+		Options:     []DiscordCommandOption{{Name: "driver", Description: "The name of the driver", Required: true}},
+		AdminOnly:   true,
+		Handler:     dm.CommandKick,
+	})
+}
+
+// findCommand finds a registered command, or the role command if it is configured in the server options.
+func (dm *DiscordManager) findCommand(name string, serverOpts *GlobalServerConfig) (*DiscordCommand, bool) {
+	if serverOpts.DiscordRoleID != "" && serverOpts.DiscordRoleCommand != "" && name == serverOpts.DiscordRoleCommand {
+		return &DiscordCommand{
+			Name:        serverOpts.DiscordRoleCommand,
+			Description: "Add or remove the notification role",
+			Handler:     dm.CommandNotify,
+		}, true
+	}
+
+	return dm.commands.Find(name)
+}
+
+// memberRoles loads the roles of the member who sent a command.
+func (dm *DiscordManager) memberRoles(ctx *DiscordCommandContext) ([]string, error) {
+	if ctx.Roles != nil {
+		return ctx.Roles, nil
+	}
+
+	member, err := ctx.Session.State.Member(ctx.GuildID, ctx.UserID)
+
+	if err == discordgo.ErrStateNotFound {
+		member, err = ctx.Session.GuildMember(ctx.GuildID, ctx.UserID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Roles = member.Roles
+
+	return ctx.Roles, nil
+}
+
+func hasDiscordRole(roles []string, roleID string) bool {
+	for _, role := range roles {
+		if strings.TrimSpace(role) == strings.TrimSpace(roleID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canRunCommand checks the member's roles against the command and admin roles in the server options. Admin commands
+// are disabled unless an admin role is set.
+func (dm *DiscordManager) canRunCommand(ctx *DiscordCommandContext, command *DiscordCommand) (bool, error) {
+	if command.AdminOnly && ctx.ServerOpts.DiscordAdminRoleID == "" {
+		return false, nil
+	}
+
+	if !command.AdminOnly && ctx.ServerOpts.DiscordCommandRoleID == "" {
+		return true, nil
+	}
+
+	roles, err := dm.memberRoles(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	if hasDiscordRole(roles, ctx.ServerOpts.DiscordAdminRoleID) && ctx.ServerOpts.DiscordAdminRoleID != "" {
+		return true, nil
+	}
+
+	return !command.AdminOnly && hasDiscordRole(roles, ctx.ServerOpts.DiscordCommandRoleID), nil
+}
+
+// runCommand checks that the member can run a command, then runs it.
+func (dm *DiscordManager) runCommand(ctx *DiscordCommandContext, command *DiscordCommand, args string) string {
+	allowed, err := dm.canRunCommand(ctx, command)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not check Discord roles for command: %s", command.Name)
+		return "A server error occurred, please try again later"
+	}
+
+	if !allowed {
+		return "Sorry, you don't have permission to use that command."
+	}
+
+	msg, err := command.Handler(ctx, strings.TrimSpace(args))
+
+	// if error, log it, but continue with message sending, handler may have put user feedback in msg
+	if err != nil {
+		logrus.WithError(err).Errorf("Error during handling of Discord command: %s", command.Name)
+	}
+
+	return truncateDiscordMessage(msg)
+}
+
+// truncateDiscordMessage shortens a message to the Discord message limit, which is counted in characters rather
+// than bytes.
+func truncateDiscordMessage(msg string) string {
+	if utf8.RuneCountInString(msg) <= discordMessageLimit {
+		return msg
+	}
+
+	return string([]rune(msg)[:discordMessageLimit-3]) + "..."
+}
+
+// CommandHandler runs commands sent as messages, e.g. "!standings My Championship".
+func (dm *DiscordManager) CommandHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author.ID == s.State.User.ID || !strings.HasPrefix(m.Content, discordCommandPrefix) {
+		return
+	}
+
+	serverOpts, err := dm.store.LoadServerOptions()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load server opts")
+		return
+	}
+
+	content := strings.TrimPrefix(strings.TrimSpace(m.Content), discordCommandPrefix)
+	name, args := content, ""
+
+	if i := strings.IndexAny(content, " \t\n"); i >= 0 {
+		name, args = content[:i], content[i+1:]
+	}
+
+	command, ok := dm.findCommand(name, serverOpts)
+
+	if !ok {
+		return
+	}
+
+	msg := dm.runCommand(&DiscordCommandContext{
+		Session:    s,
+		GuildID:    m.GuildID,
+		ChannelID:  m.ChannelID,
+		UserID:     m.Author.ID,
+		UserName:   m.Author.Username,
+		ServerOpts: serverOpts,
+	}, command, args)
+
+	if msg != "" {
+		_, err = s.ChannelMessageSend(m.ChannelID, msg)
+
+		if err != nil {
+			logrus.WithError(err).Errorf("couldn't send Discord msg")
+		}
+	}
+}
+
+// discordApplicationCommand is a slash command, as registered with the Discord API.
+type discordApplicationCommand struct {
+	Name        string                            `json:"name"`
+	Description string                            `json:"description"`
+	Options     []discordApplicationCommandOption `json:"options,omitempty"`
+}
+
+type discordApplicationCommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// discordApplicationCommandOptionString is the option type of text arguments.
+const discordApplicationCommandOptionString = 3
+
+// RegisterSlashCommands registers the commands in the registry (and the role command, if configured) as global slash
+// commands of the bot. If the server options turn slash commands off, the bot's slash commands are removed.
+func (dm *DiscordManager) RegisterSlashCommands(applicationID string) error {
+	if !dm.enabled {
+		return nil
+	}
+
+	serverOpts, err := dm.store.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	commands := []discordApplicationCommand{}
+
+	if serverOpts.DiscordSlashCommands == 1 {
+		registered := dm.commands.List()
+
+		if _, isRegistered := dm.commands.Find(serverOpts.DiscordRoleCommand); !isRegistered {
+			if command, ok := dm.findCommand(serverOpts.DiscordRoleCommand, serverOpts); ok {
+				registered = append(registered, command)
+			}
+		}
+
+		for _, command := range registered {
+			applicationCommand := discordApplicationCommand{
+				Name:        strings.ToLower(command.Name),
+				Description: command.Description,
+			}
+
+			// discord requires required options to be listed before optional ones.
+			options := make([]DiscordCommandOption, len(command.Options))
+			copy(options, command.Options)
+
+			sort.SliceStable(options, func(i, j int) bool {
+				return options[i].Required && !options[j].Required
+			})
+
+			for _, option := range options {
+				applicationCommand.Options = append(applicationCommand.Options, discordApplicationCommandOption{
+					Type:        discordApplicationCommandOptionString,
+					Name:        option.Name,
+					Description: option.Description,
+					Required:    option.Required,
+				})
+			}
+
+			commands = append(commands, applicationCommand)
+		}
+	}
+
+	_, err = dm.discord.RequestWithBucketID("PUT", discordInteractionsAPI+"applications/"+applicationID+"/commands", commands, "applications/commands")
+
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Registered %d Discord slash commands", len(commands))
+
+	return nil
+}
+
+// onReady registers slash commands once the bot knows its application ID.
+func (dm *DiscordManager) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	dm.applicationID = r.User.ID
+
+	if err := dm.RegisterSlashCommands(dm.applicationID); err != nil {
+		logrus.WithError(err).Errorf("Could not register Discord slash commands")
+	}
+}
+
+// discordInteraction is a slash command used by a Discord user. The version of discordgo that Server Manager uses
+// doesn't support interactions, so they are read from the raw gateway events.
+type discordInteraction struct {
+	ID        string            `json:"id"`
+	Type      int               `json:"type"`
+	Token     string            `json:"token"`
+	GuildID   string            `json:"guild_id"`
+	ChannelID string            `json:"channel_id"`
+	Member    *discordgo.Member `json:"member"`
+	User      *discordgo.User   `json:"user"`
+	Data      struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+const (
+	discordInteractionApplicationCommand = 2
+	discordInteractionResponseMessage    = 4
+)
+
+// InteractionHandler runs slash commands.
+func (dm *DiscordManager) InteractionHandler(s *discordgo.Session, e *discordgo.Event) {
+	if e.Type != "INTERACTION_CREATE" {
+		return
+	}
+
+	var interaction discordInteraction
+
+	if err := json.Unmarshal(e.RawData, &interaction); err != nil {
+		logrus.WithError(err).Errorf("Could not read Discord interaction")
+		return
+	}
+
+	if interaction.Type != discordInteractionApplicationCommand {
+		return
+	}
+
+	serverOpts, err := dm.store.LoadServerOptions()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load server opts")
+		return
+	}
+
+	command, ok := dm.findCommand(interaction.Data.Name, serverOpts)
+
+	if !ok {
+		return
+	}
+
+	ctx := &DiscordCommandContext{
+		Session:    s,
+		GuildID:    interaction.GuildID,
+		ChannelID:  interaction.ChannelID,
+		ServerOpts: serverOpts,
+	}
+
+	if interaction.Member != nil && interaction.Member.User != nil {
+		ctx.UserID = interaction.Member.User.ID
+		ctx.UserName = interaction.Member.User.Username
+		ctx.Roles = interaction.Member.Roles
+
+		if ctx.Roles == nil {
+			ctx.Roles = []string{}
+		}
+	} else if interaction.User != nil {
+		ctx.UserID = interaction.User.ID
+		ctx.UserName = interaction.User.Username
+	}
+
+	// options are passed to the handler in the order they are defined in the command.
+	var args []string
+
+	for _, option := range command.Options {
+		for _, value := range interaction.Data.Options {
+			if value.Name == option.Name {
+				args = append(args, fmt.Sprint(value.Value))
+			}
+		}
+	}
+
+	msg := dm.runCommand(ctx, command, strings.Join(args, " "))
+
+	if msg == "" {
+		msg = "Done!"
+	}
+
+	_, err = s.RequestWithBucketID("POST", discordInteractionsAPI+"interactions/"+interaction.ID+"/"+interaction.Token+"/callback", map[string]interface{}{
+		"type": discordInteractionResponseMessage,
+		"data": map[string]string{
+			"content": msg,
+		},
+	}, "interactions/callback")
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't respond to Discord interaction")
+	}
+}
+
+// CommandHelp lists the commands which the member can use.
+func (dm *DiscordManager) CommandHelp(ctx *DiscordCommandContext, args string) (string, error) {
+	msg := "Commands:\n"
+
+	for _, command := range dm.commands.List() {
+		if allowed, err := dm.canRunCommand(ctx, command); err != nil || !allowed {
+			continue
+		}
+
+		msg += fmt.Sprintf("`%s` - %s\n", command.Usage(), command.Description)
+	}
+
+	if ctx.ServerOpts.DiscordRoleID != "" && ctx.ServerOpts.DiscordRoleCommand != "" {
+		msg += fmt.Sprintf("`%s%s` - Add or remove the notification role\n", discordCommandPrefix, ctx.ServerOpts.DiscordRoleCommand)
+	}
+
+	return msg, nil
+}
+
+// findChampionship finds a championship by its ID, name, or part of its name.
+func (dm *DiscordManager) findChampionship(query string) (*Championship, error) {
+	championships, err := dm.store.ListChampionships()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var partialMatch *Championship
+
+	for _, championship := range championships {
+		if championship.ID.String() == query || strings.EqualFold(championship.Name, query) {
+			return championship, nil
+		}
+
+		if partialMatch == nil && strings.Contains(strings.ToLower(championship.Name), strings.ToLower(query)) {
+			partialMatch = championship
+		}
+	}
+
+	if partialMatch != nil {
+		return partialMatch, nil
+	}
+
+	return nil, ErrDiscordChampionshipNotFound
+}
+
+// CommandStandings outputs the top drivers in each class of a championship
+func (dm *DiscordManager) CommandStandings(ctx *DiscordCommandContext, args string) (string, error) {
+	if args == "" {
+		return "Usage: `!standings <championship>`", nil
+	}
+
+	championship, err := dm.findChampionship(args)
+
+	if err == ErrDiscordChampionshipNotFound {
+		return fmt.Sprintf("I couldn't find a championship called %s", args), nil
+	} else if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	msg := fmt.Sprintf("**%s** standings\n", championship.Name)
+
+	for _, class := range championship.Classes {
+		if len(championship.Classes) > 1 {
+			msg += fmt.Sprintf("\n__%s__\n", class.Name)
+		}
+
+		standings := class.Standings(championship, championship.Events)
+
+		if len(standings) == 0 {
+			msg += "No points have been scored yet\n"
+			continue
+		}
+
+		for i, standing := range standings {
+			if i == discordStandingsCount {
+				break
+			}
+
+			msg += fmt.Sprintf("%d. %s - %s pts\n", i+1, driverName(standing.Car.Driver.Name), strconv.FormatFloat(standing.Points, 'f', -1, 64))
+		}
+	}
+
+	if config != nil && config.HTTP.BaseURL != "" {
+		msg += fmt.Sprintf("\n%s/championship/%s", config.HTTP.BaseURL, championship.ID.String())
+	}
+
+	return msg, nil
+}
+
+// CommandResults outputs a summary of the latest session results, using the results notification template
+func (dm *DiscordManager) CommandResults(ctx *DiscordCommandContext, args string) (string, error) {
+	if args != "" && !strings.EqualFold(args, "latest") {
+		return "Usage: `!results latest`", nil
+	}
+
+	results, _, err := listResults(0)
+
+	if err != nil && err != ErrResultsPageNotFound {
+		return "A server error occurred, please try again later", err
+	}
+
+	if len(results) == 0 {
+		return "There are no results yet", nil
+	}
+
+	summary := NewResultsSummary(&results[0])
+
+	title, msg, err := resultsNotification(ctx.ServerOpts, summary)
+
+	if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	msg = fmt.Sprintf("**%s**\n%s", title, msg)
+
+	if config != nil && config.HTTP.BaseURL != "" {
+		msg += fmt.Sprintf("\n\n%s/results/%s", config.HTTP.BaseURL, summary.SessionFile)
+	}
+
+	return msg, nil
+}
+
+// linkedSteamGUID is the Steam GUID that a Discord user has linked to their account, if any.
+func (dm *DiscordManager) linkedSteamGUID(discordUserID string) (string, error) {
+	return findGUIDForDiscordUser(dm.store, discordUserID)
+}
+
+// CommandLink sends a Discord user a one-time code in a direct message, which they enter in the driver portal to link
+// their Discord account to their Steam GUID. Linking in the driver portal means that drivers have to sign in with
+// Steam, so they can't link somebody else's GUID.
+func (dm *DiscordManager) CommandLink(ctx *DiscordCommandContext, args string) (string, error) {
+	code, err := newDiscordLinkCode(ctx.UserID)
+
+	if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	msg := fmt.Sprintf("Your link code is **%s**. Sign in to the Driver Portal with Steam and enter it under 'Your Notifications' within %s to get direct messages about your sign ups, events and penalties.", code, durafmt.Parse(discordLinkCodeExpiry).String())
+
+	if config != nil && config.HTTP.BaseURL != "" {
+		msg += fmt.Sprintf("\n\n%s/driver", config.HTTP.BaseURL)
+	}
+
+	if err := dm.SendDirectMessage(ctx.UserID, "Link your Discord account", msg); err != nil {
+		logrus.WithError(err).Errorf("Could not send Discord link code to user: %s", ctx.UserID)
+		return "I couldn't send you a direct message. Please allow direct messages from server members and try again.", nil
+	}
+
+	return "I've sent you a direct message with your link code.", nil
+}
+
+// championshipManager is used for championship sign ups, which are the same on every server.
+func (dm *DiscordManager) championshipManager() *ChampionshipManager {
+	if dm.multiServerManager == nil {
+		return nil
+	}
+
+	for _, server := range dm.multiServerManager.Servers() {
+		return server.ChampionshipManager
+	}
+
+	return nil
+}
+
+// CommandSignUp signs a Discord user up to a championship with the Steam GUID they have linked in the driver portal.
+// Sign up forms which need more than a GUID (reCAPTCHA or extra questions) must be filled in on the website.
+func (dm *DiscordManager) CommandSignUp(ctx *DiscordCommandContext, args string) (string, error) {
+	if args == "" {
+		return "Usage: `!signup <championship>`", nil
+	}
+
+	guid, err := dm.linkedSteamGUID(ctx.UserID)
+
+	if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	if guid == "" {
+		return "Please link your Discord account to your Steam account first. Use `!link` to get a link code for the Driver Portal.", nil
+	}
+
+	championship, err := dm.findChampionship(args)
+
+	if err == ErrDiscordChampionshipNotFound {
+		return fmt.Sprintf("I couldn't find a championship called %s", args), nil
+	} else if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	if !championship.SignUpForm.Enabled {
+		return fmt.Sprintf("Sign ups are not open for %s", championship.Name), nil
+	}
+
+	if !championship.SignUpAvailable() {
+		return fmt.Sprintf("Sorry, %s is full", championship.Name), nil
+	}
+
+	if (config != nil && config.Championships.RecaptchaConfig.SecretKey != "") || len(championship.SignUpForm.ExtraFields) > 0 {
+		return dm.signUpFormMessage(championship, "needs a few more details than I can ask for"), nil
+	}
+
+	for _, response := range championship.SignUpForm.Responses {
+		if response.GUID == guid {
+			// sign ups from the website may have a chosen car and answers, which mustn't be replaced.
+			return fmt.Sprintf("You have already signed up to %s", championship.Name), nil
+		}
+	}
+
+	prefs, err := loadDriverNotificationPreferences(dm.store, guid)
+
+	if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	if championship.SignUpForm.AskForEmail && prefs.Email == "" {
+		return dm.signUpFormMessage(championship, "needs your email address"), nil
+	}
+
+	championshipManager := dm.championshipManager()
+
+	if championshipManager == nil {
+		return "A server error occurred, please try again later", ErrDiscordServerNotFound
+	}
+
+	signUpResponse := &ChampionshipSignUpResponse{
+		Created:   time.Now(),
+		Name:      ctx.UserName,
+		GUID:      guid,
+		Questions: make(map[string]string),
+		Status:    ChampionshipEntrantPending,
+	}
+
+	if championship.SignUpForm.AskForEmail {
+		signUpResponse.Email = prefs.Email
+	}
+
+	if err := validateChampionshipSignUp(championship, signUpResponse); err != nil {
+		if validationError, ok := err.(ValidationError); ok {
+			return string(validationError), nil
+		}
+
+		return "A server error occurred, please try again later", err
+	}
+
+	// a car can't be chosen from Discord, so the driver is put in the first free slot.
+	if _, err := championshipManager.SignUp(championship, signUpResponse, true); err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	switch signUpResponse.Status {
+	case ChampionshipEntrantAccepted:
+		return fmt.Sprintf("You have been entered into %s, see you on track!", championship.Name), nil
+	case ChampionshipEntrantRejected:
+		return fmt.Sprintf("Sorry, there are no free slots left in %s", championship.Name), nil
+	default:
+		return fmt.Sprintf("Thanks for signing up to %s! Your sign up will be reviewed by an admin.", championship.Name), nil
+	}
+}
+
+// signUpFormMessage asks a driver to sign up to a championship on the website instead of from Discord.
+func (dm *DiscordManager) signUpFormMessage(championship *Championship, reason string) string {
+	msg := fmt.Sprintf("The sign up form for %s %s, please sign up on the website.", championship.Name, reason)
+
+	if config != nil && config.HTTP.BaseURL != "" {
+		msg += fmt.Sprintf("\n\n%s/championship/%s/sign-up", config.HTTP.BaseURL, championship.ID.String())
+	}
+
+	return msg
+}
+
+// runningServers lists the servers which are running an event.
+func (dm *DiscordManager) runningServers() []*Server {
+	var servers []*Server
+
+	if dm.multiServerManager == nil {
+		return servers
+	}
+
+	for _, server := range dm.multiServerManager.Servers() {
+		if server.Process.IsRunning() {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers
+}
+
+// findRunningServer finds a running server by its name. If only one server is running, name can be left empty.
+func (dm *DiscordManager) findRunningServer(name string) (*Server, error) {
+	servers := dm.runningServers()
+
+	if name == "" && len(servers) == 1 {
+		return servers[0], nil
+	}
+
+	for _, server := range servers {
+		if name != "" && strings.Contains(strings.ToLower(server.ServerConfig.Name), strings.ToLower(name)) {
+			return server, nil
+		}
+	}
+
+	return nil, ErrDiscordServerNotFound
+}
+
+func (dm *DiscordManager) runningServerNames() string {
+	var names []string
+
+	for _, server := range dm.runningServers() {
+		names = append(names, server.ServerConfig.Name)
+	}
+
+	if len(names) == 0 {
+		return "No servers are running"
+	}
+
+	return "Running servers: " + strings.Join(names, ", ")
+}
+
+// CommandWhoIsOn outputs the drivers connected to each running server
+func (dm *DiscordManager) CommandWhoIsOn(ctx *DiscordCommandContext, args string) (string, error) {
+	servers := dm.runningServers()
+
+	if len(servers) == 0 {
+		return "No servers are running", nil
+	}
+
+	msg := ""
+
+	for _, server := range servers {
+		msg += fmt.Sprintf("**%s** - %s\n", server.ServerConfig.Name, server.Process.Event().EventName())
+
+		if server.RaceControl.ConnectedDrivers.Len() == 0 {
+			msg += "No drivers are connected\n\n"
+			continue
+		}
+
+		_ = server.RaceControl.ConnectedDrivers.Each(func(driverGUID udp.DriverGUID, driver *RaceControlDriver) error {
+			msg += fmt.Sprintf("%s (%s)\n", driverName(driver.CarInfo.DriverName), prettifyName(driver.CarInfo.CarModel, true))
+			return nil
+		})
+
+		msg += "\n"
+	}
+
+	return msg, nil
+}
+
+// nextScheduledEvent finds the scheduled event which starts soonest.
+func (dm *DiscordManager) nextScheduledEvent() (ScheduledEvent, error) {
+	scheduled, err := dm.scheduledRacesManager.getScheduledRaces()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var next ScheduledEvent
+
+	for _, event := range scheduled {
+		if next == nil || event.GetScheduledTime().Before(next.GetScheduledTime()) {
+			next = event
+		}
+	}
+
+	if next == nil {
+		return nil, ErrDiscordNoScheduledEvents
+	}
+
+	return next, nil
+}
+
+// CommandNextRace outputs the next scheduled event
+func (dm *DiscordManager) CommandNextRace(ctx *DiscordCommandContext, args string) (string, error) {
+	event, err := dm.nextScheduledEvent()
+
+	if err == ErrDiscordNoScheduledEvents {
+		return "There are no scheduled events", nil
+	} else if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	raceSetup := event.GetRaceSetup()
+
+	msg := fmt.Sprintf("Next event: **%s**\n", event.EventName())
+	msg += fmt.Sprintf("Date: %s\n", event.GetScheduledTime().Format("Mon, 02 Jan 2006 15:04:05 MST"))
+	msg += fmt.Sprintf("Track: %s\n", trackSummary(raceSetup.Track, raceSetup.TrackLayout))
+	msg += fmt.Sprintf("Cars: %s\n", carList(raceSetup.Cars))
+
+	return msg, nil
+}
+
+// CommandStart starts the next scheduled event straight away
+func (dm *DiscordManager) CommandStart(ctx *DiscordCommandContext, args string) (string, error) {
+	if dm.multiServerManager == nil {
+		return "A server error occurred, please try again later", ErrDiscordServerNotFound
+	}
+
+	event, err := dm.nextScheduledEvent()
+
+	if err == ErrDiscordNoScheduledEvents {
+		return "There are no scheduled events to start", nil
+	} else if err != nil {
+		return "A server error occurred, please try again later", err
+	}
+
+	schedulers := dm.multiServerManager.Schedulers()
+
+	if len(schedulers) == 0 {
+		return "A server error occurred, please try again later", ErrDiscordServerNotFound
+	}
+
+	if err := schedulers[0].StartNow(event); err != nil {
+		return fmt.Sprintf("Could not start %s, please try again later", event.EventName()), err
+	}
+
+	return fmt.Sprintf("Starting %s now", event.EventName()), nil
+}
+
+// CommandStop stops the event running on a server
+func (dm *DiscordManager) CommandStop(ctx *DiscordCommandContext, args string) (string, error) {
+	server, err := dm.findRunningServer(args)
+
+	if err != nil {
+		return "Please choose a server. " + dm.runningServerNames(), nil
+	}
+
+	if err := server.StopEvent(); err != nil {
+		return fmt.Sprintf("Could not stop %s, please try again later", server.ServerConfig.Name), err
+	}
+
+	return fmt.Sprintf("%s has been stopped", server.ServerConfig.Name), nil
+}
+
+// CommandRestart restarts the event running on a server
+func (dm *DiscordManager) CommandRestart(ctx *DiscordCommandContext, args string) (string, error) {
+	server, err := dm.findRunningServer(args)
+
+	if err != nil {
+		return "Please choose a server. " + dm.runningServerNames(), nil
+	}
+
+	if err := server.RestartEvent(); err != nil {
+		return fmt.Sprintf("Could not restart %s, please try again later", server.ServerConfig.Name), err
+	}
+
+	return fmt.Sprintf("%s has been restarted", server.ServerConfig.Name), nil
+}
+
+// CommandKick kicks a connected driver by name
+func (dm *DiscordManager) CommandKick(ctx *DiscordCommandContext, args string) (string, error) {
+	if args == "" {
+		return "Usage: `!kick <driver>`", nil
+	}
+
+	type connectedDriver struct {
+		server *Server
+		driver *RaceControlDriver
+	}
+
+	var exactMatches, partialMatches []connectedDriver
+
+	for _, server := range dm.runningServers() {
+		server := server
+
+		_ = server.RaceControl.ConnectedDrivers.Each(func(driverGUID udp.DriverGUID, driver *RaceControlDriver) error {
+			if strings.EqualFold(driver.CarInfo.DriverName, args) {
+				exactMatches = append(exactMatches, connectedDriver{server, driver})
+			} else if strings.Contains(strings.ToLower(driver.CarInfo.DriverName), strings.ToLower(args)) {
+				partialMatches = append(partialMatches, connectedDriver{server, driver})
+			}
+
+			return nil
+		})
+	}
+
+	matches := exactMatches
+
+	if len(matches) == 0 {
+		matches = partialMatches
+	}
+
+	switch {
+	case len(matches) == 0:
+		return fmt.Sprintf("I couldn't find a connected driver called %s", args), nil
+	case len(matches) > 1:
+		return fmt.Sprintf("More than one connected driver is called %s, please use their full name", args), nil
+	}
+
+	match := matches[0]
+
+	if err := match.server.Process.SendUDPMessage(udp.NewKickUser(uint8(match.driver.CarInfo.CarID))); err != nil {
+		return fmt.Sprintf("Could not kick %s, please try again later", match.driver.CarInfo.DriverName), err
+	}
+
+	return fmt.Sprintf("%s has been kicked from %s", match.driver.CarInfo.DriverName, match.server.ServerConfig.Name), nil
+}
@@ -0,0 +1,149 @@
+package servermanager
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestDiscordCommandRegistry(t *testing.T) {
+	registry := NewDiscordCommandRegistry()
+
+	registry.Register(&DiscordCommand{
+		Name: "standings",
+		Options: []DiscordCommandOption{
+			{Name: "championship", Required: true},
+		},
+	})
+
+	registry.Register(&DiscordCommand{
+		Name: "stop",
+		Options: []DiscordCommandOption{
+			{Name: "server"},
+		},
+		AdminOnly: true,
+	})
+
+	registry.Register(&DiscordCommand{Name: "nextrace"})
+
+	t.Run("Find is case insensitive", func(t *testing.T) {
+		command, ok := registry.Find("Standings")
+
+		if !ok || command.Name != "standings" {
+			t.Log("Expected to find the standings command")
+			t.Fail()
+		}
+
+		if _, ok := registry.Find("unknown"); ok {
+			t.Log("Expected an unknown command to not be found")
+			t.Fail()
+		}
+	})
+
+	t.Run("List is sorted by name", func(t *testing.T) {
+		commands := registry.List()
+
+		if len(commands) != 3 || commands[0].Name != "nextrace" || commands[1].Name != "standings" || commands[2].Name != "stop" {
+			t.Logf("Unexpected command list: %v", commands)
+			t.Fail()
+		}
+	})
+
+	t.Run("Usage shows required and optional options", func(t *testing.T) {
+		standings, _ := registry.Find("standings")
+		stop, _ := registry.Find("stop")
+
+		if standings.Usage() != "!standings <championship>" || stop.Usage() != "!stop [server]" {
+			t.Logf("Unexpected usage: %s, %s", standings.Usage(), stop.Usage())
+			t.Fail()
+		}
+	})
+}
+
+func TestTruncateDiscordMessage(t *testing.T) {
+	short := strings.Repeat("🏁", discordMessageLimit)
+
+	if truncateDiscordMessage(short) != short {
+		t.Log("Expected a message of exactly the limit to not be truncated")
+		t.Fail()
+	}
+
+	long := strings.Repeat("🏁", discordMessageLimit+10)
+	truncated := truncateDiscordMessage(long)
+
+	if !utf8.ValidString(truncated) {
+		t.Log("Expected the truncated message to be valid UTF-8")
+		t.Fail()
+	}
+
+	if utf8.RuneCountInString(truncated) != discordMessageLimit || !strings.HasSuffix(truncated, "...") {
+		t.Logf("Expected the truncated message to be %d characters ending in '...', got: %d characters", discordMessageLimit, utf8.RuneCountInString(truncated))
+		t.Fail()
+	}
+}
+
+func TestDiscordCommandReply(t *testing.T) {
+	if err := InitLocalisation(NewFilesystemLocalisationLoader("cmd/server-manager/localisation")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer InitLocalisation(nil)
+
+	serverOpts := &GlobalServerConfig{Name: "Sunday League", NotificationLanguage: "es"}
+
+	data := &NotificationTemplateData{
+		ScheduledEvents: []NotificationTemplateScheduledEvent{
+			{Date: time.Date(2020, 6, 1, 19, 0, 0, 0, time.UTC), TrackInfo: "Barcelona (GP)", CarList: "Ferrari 488 GT3"},
+		},
+	}
+
+	reply, err := discordCommandReply(serverOpts, NotificationDiscordSchedule, data)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !strings.HasPrefix(reply, "**Próximos eventos en el servidor Sunday League**\n") {
+		t.Logf("Expected the schedule reply to start with its title in Spanish, got: %s", reply)
+		t.Fail()
+	}
+
+	if !strings.Contains(reply, "Fecha: 01/06/2020 19:00:00 UTC") || !strings.Contains(reply, "Circuito: Barcelona (GP)") || !strings.Contains(reply, "Coche(s): Ferrari 488 GT3") {
+		t.Logf("Expected the schedule reply to list the event in Spanish, got: %s", reply)
+		t.Fail()
+	}
+
+	t.Run("Nothing scheduled", func(t *testing.T) {
+		reply, err := discordCommandReply(serverOpts, NotificationDiscordSessions, &NotificationTemplateData{})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if reply != "**Próximas sesiones en el servidor Sunday League**\nNo hay nada programado para la próxima semana" {
+			t.Logf("Unexpected sessions reply: %s", reply)
+			t.Fail()
+		}
+	})
+
+	t.Run("Custom template", func(t *testing.T) {
+		serverOpts := &GlobalServerConfig{Name: "Sunday League"}
+		serverOpts.NotificationTemplates.Set(NotificationDiscordSchedule, "{{ len .ScheduledEvents }} events on {{ .ServerName }}")
+
+		reply, err := discordCommandReply(serverOpts, NotificationDiscordSchedule, data)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if reply != "**1 events on Sunday League**\n" {
+			t.Logf("Expected the reply to use the template in the server options, got: %s", reply)
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,344 @@
+package servermanager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDriverNotificationPreferencesNotFound = errors.New("servermanager: driver notification preferences not found")
+	ErrInvalidDiscordLinkCode                = errors.New("servermanager: invalid or expired discord link code")
+
+	discordUserIDRegex = regexp.MustCompile("^[0-9]{15,20}$")
+)
+
+// DriverNotificationPreferences are the personal notifications that a driver receives, either as a Discord direct
+// message or by email. They are keyed by the driver's Steam GUID.
+type DriverNotificationPreferences struct {
+	GUID          string
+	DiscordUserID string
+	Email         string
+
+	SignUpApproved bool
+	EventReminders bool
+	Penalties      bool
+
+	Updated time.Time
+}
+
+// NewDriverNotificationPreferences creates preferences for a driver which receive every personal notification, once
+// the driver has linked a Discord user or email address.
+func NewDriverNotificationPreferences(guid string) *DriverNotificationPreferences {
+	return &DriverNotificationPreferences{
+		GUID:           guid,
+		SignUpApproved: true,
+		EventReminders: true,
+		Penalties:      true,
+	}
+}
+
+// Receives indicates whether the driver wants to be notified about a given type of event.
+func (p *DriverNotificationPreferences) Receives(eventType NotificationEventType) bool {
+	switch eventType {
+	case NotificationSignUpApproved:
+		return p.SignUpApproved
+	case NotificationReminder:
+		return p.EventReminders
+	case NotificationPenalty:
+		return p.Penalties
+	default:
+		return false
+	}
+}
+
+// Validate checks that the Discord user ID and email address look sensible.
+func (p *DriverNotificationPreferences) Validate() error {
+	if p.DiscordUserID != "" && !discordUserIDRegex.MatchString(p.DiscordUserID) {
+		return ValidationError("Please enter a valid Discord user ID. You can find it by enabling Developer Mode in Discord, then right clicking your name and choosing 'Copy ID'.")
+	}
+
+	if p.Email != "" && (!strings.Contains(p.Email, "@") || strings.ContainsAny(p.Email, " ,\r\n")) {
+		return ValidationError("Please enter a valid email address.")
+	}
+
+	return nil
+}
+
+// loadDriverNotificationPreferences loads the preferences of a driver, or the defaults if the driver has not saved
+// any yet.
+func loadDriverNotificationPreferences(store Store, guid string) (*DriverNotificationPreferences, error) {
+	prefs, err := store.LoadDriverNotificationPreferences(guid)
+
+	if err == ErrDriverNotificationPreferencesNotFound {
+		return NewDriverNotificationPreferences(guid), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// discordLinkCodeExpiry is how long a Discord user has to enter their link code in the driver portal.
+const discordLinkCodeExpiry = time.Minute * 15
+
+type discordLinkCode struct {
+	discordUserID string
+	expires       time.Time
+}
+
+var (
+	// discordLinkCodes are the one-time codes that Discord users are sent by the !link command. A Discord user is
+	// only linked to a Steam GUID once the code is entered in the driver portal, where drivers sign in with Steam, so
+	// that nobody can link a GUID which isn't theirs.
+	discordLinkCodes      = make(map[string]discordLinkCode)
+	discordLinkCodesMutex sync.Mutex
+)
+
+// newDiscordLinkCode creates a one-time code for a Discord user to link their account in the driver portal. Any
+// previous code for the Discord user is replaced.
+func newDiscordLinkCode(discordUserID string) (string, error) {
+	buf := make([]byte, 4)
+
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", err
+	}
+
+	code := strings.ToUpper(hex.EncodeToString(buf))
+
+	discordLinkCodesMutex.Lock()
+	defer discordLinkCodesMutex.Unlock()
+
+	for existingCode, linkCode := range discordLinkCodes {
+		if linkCode.discordUserID == discordUserID || time.Now().After(linkCode.expires) {
+			delete(discordLinkCodes, existingCode)
+		}
+	}
+
+	discordLinkCodes[code] = discordLinkCode{
+		discordUserID: discordUserID,
+		expires:       time.Now().Add(discordLinkCodeExpiry),
+	}
+
+	return code, nil
+}
+
+// consumeDiscordLinkCode returns the Discord user that a link code was created for. Each code can only be used once.
+func consumeDiscordLinkCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	discordLinkCodesMutex.Lock()
+	defer discordLinkCodesMutex.Unlock()
+
+	linkCode, ok := discordLinkCodes[code]
+
+	if !ok {
+		return "", ErrInvalidDiscordLinkCode
+	}
+
+	delete(discordLinkCodes, code)
+
+	if time.Now().After(linkCode.expires) {
+		return "", ErrInvalidDiscordLinkCode
+	}
+
+	return linkCode.discordUserID, nil
+}
+
+// findGUIDForDiscordUser finds the Steam GUID which a Discord user has linked. An empty string is returned if the
+// Discord user has not linked a GUID.
+func findGUIDForDiscordUser(store Store, discordUserID string) (string, error) {
+	allPrefs, err := store.ListDriverNotificationPreferences()
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, prefs := range allPrefs {
+		if prefs.DiscordUserID == discordUserID {
+			return prefs.GUID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// entryListGUIDs lists the GUIDs of every driver in an entry list, including each driver of a shared car.
+func entryListGUIDs(entryList EntryList) []string {
+	var guids []string
+
+	seen := make(map[string]bool)
+
+	for _, entrant := range entryList {
+		for _, guid := range strings.Split(entrant.GUID, ";") {
+			if guid = strings.TrimSpace(guid); guid == "" || seen[guid] {
+				continue
+			}
+
+			seen[guid] = true
+			guids = append(guids, guid)
+		}
+	}
+
+	return guids
+}
+
+// SendDriverMessage sends a notification to a single driver, using their Discord user and email address. Nothing is
+// sent if the driver has not linked either, or has turned off notifications of this type.
+func (nm *NotificationManager) SendDriverMessage(guid string, eventType NotificationEventType, title, msg string) error {
+	if nm.testing {
+		return nil
+	}
+
+	prefs, err := nm.store.LoadDriverNotificationPreferences(guid)
+
+	if err == ErrDriverNotificationPreferencesNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !prefs.Receives(eventType) {
+		return nil
+	}
+
+	var failedChannels []string
+
+	if prefs.DiscordUserID != "" {
+		if err := nm.discordManager.SendDirectMessage(prefs.DiscordUserID, title, msg); err != nil {
+			logrus.WithError(err).Errorf("couldn't send discord direct message to driver: %s", guid)
+			failedChannels = append(failedChannels, "Discord")
+		}
+	}
+
+	if prefs.Email != "" {
+		serverOpts, err := nm.store.LoadServerOptions()
+
+		if err != nil {
+			return err
+		}
+
+		if serverOpts.EmailNotifications.Host != "" {
+			emailConfig := serverOpts.EmailNotifications
+			emailConfig.To = prefs.Email
+
+			channel := &EmailNotificationChannel{emailConfig}
+
+			err := channel.Send(&Notification{
+				Type:       eventType,
+				Title:      title,
+				Message:    msg,
+				ServerName: serverOpts.Name,
+				Time:       time.Now(),
+			})
+
+			if err != nil {
+				logrus.WithError(err).Errorf("couldn't send email to driver: %s", guid)
+				failedChannels = append(failedChannels, "Email")
+			}
+		}
+	}
+
+	if len(failedChannels) > 0 {
+		return fmt.Errorf("servermanager: couldn't send driver notification to: %s", strings.Join(failedChannels, ", "))
+	}
+
+	return nil
+}
+
+// sendDriverMessages sends a notification to each of the given drivers. Errors are logged rather than returned, so
+// that one driver's notification failing doesn't stop the others.
+func (nm *NotificationManager) sendDriverMessages(guids []string, eventType NotificationEventType, title, msg string) {
+	for _, guid := range guids {
+		if err := nm.SendDriverMessage(guid, eventType, title, msg); err != nil {
+			logrus.WithError(err).Errorf("couldn't send notification to driver: %s", guid)
+		}
+	}
+}
+
+// SendSignUpApprovedMessage lets a driver know that their sign up to a championship has been accepted.
+func (nm *NotificationManager) SendSignUpApprovedMessage(championship *Championship, guid string) error {
+	data := &NotificationTemplateData{
+		EventName:    championship.Name,
+		Championship: championship,
+	}
+
+	if nextEvent := championshipNextEvent(championship); nextEvent != nil {
+		nm.addEventTemplateData(data, nextEvent.RaceSetup)
+		data.ChampionshipEvent = nextEvent
+		data.Date = nextEvent.Scheduled
+	}
+
+	return nm.sendDriverTemplateMessage(guid, NotificationSignUpApproved, data)
+}
+
+// championshipNextEvent is the first event of a championship which hasn't been completed.
+func championshipNextEvent(championship *Championship) *ChampionshipEvent {
+	for _, event := range championship.Events {
+		if !event.Completed() {
+			return event
+		}
+	}
+
+	return nil
+}
+
+// SendPenaltyMessage lets a driver know that they have been given a penalty. A penalty of zero is a
+// disqualification. The reason is the ID of a localised message explaining the penalty, e.g.
+// "PenaltyReasonMandatoryPitStop".
+func (nm *NotificationManager) SendPenaltyMessage(guid string, sessionType, track string, penalty time.Duration, reason string) error {
+	data := &NotificationTemplateData{
+		Track:         track,
+		TrackInfo:     track,
+		SessionType:   sessionType,
+		Disqualified:  penalty == 0,
+		PenaltyReason: reason,
+	}
+
+	if penalty != 0 {
+		data.Penalty = penalty.String()
+	}
+
+	return nm.sendDriverTemplateMessage(guid, NotificationPenalty, data)
+}
+
+// sendDriverTemplateMessage writes a notification to a driver using the notification template for its type.
+func (nm *NotificationManager) sendDriverTemplateMessage(guid string, eventType NotificationEventType, data *NotificationTemplateData) error {
+	serverOpts, err := nm.store.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	title, msg, err := renderNotification(serverOpts, eventType, data)
+
+	if err != nil {
+		return err
+	}
+
+	return nm.SendDriverMessage(guid, eventType, title, msg)
+}
+
+// notifyPenalty sends a direct message to a driver who has been given a penalty by race control.
+func (rc *RaceControl) notifyPenalty(guid string, penalty time.Duration, reason string) {
+	if rc.notificationManager == nil {
+		return
+	}
+
+	sessionType := rc.SessionInfo.Type.String()
+	track := trackSummary(rc.SessionInfo.Track, rc.SessionInfo.TrackConfig)
+
+	go func() {
+		if err := rc.notificationManager.SendPenaltyMessage(guid, sessionType, track, penalty, reason); err != nil {
+			logrus.WithError(err).Errorf("Could not send penalty notification to driver: %s", guid)
+		}
+	}()
+}
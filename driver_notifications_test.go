@@ -0,0 +1,150 @@
+package servermanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDriverNotificationPreferences_Receives(t *testing.T) {
+	prefs := NewDriverNotificationPreferences("76561198000000000")
+	prefs.Penalties = false
+
+	if !prefs.Receives(NotificationSignUpApproved) || !prefs.Receives(NotificationReminder) {
+		t.Log("Expected preferences to receive sign up approved and reminder notifications")
+		t.Fail()
+	}
+
+	if prefs.Receives(NotificationPenalty) {
+		t.Log("Expected preferences to not receive penalty notifications once turned off")
+		t.Fail()
+	}
+
+	if prefs.Receives(NotificationRaceStart) || prefs.Receives(NotificationResultsPosted) {
+		t.Log("Expected preferences to not receive notifications which are only sent to channels")
+		t.Fail()
+	}
+}
+
+func TestDriverNotificationPreferences_Validate(t *testing.T) {
+	for _, testCase := range []struct {
+		discordUserID, email string
+		valid                bool
+	}{
+		{"", "", true},
+		{"123456789012345678", "driver@example.com", true},
+		{"not-a-user-id", "", false},
+		{"", "driver.example.com", false},
+		{"", "driver1@example.com, driver2@example.com", false},
+	} {
+		prefs := NewDriverNotificationPreferences("76561198000000000")
+		prefs.DiscordUserID = testCase.discordUserID
+		prefs.Email = testCase.email
+
+		err := prefs.Validate()
+
+		if testCase.valid && err != nil {
+			t.Logf("Expected Discord user ID: %q and email: %q to be valid, got: %s", testCase.discordUserID, testCase.email, err)
+			t.Fail()
+		} else if !testCase.valid && err == nil {
+			t.Logf("Expected Discord user ID: %q and email: %q to be invalid", testCase.discordUserID, testCase.email)
+			t.Fail()
+		}
+	}
+}
+
+func TestDriverPortalManager_LinkDiscordUser(t *testing.T) {
+	store := NewJSONStore(filepath.Join(os.TempDir(), "asm-driver-notifications-store"), filepath.Join(os.TempDir(), "asm-driver-notifications-store-shared"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-driver-notifications-store"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-driver-notifications-store-shared"))
+
+	dpm := NewDriverPortalManager(nil, store)
+
+	const (
+		victimGUID      = "76561198000000001"
+		victimDiscord   = "111111111111111111"
+		attackerDiscord = "222222222222222222"
+	)
+
+	victimPrefs := NewDriverNotificationPreferences(victimGUID)
+	victimPrefs.DiscordUserID = victimDiscord
+
+	if err := store.UpsertDriverNotificationPreferences(victimPrefs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	t.Run("Link codes are required", func(t *testing.T) {
+		if _, err := newDiscordLinkCode(attackerDiscord); err != nil {
+			t.Error(err)
+			return
+		}
+
+		// the attacker can't link the victim's GUID by guessing a code
+		prefs, _ := dpm.NotificationPreferences(victimGUID)
+
+		if _, ok := dpm.UpdateNotificationPreferences(prefs, "00000000").(ValidationError); !ok {
+			t.Log("Expected an invalid link code to return a validation error")
+			t.Fail()
+		}
+
+		if linkedGUID, _ := findGUIDForDiscordUser(store, attackerDiscord); linkedGUID != "" {
+			t.Logf("Expected the attacker to not be linked to any GUID, got: %s", linkedGUID)
+			t.Fail()
+		}
+
+		if prefs, _ := store.LoadDriverNotificationPreferences(victimGUID); prefs.DiscordUserID != victimDiscord {
+			t.Logf("Expected the victim to still be linked to their own Discord user, got: %s", prefs.DiscordUserID)
+			t.Fail()
+		}
+	})
+
+	t.Run("Link codes can only be used once", func(t *testing.T) {
+		newDiscord := "333333333333333333"
+
+		code, err := newDiscordLinkCode(newDiscord)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		prefs, _ := dpm.NotificationPreferences(victimGUID)
+
+		if err := dpm.UpdateNotificationPreferences(prefs, code); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if linkedGUID, _ := findGUIDForDiscordUser(store, newDiscord); linkedGUID != victimGUID {
+			t.Logf("Expected the Discord user to be linked to the driver who entered the code, got: %s", linkedGUID)
+			t.Fail()
+		}
+
+		prefs, _ = dpm.NotificationPreferences("76561198000000002")
+
+		if _, ok := dpm.UpdateNotificationPreferences(prefs, code).(ValidationError); !ok {
+			t.Log("Expected a used link code to return a validation error")
+			t.Fail()
+		}
+	})
+
+	t.Run("Link codes expire", func(t *testing.T) {
+		code, err := newDiscordLinkCode(attackerDiscord)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		discordLinkCodesMutex.Lock()
+		discordLinkCodes[code] = discordLinkCode{discordUserID: attackerDiscord, expires: time.Now().Add(-time.Minute)}
+		discordLinkCodesMutex.Unlock()
+
+		if _, err := consumeDiscordLinkCode(code); err != ErrInvalidDiscordLinkCode {
+			t.Logf("Expected an expired link code to be invalid, got: %v", err)
+			t.Fail()
+		}
+	})
+}
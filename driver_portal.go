@@ -0,0 +1,651 @@
+package servermanager
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sessionDriverGUID              = "driver_guid"
+	requestContextKeyDriverGUID    = "driver_guid"
+	driverPortalUpcomingEventLimit = 5
+)
+
+var (
+	ErrDriverNotInChampionship    = errors.New("servermanager: driver is not an entrant in this championship")
+	ErrDriverCannotChangeCar      = errors.New("servermanager: no free slot is available in the requested car")
+	ErrDriverCarNotInChampionship = errors.New("servermanager: the requested car is not in this championship")
+)
+
+// DriverPortalManager provides self-service operations for drivers who have logged in with Steam. Drivers are
+// identified purely by their Steam GUID, and can only see and modify Championships in which they are an Entrant.
+type DriverPortalManager struct {
+	championshipManager *ChampionshipManager
+	store               Store
+}
+
+func NewDriverPortalManager(championshipManager *ChampionshipManager, store Store) *DriverPortalManager {
+	return &DriverPortalManager{
+		championshipManager: championshipManager,
+		store:               store,
+	}
+}
+
+// DriverChampionship is a Championship that a driver is entered in, along with their Entrant and Class.
+type DriverChampionship struct {
+	Championship *Championship
+	Class        *ChampionshipClass
+	Entrant      *Entrant
+
+	UpcomingEvents []*ChampionshipEvent
+}
+
+// Setups returns the setups available for the driver's car, keyed by track.
+func (dc *DriverChampionship) Setups() map[string][]string {
+	setups, err := ListSetupsForCar(dc.Entrant.Model)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not list setups for car: %s", dc.Entrant.Model)
+		return nil
+	}
+
+	return setups
+}
+
+// ListChampionshipsForDriver finds all Championships where the given GUID is an Entrant.
+func (dpm *DriverPortalManager) ListChampionshipsForDriver(guid string) ([]*DriverChampionship, error) {
+	championships, err := dpm.championshipManager.ListChampionships()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*DriverChampionship
+
+	for _, championship := range championships {
+		class, entrant := findEntrantInChampionship(championship, guid)
+
+		if entrant == nil {
+			continue
+		}
+
+		driverChampionship := &DriverChampionship{
+			Championship: championship,
+			Class:        class,
+			Entrant:      entrant,
+		}
+
+		for _, event := range championship.Events {
+			if event.Completed() {
+				continue
+			}
+
+			event.championship = championship
+			driverChampionship.UpcomingEvents = append(driverChampionship.UpcomingEvents, event)
+
+			if len(driverChampionship.UpcomingEvents) >= driverPortalUpcomingEventLimit {
+				break
+			}
+		}
+
+		out = append(out, driverChampionship)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Championship.Updated.After(out[j].Championship.Updated)
+	})
+
+	return out, nil
+}
+
+func findEntrantInChampionship(championship *Championship, guid string) (*ChampionshipClass, *Entrant) {
+	if guid == "" {
+		return nil, nil
+	}
+
+	for _, class := range championship.Classes {
+		for _, entrant := range class.Entrants {
+			if entrant.GUID == guid {
+				return class, entrant
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// UpdateDriverDetails changes the name and team of the driver in every Championship they are entered in, as well as
+// in the autofill entrant list.
+func (dpm *DriverPortalManager) UpdateDriverDetails(guid, name, team string) error {
+	if name == "" {
+		return ValidationError("Please enter a name.")
+	}
+
+	championships, err := dpm.championshipManager.ListChampionships()
+
+	if err != nil {
+		return err
+	}
+
+	for _, championship := range championships {
+		_, entrant := findEntrantInChampionship(championship, guid)
+
+		if entrant == nil {
+			continue
+		}
+
+		entrant.Name = name
+		entrant.Team = team
+
+		for _, response := range championship.SignUpForm.Responses {
+			if response.GUID == guid {
+				response.Name = name
+				response.Team = team
+			}
+		}
+
+		if err := dpm.championshipManager.UpsertChampionship(championship); err != nil {
+			return err
+		}
+	}
+
+	entrants, err := dpm.store.ListEntrants()
+
+	if err != nil {
+		return err
+	}
+
+	for _, entrant := range entrants {
+		if entrant.GUID != guid {
+			continue
+		}
+
+		entrant.Name = name
+		entrant.Team = team
+
+		if err := dpm.store.UpsertEntrant(*entrant); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NotificationPreferences loads the personal notification preferences of a driver.
+func (dpm *DriverPortalManager) NotificationPreferences(guid string) (*DriverNotificationPreferences, error) {
+	return loadDriverNotificationPreferences(dpm.store, guid)
+}
+
+// UpdateNotificationPreferences saves the email address and personal notifications of a driver. If a Discord link
+// code is given, the Discord user who was sent the code by the !link command is linked to the driver.
+func (dpm *DriverPortalManager) UpdateNotificationPreferences(prefs *DriverNotificationPreferences, discordLinkCode string) error {
+	if strings.TrimSpace(discordLinkCode) != "" {
+		discordUserID, err := consumeDiscordLinkCode(discordLinkCode)
+
+		if err == ErrInvalidDiscordLinkCode {
+			return ValidationError("That Discord link code is invalid or has expired. Type !link in the server's Discord to get a new one.")
+		} else if err != nil {
+			return err
+		}
+
+		prefs.DiscordUserID = discordUserID
+	}
+
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+
+	return dpm.store.UpsertDriverNotificationPreferences(prefs)
+}
+
+type driverPortalEntrant struct {
+	name, team, car, skin, guid string
+}
+
+func (d driverPortalEntrant) GetName() string { return d.name }
+func (d driverPortalEntrant) GetTeam() string { return d.team }
+func (d driverPortalEntrant) GetCar() string  { return d.car }
+func (d driverPortalEntrant) GetSkin() string { return d.skin }
+func (d driverPortalEntrant) GetGUID() string { return d.guid }
+
+// ChangeCar moves the driver into a free slot for the given car and skin in a Championship. If the car is unchanged,
+// only the skin is updated.
+func (dpm *DriverPortalManager) ChangeCar(guid, championshipID, car, skin string) error {
+	championship, err := dpm.championshipManager.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	_, entrant := findEntrantInChampionship(championship, guid)
+
+	if entrant == nil {
+		return ErrDriverNotInChampionship
+	}
+
+	classForCar, err := championship.FindClassForCarModel(car)
+
+	if err != nil {
+		return ErrDriverCarNotInChampionship
+	}
+
+	if entrant.Model == car {
+		entrant.Skin = skin
+
+		return dpm.championshipManager.UpsertChampionship(championship)
+	}
+
+	// AddEntrantFromSession clears the driver's current slot before it looks for a new one, so check that a free
+	// slot exists first to leave the driver in their current car if there isn't one.
+	if !classHasFreeSlotForCar(classForCar, car) {
+		return ErrDriverCannotChangeCar
+	}
+
+	potentialEntrant := driverPortalEntrant{
+		name: entrant.Name,
+		team: entrant.Team,
+		car:  car,
+		skin: skin,
+		guid: guid,
+	}
+
+	foundSlot, _, _, err := championship.AddEntrantFromSession(potentialEntrant)
+
+	if err != nil {
+		return err
+	}
+
+	if !foundSlot {
+		return ErrDriverCannotChangeCar
+	}
+
+	return dpm.championshipManager.UpsertChampionship(championship)
+}
+
+func classHasFreeSlotForCar(class *ChampionshipClass, car string) bool {
+	for _, entrant := range class.Entrants {
+		if entrant.Name == "" && entrant.GUID == "" && entrant.Model == car {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetEventWithdrawal withdraws (or re-enters) a driver from a given ChampionshipEvent.
+func (dpm *DriverPortalManager) SetEventWithdrawal(guid, championshipID, eventID string, withdraw bool) error {
+	championship, event, err := dpm.championshipManager.GetChampionshipAndEvent(championshipID, eventID)
+
+	if err != nil {
+		return err
+	}
+
+	if _, entrant := findEntrantInChampionship(championship, guid); entrant == nil {
+		return ErrDriverNotInChampionship
+	}
+
+	if event.InProgress() || event.Completed() {
+		return ValidationError("You can't change your entry for an event which has already started.")
+	}
+
+	if withdraw {
+		event.Withdraw(guid)
+	} else {
+		event.Rejoin(guid)
+	}
+
+	return dpm.championshipManager.UpsertChampionship(championship)
+}
+
+// DriverCanAccessCar indicates whether the driver is entered in any Championship which uses the given car.
+func (dpm *DriverPortalManager) DriverCanAccessCar(guid, car string) (bool, error) {
+	championships, err := dpm.ListChampionshipsForDriver(guid)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, driverChampionship := range championships {
+		for _, validCar := range driverChampionship.Championship.ValidCarIDs() {
+			if validCar == car {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// WriteSkinZip writes the contents of a skin folder to w as a zip archive.
+func (dpm *DriverPortalManager) WriteSkinZip(car, skin string, w io.Writer) error {
+	skinPath := filepath.Join(ServerInstallPath, "content", "cars", car, "skins", skin)
+
+	if _, err := os.Stat(skinPath); err != nil {
+		return err
+	}
+
+	zipWriter := zip.NewWriter(w)
+
+	err := filepath.Walk(skinPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(filepath.Join(skinPath, ".."), path)
+
+		if err != nil {
+			return err
+		}
+
+		f, err := zipWriter.Create(filepath.ToSlash(relativePath))
+
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		_, err = io.Copy(f, file)
+
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+type DriverPortalHandler struct {
+	*BaseHandler
+	SteamLoginHandler
+
+	driverPortalManager *DriverPortalManager
+}
+
+func NewDriverPortalHandler(baseHandler *BaseHandler, driverPortalManager *DriverPortalManager) *DriverPortalHandler {
+	return &DriverPortalHandler{
+		BaseHandler:         baseHandler,
+		driverPortalManager: driverPortalManager,
+	}
+}
+
+// DriverAccessMiddleware requires that a driver has logged in via Steam before accessing the driver portal.
+func (dph *DriverPortalHandler) DriverAccessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		guid, ok := getSession(r).Values[sessionDriverGUID].(string)
+
+		if !ok || guid == "" {
+			AddErrorFlash(w, r, "Please sign in with Steam to access the driver portal.")
+			http.Redirect(w, r, "/driver/login", http.StatusFound)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestContextKeyDriverGUID, guid)))
+	})
+}
+
+func DriverGUIDFromRequest(r *http.Request) string {
+	guid, _ := r.Context().Value(requestContextKeyDriverGUID).(string)
+
+	return guid
+}
+
+func (dph *DriverPortalHandler) login(w http.ResponseWriter, r *http.Request) {
+	dph.loginWithSteam(func(w http.ResponseWriter, r *http.Request, steamID string) {
+		sess := getSession(r)
+		sess.Values[sessionDriverGUID] = steamID
+
+		if err := sess.Save(r, w); err != nil {
+			logrus.WithError(err).Error("Could not save driver session")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/driver", http.StatusFound)
+	})(w, r)
+}
+
+func (dph *DriverPortalHandler) logout(w http.ResponseWriter, r *http.Request) {
+	sess := getSession(r)
+	delete(sess.Values, sessionDriverGUID)
+
+	_ = sess.Save(r, w)
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+type driverPortalTemplateVars struct {
+	BaseTemplateVars
+
+	GUID            string
+	Name            string
+	Team            string
+	Championships   []*DriverChampionship
+	Cars            Cars
+	CalendarFeedURL string
+
+	NotificationPreferences *DriverNotificationPreferences
+}
+
+func (dph *DriverPortalHandler) portal(w http.ResponseWriter, r *http.Request) {
+	guid := DriverGUIDFromRequest(r)
+
+	championships, err := dph.driverPortalManager.ListChampionshipsForDriver(guid)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not list championships for driver: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	cars, err := ListCars()
+
+	if err != nil {
+		logrus.WithError(err).Error("Could not list cars")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	notificationPreferences, err := dph.driverPortalManager.NotificationPreferences(guid)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not load notification preferences for driver: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	vars := &driverPortalTemplateVars{
+		GUID:                    guid,
+		Championships:           championships,
+		Cars:                    cars,
+		CalendarFeedURL:         CalendarFeedURL(CalendarFeedDriver, guid),
+		NotificationPreferences: notificationPreferences,
+	}
+
+	if len(championships) > 0 {
+		vars.Name = championships[0].Entrant.Name
+		vars.Team = championships[0].Entrant.Team
+	}
+
+	dph.viewRenderer.MustLoadTemplate(w, r, "driver/portal.html", vars)
+}
+
+func (dph *DriverPortalHandler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	guid := DriverGUIDFromRequest(r)
+
+	err := dph.driverPortalManager.UpdateDriverDetails(guid, strings.TrimSpace(r.FormValue("Name")), strings.TrimSpace(r.FormValue("Team")))
+
+	if validationError, ok := err.(ValidationError); ok {
+		AddErrorFlash(w, r, string(validationError))
+		http.Redirect(w, r, "/driver", http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("Could not update driver details for: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Your details have been updated in all of your championships.")
+	http.Redirect(w, r, "/driver", http.StatusFound)
+}
+
+func (dph *DriverPortalHandler) updateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	guid := DriverGUIDFromRequest(r)
+
+	prefs, err := dph.driverPortalManager.NotificationPreferences(guid)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not load notification preferences for: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("UnlinkDiscord") == "on" {
+		prefs.DiscordUserID = ""
+	}
+
+	prefs.Email = strings.TrimSpace(r.FormValue("Email"))
+	prefs.SignUpApproved = r.FormValue("SignUpApproved") == "on"
+	prefs.EventReminders = r.FormValue("EventReminders") == "on"
+	prefs.Penalties = r.FormValue("Penalties") == "on"
+
+	err = dph.driverPortalManager.UpdateNotificationPreferences(prefs, r.FormValue("DiscordLinkCode"))
+
+	if validationError, ok := err.(ValidationError); ok {
+		AddErrorFlash(w, r, string(validationError))
+		http.Redirect(w, r, "/driver", http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("Could not update notification preferences for: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Your notification preferences have been saved.")
+	http.Redirect(w, r, "/driver", http.StatusFound)
+}
+
+func (dph *DriverPortalHandler) changeCar(w http.ResponseWriter, r *http.Request) {
+	guid := DriverGUIDFromRequest(r)
+
+	err := dph.driverPortalManager.ChangeCar(guid, chi.URLParam(r, "championshipID"), r.FormValue("Car"), r.FormValue("Skin"))
+
+	if err == ErrDriverCarNotInChampionship {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	} else if err == ErrDriverNotInChampionship || err == ErrDriverCannotChangeCar {
+		AddErrorFlash(w, r, "Sorry, there is no free slot available in that car.")
+		http.Redirect(w, r, "/driver", http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("Could not change car for driver: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Your car and skin have been updated.")
+	http.Redirect(w, r, "/driver", http.StatusFound)
+}
+
+func (dph *DriverPortalHandler) eventEntry(withdraw bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guid := DriverGUIDFromRequest(r)
+
+		err := dph.driverPortalManager.SetEventWithdrawal(guid, chi.URLParam(r, "championshipID"), chi.URLParam(r, "eventID"), withdraw)
+
+		if validationError, ok := err.(ValidationError); ok {
+			AddErrorFlash(w, r, string(validationError))
+			http.Redirect(w, r, "/driver", http.StatusFound)
+			return
+		} else if err != nil {
+			logrus.WithError(err).Errorf("Could not change event entry for driver: %s", guid)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if withdraw {
+			AddFlash(w, r, "You have been withdrawn from the event.")
+		} else {
+			AddFlash(w, r, "You have been re-entered into the event.")
+		}
+
+		http.Redirect(w, r, "/driver", http.StatusFound)
+	}
+}
+
+// checkCarAccess makes sure that drivers can only download content for cars they are entered with.
+func (dph *DriverPortalHandler) checkCarAccess(w http.ResponseWriter, r *http.Request, car string) bool {
+	guid := DriverGUIDFromRequest(r)
+
+	canAccess, err := dph.driverPortalManager.DriverCanAccessCar(guid, car)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not check car access for driver: %s", guid)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+
+	if !canAccess {
+		http.NotFound(w, r)
+		return false
+	}
+
+	return true
+}
+
+func (dph *DriverPortalHandler) downloadSetup(w http.ResponseWriter, r *http.Request) {
+	car := filepath.Base(chi.URLParam(r, "car"))
+	track := filepath.Base(chi.URLParam(r, "track"))
+	setup := filepath.Base(chi.URLParam(r, "setup"))
+
+	if !dph.checkCarAccess(w, r, car) {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, setup))
+	http.ServeFile(w, r, filepath.Join(ServerInstallPath, "setups", car, track, setup))
+}
+
+func (dph *DriverPortalHandler) downloadSkin(w http.ResponseWriter, r *http.Request) {
+	car := filepath.Base(chi.URLParam(r, "car"))
+	skin := filepath.Base(chi.URLParam(r, "skin"))
+
+	if !dph.checkCarAccess(w, r, car) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s_%s.zip"`, car, skin, time.Now().Format("2006-01-02")))
+
+	if err := dph.driverPortalManager.WriteSkinZip(car, skin, w); os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("Could not write skin zip for %s/%s", car, skin)
+		return
+	}
+}
@@ -0,0 +1,207 @@
+package servermanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+const driverPortalTestGUID = "76561198000000001"
+
+// driverPortalTestChampionship has a GT3 class with the test driver in car_a and a free slot in car_b, and a full
+// GT4 class in car_c.
+func driverPortalTestChampionship(t *testing.T) *Championship {
+	championship := NewChampionship("Driver Portal Test")
+
+	slots := []struct {
+		class, name, guid, car string
+	}{
+		{"GT3", "Test Driver", driverPortalTestGUID, "car_a"},
+		{"GT3", "", "", "car_b"},
+		{"GT4", "Other Driver", "76561198000000002", "car_c"},
+	}
+
+	classes := make(map[string]*ChampionshipClass)
+
+	for _, slot := range slots {
+		class, ok := classes[slot.class]
+
+		if !ok {
+			class = NewChampionshipClass(slot.class)
+			classes[slot.class] = class
+			championship.AddClass(class)
+		}
+
+		entrant := NewEntrant()
+		entrant.Name = slot.name
+		entrant.GUID = slot.guid
+		entrant.Model = slot.car
+		entrant.Skin = "default"
+
+		class.Entrants.AddToBackOfGrid(entrant)
+	}
+
+	event := NewChampionshipEvent()
+	championship.Events = append(championship.Events, event)
+
+	if err := championshipManager.UpsertChampionship(championship); err != nil {
+		t.Error(err)
+	}
+
+	return championship
+}
+
+func TestDriverPortalManager_ChangeCar(t *testing.T) {
+	dpm := NewDriverPortalManager(championshipManager, championshipManager.store)
+
+	driverCar := func(championshipID string) (string, string) {
+		championship, err := championshipManager.LoadChampionship(championshipID)
+
+		if err != nil {
+			t.Error(err)
+			return "", ""
+		}
+
+		_, entrant := findEntrantInChampionship(championship, driverPortalTestGUID)
+
+		if entrant == nil {
+			return "", ""
+		}
+
+		return entrant.Model, entrant.Skin
+	}
+
+	t.Run("Free slot", func(t *testing.T) {
+		championship := driverPortalTestChampionship(t)
+
+		if err := dpm.ChangeCar(driverPortalTestGUID, championship.ID.String(), "car_b", "blue"); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if car, skin := driverCar(championship.ID.String()); car != "car_b" || skin != "blue" {
+			t.Logf("Expected the driver to have moved to car_b (blue), got: %s (%s)", car, skin)
+			t.Fail()
+		}
+	})
+
+	t.Run("Skin only", func(t *testing.T) {
+		championship := driverPortalTestChampionship(t)
+
+		if err := dpm.ChangeCar(driverPortalTestGUID, championship.ID.String(), "car_a", "red"); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if car, skin := driverCar(championship.ID.String()); car != "car_a" || skin != "red" {
+			t.Logf("Expected the driver to have changed skin in car_a, got: %s (%s)", car, skin)
+			t.Fail()
+		}
+	})
+
+	t.Run("No free slot", func(t *testing.T) {
+		championship := driverPortalTestChampionship(t)
+
+		if err := dpm.ChangeCar(driverPortalTestGUID, championship.ID.String(), "car_c", "default"); err != ErrDriverCannotChangeCar {
+			t.Logf("Expected a full car to be refused, got: %v", err)
+			t.Fail()
+		}
+
+		if car, _ := driverCar(championship.ID.String()); car != "car_a" {
+			t.Logf("Expected the driver to keep their slot in car_a, got: %s", car)
+			t.Fail()
+		}
+	})
+
+	t.Run("Car not in championship", func(t *testing.T) {
+		championship := driverPortalTestChampionship(t)
+
+		if err := dpm.ChangeCar(driverPortalTestGUID, championship.ID.String(), "car_z", "default"); err != ErrDriverCarNotInChampionship {
+			t.Logf("Expected an unknown car to be refused, got: %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("Driver not in championship", func(t *testing.T) {
+		championship := driverPortalTestChampionship(t)
+
+		if err := dpm.ChangeCar("76561198000000009", championship.ID.String(), "car_b", "default"); err != ErrDriverNotInChampionship {
+			t.Logf("Expected a driver who isn't entered to be refused, got: %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestDriverPortalHandler_ChangeCarUnknownCar(t *testing.T) {
+	championship := driverPortalTestChampionship(t)
+	dph := NewDriverPortalHandler(nil, NewDriverPortalManager(championshipManager, championshipManager.store))
+
+	form := url.Values{"Car": {"car_z"}, "Skin": {"default"}}
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("championshipID", championship.ID.String())
+
+	r := httptest.NewRequest(http.MethodPost, "/driver/championship/"+championship.ID.String()+"/car", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext), requestContextKeyDriverGUID, driverPortalTestGUID))
+
+	w := httptest.NewRecorder()
+
+	dph.changeCar(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Logf("Expected a car which is not in the championship to be a bad request, got: %d", w.Code)
+		t.Fail()
+	}
+}
+
+func TestDriverPortalManager_SetEventWithdrawal(t *testing.T) {
+	dpm := NewDriverPortalManager(championshipManager, championshipManager.store)
+
+	championship := driverPortalTestChampionship(t)
+	eventID := championship.Events[0].ID.String()
+
+	isWithdrawn := func() bool {
+		_, event, err := championshipManager.GetChampionshipAndEvent(championship.ID.String(), eventID)
+
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+
+		return event.IsWithdrawn(driverPortalTestGUID)
+	}
+
+	if err := dpm.SetEventWithdrawal(driverPortalTestGUID, championship.ID.String(), eventID, true); err != nil || !isWithdrawn() {
+		t.Logf("Expected the driver to be withdrawn from the event, got: %v", err)
+		t.Fail()
+	}
+
+	if err := dpm.SetEventWithdrawal(driverPortalTestGUID, championship.ID.String(), eventID, false); err != nil || isWithdrawn() {
+		t.Logf("Expected the driver to have rejoined the event, got: %v", err)
+		t.Fail()
+	}
+
+	if err := dpm.SetEventWithdrawal("76561198000000009", championship.ID.String(), eventID, true); err != ErrDriverNotInChampionship {
+		t.Logf("Expected a driver who isn't entered to be refused, got: %v", err)
+		t.Fail()
+	}
+
+	championship.Events[0].CompletedTime = time.Now()
+
+	if err := championshipManager.UpsertChampionship(championship); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, ok := dpm.SetEventWithdrawal(driverPortalTestGUID, championship.ID.String(), eventID, true).(ValidationError); !ok {
+		t.Log("Expected withdrawing from a completed event to be a validation error")
+		t.Fail()
+	}
+}
@@ -4,78 +4,116 @@ replace github.com/taviti/check => gopkg.in/check.v1 v1.0.0-20180628173108-788fd
 
 require (
 	github.com/Clinet/discordgo-embed v0.0.0-20190411043415-d754bc1a576c
-	github.com/Masterminds/goutils v1.1.0 // indirect
 	github.com/Masterminds/semver v1.4.2
 	github.com/Masterminds/sprig v2.20.0+incompatible
+	github.com/blevesearch/bleve v0.7.0
+	github.com/bwmarrin/discordgo v0.19.0
+	github.com/cj123/ini v1.42.0
+	github.com/cj123/sessions v1.1.5
+	github.com/davecgh/go-spew v1.1.1
+	github.com/dimchansky/utfbom v1.1.0
+	github.com/etcd-io/bbolt v1.3.3
+	github.com/fatih/camelcase v1.0.0
+	github.com/getsentry/raven-go v0.2.0
+	github.com/go-chi/chi v4.0.2+incompatible
+	github.com/go-http-utils/etag v0.0.0-20161124023236-513ea8f21eb1
+	github.com/google/uuid v1.1.1
+	github.com/gorilla/websocket v1.4.0
+	github.com/haisum/recaptcha v0.0.0-20170327142240-7d3b8053900e
+	github.com/hako/durafmt v0.0.0-20210608085754-5c1018a4e16b
+	github.com/heindl/caldav-go v0.0.0-20160315204950-22453f8a38b5
+	github.com/jaytaylor/html2text v0.0.0-20190408195923-01ec452cbe43
+	github.com/mattn/go-zglob v0.0.1
+	github.com/mitchellh/go-ps v0.0.0-20170309133038-4fdf99ab2936
+	github.com/mitchellh/go-wordwrap v1.0.0
+	github.com/nicksnyder/go-i18n/v2 v2.0.2
+	github.com/pkg/browser v0.0.0-20180916011732-0a3d74bf9ce4
+	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v0.9.2
+	github.com/russross/blackfriday v2.0.0+incompatible
+	github.com/sethvargo/go-diceware v0.0.0-20181024230814-74428ac65346
+	github.com/sirupsen/logrus v1.4.2
+	github.com/solovev/steam_go v0.0.0-20170222182106-48eb5aae6c50
+	github.com/teambition/rrule-go v1.4.2
+	github.com/yuin/gopher-lua v1.1.2
+	golang.org/x/crypto v0.0.0-20190829043050-9756ffdc2472
+	golang.org/x/net v0.0.0-20190827160401-ba9fcec4b297
+	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	golang.org/x/text v0.3.2
+	gopkg.in/yaml.v2 v2.2.2
+)
+
+require (
+	github.com/BurntSushi/toml v0.3.0 // indirect
+	github.com/Masterminds/goutils v1.1.0 // indirect
 	github.com/RoaringBitmap/roaring v0.4.17 // indirect
 	github.com/Smerity/govarint v0.0.0-20150407073650-7265e41f48f1 // indirect
-	github.com/blevesearch/bleve v0.7.0
+	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
 	github.com/blevesearch/blevex v0.0.0-20180227211930-4b158bb555a3 // indirect
 	github.com/blevesearch/go-porterstemmer v1.0.2 // indirect
 	github.com/blevesearch/segment v0.0.0-20160915185041-762005e7a34f // indirect
 	github.com/boltdb/bolt v1.3.1 // indirect
-	github.com/bwmarrin/discordgo v0.19.0
 	github.com/certifi/gocertifi v0.0.0-20190506164543-d2eda7129713 // indirect
-	github.com/cj123/ini v1.42.0
-	github.com/cj123/sessions v1.1.5
+	github.com/chzyer/logex v1.1.10 // indirect
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
+	github.com/chzyer/test v0.0.0-20180213035817-a1ea475d72b1 // indirect
 	github.com/couchbase/vellum v0.0.0-20190328134517-462e86d8716b // indirect
 	github.com/cznic/b v0.0.0-20181122101859-a26611c4d92d // indirect
 	github.com/cznic/mathutil v0.0.0-20181122101859-297441e03548 // indirect
 	github.com/cznic/strutil v0.0.0-20181122101858-275e90344537 // indirect
-	github.com/davecgh/go-spew v1.1.1
-	github.com/dimchansky/utfbom v1.1.0
 	github.com/edsrzf/mmap-go v1.0.0 // indirect
-	github.com/etcd-io/bbolt v1.3.3
 	github.com/facebookgo/ensure v0.0.0-20160127193407-b4ab57deab51 // indirect
 	github.com/facebookgo/stack v0.0.0-20160209184415-751773369052 // indirect
 	github.com/facebookgo/subset v0.0.0-20150612182917-8dac2c3c4870 // indirect
-	github.com/fatih/camelcase v1.0.0
-	github.com/getsentry/raven-go v0.2.0
-	github.com/go-chi/chi v4.0.2+incompatible
-	github.com/go-http-utils/etag v0.0.0-20161124023236-513ea8f21eb1
+	github.com/fsnotify/fsnotify v1.4.7 // indirect
+	github.com/glycerine/go-unsnap-stream v0.0.0-20181221182339-f9677308dec2 // indirect
+	github.com/glycerine/goconvey v0.0.0-20180728074245-46e3a41ad493 // indirect
 	github.com/go-http-utils/fresh v0.0.0-20161124030543-7231e26a4b27 // indirect
 	github.com/go-http-utils/headers v0.0.0-20181008091004-fed159eddc2a // indirect
-	github.com/google/uuid v1.1.1
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/golang/snappy v0.0.1 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20190309154008-847fc94819f9 // indirect
-	github.com/gorilla/websocket v1.4.0
-	github.com/haisum/recaptcha v0.0.0-20170327142240-7d3b8053900e
-	github.com/heindl/caldav-go v0.0.0-20160315204950-22453f8a38b5
+	github.com/gorilla/securecookie v1.1.1 // indirect
+	github.com/hpcloud/tail v1.0.0 // indirect
 	github.com/huandu/xstrings v1.2.0 // indirect
 	github.com/imdario/mergo v0.3.7 // indirect
-	github.com/jaytaylor/html2text v0.0.0-20190408195923-01ec452cbe43
 	github.com/jmhodges/levigo v1.0.0 // indirect
+	github.com/jtolds/gls v4.20.0+incompatible // indirect
 	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
 	github.com/kr/pretty v0.1.0 // indirect
+	github.com/kr/pty v1.1.1 // indirect
+	github.com/kr/text v0.1.0 // indirect
 	github.com/mattn/go-runewidth v0.0.4 // indirect
-	github.com/mattn/go-zglob v0.0.1
-	github.com/mitchellh/go-ps v0.0.0-20170309133038-4fdf99ab2936
-	github.com/mitchellh/go-wordwrap v1.0.0
-	github.com/nicksnyder/go-i18n/v2 v2.0.2
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/mschoch/smat v0.0.0-20160514031455-90eadee771ae // indirect
 	github.com/olekukonko/tablewriter v0.0.1 // indirect
-	github.com/pkg/browser v0.0.0-20180916011732-0a3d74bf9ce4
-	github.com/pkg/errors v0.8.1
-	github.com/prometheus/client_golang v0.9.2
+	github.com/onsi/ginkgo v1.7.0 // indirect
+	github.com/onsi/gomega v1.4.3 // indirect
+	github.com/philhofer/fwd v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_model v0.0.0-20180712105110-5c3871d89910 // indirect
+	github.com/prometheus/common v0.0.0-20181126121408-4724e9255275 // indirect
+	github.com/prometheus/procfs v0.0.0-20181204211112-1dc9a6cbc91a // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20190512091148-babf20351dd7 // indirect
-	github.com/russross/blackfriday v2.0.0+incompatible
-	github.com/sethvargo/go-diceware v0.0.0-20181024230814-74428ac65346
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
-	github.com/sirupsen/logrus v1.4.2
 	github.com/smartystreets/assertions v0.0.0-20190215210624-980c5ac6f3ac // indirect
-	github.com/solovev/steam_go v0.0.0-20170222182106-48eb5aae6c50
+	github.com/smartystreets/goconvey v0.0.0-20190306220146-200a235640ff // indirect
 	github.com/ssor/bom v0.0.0-20170718123548-6386211fdfcf // indirect
 	github.com/steveyen/gtreap v0.0.0-20150807155958-0abe01ef9be2 // indirect
+	github.com/stretchr/objx v0.1.1 // indirect
+	github.com/stretchr/testify v1.3.0 // indirect
 	github.com/syndtr/goleveldb v1.0.0 // indirect
 	github.com/taviti/check v0.0.0-00010101000000-000000000000 // indirect
-	github.com/teambition/rrule-go v1.4.2
 	github.com/tecbot/gorocksdb v0.0.0-20190519120508-025c3cf4ffb4 // indirect
+	github.com/tinylib/msgp v1.1.0 // indirect
+	github.com/willf/bitset v1.1.10 // indirect
 	go.etcd.io/bbolt v1.3.2 // indirect
-	golang.org/x/crypto v0.0.0-20190829043050-9756ffdc2472
-	golang.org/x/net v0.0.0-20190827160401-ba9fcec4b297
-	golang.org/x/sync v0.0.0-20190423024810-112230192c58
 	golang.org/x/sys v0.0.0-20190904005037-43c01164e931 // indirect
-	golang.org/x/text v0.3.2
+	golang.org/x/tools v0.0.0-20190506145303-2d16b83fe98c // indirect
+	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405 // indirect
+	gopkg.in/fsnotify.v1 v1.4.7 // indirect
 	gopkg.in/ini.v1 v1.42.0 // indirect
-	gopkg.in/yaml.v2 v2.2.2
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 )
 
-go 1.13
+go 1.23
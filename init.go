@@ -28,6 +28,10 @@ func InitWithResolver(resolver *Resolver) error {
 		return err
 	}
 
+	if err := InitCalendarFeeds(store); err != nil {
+		logrus.WithError(err).Error("Could not load the calendar feed secret, calendar feeds are disabled")
+	}
+
 	UseShortenedDriverNames = opts != nil && opts.UseShortenedDriverNames == 1
 	UseFallBackSorting = opts != nil && opts.FallBackResultsSorting == 1
 
@@ -92,12 +96,6 @@ func InitWithResolver(resolver *Resolver) error {
 		if err != nil {
 			return err
 		}
-
-		err = raceWeekendManager.WatchForScheduledSessions()
-
-		if err != nil {
-			return err
-		}
 	*/
 
 	carManager := resolver.resolveCarManager()
@@ -1,48 +1,245 @@
 package servermanager
 
 import (
-	"fmt"
-	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/hako/durafmt"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+	"gopkg.in/yaml.v2"
 )
 
-func ExampleLocaliser() {
-	bundle := i18n.NewBundle(language.English)
-	bundle.RegisterUnmarshalFunc("yml", yaml.Unmarshal)
+// defaultLanguage is used for any message which has not been translated into the chosen language.
+var defaultLanguage = language.English
 
-	enMessageFile, err := bundle.LoadMessageFile("./localisation/en.yml")
+// LocalisationLoader loads the message files which translate Server Manager, keyed by filename, e.g. "es.yml".
+type LocalisationLoader interface {
+	MessageFiles() (map[string][]byte, error)
+}
 
-	if err != nil {
-		logrus.WithError(err).Error("couldn't load message file")
+func NewFilesystemLocalisationLoader(dir string) LocalisationLoader {
+	return &filesystemLocalisationLoader{
+		dir: dir,
 	}
+}
 
-	err = bundle.AddMessages(language.English, enMessageFile.Messages...)
+type filesystemLocalisationLoader struct {
+	dir string
+}
+
+func (fs *filesystemLocalisationLoader) MessageFiles() (map[string][]byte, error) {
+	filenames, err := filepath.Glob(filepath.Join(fs.dir, "*.yml"))
 
 	if err != nil {
-		logrus.WithError(err).Error("couldn't add messages")
+		return nil, err
 	}
 
-	esMessageFile, err := bundle.LoadMessageFile("./localisation/es.yml")
+	files := make(map[string][]byte)
 
-	if err != nil {
-		logrus.WithError(err).Error("couldn't load message file")
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return nil, err
+		}
+
+		files[filepath.Base(filename)] = data
 	}
 
-	err = bundle.AddMessages(language.Spanish, esMessageFile.Messages...)
+	return files, nil
+}
+
+// Localiser translates messages into the languages which have message files. Messages which have not been translated
+// fall back to English.
+type Localiser struct {
+	bundle *i18n.Bundle
+}
+
+// NewLocaliser creates a Localiser containing the built in English messages and the messages in each of the files
+// from the loader.
+func NewLocaliser(loader LocalisationLoader) (*Localiser, error) {
+	bundle := i18n.NewBundle(defaultLanguage)
+	bundle.RegisterUnmarshalFunc("yml", yaml.Unmarshal)
+
+	if err := bundle.AddMessages(defaultLanguage, defaultMessages...); err != nil {
+		return nil, err
+	}
+
+	localiser := &Localiser{bundle: bundle}
+
+	if loader == nil {
+		return localiser, nil
+	}
+
+	files, err := loader.MessageFiles()
 
 	if err != nil {
-		logrus.WithError(err).Error("couldn't add messages")
+		return localiser, err
 	}
 
-	{
-		localiser := i18n.NewLocalizer(bundle, "en")
-		fmt.Println(localiser.MustLocalize(&i18n.LocalizeConfig{MessageID: "HelloWorld"}))
+	for filename, data := range files {
+		if _, err := bundle.ParseMessageFileBytes(data, filename); err != nil {
+			logrus.WithError(err).Errorf("Could not load localisation file: %s", filename)
+		}
+	}
+
+	return localiser, nil
+}
+
+var (
+	localiser      *Localiser
+	localiserMutex sync.Mutex
+)
+
+// InitLocalisation loads the message files used to translate notifications.
+func InitLocalisation(loader LocalisationLoader) error {
+	l, err := NewLocaliser(loader)
+
+	localiserMutex.Lock()
+	defer localiserMutex.Unlock()
+
+	if l != nil {
+		localiser = l
 	}
-	{
-		localiser := i18n.NewLocalizer(bundle, "es")
-		fmt.Println(localiser.MustLocalize(&i18n.LocalizeConfig{MessageID: "HelloWorld"}))
+
+	return err
+}
+
+// getLocaliser returns the Localiser created by InitLocalisation, or one which only has the built in English
+// messages if localisation has not been initialised.
+func getLocaliser() *Localiser {
+	localiserMutex.Lock()
+	defer localiserMutex.Unlock()
+
+	if localiser == nil {
+		localiser, _ = NewLocaliser(nil)
 	}
-}
\ No newline at end of file
+
+	return localiser
+}
+
+// Language is a language that Server Manager has been translated into.
+type Language struct {
+	Tag  string
+	Name string
+}
+
+// Languages lists the languages which have message files, sorted by their tag.
+func (l *Localiser) Languages() []Language {
+	var languages []Language
+
+	for _, tag := range l.bundle.LanguageTags() {
+		languages = append(languages, Language{
+			Tag:  tag.String(),
+			Name: strings.Title(display.Self.Name(tag)),
+		})
+	}
+
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i].Tag < languages[j].Tag
+	})
+
+	return languages
+}
+
+// Localise translates a message into the given language, executing it as a template with data. If the message can't
+// be translated, its ID is returned.
+func (l *Localiser) Localise(lang, messageID string, data interface{}) string {
+	msg, err := i18n.NewLocalizer(l.bundle, lang, defaultLanguage.String()).Localize(&i18n.LocalizeConfig{
+		MessageID:    messageID,
+		TemplateData: data,
+	})
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not localise message: %s", messageID)
+		return messageID
+	}
+
+	return msg
+}
+
+// Duration writes a duration using the units of the given language, e.g. "1 hour 30 minutes".
+func (l *Localiser) Duration(lang string, d time.Duration) string {
+	units, err := durafmt.DefaultUnitsCoder.Decode(l.Localise(lang, "DurationUnits", nil))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not decode duration units for language: %s", lang)
+		return durafmt.Parse(d).String()
+	}
+
+	return durafmt.Parse(d).Format(units)
+}
+
+// defaultMessages are the English messages used in notifications. Translations of them are loaded from the
+// localisation directory.
+var defaultMessages = []*i18n.Message{
+	{ID: "DurationUnits", Other: "year:years,week:weeks,day:days,hour:hours,minute:minutes,second:seconds,millisecond:milliseconds,microsecond:microseconds"},
+
+	{ID: "ServerLine", Other: "Server: {{.ServerName}}"},
+	{ID: "EventNameLine", Other: "Event name: {{.EventName}}"},
+	{ID: "DateLine", Other: "Date: {{.Date.Format \"Mon, 02 Jan 2006 15:04:05 MST\"}}"},
+	{ID: "TrackLine", Other: "Track: {{.TrackInfo}}"},
+	{ID: "CarsLine", Other: "Car(s): {{.CarList}}"},
+
+	{ID: "RaceStartTitle", Other: "Event starting at {{.Track}}"},
+	{ID: "RaceStartMessage", Other: "Event at {{.Track}} is starting now"},
+	{ID: "RaceStartNamedMessage", Other: "{{.EventName}} event at {{.Track}} is starting now"},
+	{ID: "PasswordLine", Other: "Password is '{{.Password}}' (no quotes)"},
+	{ID: "NoPasswordLine", Other: "No password"},
+	{ID: "ContentManagerJoinLink", Other: "Content Manager join link"},
+
+	{ID: "ScheduledTitle", Other: "Event scheduled at {{.Track}}"},
+	{ID: "ScheduledMessage", Other: "A new event has been scheduled"},
+
+	{ID: "CancelledTitle", Other: "Event cancelled at {{.Track}}"},
+	{ID: "CancelledMessage", Other: "The following scheduled Event has been cancelled"},
+
+	{ID: "ReminderTitle", Other: "Event reminder - {{.Reminder}}"},
+	{ID: "ReminderMessage", Other: "Event at {{.TrackInfo}} starts in {{.Reminder}}"},
+	{ID: "ReminderNamedMessage", Other: "{{.EventName}} event at {{.TrackInfo}} starts in {{.Reminder}}"},
+	{ID: "ReminderRaceWeekendMessage", Other: "{{.SessionName}} at {{.TrackInfo}} ({{.RaceWeekend.Name}} Race Weekend) starts in {{.Reminder}}"},
+
+	{ID: "ResultsPostedTitle", Other: "{{.SessionType}} results - {{.Track}}"},
+	{ID: "ResultsSessionLine", Other: "{{.SessionType}} at {{.Track}}"},
+	{ID: "ResultsFastestLap", Other: "Fastest lap: {{.DriverName}} ({{.CarName}}) {{.LapTime}}"},
+	{ID: "ResultsIncidents", Other: "Incidents:"},
+	{ID: "ResultsIncidentDisqualified", Other: "{{.DriverName}} was disqualified"},
+	{ID: "ResultsIncidentLapPenalty", Other: "{{.DriverName}} received a {{.LapPenalty}} lap penalty"},
+	{ID: "ResultsIncidentTimePenalty", Other: "{{.DriverName}} received a {{.Penalty}} penalty"},
+	{ID: "ResultsIncidentCollision", Other: "{{.DriverName}} and {{.OtherDriverName}} collided at {{.ImpactSpeed}} km/h"},
+	{ID: "ResultsStandings", Other: "Standings:"},
+	{ID: "ResultsClassStandings", Other: "{{.ClassName}} Standings:"},
+	{ID: "ResultsPoints", Other: "{{.Points}} pts"},
+	{ID: "ResultsNewInStandings", Other: "(new)"},
+	{ID: "ResultsViewLink", Other: "View results"},
+
+	{ID: "SignUpApprovedTitle", Other: "You're in! {{.Championship.Name}}"},
+	{ID: "SignUpApprovedMessage", Other: "Your sign up to {{.Championship.Name}} has been approved."},
+	{ID: "SignUpApprovedNextEvent", Other: "The next event is at {{.TrackInfo}}."},
+	{ID: "SignUpApprovedNextEventScheduled", Other: "The next event is at {{.TrackInfo}}, in {{.TimeUntil}}."},
+
+	{ID: "PenaltyTitle", Other: "Penalty - {{.SessionType}} at {{.Track}}"},
+	{ID: "PenaltyMessage", Other: "You have been given a {{.Penalty}} penalty in the {{.SessionType}} at {{.Track}}"},
+	{ID: "PenaltyDisqualifiedMessage", Other: "You have been disqualified from the {{.SessionType}} at {{.Track}}"},
+	{ID: "PenaltyReasonMandatoryPitStop", Other: "for not completing a valid mandatory pit stop"},
+	{ID: "PenaltyReasonDriverSwap", Other: "for not meeting the driver swap rules"},
+
+	{ID: "DiscordScheduleTitle", Other: "Upcoming events on server {{.ServerName}}"},
+	{ID: "DiscordSessionsTitle", Other: "Upcoming sessions on server {{.ServerName}}"},
+	{ID: "DiscordNothingScheduled", Other: "Nothing is scheduled in the next week"},
+	{ID: "DiscordRoleDefaultName", Other: "notification"},
+	{ID: "DiscordRoleMemberNotFound", Other: "You don't seem to exist, so I can't assign you that role.  Try again later."},
+	{ID: "DiscordRoleRemoveFailed", Other: "You already have the {{.RoleName}} role, and an error occurred trying to remove it.  Try again later."},
+	{ID: "DiscordRoleRemoved", Other: "You already had the {{.RoleName}} role, it has now been removed.  Type the command again to add it back."},
+	{ID: "DiscordRoleAddFailed", Other: "A server error occurred trying to assign you the {{.RoleName}} role, try again later"},
+	{ID: "DiscordRoleAdded", Other: "The {{.RoleName}} role has been assigned, you will now get pinged with notifications.  Type the command again to remove it."},
+
+	{ID: "TestNotificationTitle", Other: "Test notification: {{.Title}}"},
+}
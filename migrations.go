@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/cj123/assetto-server-manager/fixtures/race-weekend-examples"
@@ -70,6 +71,9 @@ var (
 		addRaceWeekendExamples,
 		addServerNameTemplate,
 		createFirstServer,
+		moveResultsNotificationTemplate,
+		moveTeamPenaltiesToTeamIDs,
+		addDiscordNotificationEvents,
 	}
 )
 
@@ -463,4 +467,76 @@ func createFirstServer(s Store) error {
 	}
 
 	return s.UpsertServer(server)
-}
\ No newline at end of file
+}
+
+func moveResultsNotificationTemplate(s Store) error {
+	logrus.Infof("Running migration: Move Results Notification Template to Notification Templates")
+
+	opts, err := s.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(opts.ResultsNotificationTemplate) == "" {
+		return nil
+	}
+
+	// notification templates write their own title, which the results notification template did not
+	opts.NotificationTemplates.ResultsPosted = "{{ T \"ResultsPostedTitle\" . }}\n" + opts.ResultsNotificationTemplate
+	opts.ResultsNotificationTemplate = ""
+
+	return s.UpsertServerOptions(opts)
+}
+
+func moveTeamPenaltiesToTeamIDs(s Store) error {
+	logrus.Infof("Running migration: Key Championship Team Penalties by Team ID")
+
+	championships, err := s.ListChampionships()
+
+	if err != nil {
+		return err
+	}
+
+	for _, championship := range championships {
+		teams, err := TeamsForChampionship(s, championship.ID)
+
+		if err != nil {
+			return err
+		}
+
+		// penalties for teams which have a roster are moved to their ID. other teams are still keyed by name, and
+		// are moved when they are given a roster.
+		if !keyTeamPenaltiesByTeamID(championship, teams) {
+			continue
+		}
+
+		if err := s.UpsertChampionship(championship); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addDiscordNotificationEvents(s Store) error {
+	logrus.Infof("Running migration: Add Discord Notification Events")
+
+	opts, err := s.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	// Discord received every notification before its notifications could be chosen.
+	opts.DiscordNotificationEvents = NotificationEventSelection{
+		RaceStart:     1,
+		Scheduled:     1,
+		Cancelled:     1,
+		Reminders:     1,
+		ResultsPosted: 1,
+		Other:         1,
+	}
+
+	return s.UpsertServerOptions(opts)
+}
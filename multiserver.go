@@ -110,6 +110,8 @@ func MultiServerSelectMiddleware(router chi.Router, next http.Handler) http.Hand
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/cj123/assetto-server-manager/pkg/udp"
@@ -123,6 +125,10 @@ type MultiServerManager struct {
 	notificationManager *NotificationManager
 	baseHandler         *BaseHandler
 	accountHandler      *AccountHandler
+
+	// servers are the running servers created by this MultiServerManager
+	servers      []*Server
+	serversMutex sync.RWMutex
 }
 
 func NewMultiServerManager(store Store, carManager *CarManager, notificationManager *NotificationManager, baseHandler *BaseHandler, accountHandler *AccountHandler) *MultiServerManager {
@@ -135,6 +141,9 @@ func NewMultiServerManager(store Store, carManager *CarManager, notificationMana
 	}
 }
 
+// ServerID identifies one of the servers run by a MultiServerManager.
+type ServerID = uuid.UUID
+
 type Server struct {
 	ID           uuid.UUID
 	Created      time.Time
@@ -144,22 +153,30 @@ type Server struct {
 
 	Process ServerProcess `json:"-"`
 
+	store Store
+
 	// Managers
 	RaceManager           *RaceManager           `json:"-"`
 	ChampionshipManager   *ChampionshipManager   `json:"-"`
 	RaceWeekendManager    *RaceWeekendManager    `json:"-"`
 	RaceControl           *RaceControl           `json:"-"`
 	ContentManagerWrapper *ContentManagerWrapper `json:"-"`
+	DriverPortalManager   *DriverPortalManager   `json:"-"`
+	Scheduler             *Scheduler             `json:"-"`
+	NotificationManager   *NotificationManager   `json:"-"`
 
 	// Handlers
-	QuickRaceHandler            *QuickRaceHandler            `json:"-"`
-	CustomRaceHandler           *CustomRaceHandler           `json:"-"`
-	ChampionshipsHandler        *ChampionshipsHandler        `json:"-"`
-	RaceWeekendHandler          *RaceWeekendHandler          `json:"-"`
-	RaceControlHandler          *RaceControlHandler          `json:"-"`
-	AccountHandler              *AccountHandler              `json:"-"`
-	ServerAdministrationHandler *ServerAdministrationHandler `json:"-"`
-	PenaltiesHandler            *PenaltiesHandler            `json:"-"`
+	QuickRaceHandler             *QuickRaceHandler             `json:"-"`
+	CustomRaceHandler            *CustomRaceHandler            `json:"-"`
+	ChampionshipsHandler         *ChampionshipsHandler         `json:"-"`
+	RaceWeekendHandler           *RaceWeekendHandler           `json:"-"`
+	RaceControlHandler           *RaceControlHandler           `json:"-"`
+	AccountHandler               *AccountHandler               `json:"-"`
+	ServerAdministrationHandler  *ServerAdministrationHandler  `json:"-"`
+	PenaltiesHandler             *PenaltiesHandler             `json:"-"`
+	DriverPortalHandler          *DriverPortalHandler          `json:"-"`
+	JobsHandler                  *JobsHandler                  `json:"-"`
+	NotificationTemplatesHandler *NotificationTemplatesHandler `json:"-"`
 }
 
 func (msm *MultiServerManager) NewServer(serverConfig GlobalServerConfig) (*Server, error) {
@@ -168,46 +185,200 @@ func (msm *MultiServerManager) NewServer(serverConfig GlobalServerConfig) (*Serv
 	server.Created = time.Now()
 	server.ServerConfig = serverConfig
 
+	server.store = msm.store
+	server.NotificationManager = msm.notificationManager
 	server.ContentManagerWrapper = NewContentManagerWrapper(msm.store, msm.carManager)
 	server.Process = NewAssettoServerProcess(server.UDPCallback, server.ContentManagerWrapper)
 	server.RaceManager = NewRaceManager(msm.store, server.Process, msm.carManager, msm.notificationManager)
 	server.ChampionshipManager = NewChampionshipManager(server.RaceManager)
+	server.ChampionshipManager.notificationManager = msm.notificationManager
 	server.RaceWeekendManager = NewRaceWeekendManager(server.RaceManager, server.ChampionshipManager, msm.store, server.Process, msm.notificationManager)
+	server.DriverPortalManager = NewDriverPortalManager(server.ChampionshipManager, msm.store)
+	server.Scheduler = NewScheduler(msm.store, server.RaceManager, server.ChampionshipManager, server.RaceWeekendManager, msm.notificationManager)
 
 	raceControlHub := newRaceControlHub()
 
 	server.RaceControl = NewRaceControl(raceControlHub, filesystemTrackData{}, server.Process)
+	server.RaceControl.notificationManager = msm.notificationManager
 
 	server.AccountHandler = msm.accountHandler
 	server.QuickRaceHandler = NewQuickRaceHandler(msm.baseHandler, server.RaceManager)
-	server.CustomRaceHandler = NewCustomRaceHandler(msm.baseHandler, server.RaceManager)
-	server.ChampionshipsHandler = NewChampionshipsHandler(msm.baseHandler, server.ChampionshipManager)
-	server.RaceWeekendHandler = NewRaceWeekendHandler(msm.baseHandler, server.RaceWeekendManager)
+	server.CustomRaceHandler = NewCustomRaceHandler(msm.baseHandler, server.RaceManager, server.Scheduler)
+	server.ChampionshipsHandler = NewChampionshipsHandler(msm.baseHandler, server.ChampionshipManager, server.Scheduler)
+	server.RaceWeekendHandler = NewRaceWeekendHandler(msm.baseHandler, server.RaceWeekendManager, server.Scheduler)
 	server.RaceControlHandler = NewRaceControlHandler(msm.baseHandler, msm.store, server.RaceManager, server.RaceControl, raceControlHub, server.Process)
 	server.ServerAdministrationHandler = NewServerAdministrationHandler(msm.baseHandler, msm.store, server.RaceManager, server.ChampionshipManager, server.RaceWeekendManager, server.Process)
 	server.PenaltiesHandler = NewPenaltiesHandler(msm.baseHandler, server.ChampionshipManager, server.RaceWeekendManager)
+	server.DriverPortalHandler = NewDriverPortalHandler(msm.baseHandler, server.DriverPortalManager)
+	server.JobsHandler = NewJobsHandler(msm.baseHandler, server.Scheduler)
+	server.NotificationTemplatesHandler = NewNotificationTemplatesHandler(msm.baseHandler, msm.store, msm.notificationManager)
+
+	server.RaceWeekendManager.serverID = server.ID
+	server.RaceWeekendManager.serverPool = msm
+	server.RaceWeekendManager.scheduler = server.Scheduler
+	server.Scheduler.serverID = server.ID
+	server.Scheduler.serverPool = msm
 
 	if err := msm.store.UpsertServer(server); err != nil {
 		return nil, err
 	}
 
+	msm.serversMutex.Lock()
+	msm.servers = append(msm.servers, server)
+	msm.serversMutex.Unlock()
+
+	if err := server.Scheduler.Init(); err != nil {
+		return nil, err
+	}
+
+	if err := server.RaceWeekendManager.ResumeAutopilot(); err != nil {
+		logrus.WithError(err).Errorf("Could not resume race weekend autopilot")
+	}
+
 	return server, nil
 }
 
+// FreeRaceWeekendManagers returns the RaceWeekendManagers of all servers which are not running an event.
+func (msm *MultiServerManager) FreeRaceWeekendManagers() []*RaceWeekendManager {
+	msm.serversMutex.RLock()
+	defer msm.serversMutex.RUnlock()
+
+	var raceWeekendManagers []*RaceWeekendManager
+
+	for _, server := range msm.servers {
+		if !server.Process.IsRunning() {
+			raceWeekendManagers = append(raceWeekendManagers, server.RaceWeekendManager)
+		}
+	}
+
+	return raceWeekendManagers
+}
+
+// RaceWeekendManagerForServer returns the RaceWeekendManager of a server, or nil if there is no server with that ID.
+func (msm *MultiServerManager) RaceWeekendManagerForServer(serverID ServerID) *RaceWeekendManager {
+	msm.serversMutex.RLock()
+	defer msm.serversMutex.RUnlock()
+
+	for _, server := range msm.servers {
+		if server.ID == serverID {
+			return server.RaceWeekendManager
+		}
+	}
+
+	return nil
+}
+
+// Schedulers returns the Schedulers of all servers.
+func (msm *MultiServerManager) Schedulers() []*Scheduler {
+	msm.serversMutex.RLock()
+	defer msm.serversMutex.RUnlock()
+
+	schedulers := make([]*Scheduler, 0, len(msm.servers))
+
+	for _, server := range msm.servers {
+		schedulers = append(schedulers, server.Scheduler)
+	}
+
+	return schedulers
+}
+
+// Servers returns all of the servers run by the MultiServerManager.
+func (msm *MultiServerManager) Servers() []*Server {
+	msm.serversMutex.RLock()
+	defer msm.serversMutex.RUnlock()
+
+	servers := make([]*Server, len(msm.servers))
+	copy(servers, msm.servers)
+
+	return servers
+}
+
+// StopEvent stops the event running on the server. Championship events and race weekend sessions are stopped by
+// their managers, so that their progress is kept.
+func (s *Server) StopEvent() error {
+	event := s.Process.Event()
+
+	if event.IsChampionship() && !event.IsPractice() {
+		return s.ChampionshipManager.StopActiveEvent()
+	} else if event.IsRaceWeekend() && !event.IsPractice() {
+		return s.RaceWeekendManager.StopActiveSession()
+	}
+
+	return s.Process.Stop()
+}
+
+// RestartEvent restarts the event running on the server.
+func (s *Server) RestartEvent() error {
+	event := s.Process.Event()
+
+	if event.IsChampionship() && !event.IsPractice() {
+		return s.ChampionshipManager.RestartActiveEvent()
+	} else if event.IsRaceWeekend() && !event.IsPractice() {
+		return s.RaceWeekendManager.RestartActiveSession()
+	}
+
+	return s.Process.Restart()
+}
+
 func (s *Server) UDPCallback(message udp.Message) {
 	if !config.Server.PerformanceMode {
 		s.RaceControl.UDPCallback(message)
 	}
+
+	var pendingSummary *pendingResultsSummary
+
+	if endSession, ok := message.(udp.EndSession); ok && s.NotificationManager != nil {
+		// the championship standings are recorded before the ChampionshipManager and RaceWeekendManager save the
+		// results, so that they can be compared with those after the session.
+		pendingSummary = newPendingResultsSummary(s.store, s.Process.Event(), filepath.Base(string(endSession)))
+	}
+
 	s.ChampionshipManager.ChampionshipEventCallback(message)
 	s.RaceWeekendManager.UDPCallback(message)
 	s.RaceManager.LoopCallback(message)
 	s.ContentManagerWrapper.UDPCallback(message)
+
+	if pendingSummary != nil {
+		s.sendResultsPostedMessage(pendingSummary)
+	}
+}
+
+func (s *Server) sendResultsPostedMessage(pendingSummary *pendingResultsSummary) {
+	summary, err := pendingSummary.build(s.store)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not build session results summary for notification")
+		return
+	}
+
+	go func() {
+		if err := s.NotificationManager.SendResultsPostedMessage(summary); err != nil {
+			logrus.WithError(err).Errorf("Could not send results posted notification")
+		}
+	}()
 }
 
 func (s *Server) Router() chi.Router {
 	// @TODO audit logging
 	r := chi.NewRouter()
 
+	// driver portal, available to anyone who signs in with steam
+	r.Get("/driver/login", s.DriverPortalHandler.login)
+	r.Get("/driver/logout", s.DriverPortalHandler.logout)
+
+	r.Group(func(r chi.Router) {
+		r.Use(s.DriverPortalHandler.DriverAccessMiddleware)
+
+		r.Get("/driver", s.DriverPortalHandler.portal)
+		r.Post("/driver/profile", s.DriverPortalHandler.updateProfile)
+		r.Post("/driver/notifications", s.DriverPortalHandler.updateNotificationPreferences)
+		r.Post("/driver/championship/{championshipID}/car", s.DriverPortalHandler.changeCar)
+		r.Post("/driver/championship/{championshipID}/event/{eventID}/withdraw", s.DriverPortalHandler.eventEntry(true))
+		r.Post("/driver/championship/{championshipID}/event/{eventID}/rejoin", s.DriverPortalHandler.eventEntry(false))
+		r.Get("/driver/download/setup/{car}/{track}/{setup}", s.DriverPortalHandler.downloadSetup)
+		r.Get("/driver/download/skin/{car}/{skin}", s.DriverPortalHandler.downloadSkin)
+	})
+
 	// readers
 	r.Group(func(r chi.Router) {
 		r.Use(s.AccountHandler.ReadAccessMiddleware)
@@ -254,6 +425,8 @@ func (s *Server) Router() chi.Router {
 		r.Post("/race-weekend/{raceWeekendID}/grid-preview", s.RaceWeekendHandler.gridPreview)
 		r.Get("/race-weekend/{raceWeekendID}/entrylist-preview", s.RaceWeekendHandler.entryListPreview)
 		r.Get("/race-weekend/{raceWeekendID}/export", s.RaceWeekendHandler.export)
+		r.Get("/race-weekend-templates", s.RaceWeekendHandler.listTemplates)
+		r.Get("/api/race-weekend/{raceWeekendID}/graph", s.RaceWeekendHandler.graphAPI)
 	})
 
 	// writers
@@ -270,6 +443,9 @@ func (s *Server) Router() chi.Router {
 		r.Get("/custom/load/{uuid}", s.CustomRaceHandler.start)
 		r.Post("/custom/schedule/{uuid}", s.CustomRaceHandler.schedule)
 		r.Get("/custom/schedule/{uuid}/remove", s.CustomRaceHandler.removeSchedule)
+		r.Post("/custom/schedule/{uuid}/occurrence", s.CustomRaceHandler.editOccurrence)
+		r.Post("/custom/schedule/{uuid}/add-occurrence", s.CustomRaceHandler.addOccurrence)
+		r.Post("/custom/schedule/{uuid}/end-series", s.CustomRaceHandler.endSeries)
 		r.Get("/custom/edit/{uuid}", s.CustomRaceHandler.createOrEdit)
 		r.Post("/custom/new/submit", s.CustomRaceHandler.submit)
 
@@ -287,9 +463,17 @@ func (s *Server) Router() chi.Router {
 		r.Get("/championship/{championshipID}/entrants.csv", s.ChampionshipsHandler.signedUpEntrantsCSV)
 		r.Get("/championship/{championshipID}/entrant/{entrantGUID}", s.ChampionshipsHandler.modifyEntrantStatus)
 		r.Post("/championship/{championshipID}/reorder-events", s.ChampionshipsHandler.reorderEvents)
+		r.Get("/championship/{championshipID}/reserves", s.ChampionshipsHandler.reserves)
+		r.Post("/championship/{championshipID}/reserves", s.ChampionshipsHandler.addReserve)
+		r.Post("/championship/{championshipID}/reserves/points-rule", s.ChampionshipsHandler.reservePointsRule)
+		r.Get("/championship/{championshipID}/reserve/{driverGUID}/remove", s.ChampionshipsHandler.removeReserve)
+		r.Post("/championship/{championshipID}/event/{eventID}/reserves", s.ChampionshipsHandler.assignReserve)
+		r.Get("/championship/{championshipID}/event/{eventID}/reserve/{driverGUID}/remove", s.ChampionshipsHandler.unassignReserve)
 
 		r.Get("/championship/import", s.ChampionshipsHandler.importChampionship)
 		r.Post("/championship/import", s.ChampionshipsHandler.importChampionship)
+		r.Post("/championship/import-archive", s.ChampionshipsHandler.importArchive)
+		r.Get("/championship/{championshipID}/export-archive", s.ChampionshipsHandler.exportArchive)
 		r.Get("/championship/{championshipID}/event/{eventID}/import", s.ChampionshipsHandler.eventImport)
 		r.Post("/championship/{championshipID}/event/{eventID}/import", s.ChampionshipsHandler.eventImport)
 		r.Get("/championship/{championshipID}/event/{eventID}/start", s.ChampionshipsHandler.startEvent)
@@ -324,9 +508,19 @@ func (s *Server) Router() chi.Router {
 		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/start", s.RaceWeekendHandler.startSession)
 		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/practice", s.RaceWeekendHandler.startPracticeSession)
 		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/restart", s.RaceWeekendHandler.restartSession)
+		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/start-children", s.RaceWeekendHandler.startConcurrentSessions)
+		r.Post("/race-weekend/{raceWeekendID}/autopilot", s.RaceWeekendHandler.autopilot)
+		r.Post("/race-weekend/{raceWeekendID}/session/{sessionID}/tyre-rules", s.RaceWeekendHandler.tyreRules)
+		r.Post("/race-weekend/{raceWeekendID}/session/{sessionID}/grid-overrides", s.RaceWeekendHandler.addGridOverride)
+		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/grid-overrides/{overrideID}/remove", s.RaceWeekendHandler.removeGridOverride)
 		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/cancel", s.RaceWeekendHandler.cancelSession)
 		r.Post("/race-weekend/{raceWeekendID}/session/{sessionID}/schedule", s.RaceWeekendHandler.scheduleSession)
 		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/schedule/remove", s.RaceWeekendHandler.removeSessionSchedule)
+		r.Post("/race-weekend/{raceWeekendID}/session/{sessionID}/knockout", s.RaceWeekendHandler.knockoutQualifying)
+		r.Post("/race-weekend/{raceWeekendID}/session/{sessionID}/heats", s.RaceWeekendHandler.heatRaces)
+		r.Post("/race-weekend/{raceWeekendID}/save-as-template", s.RaceWeekendHandler.saveAsTemplate)
+		r.Get("/race-weekend-template/{templateID}/instantiate", s.RaceWeekendHandler.instantiateTemplate)
+		r.Post("/race-weekend-template/{templateID}/instantiate", s.RaceWeekendHandler.instantiateTemplate)
 
 		// live timings
 		r.Post("/live-timing/save-frames", s.RaceControlHandler.saveIFrames)
@@ -345,6 +539,7 @@ func (s *Server) Router() chi.Router {
 
 		// race weekend
 		r.Get("/race-weekend/{raceWeekendID}/session/{sessionID}/delete", s.RaceWeekendHandler.deleteSession)
+		r.Get("/race-weekend-template/{templateID}/delete", s.RaceWeekendHandler.deleteTemplate)
 	})
 
 	// admins
@@ -360,6 +555,10 @@ func (s *Server) Router() chi.Router {
 		r.HandleFunc("/server-options", s.ServerAdministrationHandler.options)
 		r.HandleFunc("/blacklist", s.ServerAdministrationHandler.blacklist)
 		r.HandleFunc("/motd", s.ServerAdministrationHandler.motd)
+		r.HandleFunc("/notification-templates", s.NotificationTemplatesHandler.templates)
+
+		r.Get("/scheduled-jobs", s.JobsHandler.list)
+		r.Get("/scheduled-jobs/{jobID}/retry", s.JobsHandler.retry)
 	})
 
 	return r
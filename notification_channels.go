@@ -0,0 +1,368 @@
+package servermanager
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cj123/formulate"
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationEventType is the kind of thing that a notification is about. Each notification channel chooses which
+// types it receives.
+type NotificationEventType string
+
+const (
+	NotificationRaceStart     NotificationEventType = "race-start"
+	NotificationScheduled     NotificationEventType = "scheduled"
+	NotificationCancelled     NotificationEventType = "cancelled"
+	NotificationReminder      NotificationEventType = "reminder"
+	NotificationResultsPosted NotificationEventType = "results-posted"
+	NotificationOther         NotificationEventType = "other"
+
+	// these are only sent to individual drivers, see DriverNotificationPreferences
+	NotificationSignUpApproved NotificationEventType = "sign-up-approved"
+	NotificationPenalty        NotificationEventType = "penalty"
+
+	// these are replies to Discord commands, which are written with notification templates
+	NotificationDiscordSchedule NotificationEventType = "discord-schedule"
+	NotificationDiscordSessions NotificationEventType = "discord-sessions"
+)
+
+// A Notification is a message sent to every notification channel which receives its type.
+type Notification struct {
+	Type       NotificationEventType `json:"type"`
+	Title      string                `json:"title"`
+	Message    string                `json:"message"`
+	LinkText   string                `json:"link_text,omitempty"`
+	Link       string                `json:"link,omitempty"`
+	ServerName string                `json:"server"`
+	Time       time.Time             `json:"time"`
+}
+
+// NotificationChannel is somewhere notifications can be sent, e.g. Discord or an email address.
+type NotificationChannel interface {
+	Name() string
+	Receives(eventType NotificationEventType) bool
+	Send(notification *Notification) error
+}
+
+// NotificationEventSelection configures which types of notification a channel receives.
+type NotificationEventSelection struct {
+	RaceStart     formulate.BoolNumber `help:"Send a notification when an event starts."`
+	Scheduled     formulate.BoolNumber `help:"Send a notification when an event is scheduled. 'Notify When Scheduled' must also be turned on."`
+	Cancelled     formulate.BoolNumber `help:"Send a notification when a scheduled event is cancelled. 'Notify When Scheduled' must also be turned on."`
+	Reminders     formulate.BoolNumber `help:"Send the reminders set in 'Notification Reminder Timers'."`
+	ResultsPosted formulate.BoolNumber `help:"Send a notification when the results of a session are available."`
+	Other         formulate.BoolNumber `help:"Send any other notifications, such as missed scheduled events and Race Weekend Autopilot updates."`
+}
+
+func (s NotificationEventSelection) Receives(eventType NotificationEventType) bool {
+	switch eventType {
+	case NotificationRaceStart:
+		return s.RaceStart == 1
+	case NotificationScheduled:
+		return s.Scheduled == 1
+	case NotificationCancelled:
+		return s.Cancelled == 1
+	case NotificationReminder:
+		return s.Reminders == 1
+	case NotificationResultsPosted:
+		return s.ResultsPosted == 1
+	default:
+		return s.Other == 1
+	}
+}
+
+// notificationHTTPClient is used by the notification channels which send messages over HTTP.
+var notificationHTTPClient = &http.Client{
+	Timeout: time.Second * 10,
+}
+
+func postNotificationJSON(url string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	return postNotification(url, body, headers)
+}
+
+func postNotification(url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := notificationHTTPClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("servermanager: notification request to %s failed with status %d", req.URL.Host, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// plainTextNotification formats a notification for channels which don't support titles or links.
+func plainTextNotification(notification *Notification) string {
+	text := notification.Title + "\n\n" + notification.Message
+
+	if notification.Link != "" {
+		text += fmt.Sprintf("\n\n%s: %s", notification.LinkText, notification.Link)
+	}
+
+	return text
+}
+
+type WebhookNotificationConfig struct {
+	URL    string                     `help:"Notifications are sent to this URL as a JSON POST request."`
+	Secret string                     `type:"password" help:"If set, each request has an X-Server-Manager-Signature header containing 'sha256=' followed by the hex encoded HMAC-SHA256 of the request body, using this secret as the key."`
+	Events NotificationEventSelection `help:"Choose which notifications are sent to the webhook."`
+}
+
+// WebhookNotificationChannel sends notifications as JSON to a generic outgoing webhook.
+type WebhookNotificationChannel struct {
+	WebhookNotificationConfig
+}
+
+func (w *WebhookNotificationChannel) Name() string {
+	return "Webhook"
+}
+
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *WebhookNotificationChannel) Send(notification *Notification) error {
+	body, err := json.Marshal(notification)
+
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"X-Server-Manager-Event": string(notification.Type),
+	}
+
+	if w.Secret != "" {
+		headers["X-Server-Manager-Signature"] = webhookSignature(w.Secret, body)
+	}
+
+	return postNotification(w.URL, body, headers)
+}
+
+type SlackNotificationConfig struct {
+	WebhookURL string                     `help:"A Slack or Mattermost incoming webhook URL."`
+	Events     NotificationEventSelection `help:"Choose which notifications are sent to Slack or Mattermost."`
+}
+
+// SlackNotificationChannel sends notifications to a Slack (or Slack compatible, e.g. Mattermost) incoming webhook.
+type SlackNotificationChannel struct {
+	SlackNotificationConfig
+}
+
+func (s *SlackNotificationChannel) Name() string {
+	return "Slack"
+}
+
+func (s *SlackNotificationChannel) Send(notification *Notification) error {
+	text := fmt.Sprintf("*%s*\n%s", notification.Title, notification.Message)
+
+	if notification.Link != "" {
+		text += fmt.Sprintf("\n<%s|%s>", notification.Link, notification.LinkText)
+	}
+
+	return postNotificationJSON(s.WebhookURL, map[string]string{"text": text}, nil)
+}
+
+type TelegramNotificationConfig struct {
+	BotToken string                     `type:"password" help:"The token of your Telegram bot, from BotFather."`
+	ChatID   string                     `help:"The ID of the chat, group or channel to send messages to. The bot must be a member of it."`
+	Events   NotificationEventSelection `help:"Choose which notifications are sent to Telegram."`
+}
+
+// telegramAPIURL is the Telegram Bot API, which is replaced in tests.
+var telegramAPIURL = "https://api.telegram.org"
+
+// TelegramNotificationChannel sends notifications with the Telegram Bot API.
+type TelegramNotificationChannel struct {
+	TelegramNotificationConfig
+}
+
+func (t *TelegramNotificationChannel) Name() string {
+	return "Telegram"
+}
+
+func (t *TelegramNotificationChannel) Send(notification *Notification) error {
+	return postNotificationJSON(fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, t.BotToken), map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"text":                     plainTextNotification(notification),
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+type EmailNotificationConfig struct {
+	Host     string                     `help:"The hostname of your SMTP server."`
+	Port     int                        `min:"0" max:"65535" help:"The port of your SMTP server, usually 587."`
+	Username string                     `help:"If set, Server Manager will log in to the SMTP server with this username and the password below."`
+	Password string                     `type:"password"`
+	From     string                     `help:"The address emails are sent from."`
+	To       string                     `help:"The addresses emails are sent to. You may send to multiple addresses by using a comma separated list."`
+	Events   NotificationEventSelection `help:"Choose which notifications are sent by email."`
+}
+
+func (c EmailNotificationConfig) Recipients() []string {
+	var recipients []string
+
+	for _, recipient := range strings.Split(c.To, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	return recipients
+}
+
+// EmailNotificationChannel sends notifications by email over SMTP.
+type EmailNotificationChannel struct {
+	EmailNotificationConfig
+}
+
+func (e *EmailNotificationChannel) Name() string {
+	return "Email"
+}
+
+func (e *EmailNotificationChannel) Send(notification *Notification) error {
+	port := e.Port
+
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(e.Host, strconv.Itoa(port)), auth, e.From, e.Recipients(), []byte(e.message(notification)))
+}
+
+// message builds the email for a notification. Header values can't contain line breaks, which would start new
+// headers, and the subject is encoded so that it may contain any character.
+func (e *EmailNotificationChannel) message(notification *Notification) string {
+	return strings.Join([]string{
+		"From: " + emailHeaderValue(e.From),
+		"To: " + emailHeaderValue(strings.Join(e.Recipients(), ", ")),
+		"Subject: " + mime.QEncoding.Encode("UTF-8", emailHeaderValue(notification.Title)),
+		"Date: " + notification.Time.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		plainTextNotification(notification),
+	}, "\r\n")
+}
+
+var emailHeaderLineBreakReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+func emailHeaderValue(value string) string {
+	return emailHeaderLineBreakReplacer.Replace(value)
+}
+
+// notificationChannels are the channels which are configured in the server options, as well as Discord.
+func (nm *NotificationManager) notificationChannels(serverOpts *GlobalServerConfig) []NotificationChannel {
+	channels := []NotificationChannel{nm.discordManager}
+
+	if serverOpts.WebhookNotifications.URL != "" {
+		channels = append(channels, &WebhookNotificationChannel{serverOpts.WebhookNotifications})
+	}
+
+	if serverOpts.SlackNotifications.WebhookURL != "" {
+		channels = append(channels, &SlackNotificationChannel{serverOpts.SlackNotifications})
+	}
+
+	if serverOpts.TelegramNotifications.BotToken != "" && serverOpts.TelegramNotifications.ChatID != "" {
+		channels = append(channels, &TelegramNotificationChannel{serverOpts.TelegramNotifications})
+	}
+
+	if serverOpts.EmailNotifications.Host != "" && len(serverOpts.EmailNotifications.Recipients()) > 0 {
+		channels = append(channels, &EmailNotificationChannel{serverOpts.EmailNotifications})
+	}
+
+	return channels
+}
+
+func (w *WebhookNotificationChannel) Receives(eventType NotificationEventType) bool {
+	return w.Events.Receives(eventType)
+}
+
+func (s *SlackNotificationChannel) Receives(eventType NotificationEventType) bool {
+	return s.Events.Receives(eventType)
+}
+
+func (t *TelegramNotificationChannel) Receives(eventType NotificationEventType) bool {
+	return t.Events.Receives(eventType)
+}
+
+func (e *EmailNotificationChannel) Receives(eventType NotificationEventType) bool {
+	return e.Events.Receives(eventType)
+}
+
+// Discord is configured with its own options, in the Discord section of the server options.
+func (dm *DiscordManager) Name() string {
+	return "Discord"
+}
+
+func (dm *DiscordManager) Receives(eventType NotificationEventType) bool {
+	opts, err := dm.store.LoadServerOptions()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load server options")
+		return false
+	}
+
+	return opts.DiscordNotificationEvents.Receives(eventType)
+}
+
+func (dm *DiscordManager) Send(notification *Notification) error {
+	if notification.Link == "" {
+		return dm.SendMessage(notification.Title, notification.Message)
+	}
+
+	link, err := url.Parse(notification.Link)
+
+	if err != nil {
+		return err
+	}
+
+	return dm.SendMessageWithLink(notification.Title, notification.Message, notification.LinkText, link)
+}
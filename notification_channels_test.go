@@ -0,0 +1,331 @@
+package servermanager
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNotification = &Notification{
+	Type:       NotificationRaceStart,
+	Title:      "Event starting at Barbagello",
+	Message:    "Event at Barbagello is starting now",
+	LinkText:   "Content Manager join link",
+	Link:       "https://acstuff.ru/s/q:race/online/join?ip=127.0.0.1&httpPort=8081",
+	ServerName: "Test Server",
+	Time:       time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestNotificationEventSelection_Receives(t *testing.T) {
+	selection := NotificationEventSelection{
+		RaceStart:     1,
+		ResultsPosted: 1,
+	}
+
+	if !selection.Receives(NotificationRaceStart) || !selection.Receives(NotificationResultsPosted) {
+		t.Log("Expected selection to receive race start and results posted notifications")
+		t.Fail()
+	}
+
+	if selection.Receives(NotificationScheduled) || selection.Receives(NotificationReminder) || selection.Receives(NotificationOther) {
+		t.Log("Expected selection to not receive unselected notifications")
+		t.Fail()
+	}
+}
+
+func TestDiscordManager_Receives(t *testing.T) {
+	store := NewJSONStore(filepath.Join(os.TempDir(), "asm-discord-notifications-store"), filepath.Join(os.TempDir(), "asm-discord-notifications-store-shared"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-discord-notifications-store"))
+	defer os.RemoveAll(filepath.Join(os.TempDir(), "asm-discord-notifications-store-shared"))
+
+	// Discord received every notification before its notifications could be chosen, so upgrades keep them all.
+	if err := addDiscordNotificationEvents(store); err != nil {
+		t.Error(err)
+		return
+	}
+
+	dm := &DiscordManager{store: store}
+
+	for _, eventType := range []NotificationEventType{NotificationRaceStart, NotificationScheduled, NotificationCancelled, NotificationReminder, NotificationResultsPosted, NotificationOther} {
+		if !dm.Receives(eventType) {
+			t.Logf("Expected Discord to receive %s notifications after upgrading", eventType)
+			t.Fail()
+		}
+	}
+
+	opts, err := store.LoadServerOptions()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opts.DiscordNotificationEvents = NotificationEventSelection{ResultsPosted: 1}
+
+	if err := store.UpsertServerOptions(opts); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !dm.Receives(NotificationResultsPosted) || dm.Receives(NotificationReminder) || dm.Receives(NotificationOther) {
+		t.Log("Expected Discord to only receive the notifications chosen in the server options")
+		t.Fail()
+	}
+}
+
+func TestWebhookNotificationChannel_Send(t *testing.T) {
+	var received *Notification
+	var signature string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		signature = r.Header.Get("X-Server-Manager-Signature")
+
+		if signature != webhookSignature("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	channel := &WebhookNotificationChannel{WebhookNotificationConfig{URL: server.URL, Secret: "secret"}}
+
+	if err := channel.Send(testNotification); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Logf("Expected sha256 signature, got: %s", signature)
+		t.Fail()
+	}
+
+	if received == nil || received.Title != testNotification.Title || received.Type != NotificationRaceStart || received.Link != testNotification.Link {
+		t.Logf("Webhook payload did not match notification, got: %#v", received)
+		t.Fail()
+	}
+
+	channel.Secret = "wrong-secret"
+
+	if err := channel.Send(testNotification); err == nil {
+		t.Log("Expected a request with the wrong signature to fail")
+		t.Fail()
+	}
+}
+
+func TestSlackNotificationChannel_Send(t *testing.T) {
+	var payload map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	channel := &SlackNotificationChannel{SlackNotificationConfig{WebhookURL: server.URL}}
+
+	if err := channel.Send(testNotification); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !strings.HasPrefix(payload["text"], "*Event starting at Barbagello*") || !strings.Contains(payload["text"], "<"+testNotification.Link+"|Content Manager join link>") {
+		t.Logf("Unexpected slack message text: %s", payload["text"])
+		t.Fail()
+	}
+}
+
+func TestTelegramNotificationChannel_Send(t *testing.T) {
+	var path string
+	var payload map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	defaultTelegramAPIURL := telegramAPIURL
+	telegramAPIURL = server.URL
+	defer func() {
+		telegramAPIURL = defaultTelegramAPIURL
+	}()
+
+	channel := &TelegramNotificationChannel{TelegramNotificationConfig{BotToken: "123:abc", ChatID: "-100123"}}
+
+	if err := channel.Send(testNotification); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if path != "/bot123:abc/sendMessage" {
+		t.Logf("Unexpected telegram API path: %s", path)
+		t.Fail()
+	}
+
+	if payload["chat_id"] != "-100123" || !strings.Contains(payload["text"].(string), testNotification.Message) {
+		t.Logf("Unexpected telegram payload: %#v", payload)
+		t.Fail()
+	}
+}
+
+// smtpStandIn accepts a single email over SMTP and sends its recipients and data to the returned channel.
+func smtpStandIn(t *testing.T) (host string, port int, received chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received = make(chan []string, 1)
+
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+
+		var lines []string
+		inData := false
+
+		reply("220 localhost SMTP stand-in")
+
+		for {
+			line, err := reader.ReadString('\n')
+
+			if err != nil {
+				return
+			}
+
+			line = strings.TrimRight(line, "\r\n")
+
+			if inData {
+				if line == "." {
+					inData = false
+					reply("250 OK")
+					continue
+				}
+
+				lines = append(lines, line)
+				continue
+			}
+
+			switch {
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(line, "RCPT TO"):
+				lines = append(lines, line)
+				reply("250 OK")
+			case line == "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case line == "QUIT":
+				reply("221 Bye")
+				received <- lines
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestEmailNotificationChannel_Send(t *testing.T) {
+	host, port, received := smtpStandIn(t)
+
+	channel := &EmailNotificationChannel{EmailNotificationConfig{
+		Host: host,
+		Port: port,
+		From: "server@example.com",
+		To:   "driver1@example.com, driver2@example.com",
+	}}
+
+	if err := channel.Send(testNotification); err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case lines := <-received:
+		email := strings.Join(lines, "\n")
+
+		if !strings.Contains(email, "RCPT TO:<driver1@example.com>") || !strings.Contains(email, "RCPT TO:<driver2@example.com>") {
+			t.Logf("Expected email to be sent to both recipients, got: %s", email)
+			t.Fail()
+		}
+
+		if !strings.Contains(email, "Subject: "+testNotification.Title) || !strings.Contains(email, testNotification.Message) {
+			t.Logf("Expected email to contain the notification, got: %s", email)
+			t.Fail()
+		}
+	case <-time.After(time.Second * 5):
+		t.Log("Timed out waiting for the SMTP stand-in to receive the email, port: " + strconv.Itoa(port))
+		t.Fail()
+	}
+}
+
+func TestEmailNotificationChannel_Message(t *testing.T) {
+	channel := &EmailNotificationChannel{EmailNotificationConfig{
+		From: "server@example.com\r\nBcc: from@example.com",
+		To:   "driver1@example.com",
+	}}
+
+	notification := &Notification{
+		Title:   "Race at Nürburgring\r\nBcc: attacker@example.com",
+		Message: "The race has started",
+		Time:    time.Now(),
+	}
+
+	headers := strings.SplitN(channel.message(notification), "\r\n\r\n", 2)[0]
+
+	for _, header := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(header, "Bcc:") {
+			t.Logf("Expected line breaks to be stripped from header values, got: %s", headers)
+			t.Fail()
+		}
+	}
+
+	if !strings.Contains(headers, "Subject: =?UTF-8?q?Race_at_N=C3=BCrburgring__Bcc:_attacker@example.com?=") {
+		t.Logf("Expected the subject to be Q-encoded, got: %s", headers)
+		t.Fail()
+	}
+}
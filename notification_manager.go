@@ -8,7 +8,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/hako/durafmt"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,11 +22,15 @@ type NotificationDispatcher interface {
 	SendRaceReminderMessage(event *CustomRace, timer int) error
 	SendChampionshipReminderMessage(championship *Championship, event *ChampionshipEvent, timer int) error
 	SendRaceWeekendReminderMessage(raceWeekend *RaceWeekend, session *RaceWeekendSession, timer int) error
+	SendResultsPostedMessage(summary *ResultsSummary) error
+	SendDriverMessage(guid string, eventType NotificationEventType, title, msg string) error
+	SendSignUpApprovedMessage(championship *Championship, guid string) error
+	SendPenaltyMessage(guid string, sessionType, track string, penalty time.Duration, reason string) error
 	SaveServerOptions(oldServerOpts *GlobalServerConfig, newServerOpts *GlobalServerConfig) error
 }
 
-// NotificationManager is the generic notification handler, which calls the individual notification
-// managers. Initially, only a Discord manager is implemented.
+// NotificationManager is the generic notification handler, which sends notifications to each of the configured
+// NotificationChannels.
 type NotificationManager struct {
 	discordManager *DiscordManager
 	carManager     *CarManager
@@ -72,9 +75,15 @@ func (nm *NotificationManager) GetNotificationReminders() []int {
 		return reminders
 	}
 
-	timers := strings.Split(serverOpts.NotificationReminderTimers, ",")
+	return parseReminderTimers(serverOpts.NotificationReminderTimers)
+}
+
+// parseReminderTimers reads a comma separated list of reminder timers in minutes, e.g. "120,15".
+// Doesn't return errors, just omits anything it doesn't like and logs errors
+func parseReminderTimers(timers string) []int {
+	var reminders []int
 
-	for _, a := range timers {
+	for _, a := range strings.Split(timers, ",") {
 		if strings.TrimSpace(a) == "" {
 			continue
 		}
@@ -98,26 +107,59 @@ func (nm *NotificationManager) GetNotificationReminders() []int {
 
 // SendMessage sends a message (surprise surprise)
 func (nm *NotificationManager) SendMessage(title string, msg string) error {
-	var err error
-
-	// Call all message senders here ... atm just discord.  The manager will know if it's enabled or not, so just call it
-	if !nm.testing {
-		err = nm.discordManager.SendMessage(title, msg)
-	}
-
-	return err
+	return nm.sendNotification(NotificationOther, title, msg, "", nil)
 }
 
 // SendMessageWithLink sends a message with an embedded CM join link
 func (nm *NotificationManager) SendMessageWithLink(title string, msg string, linkText string, link *url.URL) error {
-	var err error
+	return nm.sendNotification(NotificationOther, title, msg, linkText, link)
+}
+
+// sendNotification sends a notification to every channel which receives its type. A channel failing to send does not
+// stop the others from being sent to.
+func (nm *NotificationManager) sendNotification(eventType NotificationEventType, title, msg, linkText string, link *url.URL) error {
+	if nm.testing {
+		return nil
+	}
+
+	serverOpts, err := nm.store.LoadServerOptions()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load server options, skipping notification")
+		return err
+	}
+
+	notification := &Notification{
+		Type:       eventType,
+		Title:      title,
+		Message:    msg,
+		LinkText:   linkText,
+		ServerName: serverOpts.Name,
+		Time:       time.Now(),
+	}
 
-	// Call all message senders here ... atm just discord.  The manager will know if it's enabled or not, so just call it
-	if !nm.testing {
-		err = nm.discordManager.SendMessageWithLink(title, msg, linkText, link)
+	if link != nil {
+		notification.Link = link.String()
 	}
 
-	return err
+	var failedChannels []string
+
+	for _, channel := range nm.notificationChannels(serverOpts) {
+		if !channel.Receives(eventType) {
+			continue
+		}
+
+		if err := channel.Send(notification); err != nil {
+			logrus.WithError(err).Errorf("couldn't send notification to %s", channel.Name())
+			failedChannels = append(failedChannels, channel.Name())
+		}
+	}
+
+	if len(failedChannels) > 0 {
+		return fmt.Errorf("servermanager: couldn't send notification to: %s", strings.Join(failedChannels, ", "))
+	}
+
+	return nil
 }
 
 // SendRaceStartMessage sends a message as a race session is started
@@ -129,33 +171,28 @@ func (nm *NotificationManager) SendRaceStartMessage(config ServerConfig, event R
 		return err
 	}
 
-	msg := ""
-	eventName := event.EventName()
-	trackInfo := trackSummary(config.CurrentRaceConfig.Track, config.CurrentRaceConfig.TrackLayout)
-
-	if eventName != "" {
-		msg = fmt.Sprintf("%s event at %s is starting now", eventName, trackInfo)
-	} else {
-		msg = fmt.Sprintf("Event at %s is starting now", trackInfo)
+	data := &NotificationTemplateData{
+		EventName:    event.EventName(),
+		ShowPassword: serverOpts.ShowPasswordInNotifications == 1,
 	}
 
-	msg += fmt.Sprintf("\nServer: %s", serverOpts.Name)
-
-	if serverOpts.ShowPasswordInNotifications == 1 {
-		passwordString := "\nNo password"
+	nm.addEventTemplateData(data, config.CurrentRaceConfig)
 
-		if event.OverrideServerPassword() {
-			if event.ReplacementServerPassword() != "" {
-				passwordString = fmt.Sprintf("\nPassword is '%s' (no quotes)", event.ReplacementServerPassword())
-			}
-		} else if config.GlobalServerConfig.Password != "" {
-			passwordString = fmt.Sprintf("\nPassword is '%s' (no quotes)", config.GlobalServerConfig.Password)
-		}
+	// the download links of the track and cars are not shown as the event has already started
+	data.TrackInfo = data.Track
+	data.CarList = strings.Join(data.Cars, ", ")
 
-		msg += passwordString
+	if event.OverrideServerPassword() {
+		data.Password = event.ReplacementServerPassword()
+	} else {
+		data.Password = config.GlobalServerConfig.Password
 	}
 
-	title := fmt.Sprintf("Event starting at %s", trackInfo)
+	title, msg, err := renderNotification(serverOpts, NotificationRaceStart, data)
+
+	if err != nil {
+		return err
+	}
 
 	if config.GlobalServerConfig.ShowContentManagerJoinLink == 1 {
 		link, err := getContentManagerJoinLink(config.GlobalServerConfig)
@@ -164,20 +201,20 @@ func (nm *NotificationManager) SendRaceStartMessage(config ServerConfig, event R
 		if err != nil {
 			logrus.WithError(err).Errorf("could not get CM join link")
 
-			return nm.SendMessage(title, msg)
+			return nm.sendNotification(NotificationRaceStart, title, msg, "", nil)
 		}
 
-		linkText = "Content Manager join link"
+		linkText = getLocaliser().Localise(serverOpts.NotificationLanguage, "ContentManagerJoinLink", data)
 
 		// delay sending message by 20 seconds to give server time to register with lobby so CM link works
 		time.AfterFunc(time.Duration(20)*time.Second, func() {
-			_ = nm.SendMessageWithLink(title, msg, linkText, link)
+			_ = nm.sendNotification(NotificationRaceStart, title, msg, linkText, link)
 		})
 
 		return nil
 	}
 
-	return nm.SendMessage(title, msg)
+	return nm.sendNotification(NotificationRaceStart, title, msg, "", nil)
 }
 
 // GetCarList takes a ; sep string of cars from a race config, returns , sep of UI names with download links added
@@ -217,6 +254,25 @@ func (nm *NotificationManager) GetTrackInfo(track string, layout string, downloa
 	return trackInfo
 }
 
+// addEventTemplateData adds the track and cars of an event to the data used by notification templates.
+func (nm *NotificationManager) addEventTemplateData(data *NotificationTemplateData, raceConfig CurrentRaceConfig) {
+	data.Track = trackSummary(raceConfig.Track, raceConfig.TrackLayout)
+	data.TrackInfo = nm.GetTrackInfo(raceConfig.Track, raceConfig.TrackLayout, true)
+	data.CarList = nm.GetCarList(raceConfig.Cars)
+
+	for _, carName := range strings.Split(raceConfig.Cars, ";") {
+		if carName == "" {
+			continue
+		}
+
+		if car, err := nm.carManager.LoadCar(carName, nil); err == nil {
+			data.Cars = append(data.Cars, car.Details.Name)
+		} else {
+			data.Cars = append(data.Cars, prettifyName(carName, true))
+		}
+	}
+}
+
 // SendRaceScheduledMessage sends a notification when a race is scheduled
 func (nm *NotificationManager) SendRaceScheduledMessage(event *CustomRace, date time.Time) error {
 	serverOpts, err := nm.store.LoadServerOptions()
@@ -230,22 +286,20 @@ func (nm *NotificationManager) SendRaceScheduledMessage(event *CustomRace, date
 		return nil
 	}
 
-	msg := "A new event has been scheduled\n"
-	msg += fmt.Sprintf("Server: %s\n", serverOpts.Name)
-	eventName := event.EventName()
-
-	if eventName != "" {
-		msg += fmt.Sprintf("Event name: %s\n", eventName)
+	data := &NotificationTemplateData{
+		EventName: event.EventName(),
+		Date:      date,
 	}
 
-	msg += fmt.Sprintf("Date: %s\n", date.Format("Mon, 02 Jan 2006 15:04:05 MST"))
-	carNames := nm.GetCarList(event.RaceConfig.Cars)
-	trackInfo := nm.GetTrackInfo(event.RaceConfig.Track, event.RaceConfig.TrackLayout, true)
-	msg += fmt.Sprintf("Track: %s\n", trackInfo)
-	msg += fmt.Sprintf("Car(s): %s\n", carNames)
-	title := fmt.Sprintf("Event scheduled at %s", nm.GetTrackInfo(event.RaceConfig.Track, event.RaceConfig.TrackLayout, false))
+	nm.addEventTemplateData(data, event.RaceConfig)
+
+	title, msg, err := renderNotification(serverOpts, NotificationScheduled, data)
+
+	if err != nil {
+		return err
+	}
 
-	return nm.SendMessage(title, msg)
+	return nm.sendNotification(NotificationScheduled, title, msg, "", nil)
 }
 
 // SendRaceCancelledMessage sends a notification when a race is cancelled
@@ -261,56 +315,164 @@ func (nm *NotificationManager) SendRaceCancelledMessage(event *CustomRace, date
 		return nil
 	}
 
-	dateStr := date.Format("Mon, 02 Jan 2006 15:04:05 MST")
+	data := &NotificationTemplateData{
+		EventName: event.EventName(),
+		Date:      date,
+	}
+
+	nm.addEventTemplateData(data, event.RaceConfig)
+
+	// the event won't happen, so there's no need to download its content
+	data.TrackInfo = data.Track
+
+	title, msg, err := renderNotification(serverOpts, NotificationCancelled, data)
+
+	if err != nil {
+		return err
+	}
+
+	return nm.sendNotification(NotificationCancelled, title, msg, "", nil)
+}
 
-	msg := "The following scheduled Event has been cancelled\n"
-	msg += fmt.Sprintf("Server: %s\n", serverOpts.Name)
-	eventName := event.EventName()
-	trackInfo := trackSummary(event.RaceConfig.Track, event.RaceConfig.TrackLayout)
+// sendReminder writes a reminder using the reminder notification template, and sends it to the notification channels
+// and to each of the given drivers.
+func (nm *NotificationManager) sendReminder(guids []string, data *NotificationTemplateData) error {
+	serverOpts, err := nm.store.LoadServerOptions()
 
-	if eventName != "" {
-		msg += fmt.Sprintf("Event name: %s\n", eventName)
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load server options, skipping notification")
+		return err
 	}
 
-	msg += fmt.Sprintf("Date: %s\n", dateStr)
-	msg += fmt.Sprintf("Track: %s\n", trackInfo)
-	title := fmt.Sprintf("Event cancelled at %s", trackInfo)
+	title, msg, err := renderNotification(serverOpts, NotificationReminder, data)
 
-	return nm.SendMessage(title, msg)
+	if err != nil {
+		return err
+	}
+
+	nm.sendDriverMessages(guids, NotificationReminder, title, msg)
+
+	return nm.sendNotification(NotificationReminder, title, msg, "", nil)
 }
 
-// SendRaceReminderMessage sends a reminder a configurable number of minutes prior to a race starting
+// SendRaceReminderMessage sends a reminder a configurable number of minutes prior to a race starting, to the
+// notification channels and to each driver in the entry list.
 func (nm *NotificationManager) SendRaceReminderMessage(event *CustomRace, timer int) error {
-	msg := ""
-	trackInfo := nm.GetTrackInfo(event.RaceConfig.Track, event.RaceConfig.TrackLayout, true)
-	eventName := event.EventName()
-	carList := nm.GetCarList(event.RaceConfig.Cars)
-	reminder := durafmt.Parse(time.Duration(timer) * time.Minute).String()
-
-	if eventName != "" {
-		msg = fmt.Sprintf("%s event at %s starts in %s\nCars: %s", eventName, trackInfo, reminder, carList)
-	} else {
-		msg = fmt.Sprintf("Event at %s starts in %s\nCars: %s", trackInfo, reminder, carList)
+	data := &NotificationTemplateData{
+		EventName:       event.EventName(),
+		ReminderMinutes: timer,
 	}
 
-	title := fmt.Sprintf("Event reminder - %s", reminder)
-	return nm.SendMessage(title, msg)
+	nm.addEventTemplateData(data, event.RaceConfig)
+
+	return nm.sendReminder(entryListGUIDs(event.EntryList), data)
 }
 
 // SendChampionshipReminderMessage sends a reminder a configurable number of minutes prior to a championship race starting
 func (nm *NotificationManager) SendChampionshipReminderMessage(championship *Championship, event *ChampionshipEvent, timer int) error {
-	reminder := durafmt.Parse(time.Duration(timer) * time.Minute).String()
-	title := fmt.Sprintf("Event reminder - %s", reminder)
-	trackInfo := nm.GetTrackInfo(event.RaceSetup.Track, event.RaceSetup.TrackLayout, true)
-	msg := fmt.Sprintf("%s event at %s starts in %s", championship.Name, trackInfo, reminder)
-	return nm.SendMessage(title, msg)
+	data := &NotificationTemplateData{
+		EventName:         championship.Name,
+		ReminderMinutes:   timer,
+		Championship:      championship,
+		ChampionshipEvent: event,
+	}
+
+	nm.addEventTemplateData(data, event.RaceSetup)
+
+	// the default reminder template only lists the cars of Custom Races, templates can still use .Cars
+	data.CarList = ""
+
+	var guids []string
+
+	for _, guid := range entryListGUIDs(championship.AllEntrants()) {
+		if !event.IsWithdrawn(guid) {
+			guids = append(guids, guid)
+		}
+	}
+
+	return nm.sendReminder(guids, data)
 }
 
 // SendRaceWeekendReminderMessage sends a reminder a configurable number of minutes prior to a RaceWeekendSession starting
 func (nm *NotificationManager) SendRaceWeekendReminderMessage(raceWeekend *RaceWeekend, session *RaceWeekendSession, timer int) error {
-	reminder := durafmt.Parse(time.Duration(timer) * time.Minute).String()
-	title := fmt.Sprintf("Event reminder - %s", reminder)
-	trackInfo := nm.GetTrackInfo(session.RaceConfig.Track, session.RaceConfig.TrackLayout, true)
-	msg := fmt.Sprintf("%s at %s (%s Race Weekend) starts in %s", session.Name(), raceWeekend.Name, trackInfo, reminder)
-	return nm.SendMessage(title, msg)
+	data := &NotificationTemplateData{
+		EventName:          raceWeekend.Name,
+		ReminderMinutes:    timer,
+		RaceWeekend:        raceWeekend,
+		RaceWeekendSession: session,
+		SessionName:        session.Name(),
+	}
+
+	nm.addEventTemplateData(data, session.RaceConfig)
+
+	data.CarList = ""
+
+	return nm.sendReminder(entryListGUIDs(raceWeekend.GetEntryList()), data)
+}
+
+// resultsNotification writes a summary of the results of a session using the results notification template.
+func resultsNotification(serverOpts *GlobalServerConfig, summary *ResultsSummary) (title, msg string, err error) {
+	return renderNotification(serverOpts, NotificationResultsPosted, &NotificationTemplateData{
+		EventName:      summary.EventName,
+		Track:          summary.Track,
+		TrackInfo:      summary.Track,
+		Date:           summary.Date,
+		SessionType:    summary.SessionType,
+		ResultsSummary: summary,
+	})
+}
+
+// SendResultsPostedMessage sends a summary of the results of a session, written using the results notification
+// template in the server options.
+func (nm *NotificationManager) SendResultsPostedMessage(summary *ResultsSummary) error {
+	serverOpts, err := nm.store.LoadServerOptions()
+
+	if err != nil {
+		return err
+	}
+
+	title, msg, err := resultsNotification(serverOpts, summary)
+
+	if err != nil {
+		return err
+	}
+
+	if config == nil || config.HTTP.BaseURL == "" {
+		return nm.sendNotification(NotificationResultsPosted, title, msg, "", nil)
+	}
+
+	link, err := url.Parse(config.HTTP.BaseURL + "/results/" + summary.SessionFile)
+
+	if err != nil {
+		return err
+	}
+
+	return nm.sendNotification(NotificationResultsPosted, title, msg, getLocaliser().Localise(serverOpts.NotificationLanguage, "ResultsViewLink", nil), link)
+}
+
+// SendTestNotification sends a notification to every notification channel, whichever notifications they receive.
+// It is used to try out notification templates.
+func (nm *NotificationManager) SendTestNotification(serverOpts *GlobalServerConfig, title, msg string) error {
+	notification := &Notification{
+		Type:       NotificationOther,
+		Title:      getLocaliser().Localise(serverOpts.NotificationLanguage, "TestNotificationTitle", map[string]string{"Title": title}),
+		Message:    msg,
+		ServerName: serverOpts.Name,
+		Time:       time.Now(),
+	}
+
+	var failedChannels []string
+
+	for _, channel := range nm.notificationChannels(serverOpts) {
+		if err := channel.Send(notification); err != nil {
+			logrus.WithError(err).Errorf("couldn't send test notification to %s", channel.Name())
+			failedChannels = append(failedChannels, channel.Name())
+		}
+	}
+
+	if len(failedChannels) > 0 {
+		return fmt.Errorf("servermanager: couldn't send notification to: %s", strings.Join(failedChannels, ", "))
+	}
+
+	return nil
 }
@@ -0,0 +1,472 @@
+package servermanager
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrEmptyNotificationTemplate = errors.New("servermanager: notification template produced an empty title")
+
+// NotificationTemplates customise the notifications that Server Manager sends. Each template is a Go text/template
+// which is executed with a NotificationTemplateData. The first line that the template writes is used as the title of
+// the notification, the rest is its message. Empty templates use the default templates.
+type NotificationTemplates struct {
+	RaceStart      string
+	RaceScheduled  string
+	RaceCancelled  string
+	Reminder       string
+	ResultsPosted  string
+	SignUpApproved string
+	Penalty        string
+
+	DiscordSchedule string
+	DiscordSessions string
+}
+
+// Get returns the template for a type of notification, or the default template if it has not been customised.
+func (t NotificationTemplates) Get(eventType NotificationEventType) string {
+	var tmpl string
+
+	switch eventType {
+	case NotificationRaceStart:
+		tmpl = t.RaceStart
+	case NotificationScheduled:
+		tmpl = t.RaceScheduled
+	case NotificationCancelled:
+		tmpl = t.RaceCancelled
+	case NotificationReminder:
+		tmpl = t.Reminder
+	case NotificationResultsPosted:
+		tmpl = t.ResultsPosted
+	case NotificationSignUpApproved:
+		tmpl = t.SignUpApproved
+	case NotificationPenalty:
+		tmpl = t.Penalty
+	case NotificationDiscordSchedule:
+		tmpl = t.DiscordSchedule
+	case NotificationDiscordSessions:
+		tmpl = t.DiscordSessions
+	}
+
+	if strings.TrimSpace(tmpl) == "" {
+		return defaultNotificationTemplates[eventType]
+	}
+
+	return tmpl
+}
+
+// Set customises the template for a type of notification. Setting a template to its default clears it, so that
+// changes to the default template are picked up.
+func (t *NotificationTemplates) Set(eventType NotificationEventType, tmpl string) {
+	tmpl = strings.Replace(tmpl, "\r\n", "\n", -1)
+
+	if strings.TrimSpace(tmpl) == strings.TrimSpace(defaultNotificationTemplates[eventType]) {
+		tmpl = ""
+	}
+
+	switch eventType {
+	case NotificationRaceStart:
+		t.RaceStart = tmpl
+	case NotificationScheduled:
+		t.RaceScheduled = tmpl
+	case NotificationCancelled:
+		t.RaceCancelled = tmpl
+	case NotificationReminder:
+		t.Reminder = tmpl
+	case NotificationResultsPosted:
+		t.ResultsPosted = tmpl
+	case NotificationSignUpApproved:
+		t.SignUpApproved = tmpl
+	case NotificationPenalty:
+		t.Penalty = tmpl
+	case NotificationDiscordSchedule:
+		t.DiscordSchedule = tmpl
+	case NotificationDiscordSessions:
+		t.DiscordSessions = tmpl
+	}
+}
+
+// NotificationTemplateType describes a type of notification which can be customised with a template.
+type NotificationTemplateType struct {
+	Type        NotificationEventType
+	Name        string
+	Description string
+}
+
+var notificationTemplateTypes = []NotificationTemplateType{
+	{NotificationRaceStart, "Race Start", "Sent to the notification channels when an event starts. .ShowPassword is true if 'Show Password In Notifications' is on."},
+	{NotificationScheduled, "Race Scheduled", "Sent to the notification channels when a Custom Race is scheduled, if 'Notify When Scheduled' is on."},
+	{NotificationCancelled, "Race Cancelled", "Sent to the notification channels when a scheduled Custom Race is cancelled, if 'Notify When Scheduled' is on."},
+	{NotificationReminder, "Reminder", "Sent to the notification channels and entered drivers before an event starts. .Championship is set for Championship events, .RaceWeekend and .SessionName for Race Weekend sessions."},
+	{NotificationResultsPosted, "Results Posted", "Sent to the notification channels when a session ends, and used by the Discord !results command. Use .Podium, .FastestLap, .Incidents, .ChampionshipName and .Standings."},
+	{NotificationSignUpApproved, "Sign Up Approved", "Sent to a driver when their Championship sign up is accepted. .ChampionshipEvent is the next event of the Championship, if there is one."},
+	{NotificationPenalty, "Penalty", "Sent to a driver when race control gives them a penalty. .Disqualified is true for disqualifications, .PenaltyReason is the ID of a message explaining the penalty."},
+	{NotificationDiscordSchedule, "Discord !schedule", "The reply to the Discord !schedule command. .ScheduledEvents are the events in the next week, each with a .Date, .TrackInfo and .CarList."},
+	{NotificationDiscordSessions, "Discord !sessions", "The reply to the Discord !sessions command. .ScheduledEvents are the sessions in the next week, each with a .Date, .Title and .Description."},
+}
+
+// defaultNotificationTemplates are used for each type of notification unless a template is set in the server options.
+// They are written with translated messages, see localisation.go.
+var defaultNotificationTemplates = map[NotificationEventType]string{
+	NotificationRaceStart: `{{ T "RaceStartTitle" . }}
+{{ if .EventName }}{{ T "RaceStartNamedMessage" . }}{{ else }}{{ T "RaceStartMessage" . }}{{ end }}
+{{ T "ServerLine" . }}{{ if .ShowPassword }}
+{{ if .Password }}{{ T "PasswordLine" . }}{{ else }}{{ T "NoPasswordLine" . }}{{ end }}{{ end }}`,
+
+	NotificationScheduled: `{{ T "ScheduledTitle" . }}
+{{ T "ScheduledMessage" . }}
+{{ T "ServerLine" . }}{{ if .EventName }}
+{{ T "EventNameLine" . }}{{ end }}
+{{ T "DateLine" . }}
+{{ T "TrackLine" . }}
+{{ T "CarsLine" . }}`,
+
+	NotificationCancelled: `{{ T "CancelledTitle" . }}
+{{ T "CancelledMessage" . }}
+{{ T "ServerLine" . }}{{ if .EventName }}
+{{ T "EventNameLine" . }}{{ end }}
+{{ T "DateLine" . }}
+{{ T "TrackLine" . }}`,
+
+	NotificationReminder: `{{ T "ReminderTitle" . }}
+{{ if .RaceWeekend }}{{ T "ReminderRaceWeekendMessage" . }}{{ else if .EventName }}{{ T "ReminderNamedMessage" . }}{{ else }}{{ T "ReminderMessage" . }}{{ end }}{{ if .CarList }}
+{{ T "CarsLine" . }}{{ end }}`,
+
+	NotificationResultsPosted: `{{ T "ResultsPostedTitle" . }}
+{{ if .EventName }}{{ .EventName }} - {{ end }}{{ T "ResultsSessionLine" . }}
+{{ range .Podium }}
+P{{ .Position }}: {{ .DriverName }} ({{ .CarName }}){{ if .Time }} {{ .Time }}{{ end }}{{ end }}
+{{ with .FastestLap }}
+{{ T "ResultsFastestLap" . }}
+{{ end }}{{ if .Incidents }}
+{{ T "ResultsIncidents" . }}{{ range .Incidents }}
+- {{ if .Disqualified }}{{ T "ResultsIncidentDisqualified" . }}{{ else if .LapPenalty }}{{ T "ResultsIncidentLapPenalty" . }}{{ else if .Penalty }}{{ T "ResultsIncidentTimePenalty" . }}{{ else }}{{ T "ResultsIncidentCollision" . }}{{ end }}{{ end }}
+{{ end }}{{ range .Standings }}
+{{ if .ClassName }}{{ T "ResultsClassStandings" . }}{{ else }}{{ T "ResultsStandings" . }}{{ end }}{{ range .Standings }}
+{{ .Position }}. {{ .DriverName }} - {{ T "ResultsPoints" . }} {{ if .New }}{{ T "ResultsNewInStandings" . }}{{ else }}{{ .MovementArrow }}{{ end }}{{ end }}
+{{ end }}`,
+
+	NotificationSignUpApproved: `{{ T "SignUpApprovedTitle" . }}
+{{ T "SignUpApprovedMessage" . }}{{ if .ChampionshipEvent }}
+{{ if .TimeUntil }}{{ T "SignUpApprovedNextEventScheduled" . }}{{ else }}{{ T "SignUpApprovedNextEvent" . }}{{ end }}{{ end }}`,
+
+	NotificationPenalty: `{{ T "PenaltyTitle" . }}
+{{ if .Disqualified }}{{ T "PenaltyDisqualifiedMessage" . }}{{ else }}{{ T "PenaltyMessage" . }}{{ end }}{{ with .PenaltyReason }} {{ T . }}{{ end }}.`,
+
+	NotificationDiscordSchedule: `{{ T "DiscordScheduleTitle" . }}
+{{ range .ScheduledEvents }}
+{{ T "DateLine" . }}
+{{ T "TrackLine" . }}
+{{ T "CarsLine" . }}
+{{ else }}{{ T "DiscordNothingScheduled" . }}{{ end }}`,
+
+	NotificationDiscordSessions: `{{ T "DiscordSessionsTitle" . }}
+{{ range .ScheduledEvents }}
+{{ T "DateLine" . }}
+{{ .Title }}
+{{ .Description }}
+{{ else }}{{ T "DiscordNothingScheduled" . }}{{ end }}`,
+}
+
+// NotificationTemplateData is the data that notification templates are executed with. Only the fields relevant to
+// the type of notification are set.
+type NotificationTemplateData struct {
+	ServerName string
+	Time       time.Time
+
+	// Event details. Track is the name and layout of the track, TrackInfo adds a download link if the track has one.
+	// CarList is the name of each car with its download link.
+	EventName    string
+	Track        string
+	TrackInfo    string
+	Cars         []string
+	CarList      string
+	Date         time.Time
+	ShowPassword bool
+	Password     string
+
+	// Reminder is how long it is until the event starts, e.g. "15 minutes". TimeUntil is the time until Date, if Date
+	// is in the future.
+	ReminderMinutes int
+	Reminder        string
+	TimeUntil       string
+
+	Championship       *Championship
+	ChampionshipEvent  *ChampionshipEvent
+	RaceWeekend        *RaceWeekend
+	RaceWeekendSession *RaceWeekendSession
+	SessionName        string
+
+	// Results notifications can use the fields of the ResultsSummary, e.g. .Podium and .Standings.
+	*ResultsSummary
+
+	// Penalty notifications
+	SessionType   string
+	Penalty       string
+	Disqualified  bool
+	PenaltyReason string
+
+	// Discord !schedule and !sessions command replies
+	ScheduledEvents []NotificationTemplateScheduledEvent
+}
+
+// NotificationTemplateScheduledEvent is an event or session listed in a reply to a Discord command.
+type NotificationTemplateScheduledEvent struct {
+	Date        time.Time
+	Title       string
+	Description string
+	TrackInfo   string
+	CarList     string
+}
+
+// localise fills in the fields of the data which are written in the given language.
+func (d *NotificationTemplateData) localise(l *Localiser, lang string) {
+	if d.ReminderMinutes > 0 {
+		d.Reminder = l.Duration(lang, time.Duration(d.ReminderMinutes)*time.Minute)
+	}
+
+	if d.Date.After(time.Now()) {
+		d.TimeUntil = l.Duration(lang, time.Until(d.Date).Round(time.Minute))
+	}
+
+	if d.ResultsSummary != nil {
+		d.ResultsSummary = d.ResultsSummary.inLanguage(lang)
+	}
+}
+
+// RenderNotification executes a notification template in the given language, returning the title and message of
+// the notification.
+func RenderNotification(tmpl, lang string, data *NotificationTemplateData) (title, msg string, err error) {
+	l := getLocaliser()
+
+	data.localise(l, lang)
+
+	t, err := template.New("notification").Funcs(template.FuncMap{
+		"T": func(messageID string, data ...interface{}) string {
+			var messageData interface{}
+
+			if len(data) > 0 {
+				messageData = data[0]
+			}
+
+			return l.Localise(lang, messageID, messageData)
+		},
+	}).Parse(tmpl)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := t.Execute(buf, data); err != nil {
+		return "", "", err
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(buf.String()), "\n", 2)
+
+	title = strings.TrimSpace(parts[0])
+
+	if title == "" {
+		return "", "", ErrEmptyNotificationTemplate
+	}
+
+	if len(parts) > 1 {
+		msg = strings.TrimSpace(parts[1])
+	}
+
+	return title, msg, nil
+}
+
+// renderNotification writes a notification using the template and language in the server options. If the template
+// in the server options can't be used, the default template is used instead.
+func renderNotification(serverOpts *GlobalServerConfig, eventType NotificationEventType, data *NotificationTemplateData) (title, msg string, err error) {
+	data.ServerName = serverOpts.Name
+	data.Time = time.Now()
+
+	title, msg, err = RenderNotification(serverOpts.NotificationTemplates.Get(eventType), serverOpts.NotificationLanguage, data)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not render %s notification template, using the default template instead", eventType)
+
+		return RenderNotification(defaultNotificationTemplates[eventType], serverOpts.NotificationLanguage, data)
+	}
+
+	return title, msg, nil
+}
+
+// sampleNotificationTemplateData is used to preview notification templates.
+func sampleNotificationTemplateData(serverOpts *GlobalServerConfig, eventType NotificationEventType) *NotificationTemplateData {
+	championship := NewChampionship("Sample Championship")
+	championshipEvent := NewChampionshipEvent()
+	championshipEvent.RaceSetup.Track = "ks_barcelona"
+	championshipEvent.RaceSetup.TrackLayout = "layout_gp"
+	championship.Events = append(championship.Events, championshipEvent)
+
+	data := &NotificationTemplateData{
+		ServerName:   serverOpts.Name,
+		Time:         time.Now(),
+		EventName:    "Sample Event",
+		Track:        "Barcelona (GP)",
+		TrackInfo:    "Barcelona (GP)",
+		Cars:         []string{"Ferrari 488 GT3", "Porsche 911 GT3 R 2016"},
+		CarList:      "Ferrari 488 GT3, Porsche 911 GT3 R 2016",
+		Date:         time.Now().Add(time.Hour * 26),
+		ShowPassword: true,
+		Password:     "password",
+	}
+
+	switch eventType {
+	case NotificationReminder:
+		data.ReminderMinutes = 15
+	case NotificationResultsPosted:
+		data.ResultsSummary = &ResultsSummary{
+			SessionType: "Race",
+			Track:       "Barcelona (GP)",
+			EventName:   championship.Name,
+			Podium: []ResultsSummaryDriver{
+				{Position: 1, DriverName: "Driver One", CarName: "Ferrari 488 GT3", Time: "40:01.123"},
+				{Position: 2, DriverName: "Driver Two", CarName: "Porsche 911 GT3 R 2016", Time: "40:02.456"},
+				{Position: 3, DriverName: "Driver Three", CarName: "Ferrari 488 GT3", Time: "40:05.789"},
+			},
+			FastestLap: &ResultsSummaryLap{DriverName: "Driver Two", CarName: "Porsche 911 GT3 R 2016", LapTime: "01:44.345"},
+			Incidents: []ResultsSummaryIncident{
+				{DriverName: "Driver Four", Penalty: "5s"},
+				{DriverName: "Driver One", OtherDriverName: "Driver Three", ImpactSpeed: 54},
+			},
+			ChampionshipName: championship.Name,
+			Standings: []ResultsSummaryClassStandings{
+				{
+					Standings: []ResultsSummaryStanding{
+						{Position: 1, DriverName: "Driver Two", Points: 43, Movement: 1},
+						{Position: 2, DriverName: "Driver One", Points: 40, Movement: -1},
+						{Position: 3, DriverName: "Driver Three", Points: 15, New: true},
+					},
+				},
+			},
+		}
+		data.EventName = championship.Name
+		data.SessionType = data.ResultsSummary.SessionType
+	case NotificationSignUpApproved:
+		data.Championship = championship
+		data.ChampionshipEvent = championshipEvent
+	case NotificationDiscordSchedule:
+		data.ScheduledEvents = []NotificationTemplateScheduledEvent{
+			{Date: data.Date, TrackInfo: data.TrackInfo, CarList: data.CarList},
+		}
+	case NotificationDiscordSessions:
+		data.ScheduledEvents = []NotificationTemplateScheduledEvent{
+			{Date: data.Date, Title: "Practice at Barcelona (GP)", Description: "Sample Event"},
+			{Date: data.Date.Add(time.Hour), Title: "Race at Barcelona (GP)", Description: "Sample Event"},
+		}
+	case NotificationPenalty:
+		data.SessionType = "Race"
+		data.Penalty = (time.Second * 10).String()
+		data.PenaltyReason = "PenaltyReasonMandatoryPitStop"
+	}
+
+	return data
+}
+
+type NotificationTemplatesHandler struct {
+	*BaseHandler
+
+	store               Store
+	notificationManager *NotificationManager
+}
+
+func NewNotificationTemplatesHandler(baseHandler *BaseHandler, store Store, notificationManager *NotificationManager) *NotificationTemplatesHandler {
+	return &NotificationTemplatesHandler{
+		BaseHandler:         baseHandler,
+		store:               store,
+		notificationManager: notificationManager,
+	}
+}
+
+// NotificationTemplatePreview is a notification template, along with a preview of it written with sample data.
+type NotificationTemplatePreview struct {
+	NotificationTemplateType
+
+	Template string
+	Title    string
+	Message  string
+	Error    error
+}
+
+type notificationTemplatesTemplateVars struct {
+	BaseTemplateVars
+
+	Language  string
+	Languages []Language
+	Templates []*NotificationTemplatePreview
+}
+
+func (nth *NotificationTemplatesHandler) templates(w http.ResponseWriter, r *http.Request) {
+	serverOpts, err := nth.store.LoadServerOptions()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't load server options")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		// previews and test notifications use the submitted templates, which are only saved with the save action
+		serverOpts.NotificationLanguage = r.FormValue("NotificationLanguage")
+
+		for _, templateType := range notificationTemplateTypes {
+			serverOpts.NotificationTemplates.Set(templateType.Type, r.FormValue(string(templateType.Type)))
+		}
+
+		action := r.FormValue("action")
+
+		switch {
+		case action == "save":
+			if err := nth.store.UpsertServerOptions(serverOpts); err != nil {
+				logrus.WithError(err).Error("couldn't save notification templates")
+				AddErrorFlash(w, r, "Failed to save notification templates")
+			} else {
+				AddFlash(w, r, "Notification templates successfully saved!")
+			}
+		case strings.HasPrefix(action, "test:"):
+			eventType := NotificationEventType(strings.TrimPrefix(action, "test:"))
+
+			title, msg, err := RenderNotification(serverOpts.NotificationTemplates.Get(eventType), serverOpts.NotificationLanguage, sampleNotificationTemplateData(serverOpts, eventType))
+
+			if err == nil {
+				err = nth.notificationManager.SendTestNotification(serverOpts, title, msg)
+			}
+
+			if err != nil {
+				logrus.WithError(err).Errorf("couldn't send test %s notification", eventType)
+				AddErrorFlash(w, r, "Failed to send the test notification: "+err.Error())
+			} else {
+				AddFlash(w, r, "A test notification has been sent to each notification channel.")
+			}
+		}
+	}
+
+	vars := &notificationTemplatesTemplateVars{
+		Language:  serverOpts.NotificationLanguage,
+		Languages: getLocaliser().Languages(),
+	}
+
+	for _, templateType := range notificationTemplateTypes {
+		preview := &NotificationTemplatePreview{
+			NotificationTemplateType: templateType,
+			Template:                 serverOpts.NotificationTemplates.Get(templateType.Type),
+		}
+
+		preview.Title, preview.Message, preview.Error = RenderNotification(preview.Template, serverOpts.NotificationLanguage, sampleNotificationTemplateData(serverOpts, templateType.Type))
+
+		vars.Templates = append(vars.Templates, preview)
+	}
+
+	nth.viewRenderer.MustLoadTemplate(w, r, "server/notification-templates.html", vars)
+}
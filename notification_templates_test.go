@@ -0,0 +1,60 @@
+package servermanager
+
+import (
+	"testing"
+)
+
+func TestNotificationTemplates_GetSet(t *testing.T) {
+	var templates NotificationTemplates
+
+	if templates.Get(NotificationPenalty) != defaultNotificationTemplates[NotificationPenalty] {
+		t.Log("Expected an empty template to use the default template")
+		t.Fail()
+	}
+
+	templates.Set(NotificationPenalty, "Penalty\r\n{{ .Penalty }}")
+
+	if templates.Get(NotificationPenalty) != "Penalty\n{{ .Penalty }}" {
+		t.Logf("Unexpected penalty template: %q", templates.Get(NotificationPenalty))
+		t.Fail()
+	}
+
+	templates.Set(NotificationPenalty, defaultNotificationTemplates[NotificationPenalty])
+
+	if templates.Penalty != "" {
+		t.Log("Expected setting the default template to clear the template")
+		t.Fail()
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	data := &NotificationTemplateData{
+		Track:         "Barbagello",
+		SessionType:   "Race",
+		Penalty:       "10s",
+		PenaltyReason: "PenaltyReasonMandatoryPitStop",
+	}
+
+	title, msg, err := RenderNotification(defaultNotificationTemplates[NotificationPenalty], "es", data)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// there are no translations loaded, so the messages fall back to English
+	if title != "Penalty - Race at Barbagello" {
+		t.Logf("Unexpected penalty title: %s", title)
+		t.Fail()
+	}
+
+	if msg != "You have been given a 10s penalty in the Race at Barbagello for not completing a valid mandatory pit stop." {
+		t.Logf("Unexpected penalty message: %s", msg)
+		t.Fail()
+	}
+
+	if _, _, err := RenderNotification("{{ if .Disqualified }}{{ .Track }}{{ end }}\n", "en", data); err != ErrEmptyNotificationTemplate {
+		t.Logf("Expected a template which writes nothing to return an error, got: %v", err)
+		t.Fail()
+	}
+}
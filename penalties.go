@@ -110,36 +110,7 @@ func applyPenalty(r *http.Request, server *MultiServer) (bool, error) {
 		}
 	}
 
-	// sort results.Result, if disqualified go to back, if time penalty sort by laps completed then lap time
-	sort.Slice(results.Result, func(i, j int) bool {
-		if !results.Result[i].Disqualified && !results.Result[j].Disqualified {
-
-			// if both drivers aren't disqualified
-			if results.GetNumLaps(results.Result[i].DriverGUID) == results.GetNumLaps(results.Result[j].DriverGUID) {
-				// if their number of laps are equal, compare lap times
-
-				return results.GetTime(results.Result[i].TotalTime, results.Result[i].DriverGUID, true) <
-					results.GetTime(results.Result[j].TotalTime, results.Result[j].DriverGUID, true)
-			}
-
-			return results.GetNumLaps(results.Result[i].DriverGUID) >= results.GetNumLaps(results.Result[j].DriverGUID)
-
-		} else if results.Result[i].Disqualified && results.Result[j].Disqualified {
-
-			// if both drivers ARE disqualified, compare their lap times / num laps
-			if results.GetNumLaps(results.Result[i].DriverGUID) == results.GetNumLaps(results.Result[j].DriverGUID) {
-				// if their number of laps are equal, compare lap times
-				return results.GetTime(results.Result[i].TotalTime, results.Result[i].DriverGUID, true) <
-					results.GetTime(results.Result[j].TotalTime, results.Result[j].DriverGUID, true)
-			}
-
-			return results.GetNumLaps(results.Result[i].DriverGUID) >= results.GetNumLaps(results.Result[j].DriverGUID)
-
-		} else {
-			// driver i is closer to the front than j if they are not disqualified and j is
-			return results.Result[j].Disqualified
-		}
-	})
+	results.sortByPenalties()
 
 	err = saveResults(jsonFileName+".json", results)
 
@@ -178,6 +149,32 @@ func applyPenalty(r *http.Request, server *MultiServer) (bool, error) {
 	return remove, nil
 }
 
+// sortByPenalties sorts the results after penalties have been applied. Disqualified drivers go to the back, then
+// drivers are sorted by laps completed then total time (including penalties).
+func (s *SessionResults) sortByPenalties() {
+	lessByLapsThenTime := func(i, j int) bool {
+		a, b := s.Result[i], s.Result[j]
+
+		aLaps, bLaps := s.GetNumLaps(a.DriverGUID, a.CarModel), s.GetNumLaps(b.DriverGUID, b.CarModel)
+
+		if aLaps == bLaps {
+			// if their number of laps are equal, compare total times
+			return s.GetTime(a.TotalTime, a.DriverGUID, a.CarModel, true) < s.GetTime(b.TotalTime, b.DriverGUID, b.CarModel, true)
+		}
+
+		return aLaps > bLaps
+	}
+
+	sort.SliceStable(s.Result, func(i, j int) bool {
+		if s.Result[i].Disqualified == s.Result[j].Disqualified {
+			return lessByLapsThenTime(i, j)
+		}
+
+		// driver i is closer to the front than j if they are not disqualified and j is
+		return s.Result[j].Disqualified
+	})
+}
+
 // saveResults takes a full json filepath (including the json extension) and saves the results to that file.
 func saveResults(jsonFileName string, results *SessionResults) error {
 	path := filepath.Join(ServerInstallPath, "results", jsonFileName)
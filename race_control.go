@@ -25,6 +25,8 @@ type RaceControl struct {
 	store            Store
 	penaltiesManager *PenaltiesManager
 
+	notificationManager NotificationDispatcher
+
 	SessionInfo                udp.SessionInfo `json:"SessionInfo"`
 	TrackMapData               TrackMapData    `json:"TrackMapData"`
 	TrackInfo                  TrackInfo       `json:"TrackInfo"`
@@ -42,6 +44,10 @@ type RaceControl struct {
 
 	broadcaster      Broadcaster
 	trackDataGateway TrackDataGateway
+	pitLane          *TrackPitLane
+
+	pitStopsInProgress      map[udp.CarID]*pitStopInProgress
+	pitStopsInProgressMutex sync.Mutex
 
 	currentTimeAttackEvent *CustomRace
 
@@ -92,6 +98,7 @@ func NewRaceControl(broadcaster Broadcaster, trackDataGateway TrackDataGateway,
 		penaltiesManager:     penaltiesManager,
 		carUpdaters:          make(map[udp.CarID]chan udp.CarUpdate),
 		serverProcessStopped: make(chan struct{}),
+		pitStopsInProgress:   make(map[udp.CarID]*pitStopInProgress),
 	}
 
 	process.NotifyDone(rc.serverProcessStopped)
@@ -273,6 +280,8 @@ func (rc *RaceControl) handleCarUpdate(update udp.CarUpdate) error {
 	driver.LastSeen = time.Now()
 	driver.LastPos = update.Pos
 
+	rc.updatePitStatus(driver, update, driver.LastSeen)
+
 	_, err = rc.broadcaster.Send(update)
 
 	return err
@@ -293,6 +302,10 @@ func (rc *RaceControl) OnNewSession(sessionInfo udp.SessionInfo) error {
 	rc.driverSwapPenalties = make(map[udp.DriverGUID]*driverSwapPenalty)
 	rc.driverSwapPenaltiesMutex.Unlock()
 
+	rc.pitStopsInProgressMutex.Lock()
+	rc.pitStopsInProgress = make(map[udp.CarID]*pitStopInProgress)
+	rc.pitStopsInProgressMutex.Unlock()
+
 	if (rc.ConnectedDrivers.Len() > 0 || rc.DisconnectedDrivers.Len() > 0) && sessionInfo.Type == udp.SessionTypePractice {
 		if oldSessionInfo.Type == sessionInfo.Type && oldSessionInfo.Track == sessionInfo.Track && oldSessionInfo.TrackConfig == sessionInfo.TrackConfig && oldSessionInfo.Name == sessionInfo.Name {
 			// this is a looped event, keep the cars
@@ -343,6 +356,12 @@ func (rc *RaceControl) OnNewSession(sessionInfo udp.SessionInfo) error {
 		rc.TrackMapData = *trackMapData
 	}
 
+	rc.pitLane, err = rc.trackDataGateway.TrackPitLane(sessionInfo.Track, sessionInfo.TrackConfig)
+
+	if err != nil {
+		logrus.WithError(err).Warnf("Could not load track pit lane, pit stops will not be detected")
+	}
+
 	logrus.Debugf("New session detected: %s at %s (%s) [emptyCarInfo: %t]", sessionInfo.Type.String(), sessionInfo.Track, sessionInfo.TrackConfig, emptyCarInfo)
 
 	// look for live timings stored previously
@@ -530,6 +549,8 @@ func (rc *RaceControl) OnEndSession(sessionFile udp.EndSession) error {
 
 	config := rc.process.Event().GetRaceConfig()
 
+	rc.recordPitStops(filename)
+
 	if config.DriverSwapEnabled == 1 {
 		_ = rc.ConnectedDrivers.Each(func(driverGUID udp.DriverGUID, driver *RaceControlDriver) error {
 			if driver.driverSwapCfn != nil {
@@ -585,6 +606,8 @@ func (rc *RaceControl) OnEndSession(sessionFile udp.EndSession) error {
 				logrus.WithError(err).Errorf("could not apply driver swap penalty of %s to driver %s", penalty.penalty.String(), guid)
 				continue
 			}
+
+			rc.notifyPenalty(string(guid), penalty.penalty, "PenaltyReasonDriverSwap")
 		}
 	}
 
@@ -726,6 +749,8 @@ func (rc *RaceControl) OnClientDisconnect(client udp.SessionCarInfo) error {
 
 	driver.LoadedTime = time.Time{}
 
+	rc.holdPitStop(driver)
+
 	rc.ConnectedDrivers.Del(driver.CarInfo.DriverGUID)
 
 	if driver.TotalNumLaps > 0 {
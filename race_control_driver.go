@@ -43,6 +43,12 @@ type RaceControlDriver struct {
 
 	Collisions []Collision `json:"Collisions"`
 
+	InPitLane bool       `json:"InPitLane"`
+	PitStops  []*PitStop `json:"PitStops"`
+
+	currentPitStop  *PitStop
+	stationarySince time.Time
+
 	driverSwapContext context.Context
 	driverSwapCfn     context.CancelFunc
 
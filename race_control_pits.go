@@ -0,0 +1,247 @@
+package servermanager
+
+import (
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/JustaPenguin/assetto-server-manager/pkg/udp"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pitStationarySpeed is the speed in m/s below which a car in the pit lane is considered stationary.
+	pitStationarySpeed = 0.5
+	// minimumPitLaneTime filters out cars which only brush past the pit lane, e.g. at pit entry.
+	minimumPitLaneTime = time.Second * 2
+)
+
+// A PitStop is a visit to the pit lane by a car, from pit entry to pit exit.
+type PitStop struct {
+	CarID          udp.CarID      `json:"CarID"`
+	DriverGUID     udp.DriverGUID `json:"DriverGUID"`
+	DriverName     string         `json:"DriverName"`
+	CarModel       string         `json:"CarModel"`
+	Lap            int            `json:"Lap"`
+	EntryTime      time.Time      `json:"EntryTime" ts:"date"`
+	ExitTime       time.Time      `json:"ExitTime" ts:"date"`
+	StationaryTime time.Duration  `json:"StationaryTime"`
+}
+
+func (ps *PitStop) PitLaneTime() time.Duration {
+	return ps.ExitTime.Sub(ps.EntryTime)
+}
+
+// A pitStopInProgress is a pit stop which was in progress when the driver of the car disconnected, e.g. during a
+// driver swap. It is continued by the next driver of the car.
+type pitStopInProgress struct {
+	pitStop         *PitStop
+	stationarySince time.Time
+}
+
+// holdPitStop keeps the in progress pit stop of a disconnecting driver, so that it can be continued when the car
+// is next updated. The driver must be locked by the caller.
+func (rc *RaceControl) holdPitStop(driver *RaceControlDriver) {
+	if driver.currentPitStop == nil {
+		return
+	}
+
+	rc.pitStopsInProgressMutex.Lock()
+	rc.pitStopsInProgress[driver.CarInfo.CarID] = &pitStopInProgress{
+		pitStop:         driver.currentPitStop,
+		stationarySince: driver.stationarySince,
+	}
+	rc.pitStopsInProgressMutex.Unlock()
+
+	driver.currentPitStop = nil
+	driver.stationarySince = time.Time{}
+}
+
+// resumePitStop returns the pit stop which was in progress in a car when its driver disconnected, if any.
+func (rc *RaceControl) resumePitStop(carID udp.CarID) *pitStopInProgress {
+	rc.pitStopsInProgressMutex.Lock()
+	defer rc.pitStopsInProgressMutex.Unlock()
+
+	inProgress, ok := rc.pitStopsInProgress[carID]
+
+	if !ok {
+		return nil
+	}
+
+	delete(rc.pitStopsInProgress, carID)
+
+	return inProgress
+}
+
+// updatePitStatus detects pit entries, exits and stationary time for a driver from a car update received at now.
+// The driver must be locked by the caller.
+func (rc *RaceControl) updatePitStatus(driver *RaceControlDriver, update udp.CarUpdate, now time.Time) {
+	inPitLane := rc.pitLane.Contains(update.Pos)
+	speed := math.Sqrt(math.Pow(float64(update.Velocity.X), 2) + math.Pow(float64(update.Velocity.Z), 2))
+
+	driver.InPitLane = inPitLane
+
+	if driver.currentPitStop == nil {
+		if inProgress := rc.resumePitStop(update.CarID); inProgress != nil {
+			// the car was handed over mid pit stop. time spent stationary while it had no driver still counts.
+			driver.currentPitStop = inProgress.pitStop
+			driver.stationarySince = inProgress.stationarySince
+		}
+	}
+
+	if inPitLane && driver.currentPitStop == nil {
+		driver.currentPitStop = &PitStop{
+			CarID:      driver.CarInfo.CarID,
+			DriverGUID: driver.CarInfo.DriverGUID,
+			DriverName: driver.CarInfo.DriverName,
+			CarModel:   driver.CarInfo.CarModel,
+			Lap:        driver.TotalNumLaps + 1,
+			EntryTime:  now,
+		}
+
+		driver.stationarySince = time.Time{}
+	}
+
+	pitStop := driver.currentPitStop
+
+	if pitStop == nil {
+		return
+	}
+
+	if inPitLane && speed < pitStationarySpeed {
+		if driver.stationarySince.IsZero() {
+			driver.stationarySince = now
+		}
+
+		return
+	}
+
+	if !driver.stationarySince.IsZero() {
+		pitStop.StationaryTime += now.Sub(driver.stationarySince)
+		driver.stationarySince = time.Time{}
+	}
+
+	if inPitLane {
+		return
+	}
+
+	pitStop.ExitTime = now
+	driver.currentPitStop = nil
+
+	if pitStop.PitLaneTime() < minimumPitLaneTime {
+		return
+	}
+
+	logrus.Infof("Driver: %s (%s) completed a pit stop on lap %d, stationary for %s", pitStop.DriverName, pitStop.DriverGUID, pitStop.Lap, pitStop.StationaryTime)
+
+	driver.PitStops = append(driver.PitStops, pitStop)
+}
+
+// pitStopsByCarID collects the pit stops of all drivers, grouped by the car they were made in. Driver swaps
+// mean that a car can have pit stops from more than one driver.
+func (rc *RaceControl) pitStopsByCarID() map[udp.CarID][]*PitStop {
+	pitStops := make(map[udp.CarID][]*PitStop)
+
+	collect := func(driverGUID udp.DriverGUID, driver *RaceControlDriver) error {
+		driver.mutex.Lock()
+		defer driver.mutex.Unlock()
+
+		for _, pitStop := range driver.PitStops {
+			pitStops[pitStop.CarID] = append(pitStops[pitStop.CarID], pitStop)
+		}
+
+		return nil
+	}
+
+	_ = rc.ConnectedDrivers.Each(collect)
+	_ = rc.DisconnectedDrivers.Each(collect)
+
+	return pitStops
+}
+
+// isValidMandatoryPitStop checks that a pit stop was made within the race pit window, and that the car was
+// stationary for long enough. Pit windows are in laps for lap races and minutes for timed races.
+func (rc *RaceControl) isValidMandatoryPitStop(config CurrentRaceConfig, pitStop *PitStop) bool {
+	if pitStop.StationaryTime < time.Duration(config.MandatoryPitStopMinStationaryTime)*time.Second {
+		return false
+	}
+
+	if config.RacePitWindowEnd <= 0 {
+		return true
+	}
+
+	windowPosition := pitStop.Lap
+
+	if rc.SessionInfo.Laps == 0 {
+		windowPosition = int(pitStop.EntryTime.Sub(rc.SessionStartTime).Minutes())
+	}
+
+	return windowPosition >= config.RacePitWindowStart && windowPosition <= config.RacePitWindowEnd
+}
+
+// recordPitStops saves the pit stops made during a session into its results file, then penalises any car in a
+// race which did not make a valid mandatory pit stop.
+func (rc *RaceControl) recordPitStops(filename string) {
+	pitStops := rc.pitStopsByCarID()
+	config := rc.process.Event().GetRaceConfig()
+
+	results, err := LoadResult(filename, LoadResultWithoutPluginFire)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not load results file to record pit stops")
+		return
+	}
+
+	results.PitStops = nil
+
+	for _, carPitStops := range pitStops {
+		results.PitStops = append(results.PitStops, carPitStops...)
+	}
+
+	if err := saveResults(filepath.Base(filename), results); err != nil {
+		logrus.WithError(err).Errorf("Could not save pit stops to results file")
+		return
+	}
+
+	if config.MandatoryPitStopEnabled != 1 || rc.SessionInfo.Type != udp.SessionTypeRace {
+		return
+	}
+
+results:
+	for _, result := range results.Result {
+		if results.GetNumLaps(result.DriverGUID, result.CarModel) == 0 {
+			// cars which didn't start the race have nothing to penalise
+			continue
+		}
+
+		for _, pitStop := range pitStops[udp.CarID(result.CarID)] {
+			if rc.isValidMandatoryPitStop(config, pitStop) {
+				continue results
+			}
+		}
+
+		logrus.Infof("Driver: %s (%s) did not complete a valid mandatory pit stop", result.DriverName, result.DriverGUID)
+
+		err := rc.penaltiesManager.applyPenalty(filename, result.DriverGUID, result.CarModel, float64(config.MandatoryPitStopPenalty), true)
+
+		if err != nil {
+			logrus.WithError(err).Errorf("could not apply mandatory pit stop penalty to driver %s", result.DriverGUID)
+			continue
+		}
+
+		rc.notifyPenalty(result.DriverGUID, time.Duration(config.MandatoryPitStopPenalty)*time.Second, "PenaltyReasonMandatoryPitStop")
+	}
+}
+
+// PitStopsForCar returns the pit stops made by a car during the session.
+func (s *SessionResults) PitStopsForCar(carID int) []*PitStop {
+	var pitStops []*PitStop
+
+	for _, pitStop := range s.PitStops {
+		if int(pitStop.CarID) == carID {
+			pitStops = append(pitStops, pitStop)
+		}
+	}
+
+	return pitStops
+}
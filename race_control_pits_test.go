@@ -0,0 +1,173 @@
+package servermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JustaPenguin/assetto-server-manager/pkg/udp"
+)
+
+var (
+	pitsTestInPitLane     = udp.Vec{X: 50}
+	pitsTestOnTrack       = udp.Vec{X: 50, Z: 100}
+	pitsTestMoving        = udp.Vec{X: 10, Z: 10}
+	pitsTestStationary    = udp.Vec{}
+	pitsTestPitLaneSpline = []udp.Vec{{X: 0}, {X: 25}, {X: 50}, {X: 75}, {X: 100}}
+)
+
+func newPitsTestRaceControl(t *testing.T) *RaceControl {
+	raceControl := NewRaceControl(NilBroadcaster{}, nilTrackData{}, dummyServerProcess{}, testStore, NewPenaltiesManager(testStore))
+	raceControl.pitLane = &TrackPitLane{PitLane: pitsTestPitLaneSpline}
+
+	if err := raceControl.OnClientConnect(drivers[0]); err != nil {
+		t.Error(err)
+	}
+
+	return raceControl
+}
+
+func pitsTestUpdate(raceControl *RaceControl, driver *RaceControlDriver, pos, velocity udp.Vec, now time.Time) {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	raceControl.updatePitStatus(driver, udp.CarUpdate{CarID: driver.CarInfo.CarID, Pos: pos, Velocity: velocity}, now)
+}
+
+func TestRaceControl_UpdatePitStatus(t *testing.T) {
+	start := time.Now()
+
+	t.Run("Pit entry and exit", func(t *testing.T) {
+		raceControl := newPitsTestRaceControl(t)
+
+		driver, _ := raceControl.ConnectedDrivers.Get(drivers[0].DriverGUID)
+		driver.TotalNumLaps = 4
+
+		pitsTestUpdate(raceControl, driver, pitsTestOnTrack, pitsTestMoving, start)
+		pitsTestUpdate(raceControl, driver, pitsTestInPitLane, pitsTestMoving, start.Add(time.Second*5))
+
+		if !driver.InPitLane || driver.currentPitStop == nil {
+			t.Log("Expected the driver to have entered the pit lane")
+			t.Fail()
+			return
+		}
+
+		pitsTestUpdate(raceControl, driver, pitsTestInPitLane, pitsTestStationary, start.Add(time.Second*10))
+		pitsTestUpdate(raceControl, driver, pitsTestInPitLane, pitsTestMoving, start.Add(time.Second*35))
+		pitsTestUpdate(raceControl, driver, pitsTestOnTrack, pitsTestMoving, start.Add(time.Second*45))
+
+		if driver.InPitLane || driver.currentPitStop != nil || len(driver.PitStops) != 1 {
+			t.Logf("Expected the driver to have completed one pit stop, got: %d", len(driver.PitStops))
+			t.Fail()
+			return
+		}
+
+		pitStop := driver.PitStops[0]
+
+		if pitStop.Lap != 5 || pitStop.PitLaneTime() != time.Second*40 || pitStop.StationaryTime != time.Second*25 {
+			t.Logf("Expected a 40s pit stop on lap 5, stationary for 25s, got: %s on lap %d, stationary for %s", pitStop.PitLaneTime(), pitStop.Lap, pitStop.StationaryTime)
+			t.Fail()
+		}
+	})
+
+	t.Run("Passing through pit entry", func(t *testing.T) {
+		raceControl := newPitsTestRaceControl(t)
+
+		driver, _ := raceControl.ConnectedDrivers.Get(drivers[0].DriverGUID)
+
+		pitsTestUpdate(raceControl, driver, pitsTestInPitLane, pitsTestMoving, start)
+		pitsTestUpdate(raceControl, driver, pitsTestOnTrack, pitsTestMoving, start.Add(time.Second))
+
+		if len(driver.PitStops) != 0 {
+			t.Log("Expected a car which was only briefly in the pit lane to not make a pit stop")
+			t.Fail()
+		}
+	})
+
+	t.Run("Driver swap during a pit stop", func(t *testing.T) {
+		raceControl := newPitsTestRaceControl(t)
+
+		firstDriver, _ := raceControl.ConnectedDrivers.Get(drivers[0].DriverGUID)
+
+		pitsTestUpdate(raceControl, firstDriver, pitsTestInPitLane, pitsTestMoving, start)
+		pitsTestUpdate(raceControl, firstDriver, pitsTestInPitLane, pitsTestStationary, start.Add(time.Second*5))
+
+		disconnect := firstDriver.CarInfo
+		disconnect.EventType = udp.EventConnectionClosed
+
+		if err := raceControl.OnClientDisconnect(disconnect); err != nil {
+			t.Error(err)
+			return
+		}
+
+		// the second driver takes over the same car
+		swap := drivers[0]
+		swap.DriverGUID = "7827162738270000"
+		swap.DriverName = "Swap Driver"
+
+		if err := raceControl.OnClientConnect(swap); err != nil {
+			t.Error(err)
+			return
+		}
+
+		secondDriver, _ := raceControl.ConnectedDrivers.Get(swap.DriverGUID)
+
+		pitsTestUpdate(raceControl, secondDriver, pitsTestInPitLane, pitsTestStationary, start.Add(time.Second*35))
+		pitsTestUpdate(raceControl, secondDriver, pitsTestInPitLane, pitsTestMoving, start.Add(time.Second*50))
+		pitsTestUpdate(raceControl, secondDriver, pitsTestOnTrack, pitsTestMoving, start.Add(time.Second*60))
+
+		if len(firstDriver.PitStops) != 0 || len(secondDriver.PitStops) != 1 {
+			t.Logf("Expected the pit stop to be completed by the second driver, got: %d and %d", len(firstDriver.PitStops), len(secondDriver.PitStops))
+			t.Fail()
+			return
+		}
+
+		pitStop := secondDriver.PitStops[0]
+
+		if !pitStop.EntryTime.Equal(start) || pitStop.StationaryTime != time.Second*45 || pitStop.CarID != drivers[0].CarID {
+			t.Logf("Expected the pit stop to continue from pit entry, stationary for 45s, got: entry %s, stationary for %s", pitStop.EntryTime, pitStop.StationaryTime)
+			t.Fail()
+		}
+	})
+}
+
+func TestRaceControl_IsValidMandatoryPitStop(t *testing.T) {
+	raceControl := NewRaceControl(NilBroadcaster{}, nilTrackData{}, dummyServerProcess{}, testStore, NewPenaltiesManager(testStore))
+
+	raceControl.SessionStartTime = time.Now()
+
+	window := CurrentRaceConfig{MandatoryPitStopMinStationaryTime: 10, RacePitWindowStart: 5, RacePitWindowEnd: 15}
+	noWindow := CurrentRaceConfig{MandatoryPitStopMinStationaryTime: 10}
+
+	testCases := []struct {
+		name       string
+		config     CurrentRaceConfig
+		laps       int
+		lap        int
+		minutes    int
+		stationary time.Duration
+		valid      bool
+	}{
+		{"Within lap window", window, 30, 10, 0, time.Second * 12, true},
+		{"Not stationary for long enough", window, 30, 10, 0, time.Second * 5, false},
+		{"Before lap window", window, 30, 4, 0, time.Second * 12, false},
+		{"After lap window", window, 30, 16, 0, time.Second * 12, false},
+		{"No window", noWindow, 30, 29, 0, time.Second * 12, true},
+		{"Within timed window", window, 0, 20, 10, time.Second * 12, true},
+		{"After timed window", window, 0, 5, 20, time.Second * 12, false},
+	}
+
+	for _, testCase := range testCases {
+		raceControl.SessionInfo.Laps = uint16(testCase.laps)
+
+		pitStop := &PitStop{
+			Lap:            testCase.lap,
+			EntryTime:      raceControl.SessionStartTime.Add(time.Duration(testCase.minutes)*time.Minute + time.Second),
+			StationaryTime: testCase.stationary,
+		}
+
+		if valid := raceControl.isValidMandatoryPitStop(testCase.config, pitStop); valid != testCase.valid {
+			t.Logf("%s: expected pit stop validity to be %t, got: %t", testCase.name, testCase.valid, valid)
+			t.Fail()
+		}
+	}
+}
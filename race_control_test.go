@@ -279,6 +279,10 @@ func (nilTrackData) TrackMap(name, layout string) (*TrackMapData, error) {
 	return &TrackMapData{}, nil
 }
 
+func (nilTrackData) TrackPitLane(name, layout string) (*TrackPitLane, error) {
+	return &TrackPitLane{}, nil
+}
+
 func TestRaceControl_OnNewSession(t *testing.T) {
 	t.Run("New session, no previous data", func(t *testing.T) {
 		raceControl := NewRaceControl(NilBroadcaster{}, nilTrackData{}, dummyServerProcess{}, testStore, NewPenaltiesManager(testStore))
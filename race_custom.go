@@ -83,12 +83,14 @@ type CustomRaceHandler struct {
 	*BaseHandler
 
 	raceManager *RaceManager
+	scheduler   *Scheduler
 }
 
-func NewCustomRaceHandler(base *BaseHandler, raceManager *RaceManager) *CustomRaceHandler {
+func NewCustomRaceHandler(base *BaseHandler, raceManager *RaceManager, scheduler *Scheduler) *CustomRaceHandler {
 	return &CustomRaceHandler{
 		BaseHandler: base,
 		raceManager: raceManager,
+		scheduler:   scheduler,
 	}
 }
 
@@ -161,7 +163,14 @@ func (crh *CustomRaceHandler) schedule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	raceID := chi.URLParam(r, "uuid")
+	customRace, err := crh.raceManager.raceStore.FindCustomRaceByID(chi.URLParam(r, "uuid"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't find custom race")
+		http.NotFound(w, r)
+		return
+	}
+
 	dateString := r.FormValue("event-schedule-date")
 	timeString := r.FormValue("event-schedule-time")
 	timezone := r.FormValue("event-schedule-timezone")
@@ -182,22 +191,77 @@ func (crh *CustomRaceHandler) schedule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = crh.raceManager.ScheduleRace(raceID, date, r.FormValue("action"), r.FormValue("event-schedule-recurrence"))
+	err = crh.scheduler.SetMissedEventPolicy(customRace, missedEventPolicyFromForm(r))
 
 	if err != nil {
+		logrus.WithError(err).Errorf("couldn't set race missed event policy")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	conflicts, err := crh.scheduler.ResolveScheduleConflicts(customRace, date, ScheduleConflictAction(r.FormValue("schedule-conflict-action")))
+
+	switch err {
+	case nil:
+	case ErrScheduleConflict, ErrNoFreeServer:
+		message := "The race was not scheduled, it conflicts with other events on the server: "
+
+		if err == ErrNoFreeServer {
+			message = "The race was not scheduled, no server is free at that time: "
+		}
+
+		AddErrorFlash(w, r, message+scheduleConflictsDescription(conflicts))
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	default:
+		logrus.WithError(err).Errorf("couldn't check race for schedule conflicts")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if recurrence := r.FormValue("event-schedule-recurrence"); recurrence != "" {
+		if err := customRace.SetRecurrenceRule(recurrence); err != nil {
+			logrus.WithError(err).Errorf("couldn't parse race recurrence rule")
+			AddErrorFlash(w, r, "The race was not scheduled, its recurrence rule is invalid")
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
+	} else {
+		customRace.ClearRecurrenceRule()
+	}
+
+	customRace.SetScheduledTime(date)
+
+	if err := crh.raceManager.raceStore.UpsertCustomRace(customRace); err != nil {
+		logrus.WithError(err).Errorf("couldn't save race schedule")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if err := crh.scheduler.Schedule(customRace, date); err != nil {
 		logrus.WithError(err).Errorf("couldn't schedule race")
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
+	if len(conflicts) > 0 {
+		AddErrorFlash(w, r, "Warning, this race conflicts with other events on the server: "+scheduleConflictsDescription(conflicts))
+	}
+
 	AddFlash(w, r, fmt.Sprintf("We have scheduled the race to begin at %s", date.Format(time.RFC1123)))
 	http.Redirect(w, r, r.Referer(), http.StatusFound)
 }
 
 func (crh *CustomRaceHandler) removeSchedule(w http.ResponseWriter, r *http.Request) {
-	err := crh.raceManager.ScheduleRace(chi.URLParam(r, "uuid"), time.Time{}, "remove", "")
+	customRace, err := crh.raceManager.raceStore.FindCustomRaceByID(chi.URLParam(r, "uuid"))
 
 	if err != nil {
+		logrus.WithError(err).Errorf("couldn't find custom race")
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := crh.scheduler.DeSchedule(customRace); err != nil {
 		logrus.WithError(err).Errorf("couldn't remove scheduled race")
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
@@ -510,6 +510,10 @@ func (rm *RaceManager) BuildCustomRaceFromForm(r *http.Request) (*CurrentRaceCon
 		RaceExtraLap:              formValueAsInt(r.FormValue("RaceExtraLap")),
 		MaxContactsPerKilometer:   formValueAsInt(r.FormValue("MaxContactsPerKilometer")),
 		ResultScreenTime:          formValueAsInt(r.FormValue("ResultScreenTime")),
+
+		MandatoryPitStopEnabled:           formValueAsInt(r.FormValue("MandatoryPitStopEnabled")),
+		MandatoryPitStopMinStationaryTime: formValueAsInt(r.FormValue("MandatoryPitStopMinStationaryTime")),
+		MandatoryPitStopPenalty:           formValueAsInt(r.FormValue("MandatoryPitStopPenalty")),
 	}
 
 	if isSol {
@@ -998,6 +1002,17 @@ func (rm *RaceManager) StartCustomRace(uuid string, forceRestart bool) error {
 	return rm.applyConfigAndStart(cfg, race.EntryList, forceRestart, race)
 }
 
+// StartCustomRaceWithOverride starts an occurrence of a recurring custom race which has been changed to run at a
+// different track.
+func (rm *RaceManager) StartCustomRaceWithOverride(race *CustomRace, override *RecurrenceOverride) error {
+	cfg := ConfigIniDefault
+	cfg.CurrentRaceConfig = race.RaceConfig
+
+	override.Apply(&cfg.CurrentRaceConfig)
+
+	return rm.applyConfigAndStart(cfg, race.EntryList, false, race)
+}
+
 func (rm *RaceManager) DeleteCustomRace(uuid string) error {
 	race, err := rm.raceStore.FindCustomRaceByID(uuid)
 
@@ -61,6 +61,13 @@ type RaceWeekend struct {
 
 	SpectatorCar        Entrant
 	SpectatorCarEnabled bool
+
+	// Autopilot runs the RaceWeekend without admin intervention. Timeline records what the autopilot has done.
+	Autopilot RaceWeekendAutopilot
+	Timeline  []*RaceWeekendTimelineEvent
+
+	// PenaltyHistory records penalties given to drivers during the RaceWeekend, e.g. grid drops.
+	PenaltyHistory []*RaceWeekendPenalty
 =======
 >>>>>>> origin/multiserver2
 }
@@ -169,6 +176,13 @@ func (rw *RaceWeekend) GetEntryList() EntryList {
 			}
 		}
 
+		if event := rw.championshipEvent(); event != nil {
+			// drivers who have withdrawn from this race weekend are left out, and reserves drive in place of the
+			// entrants whose seats they have been given.
+			entryList = event.removeWithdrawnEntrants(entryList)
+			entryList = event.applyReserves(rw.Championship, entryList)
+		}
+
 		return entryList
 	}
 
@@ -190,6 +204,21 @@ func (rw *RaceWeekend) GetEntryList() EntryList {
 >>>>>>> origin/multiserver2
 }
 
+// championshipEvent returns the event of the linked Championship which this RaceWeekend is run as, if any.
+func (rw *RaceWeekend) championshipEvent() *ChampionshipEvent {
+	if !rw.HasLinkedChampionship() || rw.Championship == nil {
+		return nil
+	}
+
+	for _, event := range rw.Championship.Events {
+		if event.RaceWeekendID == rw.ID {
+			return event
+		}
+	}
+
+	return nil
+}
+
 func (rw *RaceWeekend) Completed() bool {
 	for _, session := range rw.Sessions {
 		if !session.Completed() {
@@ -638,9 +667,18 @@ type RaceWeekendSession struct {
 	CompletedTime              time.Time
 	ScheduledTime              time.Time
 	ScheduledServerID          ServerID
+	RunningServerID            uuid.UUID
 	Results                    *SessionResults
 	StartWhenParentHasFinished bool
 
+	TyreRules          RaceWeekendTyreRules
+	TyreRuleViolations []*TyreRuleViolation
+
+	// GridOverrides move drivers on the grid after it has been computed from filters and sorting.
+	GridOverrides []*RaceWeekendGridOverride
+
+	MissedEventCatchUp
+
 	Scheduled     time.Time
 =======
 	StartedTime   time.Time
@@ -942,8 +980,25 @@ func (rws *RaceWeekendSession) HasParent(id string) bool {
 
 var ErrRaceWeekendSessionDependencyIncomplete = errors.New("servermanager: race weekend session dependency incomplete")
 
-// GetRaceWeekendEntryList returns the RaceWeekendEntryList for the given session, built from the parent session(s) results and applied filters.
+// GetRaceWeekendEntryList returns the RaceWeekendEntryList for the given session, built from the parent session(s)
+// results and applied filters, with any grid overrides applied.
 func (rws *RaceWeekendSession) GetRaceWeekendEntryList(rw *RaceWeekend, overrideFilter *RaceWeekendSessionToSessionFilter, overrideFilterSessionID string) (RaceWeekendEntryList, error) {
+	entryList, err := rws.GetComputedRaceWeekendEntryList(rw, overrideFilter, overrideFilterSessionID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rws.GridOverrides) > 0 && rw.SessionCanBeRun(rws) {
+		entryList = rws.ApplyGridOverrides(entryList)
+	}
+
+	return entryList, nil
+}
+
+// GetComputedRaceWeekendEntryList returns the RaceWeekendEntryList for the given session, built from the parent
+// session(s) results and applied filters, before any grid overrides are applied.
+func (rws *RaceWeekendSession) GetComputedRaceWeekendEntryList(rw *RaceWeekend, overrideFilter *RaceWeekendSessionToSessionFilter, overrideFilterSessionID string) (RaceWeekendEntryList, error) {
 <<<<<<< HEAD
 	var entryList RaceWeekendEntryList
 
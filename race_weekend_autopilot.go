@@ -0,0 +1,572 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// autopilotWatchdogInterval is how often a session started by the autopilot is checked to see if it is still running.
+var autopilotWatchdogInterval = time.Second * 30
+
+var ErrRaceWeekendAutopilotNotEnabled = errors.New("servermanager: race weekend autopilot is not enabled")
+
+// RaceWeekendAutopilot configures a RaceWeekend to progress through its sessions without any admin intervention.
+type RaceWeekendAutopilot struct {
+	Enabled bool
+
+	// GapMinutes is the time waited between one session finishing and the next starting.
+	GapMinutes int
+	// MaxRetries is the number of times a failed session is restarted before it is skipped.
+	MaxRetries int
+	// SkipFailedSessions completes a session which has failed more than MaxRetries times using its starting grid as
+	// its results, so that the rest of the RaceWeekend can continue. If false, the autopilot stops instead.
+	SkipFailedSessions bool
+	// SessionTimeoutMinutes is the length of time after which a session which has not finished is considered
+	// to have failed. 0 means sessions never time out.
+	SessionTimeoutMinutes int
+
+	// ServerID is the server whose autopilot runs the RaceWeekend. It is set when the autopilot is enabled.
+	ServerID ServerID
+	// QueuedSessionID is the session which the autopilot has queued to start on the Scheduler of its server.
+	QueuedSessionID uuid.UUID
+}
+
+func (a RaceWeekendAutopilot) Gap() time.Duration {
+	return time.Duration(a.GapMinutes) * time.Minute
+}
+
+type RaceWeekendTimelineEventType string
+
+const (
+	RaceWeekendTimelineAutopilotEnabled  RaceWeekendTimelineEventType = "autopilot_enabled"
+	RaceWeekendTimelineAutopilotDisabled RaceWeekendTimelineEventType = "autopilot_disabled"
+	RaceWeekendTimelineSessionQueued     RaceWeekendTimelineEventType = "session_queued"
+	RaceWeekendTimelineSessionStarted    RaceWeekendTimelineEventType = "session_started"
+	RaceWeekendTimelineSessionCompleted  RaceWeekendTimelineEventType = "session_completed"
+	RaceWeekendTimelineSessionFailed     RaceWeekendTimelineEventType = "session_failed"
+	RaceWeekendTimelineSessionRetried    RaceWeekendTimelineEventType = "session_retried"
+	RaceWeekendTimelineSessionSkipped    RaceWeekendTimelineEventType = "session_skipped"
+	RaceWeekendTimelineCompleted         RaceWeekendTimelineEventType = "completed"
+)
+
+// A RaceWeekendTimelineEvent records something that happened to a RaceWeekend while it was run by the autopilot.
+type RaceWeekendTimelineEvent struct {
+	Time      time.Time
+	Type      RaceWeekendTimelineEventType
+	SessionID uuid.UUID
+	Message   string
+}
+
+// AddTimelineEvent records an event in the RaceWeekend's Timeline.
+func (rw *RaceWeekend) AddTimelineEvent(eventType RaceWeekendTimelineEventType, session *RaceWeekendSession, format string, args ...interface{}) {
+	event := &RaceWeekendTimelineEvent{
+		Time:    time.Now(),
+		Type:    eventType,
+		Message: fmt.Sprintf(format, args...),
+	}
+
+	if session != nil {
+		event.SessionID = session.ID
+	}
+
+	logrus.Infof("Race Weekend: %s: %s", rw.Name, event.Message)
+
+	rw.Timeline = append(rw.Timeline, event)
+}
+
+// NumTimelineEvents counts the events of a given type which have been recorded for a session.
+func (rw *RaceWeekend) NumTimelineEvents(eventType RaceWeekendTimelineEventType, session *RaceWeekendSession) int {
+	numEvents := 0
+
+	for _, event := range rw.Timeline {
+		if event.Type == eventType && event.SessionID == session.ID {
+			numEvents++
+		}
+	}
+
+	return numEvents
+}
+
+// NextAutopilotSession returns the next session which the autopilot should start. If a session is already in progress,
+// or no session can be run, nil is returned.
+func (rw *RaceWeekend) NextAutopilotSession() *RaceWeekendSession {
+	for _, session := range rw.Sessions {
+		if session.InProgress() {
+			return nil
+		}
+	}
+
+	for _, session := range rw.SortedSessions() {
+		if !session.Completed() && rw.SessionCanBeRun(session) {
+			return session
+		}
+	}
+
+	return nil
+}
+
+// updateRaceWeekend loads the RaceWeekend, calls fn and persists any changes made by fn.
+func (rwm *RaceWeekendManager) updateRaceWeekend(raceWeekendID string, fn func(raceWeekend *RaceWeekend) error) (*RaceWeekend, error) {
+	unlock := lockRaceWeekend(raceWeekendID)
+	defer unlock()
+
+	raceWeekend, err := rwm.LoadRaceWeekend(raceWeekendID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fn(raceWeekend); err != nil {
+		return nil, err
+	}
+
+	return raceWeekend, rwm.UpsertRaceWeekend(raceWeekend)
+}
+
+// SetAutopilot updates the autopilot configuration of a RaceWeekend. If the autopilot is enabled, the next session
+// which can be run is queued.
+func (rwm *RaceWeekendManager) SetAutopilot(raceWeekendID string, autopilot RaceWeekendAutopilot) error {
+	var queuedSession *RaceWeekendSession
+
+	_, err := rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		wasEnabled := raceWeekend.Autopilot.Enabled
+		autopilot.QueuedSessionID = raceWeekend.Autopilot.QueuedSessionID
+		raceWeekend.Autopilot = autopilot
+
+		if !autopilot.Enabled {
+			queuedSession = raceWeekend.dequeueAutopilotSession()
+		}
+
+		if autopilot.Enabled {
+			raceWeekend.Autopilot.ServerID = rwm.serverID
+		}
+
+		if autopilot.Enabled && !wasEnabled {
+			raceWeekend.AddTimelineEvent(RaceWeekendTimelineAutopilotEnabled, nil, "Autopilot enabled")
+		} else if !autopilot.Enabled && wasEnabled {
+			raceWeekend.AddTimelineEvent(RaceWeekendTimelineAutopilotDisabled, nil, "Autopilot disabled")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if !autopilot.Enabled {
+		if queuedSession != nil {
+			rwm.scheduler.cancelJobs(queuedSession)
+		}
+
+		return nil
+	}
+
+	return rwm.autopilotQueueNextSession(raceWeekendID)
+}
+
+// ResumeAutopilot queues the next session for any RaceWeekends which were running on this server's autopilot when
+// the server was stopped. A RaceWeekend which fails to resume doesn't stop the others from resuming.
+func (rwm *RaceWeekendManager) ResumeAutopilot() error {
+	raceWeekends, err := rwm.ListRaceWeekends()
+
+	if err != nil {
+		return err
+	}
+
+	for _, raceWeekend := range raceWeekends {
+		if !raceWeekend.Autopilot.Enabled {
+			continue
+		}
+
+		for _, session := range raceWeekend.Sessions {
+			if session.InProgress() && session.RunningServerID == rwm.serverID {
+				// the session was running when the server stopped, the watchdog will fail it.
+				go rwm.autopilotWatchSession(raceWeekend.ID.String(), session.ID.String())
+			}
+		}
+
+		ownerID := raceWeekend.Autopilot.ServerID
+
+		if ownerID == uuid.Nil {
+			// the autopilot was enabled before it belonged to a server, so the first server to resume it takes it over.
+			raceWeekend, err = rwm.updateRaceWeekend(raceWeekend.ID.String(), func(raceWeekend *RaceWeekend) error {
+				if raceWeekend.Autopilot.ServerID == uuid.Nil {
+					raceWeekend.Autopilot.ServerID = rwm.serverID
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				logrus.WithError(err).Errorf("Could not assign race weekend autopilot to server: %s", rwm.serverID.String())
+				continue
+			}
+
+			ownerID = raceWeekend.Autopilot.ServerID
+		}
+
+		if ownerID != rwm.serverID {
+			continue
+		}
+
+		if err := rwm.autopilotQueueNextSession(raceWeekend.ID.String()); err != nil {
+			logrus.WithError(err).Errorf("Could not resume autopilot for race weekend: %s", raceWeekend.Name)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// dequeueAutopilotSession clears the schedule of the session queued by the autopilot, if it has not yet started.
+// The start job of the returned session must be cancelled once the RaceWeekend is saved.
+func (rw *RaceWeekend) dequeueAutopilotSession() *RaceWeekendSession {
+	queuedSessionID := rw.Autopilot.QueuedSessionID
+	rw.Autopilot.QueuedSessionID = uuid.Nil
+
+	if queuedSessionID == uuid.Nil {
+		return nil
+	}
+
+	session, err := rw.FindSessionByID(queuedSessionID.String())
+
+	if err != nil || session.ScheduledTime.IsZero() {
+		return nil
+	}
+
+	session.ScheduledTime = time.Time{}
+	session.ScheduledServerID = uuid.Nil
+
+	return session
+}
+
+// autopilotQueueNextSession posts a preview of the grid of the next session to notifications, then queues a job on
+// the Scheduler to start the session once the autopilot gap has passed.
+func (rwm *RaceWeekendManager) autopilotQueueNextSession(raceWeekendID string) error {
+	var next *RaceWeekendSession
+	var grid RaceWeekendEntryList
+	alreadyQueued := false
+
+	raceWeekend, err := rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		if !raceWeekend.Autopilot.Enabled {
+			return ErrRaceWeekendAutopilotNotEnabled
+		}
+
+		next = raceWeekend.NextAutopilotSession()
+
+		if next == nil {
+			if raceWeekend.Completed() {
+				raceWeekend.AddTimelineEvent(RaceWeekendTimelineCompleted, nil, "All sessions have been completed")
+				raceWeekend.Autopilot.Enabled = false
+			}
+
+			return nil
+		}
+
+		if raceWeekend.Autopilot.QueuedSessionID == next.ID && !next.ScheduledTime.IsZero() {
+			// the session was queued before the server was restarted, its start job is still in the store.
+			alreadyQueued = true
+			return nil
+		}
+
+		var err error
+
+		grid, err = next.GetRaceWeekendEntryList(raceWeekend, nil, "")
+
+		if err != nil {
+			return err
+		}
+
+		next.ScheduledTime = time.Now().Add(raceWeekend.Autopilot.Gap())
+		next.ScheduledServerID = rwm.serverID
+		raceWeekend.Autopilot.QueuedSessionID = next.ID
+
+		raceWeekend.AddTimelineEvent(RaceWeekendTimelineSessionQueued, next, "%s will start in %d minutes with %d entrants", next.Name(), raceWeekend.Autopilot.GapMinutes, len(grid))
+
+		return nil
+	})
+
+	if err == ErrRaceWeekendAutopilotNotEnabled {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if next == nil {
+		return nil
+	}
+
+	if !alreadyQueued {
+		// any start jobs from when the session was last scheduled are replaced by the autopilot's job.
+		rwm.scheduler.cancelJobs(next)
+		rwm.autopilotSendGridPreview(raceWeekend, next, grid)
+	}
+
+	// the job is not queued twice if it is already in the store.
+	return rwm.scheduler.enqueueJob(rwm.scheduler.newJob(JobTypeStartEvent, next, next.ScheduledTime, next.ScheduledTime))
+}
+
+func (rwm *RaceWeekendManager) autopilotSendGridPreview(raceWeekend *RaceWeekend, session *RaceWeekendSession, grid RaceWeekendEntryList) {
+	var lines []string
+
+	for i, entrant := range grid {
+		if entrant.Car == nil {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, entrant.Car.Driver.Name))
+	}
+
+	title := fmt.Sprintf("%s - %s starts in %d minutes", raceWeekend.Name, session.Name(), raceWeekend.Autopilot.GapMinutes)
+
+	if err := rwm.notificationManager.SendMessage(title, strings.Join(lines, "\n")); err != nil {
+		logrus.WithError(err).Errorf("Could not send race weekend grid preview")
+	}
+}
+
+func (rwm *RaceWeekendManager) autopilotStartSession(raceWeekendID, sessionID string) {
+	err := rwm.StartSession(raceWeekendID, sessionID, false)
+
+	if err == ErrRaceWeekendSessionAlreadyRunning {
+		logrus.Infof("Race Weekend: autopilot session %s was started elsewhere, not starting it again", sessionID)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("Autopilot could not start race weekend session")
+		rwm.autopilotSessionFailed(raceWeekendID, sessionID, fmt.Sprintf("could not be started: %s", err))
+		return
+	}
+
+	_, err = rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		session, err := raceWeekend.FindSessionByID(sessionID)
+
+		if err != nil {
+			return err
+		}
+
+		raceWeekend.AddTimelineEvent(RaceWeekendTimelineSessionStarted, session, "%s started", session.Name())
+
+		if raceWeekend.Autopilot.QueuedSessionID == session.ID {
+			raceWeekend.Autopilot.QueuedSessionID = uuid.Nil
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not record race weekend session start")
+	}
+
+	go rwm.autopilotWatchSession(raceWeekendID, sessionID)
+}
+
+// autopilotWatchSession checks a running session until it finishes. Sessions whose server process stops before they
+// produce results, or which run past the autopilot session timeout, are failed.
+func (rwm *RaceWeekendManager) autopilotWatchSession(raceWeekendID, sessionID string) {
+	ticker := time.NewTicker(autopilotWatchdogInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		raceWeekend, session, err := rwm.FindSession(raceWeekendID, sessionID)
+
+		if err != nil {
+			logrus.WithError(err).Errorf("Autopilot could not find race weekend session")
+			return
+		}
+
+		if !session.InProgress() || !raceWeekend.Autopilot.Enabled {
+			return
+		}
+
+		if !rwm.process.IsRunning() {
+			rwm.autopilotSessionFailed(raceWeekendID, sessionID, "the server stopped before the session finished")
+			return
+		}
+
+		timeout := time.Duration(raceWeekend.Autopilot.SessionTimeoutMinutes) * time.Minute
+
+		if timeout > 0 && time.Since(session.StartedTime) > timeout {
+			rwm.autopilotSessionFailed(raceWeekendID, sessionID, fmt.Sprintf("the session did not finish within %d minutes", raceWeekend.Autopilot.SessionTimeoutMinutes))
+			return
+		}
+	}
+}
+
+// autopilotSessionFinished is called once a session on an autopilot RaceWeekend has saved its results.
+func (rwm *RaceWeekendManager) autopilotSessionFinished(raceWeekendID, sessionID string) {
+	_, session, err := rwm.FindSession(raceWeekendID, sessionID)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Autopilot could not find race weekend session")
+		return
+	}
+
+	if session.Results == nil || len(session.Results.Result) == 0 {
+		rwm.autopilotSessionFailed(raceWeekendID, sessionID, "the session finished without any results")
+		return
+	}
+
+	_, err = rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		raceWeekend.AddTimelineEvent(RaceWeekendTimelineSessionCompleted, session, "%s completed", session.Name())
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not record race weekend session completion")
+	}
+
+	if err := rwm.autopilotQueueNextSession(raceWeekendID); err != nil {
+		logrus.WithError(err).Errorf("Autopilot could not queue next race weekend session")
+	}
+}
+
+// autopilotSessionFailed retries a failed session, skips it, or stops the autopilot, depending on the autopilot
+// configuration of the RaceWeekend.
+func (rwm *RaceWeekendManager) autopilotSessionFailed(raceWeekendID, sessionID, reason string) {
+	if rwm.process.IsRunning() {
+		if err := rwm.process.Stop(); err != nil {
+			logrus.WithError(err).Error("Could not stop assetto server process")
+		}
+	}
+
+	var message string
+	requeue := false
+
+	_, err := rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		session, err := raceWeekend.FindSessionByID(sessionID)
+
+		if err != nil {
+			return err
+		}
+
+		raceWeekend.AddTimelineEvent(RaceWeekendTimelineSessionFailed, session, "%s failed: %s", session.Name(), reason)
+
+		session.StartedTime = time.Time{}
+		session.CompletedTime = time.Time{}
+		session.RunningServerID = uuid.Nil
+		session.Results = nil
+		session.TyreRuleViolations = nil
+
+		numFailures := raceWeekend.NumTimelineEvents(RaceWeekendTimelineSessionFailed, session)
+
+		switch {
+		case numFailures <= raceWeekend.Autopilot.MaxRetries:
+			message = fmt.Sprintf("%s failed (%s), it will be retried (attempt %d of %d)", session.Name(), reason, numFailures+1, raceWeekend.Autopilot.MaxRetries+1)
+			raceWeekend.AddTimelineEvent(RaceWeekendTimelineSessionRetried, session, "%s will be retried", session.Name())
+			requeue = true
+		case raceWeekend.Autopilot.SkipFailedSessions:
+			if err := raceWeekend.CompleteSessionWithGrid(session); err != nil {
+				return err
+			}
+
+			message = fmt.Sprintf("%s failed (%s) and has been skipped, its starting grid has been used as its results", session.Name(), reason)
+			raceWeekend.AddTimelineEvent(RaceWeekendTimelineSessionSkipped, session, "%s skipped, starting grid used as results", session.Name())
+			requeue = true
+		default:
+			message = fmt.Sprintf("%s failed (%s), the autopilot has been stopped", session.Name(), reason)
+			raceWeekend.AddTimelineEvent(RaceWeekendTimelineAutopilotDisabled, session, "Autopilot stopped after %s failed", session.Name())
+			raceWeekend.Autopilot.Enabled = false
+		}
+
+		if err := rwm.notificationManager.SendMessage(raceWeekend.Name, message); err != nil {
+			logrus.WithError(err).Errorf("Could not send race weekend autopilot notification")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not handle failed race weekend session")
+		return
+	}
+
+	if requeue {
+		if err := rwm.autopilotQueueNextSession(raceWeekendID); err != nil {
+			logrus.WithError(err).Errorf("Autopilot could not queue next race weekend session")
+		}
+	}
+}
+
+// CompleteSessionWithGrid marks a session as completed, using its starting grid as its results.
+func (rw *RaceWeekend) CompleteSessionWithGrid(session *RaceWeekendSession) error {
+	grid, err := session.GetRaceWeekendEntryList(rw, nil, "")
+
+	if err != nil {
+		return err
+	}
+
+	results := &SessionResults{
+		Type:          session.SessionType(),
+		Date:          time.Now(),
+		TrackName:     session.RaceConfig.Track,
+		TrackConfig:   session.RaceConfig.TrackLayout,
+		RaceWeekendID: rw.ID.String(),
+	}
+
+	for carID, entrant := range grid {
+		if entrant.Car == nil {
+			continue
+		}
+
+		car := *entrant.Car
+		car.CarID = carID
+
+		result := &SessionResult{
+			CarID:      carID,
+			CarModel:   car.Model,
+			DriverGUID: car.Driver.GUID,
+			DriverName: car.Driver.Name,
+			ClassID:    car.Driver.ClassID,
+		}
+
+		if entrant.EntrantResult != nil {
+			result.ClassID = entrant.EntrantResult.ClassID
+		}
+
+		results.Cars = append(results.Cars, &car)
+		results.Result = append(results.Result, result)
+	}
+
+	session.StartedTime = time.Now()
+	session.CompletedTime = time.Now()
+	session.Results = results
+
+	return nil
+}
+
+func (rwh *RaceWeekendHandler) autopilot(w http.ResponseWriter, r *http.Request) {
+	raceWeekendID := chi.URLParam(r, "raceWeekendID")
+
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse autopilot form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	autopilot := RaceWeekendAutopilot{
+		Enabled:               r.FormValue("Enabled") == "on",
+		GapMinutes:            formValueAsInt(r.FormValue("GapMinutes")),
+		MaxRetries:            formValueAsInt(r.FormValue("MaxRetries")),
+		SkipFailedSessions:    r.FormValue("SkipFailedSessions") == "on",
+		SessionTimeoutMinutes: formValueAsInt(r.FormValue("SessionTimeoutMinutes")),
+	}
+
+	if err := rwh.raceWeekendManager.SetAutopilot(raceWeekendID, autopilot); err != nil {
+		logrus.WithError(err).Errorf("Could not update race weekend autopilot")
+		AddErrorFlash(w, r, "Couldn't update the autopilot")
+	} else if autopilot.Enabled {
+		AddFlash(w, r, "Autopilot enabled. The race weekend will now run on its own.")
+	} else {
+		AddFlash(w, r, "Autopilot disabled")
+	}
+
+	http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+}
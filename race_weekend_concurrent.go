@@ -0,0 +1,165 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrRaceWeekendSessionAlreadyRunning = errors.New("servermanager: race weekend session is already running")
+	ErrRaceWeekendNoConcurrentSessions  = errors.New("servermanager: no sessions are ready to be started concurrently")
+	ErrRaceWeekendNotEnoughFreeServers  = errors.New("servermanager: not enough free servers to start sessions concurrently")
+)
+
+// RaceWeekendServerPool provides access to the RaceWeekendManagers of servers which are not currently running an
+// event, so that sibling sessions of a RaceWeekend (e.g. Split A and Split B) can run at the same time.
+type RaceWeekendServerPool interface {
+	FreeRaceWeekendManagers() []*RaceWeekendManager
+	RaceWeekendManagerForServer(serverID ServerID) *RaceWeekendManager
+}
+
+// raceWeekendLocks guard a RaceWeekend between being loaded and persisted. Each server has its own
+// RaceWeekendManager, so two servers running sessions from the same RaceWeekend must not overwrite each other's
+// session results.
+var raceWeekendLocks = struct {
+	sync.Mutex
+
+	locks map[string]*sync.Mutex
+}{
+	locks: make(map[string]*sync.Mutex),
+}
+
+func lockRaceWeekend(raceWeekendID string) (unlock func()) {
+	raceWeekendLocks.Lock()
+	lock, ok := raceWeekendLocks.locks[raceWeekendID]
+
+	if !ok {
+		lock = &sync.Mutex{}
+		raceWeekendLocks.locks[raceWeekendID] = lock
+	}
+	raceWeekendLocks.Unlock()
+
+	lock.Lock()
+
+	return lock.Unlock
+}
+
+// markSessionStarted reloads the RaceWeekend and records that the session has been started on this server.
+func (rwm *RaceWeekendManager) markSessionStarted(raceWeekendID string, sessionID string) error {
+	unlock := lockRaceWeekend(raceWeekendID)
+	defer unlock()
+
+	raceWeekend, session, err := rwm.FindSession(raceWeekendID, sessionID)
+
+	if err != nil {
+		return err
+	}
+
+	if session.InProgress() {
+		return ErrRaceWeekendSessionAlreadyRunning
+	}
+
+	session.StartedTime = time.Now()
+	session.RunningServerID = rwm.serverID
+
+	return rwm.UpsertRaceWeekend(raceWeekend)
+}
+
+// managerForServer returns the RaceWeekendManager of the server that a session is running on. Sessions which are
+// not running on a known server are handled by this RaceWeekendManager.
+func (rwm *RaceWeekendManager) managerForServer(serverID ServerID) *RaceWeekendManager {
+	if serverID == uuid.Nil || serverID == rwm.serverID || rwm.serverPool == nil {
+		return rwm
+	}
+
+	if manager := rwm.serverPool.RaceWeekendManagerForServer(serverID); manager != nil {
+		return manager
+	}
+
+	logrus.Warnf("Race Weekend: could not find server: %s, using server: %s instead", serverID.String(), rwm.serverID.String())
+
+	return rwm
+}
+
+// FindConcurrentSessions returns the children of the parent session which are ready to be run and have not yet been
+// started. Each of these can be run at the same time on a separate server.
+func (rw *RaceWeekend) FindConcurrentSessions(parentID string) []*RaceWeekendSession {
+	var sessions []*RaceWeekendSession
+
+	for _, child := range rw.FindChildren(parentID) {
+		if child.InProgress() || child.Completed() || !rw.SessionCanBeRun(child) {
+			continue
+		}
+
+		sessions = append(sessions, child)
+	}
+
+	return sessions
+}
+
+// StartConcurrentSessions starts each of the given sessions on its own free server. Results of each session are
+// collected independently by the server that runs it, and sessions further down the RaceWeekend only become
+// available once all of their parents have completed.
+func (rwm *RaceWeekendManager) StartConcurrentSessions(raceWeekendID string, sessions []*RaceWeekendSession) error {
+	if len(sessions) == 0 {
+		return ErrRaceWeekendNoConcurrentSessions
+	}
+
+	if rwm.serverPool == nil {
+		return ErrRaceWeekendNotEnoughFreeServers
+	}
+
+	freeServers := rwm.serverPool.FreeRaceWeekendManagers()
+
+	if len(freeServers) < len(sessions) {
+		return ErrRaceWeekendNotEnoughFreeServers
+	}
+
+	for i, session := range sessions {
+		logrus.Infof("Race Weekend: starting session: %s on server: %s", session.Name(), freeServers[i].serverID.String())
+
+		if err := freeServers[i].StartSession(raceWeekendID, session.ID.String(), false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartChildSessionsConcurrently starts all runnable children of the parent session on separate servers.
+func (rwm *RaceWeekendManager) StartChildSessionsConcurrently(raceWeekendID, parentSessionID string) (int, error) {
+	raceWeekend, err := rwm.LoadRaceWeekend(raceWeekendID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sessions := raceWeekend.FindConcurrentSessions(parentSessionID)
+
+	return len(sessions), rwm.StartConcurrentSessions(raceWeekendID, sessions)
+}
+
+func (rwh *RaceWeekendHandler) startConcurrentSessions(w http.ResponseWriter, r *http.Request) {
+	numSessions, err := rwh.raceWeekendManager.StartChildSessionsConcurrently(chi.URLParam(r, "raceWeekendID"), chi.URLParam(r, "sessionID"))
+
+	switch err {
+	case nil:
+		AddFlash(w, r, fmt.Sprintf("%d sessions were started on separate servers", numSessions))
+	case ErrRaceWeekendNoConcurrentSessions:
+		AddErrorFlash(w, r, "There are no sessions ready to be started from this session")
+	case ErrRaceWeekendNotEnoughFreeServers:
+		AddErrorFlash(w, r, fmt.Sprintf("%d free servers are needed to start these sessions at the same time", numSessions))
+	default:
+		logrus.WithError(err).Errorf("Could not start concurrent race weekend sessions")
+		AddErrorFlash(w, r, "Couldn't start the sessions")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
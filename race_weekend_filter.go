@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/cj123/ini"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,6 +29,10 @@ type RaceWeekendSessionToSessionFilter struct {
 	// ResultEnd is the end of the split from the previous session's result
 	ResultEnd int
 
+	// ToEndOfResults ignores ResultEnd and takes every entrant from ResultStart to the end of the previous
+	// session's result, however many entrants there are when the filter is run.
+	ToEndOfResults bool
+
 	// NumEntrantsToReverse defines how many entrants to reverse. -1 indicates all, 0 indicates none, or N entrants.
 	NumEntrantsToReverse int
 
@@ -49,6 +54,10 @@ type RaceWeekendSessionToSessionFilter struct {
 
 	// SelectedDriverGUIDs is a list of the currently selected driver GUIDs.
 	SelectedDriverGUIDs []string
+
+	// PerClass applies ResultStart and ResultEnd to each class of the previous session's result separately,
+	// e.g. a split of 1-10 takes the top 10 of every class.
+	PerClass bool
 =======
 	ResultStart int
 	ResultEnd   int
@@ -124,8 +133,10 @@ func (f RaceWeekendSessionToSessionFilter) Filter(parentSession, childSession *R
 				}
 			}
 		}
+	} else if f.PerClass {
+		split = f.splitPerClass(parentSessionResults)
 	} else {
-		resultStart, resultEnd := f.ResultStart, f.ResultEnd
+		resultStart, resultEnd := f.ResultStart, f.resultEnd(len(parentSessionResults))
 
 		resultStart--
 
@@ -198,6 +209,51 @@ func (f RaceWeekendSessionToSessionFilter) Filter(parentSession, childSession *R
 }
 <<<<<<< HEAD
 
+// splitPerClass takes ResultStart to ResultEnd of each class in the parent session's results. Classes are kept
+// together, in the order that they first appear in the results.
+func (f RaceWeekendSessionToSessionFilter) splitPerClass(parentSessionResults []*RaceWeekendSessionEntrant) []*RaceWeekendSessionEntrant {
+	var classOrder []uuid.UUID
+	entrantsForClass := make(map[uuid.UUID][]*RaceWeekendSessionEntrant)
+
+	for _, entrant := range parentSessionResults {
+		classID := entrant.EntrantResult.ClassID
+
+		if _, ok := entrantsForClass[classID]; !ok {
+			classOrder = append(classOrder, classID)
+		}
+
+		entrantsForClass[classID] = append(entrantsForClass[classID], entrant)
+	}
+
+	var split []*RaceWeekendSessionEntrant
+
+	for _, classID := range classOrder {
+		entrants := entrantsForClass[classID]
+		resultStart, resultEnd := f.ResultStart-1, f.resultEnd(len(entrants))
+
+		if resultStart >= len(entrants) {
+			continue
+		}
+
+		if resultEnd > len(entrants) {
+			resultEnd = len(entrants)
+		}
+
+		split = append(split, entrants[resultStart:resultEnd]...)
+	}
+
+	return split
+}
+
+// resultEnd is the end of the split from a result of numResults entrants.
+func (f RaceWeekendSessionToSessionFilter) resultEnd(numResults int) int {
+	if f.ToEndOfResults {
+		return numResults
+	}
+
+	return f.ResultEnd
+}
+
 const lockedTyreSetupFolder = "server_manager_locked_tyres"
 
 func (rw *RaceWeekend) buildLockedTyreSetup(session *RaceWeekendSession, entrant *RaceWeekendSessionEntrant, fastestLap *SessionLap) error {
@@ -0,0 +1,344 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrGridOverrideNeedsReason      = errors.New("servermanager: grid override needs a reason")
+	ErrGridOverrideNeedsGridPlaces  = errors.New("servermanager: grid override must move the driver at least one place")
+	ErrGridOverrideNotFound         = errors.New("servermanager: grid override not found")
+	ErrGridOverrideDriverNotEntered = errors.New("servermanager: grid override driver is not in the session grid")
+)
+
+// A RaceWeekendGridOverride moves a driver on the starting grid of a RaceWeekendSession, after the grid has been
+// computed from the session's filters and sorting. Overrides are applied in the order they were added.
+type RaceWeekendGridOverride struct {
+	ID         uuid.UUID
+	Created    time.Time
+	DriverGUID string
+	DriverName string
+
+	// GridPlaces is the number of places the driver is moved. Positive values move the driver back (a grid drop),
+	// negative values move the driver forward.
+	GridPlaces int
+	Reason     string
+}
+
+// Description summarises the override, e.g. "3 place grid drop"
+func (o *RaceWeekendGridOverride) Description() string {
+	places := o.GridPlaces
+
+	if places < 0 {
+		places = -places
+	}
+
+	plural := "s"
+
+	if places == 1 {
+		plural = ""
+	}
+
+	if o.GridPlaces > 0 {
+		return fmt.Sprintf("%d place%s grid drop", places, plural)
+	}
+
+	return fmt.Sprintf("moved forward %d grid place%s", places, plural)
+}
+
+// A RaceWeekendPenalty is an entry in the penalty history of a RaceWeekend.
+type RaceWeekendPenalty struct {
+	ID          uuid.UUID
+	Time        time.Time
+	SessionID   uuid.UUID
+	DriverGUID  string
+	DriverName  string
+	Description string
+	Reason      string
+
+	// Revoked is set when the penalty is withdrawn. Revoked penalties are kept so that the history is complete.
+	Revoked time.Time
+}
+
+func (p *RaceWeekendPenalty) IsRevoked() bool {
+	return !p.Revoked.IsZero()
+}
+
+// AddPenalty records a penalty in the RaceWeekend's penalty history.
+func (rw *RaceWeekend) AddPenalty(penalty *RaceWeekendPenalty) {
+	logrus.Infof("Race Weekend: %s: penalty for %s (%s): %s, reason: %s", rw.Name, penalty.DriverName, penalty.DriverGUID, penalty.Description, penalty.Reason)
+
+	rw.PenaltyHistory = append(rw.PenaltyHistory, penalty)
+}
+
+// FindPenaltyByID finds a penalty in the RaceWeekend's penalty history.
+func (rw *RaceWeekend) FindPenaltyByID(id uuid.UUID) *RaceWeekendPenalty {
+	for _, penalty := range rw.PenaltyHistory {
+		if penalty.ID == id {
+			return penalty
+		}
+	}
+
+	return nil
+}
+
+// ApplyGridOverrides moves drivers in the computed grid according to the session's grid overrides. Drivers are
+// moved within their class, so that multiclass grids stay grouped by class. The final grid uses the same pitboxes
+// as the computed grid. Overrides for drivers who are not in the grid are ignored.
+func (rws *RaceWeekendSession) ApplyGridOverrides(entryList RaceWeekendEntryList) RaceWeekendEntryList {
+	grid := RaceWeekendEntryList(entryList.Sorted())
+
+	pitBoxes := make([]int, len(grid))
+
+	for i, entrant := range grid {
+		pitBoxes[i] = entrant.PitBox
+	}
+
+	for _, override := range rws.GridOverrides {
+		i := grid.indexOfDriver(override.DriverGUID)
+
+		if i < 0 {
+			continue
+		}
+
+		// the grid positions of every entrant in the driver's class
+		var classPositions []int
+
+		for position, entrant := range grid {
+			if entrant.EntrantResult.ClassID == grid[i].EntrantResult.ClassID {
+				classPositions = append(classPositions, position)
+			}
+		}
+
+		class := make(RaceWeekendEntryList, len(classPositions))
+
+		for classIndex, position := range classPositions {
+			class[classIndex] = grid[position]
+		}
+
+		from := class.indexOfDriver(override.DriverGUID)
+		to := from + override.GridPlaces
+
+		if to < 0 {
+			to = 0
+		} else if to >= len(class) {
+			to = len(class) - 1
+		}
+
+		entrant := class[from]
+
+		moved := make(RaceWeekendEntryList, 0, len(class))
+		moved = append(moved, class[:from]...)
+		moved = append(moved, class[from+1:]...)
+
+		moved = append(moved[:to], append(RaceWeekendEntryList{entrant}, moved[to:]...)...)
+
+		for classIndex, position := range classPositions {
+			grid[position] = moved[classIndex]
+		}
+	}
+
+	for i, entrant := range grid {
+		entrant.PitBox = pitBoxes[i]
+	}
+
+	return grid
+}
+
+func (e RaceWeekendEntryList) indexOfDriver(guid string) int {
+	for i, entrant := range e {
+		if entrant.Car.Driver.GUID == guid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// A RaceWeekendGridChange is a driver whose final grid position differs from the computed grid.
+type RaceWeekendGridChange struct {
+	Name             string
+	ComputedPosition int
+	FinalPosition    int
+	Reasons          []string
+}
+
+// GridChanges compares a computed grid with the final grid, explaining each change with the overrides that caused it.
+func (rws *RaceWeekendSession) GridChanges(computedGrid, finalGrid RaceWeekendEntryList) []*RaceWeekendGridChange {
+	var changes []*RaceWeekendGridChange
+
+	computed := computedGrid.Sorted()
+	final := RaceWeekendEntryList(finalGrid.Sorted())
+
+	for computedPosition, entrant := range computed {
+		finalPosition := final.indexOfDriver(entrant.Car.Driver.GUID)
+
+		if finalPosition < 0 || finalPosition == computedPosition {
+			continue
+		}
+
+		change := &RaceWeekendGridChange{
+			Name:             entrant.Car.GetName(),
+			ComputedPosition: computedPosition + 1,
+			FinalPosition:    finalPosition + 1,
+		}
+
+		for _, override := range rws.GridOverrides {
+			if override.DriverGUID == entrant.Car.Driver.GUID {
+				change.Reasons = append(change.Reasons, fmt.Sprintf("%s: %s", override.Description(), override.Reason))
+			}
+		}
+
+		if len(change.Reasons) == 0 {
+			change.Reasons = append(change.Reasons, "Moved by another driver's grid override")
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// AddGridOverride adds a grid override to a session, recording it in the RaceWeekend's penalty history.
+func (rwm *RaceWeekendManager) AddGridOverride(raceWeekendID, sessionID string, override *RaceWeekendGridOverride) error {
+	if strings.TrimSpace(override.Reason) == "" {
+		return ErrGridOverrideNeedsReason
+	}
+
+	if override.GridPlaces == 0 {
+		return ErrGridOverrideNeedsGridPlaces
+	}
+
+	_, err := rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		session, err := raceWeekend.FindSessionByID(sessionID)
+
+		if err != nil {
+			return err
+		}
+
+		entryList, err := session.GetRaceWeekendEntryList(raceWeekend, nil, "")
+
+		if err != nil {
+			return err
+		}
+
+		i := entryList.indexOfDriver(override.DriverGUID)
+
+		if i < 0 {
+			return ErrGridOverrideDriverNotEntered
+		}
+
+		override.ID = uuid.New()
+		override.Created = time.Now()
+		override.DriverName = entryList[i].Car.GetName()
+
+		session.GridOverrides = append(session.GridOverrides, override)
+
+		raceWeekend.AddPenalty(&RaceWeekendPenalty{
+			// the penalty shares its ID with the override so that it can be revoked with the override.
+			ID:          override.ID,
+			Time:        override.Created,
+			SessionID:   session.ID,
+			DriverGUID:  override.DriverGUID,
+			DriverName:  override.DriverName,
+			Description: fmt.Sprintf("%s: %s", session.Name(), override.Description()),
+			Reason:      override.Reason,
+		})
+
+		return nil
+	})
+
+	return err
+}
+
+// RemoveGridOverride removes a grid override from a session, marking its penalty as revoked.
+func (rwm *RaceWeekendManager) RemoveGridOverride(raceWeekendID, sessionID, overrideID string) error {
+	id, err := uuid.Parse(overrideID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		session, err := raceWeekend.FindSessionByID(sessionID)
+
+		if err != nil {
+			return err
+		}
+
+		for i, override := range session.GridOverrides {
+			if override.ID != id {
+				continue
+			}
+
+			session.GridOverrides = append(session.GridOverrides[:i], session.GridOverrides[i+1:]...)
+
+			if penalty := raceWeekend.FindPenaltyByID(id); penalty != nil {
+				penalty.Revoked = time.Now()
+			}
+
+			return nil
+		}
+
+		return ErrGridOverrideNotFound
+	})
+
+	return err
+}
+
+func (rwh *RaceWeekendHandler) addGridOverride(w http.ResponseWriter, r *http.Request) {
+	raceWeekendID := chi.URLParam(r, "raceWeekendID")
+
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse grid override form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	override := &RaceWeekendGridOverride{
+		DriverGUID: r.FormValue("DriverGUID"),
+		GridPlaces: formValueAsInt(r.FormValue("GridPlaces")),
+		Reason:     strings.TrimSpace(r.FormValue("Reason")),
+	}
+
+	err := rwh.raceWeekendManager.AddGridOverride(raceWeekendID, chi.URLParam(r, "sessionID"), override)
+
+	switch err {
+	case nil:
+		AddFlash(w, r, fmt.Sprintf("%s: %s", override.DriverName, override.Description()))
+	case ErrGridOverrideNeedsReason:
+		AddErrorFlash(w, r, "Grid overrides must have a reason")
+	case ErrGridOverrideNeedsGridPlaces:
+		AddErrorFlash(w, r, "Grid overrides must move the driver at least one place")
+	case ErrGridOverrideDriverNotEntered:
+		AddErrorFlash(w, r, "That driver is not on the grid for this session")
+	default:
+		logrus.WithError(err).Errorf("Could not add race weekend grid override")
+		AddErrorFlash(w, r, "Couldn't add the grid override")
+	}
+
+	http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+}
+
+func (rwh *RaceWeekendHandler) removeGridOverride(w http.ResponseWriter, r *http.Request) {
+	raceWeekendID := chi.URLParam(r, "raceWeekendID")
+
+	err := rwh.raceWeekendManager.RemoveGridOverride(raceWeekendID, chi.URLParam(r, "sessionID"), chi.URLParam(r, "overrideID"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not remove race weekend grid override")
+		AddErrorFlash(w, r, "Couldn't remove the grid override")
+	} else {
+		AddFlash(w, r, "Grid override removed, the penalty has been marked as revoked")
+	}
+
+	http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+}
@@ -0,0 +1,189 @@
+package servermanager
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	gridOverridesTestClassA = uuid.New()
+	gridOverridesTestClassB = uuid.New()
+)
+
+// gridOverridesTestEntryList builds a grid of drivers "A" to "E". A, C and E are in class A and B and D are in
+// class B. Drivers are placed in every other pitbox, so that pitboxes don't match grid positions.
+func gridOverridesTestEntryList(classes ...uuid.UUID) RaceWeekendEntryList {
+	var entryList RaceWeekendEntryList
+
+	for i, classID := range classes {
+		guid := string(rune('A' + i))
+
+		entrant := NewRaceWeekendSessionEntrant(
+			uuid.New(),
+			&SessionCar{Driver: SessionDriver{GUID: guid, Name: "Driver " + guid}},
+			&SessionResult{DriverGUID: guid, ClassID: classID},
+			nil,
+		)
+
+		entryList.AddInPitBox(entrant, i*2)
+	}
+
+	return entryList
+}
+
+func gridOverridesTestOrder(entryList RaceWeekendEntryList) (order string, pitBoxes []int) {
+	for _, entrant := range entryList.Sorted() {
+		order += entrant.Car.Driver.GUID
+		pitBoxes = append(pitBoxes, entrant.PitBox)
+	}
+
+	return order, pitBoxes
+}
+
+func TestRaceWeekendSession_ApplyGridOverrides(t *testing.T) {
+	singleClass := []uuid.UUID{gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA}
+	multiClass := []uuid.UUID{gridOverridesTestClassA, gridOverridesTestClassB, gridOverridesTestClassA, gridOverridesTestClassB, gridOverridesTestClassA}
+
+	testCases := []struct {
+		name      string
+		classes   []uuid.UUID
+		overrides []*RaceWeekendGridOverride
+		order     string
+	}{
+		{
+			name:      "No overrides",
+			classes:   singleClass,
+			overrides: nil,
+			order:     "ABCDE",
+		},
+		{
+			name:      "Grid drop",
+			classes:   singleClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "A", GridPlaces: 2}},
+			order:     "BCADE",
+		},
+		{
+			name:      "Move forward",
+			classes:   singleClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "D", GridPlaces: -2}},
+			order:     "ADBCE",
+		},
+		{
+			name:      "Grid drop past the back of the grid",
+			classes:   singleClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "B", GridPlaces: 10}},
+			order:     "ACDEB",
+		},
+		{
+			name:      "Overrides are applied in order",
+			classes:   singleClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "A", GridPlaces: 1}, {DriverGUID: "B", GridPlaces: 1}},
+			order:     "ABCDE",
+		},
+		{
+			name:      "Driver not in grid",
+			classes:   singleClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "Z", GridPlaces: 1}},
+			order:     "ABCDE",
+		},
+		{
+			name:      "Multiclass grid drop stays within class",
+			classes:   multiClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "A", GridPlaces: 1}},
+			order:     "CBADE",
+		},
+		{
+			name:      "Multiclass move forward stays within class",
+			classes:   multiClass,
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "D", GridPlaces: -5}},
+			order:     "ADCBE",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			session := NewRaceWeekendSession()
+			session.GridOverrides = testCase.overrides
+
+			order, pitBoxes := gridOverridesTestOrder(session.ApplyGridOverrides(gridOverridesTestEntryList(testCase.classes...)))
+
+			if order != testCase.order {
+				t.Logf("Expected grid order %s, got: %s", testCase.order, order)
+				t.Fail()
+			}
+
+			for i, pitBox := range pitBoxes {
+				if pitBox != i*2 {
+					t.Logf("Expected the grid to keep its pitboxes, got: %v", pitBoxes)
+					t.Fail()
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRaceWeekendSession_GridChanges(t *testing.T) {
+	testCases := []struct {
+		name      string
+		overrides []*RaceWeekendGridOverride
+		changes   []RaceWeekendGridChange
+	}{
+		{
+			name:      "No overrides",
+			overrides: nil,
+			changes:   nil,
+		},
+		{
+			name:      "Grid drop",
+			overrides: []*RaceWeekendGridOverride{{DriverGUID: "A", GridPlaces: 1, Reason: "Causing a collision"}},
+			changes: []RaceWeekendGridChange{
+				{Name: "Driver A", ComputedPosition: 1, FinalPosition: 2, Reasons: []string{"1 place grid drop: Causing a collision"}},
+				{Name: "Driver B", ComputedPosition: 2, FinalPosition: 1, Reasons: []string{"Moved by another driver's grid override"}},
+			},
+		},
+		{
+			name: "Overrides cancel out",
+			overrides: []*RaceWeekendGridOverride{
+				{DriverGUID: "C", GridPlaces: 2, Reason: "Engine change"},
+				{DriverGUID: "C", GridPlaces: -2, Reason: "Appeal"},
+			},
+			changes: nil,
+		},
+	}
+
+	classes := []uuid.UUID{gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			session := NewRaceWeekendSession()
+			session.GridOverrides = testCase.overrides
+
+			computedGrid := gridOverridesTestEntryList(classes...)
+			finalGrid := session.ApplyGridOverrides(gridOverridesTestEntryList(classes...))
+
+			changes := session.GridChanges(computedGrid, finalGrid)
+
+			if len(changes) != len(testCase.changes) {
+				t.Logf("Expected %d grid changes, got: %d", len(testCase.changes), len(changes))
+				t.Fail()
+				return
+			}
+
+			for i, expected := range testCase.changes {
+				change := changes[i]
+
+				if change.Name != expected.Name || change.ComputedPosition != expected.ComputedPosition || change.FinalPosition != expected.FinalPosition {
+					t.Logf("Expected %s to move from %d to %d, got: %s from %d to %d", expected.Name, expected.ComputedPosition, expected.FinalPosition, change.Name, change.ComputedPosition, change.FinalPosition)
+					t.Fail()
+				}
+
+				if len(change.Reasons) != len(expected.Reasons) || change.Reasons[0] != expected.Reasons[0] {
+					t.Logf("Expected reasons %v for %s, got: %v", expected.Reasons, expected.Name, change.Reasons)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
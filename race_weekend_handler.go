@@ -11,6 +11,7 @@ import (
 =======
 >>>>>>> origin/multiserver2
 	"github.com/go-chi/chi"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,12 +19,14 @@ type RaceWeekendHandler struct {
 	*BaseHandler
 
 	raceWeekendManager *RaceWeekendManager
+	scheduler          *Scheduler
 }
 
-func NewRaceWeekendHandler(baseHandler *BaseHandler, raceWeekendManager *RaceWeekendManager) *RaceWeekendHandler {
+func NewRaceWeekendHandler(baseHandler *BaseHandler, raceWeekendManager *RaceWeekendManager, scheduler *Scheduler) *RaceWeekendHandler {
 	return &RaceWeekendHandler{
 		BaseHandler:        baseHandler,
 		raceWeekendManager: raceWeekendManager,
+		scheduler:          scheduler,
 	}
 }
 
@@ -52,6 +55,7 @@ type raceWeekendViewTemplateVars struct {
 
 	RaceWeekend *RaceWeekend
 	Account     *Account
+	Problems    []*RaceWeekendProblem
 }
 
 func (rwh *RaceWeekendHandler) view(w http.ResponseWriter, r *http.Request) {
@@ -69,6 +73,7 @@ func (rwh *RaceWeekendHandler) view(w http.ResponseWriter, r *http.Request) {
 		},
 		RaceWeekend: raceWeekend,
 		Account:     AccountFromRequest(r),
+		Problems:    raceWeekend.Validate(),
 	})
 }
 
@@ -380,7 +385,7 @@ func (rwh *RaceWeekendHandler) manageFilters(w http.ResponseWriter, r *http.Requ
 		ChildSession:               childSession,
 		ResultsAvailableForSorting: sessionResults,
 		Filter:                     filter,
-		AvailableSorters:           RaceWeekendEntryListSorters,
+		AvailableSorters:           AvailableRaceWeekendEntryListSorters(),
 		ParentSessionResults:       parentSessionResults,
 =======
 	rwh.viewRenderer.MustLoadPartial(w, r, "race-weekend/popups/manage-filters.html", &raceWeekendFilterTemplateVars{
@@ -388,7 +393,7 @@ func (rwh *RaceWeekendHandler) manageFilters(w http.ResponseWriter, r *http.Requ
 		ParentSession:    parentSession,
 		ChildSession:     childSession,
 		Filter:           filter,
-		AvailableSorters: RaceWeekendEntryListSorters,
+		AvailableSorters: AvailableRaceWeekendEntryListSorters(),
 >>>>>>> origin/multiserver2
 	})
 }
@@ -450,6 +455,7 @@ type raceWeekendManageEntryListTemplateVars struct {
 	RaceWeekend      *RaceWeekend
 	Session          *RaceWeekendSession
 	AvailableSorters []RaceWeekendEntryListSorterDescription
+	Grid             []*RaceWeekendSessionEntrant
 }
 
 func (rwh *RaceWeekendHandler) manageEntryList(w http.ResponseWriter, r *http.Request) {
@@ -464,10 +470,26 @@ func (rwh *RaceWeekendHandler) manageEntryList(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	var grid []*RaceWeekendSessionEntrant
+
+	if raceWeekend.SessionCanBeRun(session) {
+		// the computed grid is used to pick drivers for grid overrides
+		entryList, err := session.GetComputedRaceWeekendEntryList(raceWeekend, nil, "")
+
+		if err != nil {
+			logrus.WithError(err).Errorf("Couldn't load session grid")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		grid = entryList.Sorted()
+	}
+
 	rwh.viewRenderer.MustLoadPartial(w, r, "race-weekend/popups/manage-entrylist.html", &raceWeekendManageEntryListTemplateVars{
 		RaceWeekend:      raceWeekend,
 		Session:          session,
-		AvailableSorters: RaceWeekendEntryListSorters,
+		AvailableSorters: AvailableRaceWeekendEntryListSorters(),
+		Grid:             grid,
 	})
 }
 
@@ -590,20 +612,91 @@ func (rwh *RaceWeekendHandler) scheduleSession(w http.ResponseWriter, r *http.Re
 		message = "We have scheduled the Race Weekend Session to begin after the parent session(s) complete."
 	}
 
-	err := rwh.raceWeekendManager.ScheduleSession(championshipID, championshipEventID, date, startWhenParentFinished)
+	conflicts, err := rwh.scheduleSessionFromForm(r, championshipID, championshipEventID, date, startWhenParentFinished)
 
-	if err != nil {
+	switch err {
+	case nil:
+	case ErrScheduleConflict, ErrNoFreeServer:
+		message := "The Race Weekend Session was not scheduled, it conflicts with other events on the server: "
+
+		if err == ErrNoFreeServer {
+			message = "The Race Weekend Session was not scheduled, no server is free at that time: "
+		}
+
+		AddErrorFlash(w, r, message+scheduleConflictsDescription(conflicts))
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	default:
 		logrus.WithError(err).Errorf("couldn't schedule race weekend session")
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
+	if len(conflicts) > 0 {
+		AddErrorFlash(w, r, "Warning, this session conflicts with other events on the server: "+scheduleConflictsDescription(conflicts))
+	}
+
 	AddFlash(w, r, message)
 	http.Redirect(w, r, r.Referer(), http.StatusFound)
 }
 
+// scheduleSessionFromForm schedules a Race Weekend session with the missed event policy and schedule conflict
+// action in the form. Sessions which conflict with other events are not scheduled, and ErrScheduleConflict or
+// ErrNoFreeServer is returned along with the conflicts.
+func (rwh *RaceWeekendHandler) scheduleSessionFromForm(r *http.Request, raceWeekendID, sessionID string, date time.Time, startWhenParentFinished bool) ([]*ScheduleConflict, error) {
+	_, session, err := rwh.raceWeekendManager.FindSession(raceWeekendID, sessionID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = rwh.scheduler.SetMissedEventPolicy(session, missedEventPolicyFromForm(r))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []*ScheduleConflict
+
+	if !startWhenParentFinished {
+		if session.ScheduledServerID == uuid.Nil {
+			// sessions are run on the server they are scheduled from, unless they conflict with another event.
+			session.ScheduledServerID = rwh.scheduler.serverID
+		}
+
+		conflicts, err = rwh.scheduler.ResolveScheduleConflicts(session, date, ScheduleConflictAction(r.FormValue("schedule-conflict-action")))
+
+		if err != nil {
+			return conflicts, err
+		}
+	}
+
+	err = rwh.raceWeekendManager.ScheduleSession(raceWeekendID, sessionID, date, startWhenParentFinished)
+
+	if err != nil {
+		return conflicts, err
+	}
+
+	// the stored session has the server that the session was assigned to.
+	_, session, err = rwh.raceWeekendManager.FindSession(raceWeekendID, sessionID)
+
+	if err != nil {
+		return conflicts, err
+	}
+
+	if startWhenParentFinished {
+		// the session is started by its parents finishing, not by the scheduler.
+		rwh.scheduler.cancelJobs(session)
+		return conflicts, nil
+	}
+
+	return conflicts, rwh.scheduler.Schedule(session, date)
+}
+
 func (rwh *RaceWeekendHandler) removeSessionSchedule(w http.ResponseWriter, r *http.Request) {
-	err := rwh.raceWeekendManager.DeScheduleSession(chi.URLParam(r, "raceWeekendID"), chi.URLParam(r, "sessionID"))
+	raceWeekendID, sessionID := chi.URLParam(r, "raceWeekendID"), chi.URLParam(r, "sessionID")
+
+	err := rwh.raceWeekendManager.DeScheduleSession(raceWeekendID, sessionID)
 
 	if err != nil {
 		logrus.WithError(err).Errorf("couldn't de-schedule race weekend session")
@@ -611,6 +704,16 @@ func (rwh *RaceWeekendHandler) removeSessionSchedule(w http.ResponseWriter, r *h
 		return
 	}
 
+	_, session, err := rwh.raceWeekendManager.FindSession(raceWeekendID, sessionID)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't find race weekend session")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rwh.scheduler.cancelJobs(session)
+
 	http.Redirect(w, r, r.Referer(), http.StatusFound)
 }
 =======
@@ -0,0 +1,350 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const heatPositionSortKey = "heat_position"
+
+var (
+	ErrHeatRacesInvalidSession     = errors.New("servermanager: heat races can only be set up for a race session with one parent which has not yet been run")
+	ErrHeatRacesNotNeeded          = errors.New("servermanager: all entrants fit on the grid, heat races are not needed")
+	ErrHeatRacesFinalTooBig        = errors.New("servermanager: the number of drivers advancing to the final exceeds the grid size")
+	ErrHeatRacesHeatTooSmall       = errors.New("servermanager: heats must have more entrants than the number of drivers advancing from them")
+	ErrHeatRacesLastChanceTooBig   = errors.New("servermanager: the last chance qualifier has more entrants than the grid size")
+	ErrHeatRacesUnknownGridSize    = errors.New("servermanager: could not determine the grid size of the session")
+	ErrHeatRacesNoDriversAdvancing = errors.New("servermanager: at least one driver must advance from each heat")
+)
+
+// HeatRaceSplits describes how a field of entrants is split into heats. Heats are filled from the entrant order,
+// so entrants 1 to N go into the first heat, N+1 to 2N into the second, and so on.
+type HeatRaceSplits struct {
+	NumEntrants int
+	GridSize    int
+
+	// Heats are the [ResultStart, ResultEnd] ranges of each heat
+	Heats [][2]int
+
+	// AdvancePerHeat is the number of drivers from each heat who advance to the final.
+	AdvancePerHeat int
+
+	// LastChanceAdvance is the number of drivers who advance from the last chance qualifier. The last chance
+	// qualifier is made up of all drivers who did not advance from their heat. If zero, there is no last chance
+	// qualifier.
+	LastChanceAdvance int
+}
+
+// NumLastChanceEntrants is the number of entrants in the last chance qualifier.
+func (h *HeatRaceSplits) NumLastChanceEntrants() int {
+	if h.LastChanceAdvance == 0 {
+		return 0
+	}
+
+	return h.NumEntrants - h.AdvancePerHeat*len(h.Heats)
+}
+
+// ComputeHeatRaceSplits computes the fewest, evenly sized heats which fit numEntrants onto a grid of gridSize. If
+// advancePerHeat is zero, as many drivers as possible advance from each heat while leaving room on the grid of the
+// final for the lastChanceAdvance drivers.
+func ComputeHeatRaceSplits(numEntrants, gridSize, advancePerHeat, lastChanceAdvance int) (*HeatRaceSplits, error) {
+	if gridSize <= 0 {
+		return nil, ErrHeatRacesUnknownGridSize
+	}
+
+	if numEntrants <= gridSize {
+		return nil, ErrHeatRacesNotNeeded
+	}
+
+	numHeats := (numEntrants + gridSize - 1) / gridSize
+
+	if advancePerHeat == 0 {
+		advancePerHeat = (gridSize - lastChanceAdvance) / numHeats
+	}
+
+	if advancePerHeat <= 0 {
+		return nil, ErrHeatRacesNoDriversAdvancing
+	}
+
+	if advancePerHeat*numHeats+lastChanceAdvance > gridSize {
+		return nil, ErrHeatRacesFinalTooBig
+	}
+
+	splits := &HeatRaceSplits{
+		NumEntrants:       numEntrants,
+		GridSize:          gridSize,
+		AdvancePerHeat:    advancePerHeat,
+		LastChanceAdvance: lastChanceAdvance,
+	}
+
+	heatSize, remainder := numEntrants/numHeats, numEntrants%numHeats
+	resultStart := 1
+
+	for i := 0; i < numHeats; i++ {
+		size := heatSize
+
+		if i < remainder {
+			size++
+		}
+
+		splits.Heats = append(splits.Heats, [2]int{resultStart, resultStart + size - 1})
+		resultStart += size
+	}
+
+	if advancePerHeat >= heatSize {
+		return nil, ErrHeatRacesHeatTooSmall
+	}
+
+	if splits.NumLastChanceEntrants() > gridSize {
+		return nil, ErrHeatRacesLastChanceTooBig
+	}
+
+	return splits, nil
+}
+
+// RaceWeekendSessionGridSize returns the number of entrants that can take part in a session. This is the lowest of
+// the session's MaxClients, the number of pit boxes at the session's track and the server's MaxClientsOverride.
+func RaceWeekendSessionGridSize(session *RaceWeekendSession) int {
+	gridSize := session.RaceConfig.MaxClients
+
+	trackInfo, err := GetTrackInfo(session.RaceConfig.Track, session.RaceConfig.TrackLayout)
+
+	if err == nil {
+		boxes, err := trackInfo.Pitboxes.Int64()
+
+		if err == nil && boxes > 0 && (gridSize <= 0 || int(boxes) < gridSize) {
+			gridSize = int(boxes)
+		}
+	}
+
+	if MaxClientsOverride > 0 && (gridSize <= 0 || MaxClientsOverride < gridSize) {
+		gridSize = MaxClientsOverride
+	}
+
+	return gridSize
+}
+
+// HeatPositionEntryListSort builds a grid from heat races. Entrants from sessions earlier in the Race Weekend
+// (e.g. the heats) start ahead of entrants from later sessions (e.g. the last chance qualifier). Entrants from
+// sessions at the same stage are interleaved by their finishing position, so the winners of each heat start at
+// the front, followed by the drivers who finished second, and so on.
+type HeatPositionEntryListSort struct{}
+
+func (HeatPositionEntryListSort) Sort(rw *RaceWeekend, _ *RaceWeekendSession, entrants []*RaceWeekendSessionEntrant, _ *RaceWeekendSessionToSessionFilter) error {
+	stageDepth := make(map[uuid.UUID]int)
+	entrantsInSession := make(map[uuid.UUID]int)
+	positions := make(map[*RaceWeekendSessionEntrant]int)
+
+	for _, entrant := range entrants {
+		// entrants arrive in their finishing order for each session
+		entrantsInSession[entrant.SessionID]++
+		positions[entrant] = entrantsInSession[entrant.SessionID]
+
+		if _, ok := stageDepth[entrant.SessionID]; ok {
+			continue
+		}
+
+		session, err := rw.FindSessionByID(entrant.SessionID.String())
+
+		if err != nil {
+			stageDepth[entrant.SessionID] = 0
+			continue
+		}
+
+		stageDepth[entrant.SessionID] = rw.FindTotalNumParents(session)
+	}
+
+	sort.SliceStable(entrants, func(i, j int) bool {
+		entrantI, entrantJ := entrants[i], entrants[j]
+
+		if stageDepth[entrantI.SessionID] != stageDepth[entrantJ.SessionID] {
+			return stageDepth[entrantI.SessionID] < stageDepth[entrantJ.SessionID]
+		}
+
+		return positions[entrantI] < positions[entrantJ]
+	})
+
+	return nil
+}
+
+// copyRaceWeekendSessionConfig creates a new, unscheduled RaceWeekendSession with the same race setup as session.
+// The session's name is changed to name and if length is greater than zero, it is used as the session's length in
+// laps (for lap based races) or minutes.
+func copyRaceWeekendSessionConfig(session *RaceWeekendSession, name string, length int) *RaceWeekendSession {
+	newSession := NewRaceWeekendSession()
+	newSession.RaceConfig = session.RaceConfig
+	newSession.RaceConfig.Sessions = make(Sessions)
+	newSession.OverridePassword = session.OverridePassword
+	newSession.ReplacementPassword = session.ReplacementPassword
+
+	for sessionType, sessionConfig := range session.RaceConfig.Sessions {
+		newConfig := *sessionConfig
+		newConfig.Name = name
+
+		if length > 0 {
+			if sessionType == SessionTypeRace && newConfig.Laps > 0 {
+				newConfig.Laps = length
+			} else {
+				newConfig.Time = length
+			}
+		}
+
+		newSession.RaceConfig.Sessions[sessionType] = &newConfig
+	}
+
+	return newSession
+}
+
+// ConvertToHeatRaces splits the entrants of a race session into heats when they don't all fit on its grid. The
+// top advancePerHeat drivers of each heat advance to the original session (the final), and if lastChanceAdvance is
+// greater than zero, all other drivers take part in a last chance qualifier, from which the top lastChanceAdvance
+// drivers also advance. Heats have no dependencies on each other, so they can be run sequentially, or at the same
+// time on different servers.
+func (rwm *RaceWeekendManager) ConvertToHeatRaces(raceWeekendID, finalSessionID string, advancePerHeat, lastChanceAdvance, heatLength int) (*HeatRaceSplits, error) {
+	raceWeekend, final, err := rwm.FindSession(raceWeekendID, finalSessionID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if final.SessionType() != SessionTypeRace || final.InProgress() || final.Completed() || len(final.ParentIDs) != 1 {
+		return nil, ErrHeatRacesInvalidSession
+	}
+
+	// heats are split from the session before the final, which may be the Race Weekend entry list.
+	source, err := raceWeekend.FindSessionByID(final.ParentIDs[0].String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	var numEntrants int
+
+	if source.IsBase() {
+		numEntrants = len(raceWeekend.GetEntryList())
+	} else {
+		entryList, err := source.GetRaceWeekendEntryList(raceWeekend, nil, "")
+
+		if err != nil {
+			return nil, err
+		}
+
+		numEntrants = len(entryList)
+	}
+
+	splits, err := ComputeHeatRaceSplits(numEntrants, RaceWeekendSessionGridSize(final), advancePerHeat, lastChanceAdvance)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sourceID := source.ID.String()
+	sourceSortType := ""
+
+	if filter, err := raceWeekend.GetFilter(sourceID, final.ID.String()); err == nil {
+		sourceSortType = filter.SortType
+	}
+
+	raceWeekend.RemoveFilter(sourceID, final.ID.String())
+	final.RemoveParent(sourceID)
+
+	var heats []*RaceWeekendSession
+
+	for i, heatRange := range splits.Heats {
+		heat := copyRaceWeekendSessionConfig(final, fmt.Sprintf("Heat %d", i+1), heatLength)
+
+		raceWeekend.AddSession(heat, source)
+		raceWeekend.AddFilter(sourceID, heat.ID.String(), &RaceWeekendSessionToSessionFilter{
+			ResultStart:    heatRange[0],
+			ResultEnd:      heatRange[1],
+			EntryListStart: 1,
+			SortType:       sourceSortType,
+		})
+
+		heats = append(heats, heat)
+	}
+
+	if splits.LastChanceAdvance > 0 {
+		lastChance := copyRaceWeekendSessionConfig(final, "Last Chance Qualifier", heatLength)
+		lastChance.SortType = heatPositionSortKey
+
+		entryListStart := 1
+
+		for i, heat := range heats {
+			heatSize := splits.Heats[i][1] - splits.Heats[i][0] + 1
+
+			lastChance.ParentIDs = append(lastChance.ParentIDs, heat.ID)
+			raceWeekend.AddFilter(heat.ID.String(), lastChance.ID.String(), &RaceWeekendSessionToSessionFilter{
+				ResultStart:    splits.AdvancePerHeat + 1,
+				ResultEnd:      heatSize,
+				EntryListStart: entryListStart,
+			})
+
+			entryListStart += heatSize - splits.AdvancePerHeat
+		}
+
+		raceWeekend.AddSession(lastChance, nil)
+
+		final.ParentIDs = append(final.ParentIDs, lastChance.ID)
+		raceWeekend.AddFilter(lastChance.ID.String(), final.ID.String(), &RaceWeekendSessionToSessionFilter{
+			ResultStart:    1,
+			ResultEnd:      splits.LastChanceAdvance,
+			EntryListStart: splits.AdvancePerHeat*len(heats) + 1,
+		})
+	}
+
+	for i, heat := range heats {
+		final.ParentIDs = append(final.ParentIDs, heat.ID)
+		raceWeekend.AddFilter(heat.ID.String(), final.ID.String(), &RaceWeekendSessionToSessionFilter{
+			ResultStart:    1,
+			ResultEnd:      splits.AdvancePerHeat,
+			EntryListStart: i*splits.AdvancePerHeat + 1,
+		})
+	}
+
+	final.SortType = heatPositionSortKey
+
+	return splits, rwm.UpsertRaceWeekend(raceWeekend)
+}
+
+func (rwh *RaceWeekendHandler) heatRaces(w http.ResponseWriter, r *http.Request) {
+	raceWeekendID := chi.URLParam(r, "raceWeekendID")
+
+	splits, err := rwh.raceWeekendManager.ConvertToHeatRaces(
+		raceWeekendID,
+		chi.URLParam(r, "sessionID"),
+		formValueAsInt(r.FormValue("AdvancePerHeat")),
+		formValueAsInt(r.FormValue("LastChanceAdvance")),
+		formValueAsInt(r.FormValue("HeatLength")),
+	)
+
+	switch err {
+	case nil:
+		AddFlash(w, r, fmt.Sprintf("%d entrants have been split into %d heats, with the top %d of each heat advancing to the final", splits.NumEntrants, len(splits.Heats), splits.AdvancePerHeat))
+	case ErrHeatRacesInvalidSession:
+		AddErrorFlash(w, r, "Heat races can only be set up for a race which has not been run, and which has one parent session")
+	case ErrHeatRacesNotNeeded:
+		AddErrorFlash(w, r, "All entrants fit on the grid of this session, heat races are not needed")
+	case ErrHeatRacesFinalTooBig:
+		AddErrorFlash(w, r, "Too many drivers would advance to the final for its grid, please advance fewer drivers")
+	case ErrHeatRacesHeatTooSmall:
+		AddErrorFlash(w, r, "Fewer drivers must advance from each heat than there are drivers in the heat")
+	case ErrHeatRacesLastChanceTooBig:
+		AddErrorFlash(w, r, "The last chance qualifier would be too big for the grid, please advance more drivers from each heat")
+	case ErrHeatRacesUnknownGridSize:
+		AddErrorFlash(w, r, "Couldn't work out the grid size for this session, please set Max Clients for the session")
+	case ErrHeatRacesNoDriversAdvancing:
+		AddErrorFlash(w, r, "At least one driver must advance from each heat")
+	default:
+		logrus.WithError(err).Errorf("Could not set up heat races")
+		AddErrorFlash(w, r, "Couldn't set up heat races")
+	}
+
+	http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+}
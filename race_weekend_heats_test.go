@@ -0,0 +1,337 @@
+package servermanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeHeatRaceSplits(t *testing.T) {
+	testCases := []struct {
+		name              string
+		numEntrants       int
+		gridSize          int
+		advancePerHeat    int
+		lastChanceAdvance int
+
+		err            error
+		heats          [][2]int
+		advance        int
+		lastChanceSize int
+	}{
+		{name: "Even heats", numEntrants: 40, gridSize: 24, heats: [][2]int{{1, 20}, {21, 40}}, advance: 12},
+		{name: "Uneven heats", numEntrants: 41, gridSize: 24, heats: [][2]int{{1, 21}, {22, 41}}, advance: 12},
+		{name: "Last chance qualifier", numEntrants: 40, gridSize: 24, advancePerHeat: 10, lastChanceAdvance: 4, heats: [][2]int{{1, 20}, {21, 40}}, advance: 10, lastChanceSize: 20},
+		{name: "Room left for the last chance qualifier", numEntrants: 40, gridSize: 24, lastChanceAdvance: 4, heats: [][2]int{{1, 20}, {21, 40}}, advance: 10, lastChanceSize: 20},
+		{name: "Everyone fits on the grid", numEntrants: 20, gridSize: 24, err: ErrHeatRacesNotNeeded},
+		{name: "Unknown grid size", numEntrants: 40, gridSize: 0, err: ErrHeatRacesUnknownGridSize},
+		{name: "Final too big", numEntrants: 40, gridSize: 24, advancePerHeat: 12, lastChanceAdvance: 4, err: ErrHeatRacesFinalTooBig},
+		{name: "Heat too small", numEntrants: 25, gridSize: 24, advancePerHeat: 12, err: ErrHeatRacesHeatTooSmall},
+		{name: "Last chance qualifier too big", numEntrants: 60, gridSize: 24, lastChanceAdvance: 10, err: ErrHeatRacesLastChanceTooBig},
+		{name: "No drivers advancing", numEntrants: 25, gridSize: 24, lastChanceAdvance: 23, err: ErrHeatRacesNoDriversAdvancing},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			splits, err := ComputeHeatRaceSplits(testCase.numEntrants, testCase.gridSize, testCase.advancePerHeat, testCase.lastChanceAdvance)
+
+			if err != testCase.err {
+				t.Logf("Expected error: %v, got: %v", testCase.err, err)
+				t.Fail()
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(splits.Heats) != len(testCase.heats) {
+				t.Logf("Expected %d heats, got: %d", len(testCase.heats), len(splits.Heats))
+				t.Fail()
+				return
+			}
+
+			for i, heat := range testCase.heats {
+				if splits.Heats[i] != heat {
+					t.Logf("Expected heat %d to be %v, got: %v", i+1, heat, splits.Heats[i])
+					t.Fail()
+				}
+			}
+
+			if splits.AdvancePerHeat != testCase.advance || splits.NumLastChanceEntrants() != testCase.lastChanceSize {
+				t.Logf("Expected %d to advance from each heat and %d in the last chance qualifier, got: %d and %d", testCase.advance, testCase.lastChanceSize, splits.AdvancePerHeat, splits.NumLastChanceEntrants())
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestHeatPositionEntryListSort_Sort(t *testing.T) {
+	raceWeekend := NewRaceWeekend()
+
+	base, err := raceWeekend.FindSessionByID(raceWeekend.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	heat1, heat2, lastChance := NewRaceWeekendSession(), NewRaceWeekendSession(), NewRaceWeekendSession()
+
+	raceWeekend.AddSession(heat1, base)
+	raceWeekend.AddSession(heat2, base)
+	raceWeekend.AddSession(lastChance, heat1)
+	lastChance.ParentIDs = append(lastChance.ParentIDs, heat2.ID)
+
+	var entrants []*RaceWeekendSessionEntrant
+
+	// entrants arrive in finishing order for each session
+	for _, entrant := range []struct {
+		guid    string
+		session *RaceWeekendSession
+	}{
+		{"L1", lastChance}, {"L2", lastChance},
+		{"A1", heat1}, {"A2", heat1}, {"A3", heat1},
+		{"B1", heat2}, {"B2", heat2},
+	} {
+		entrants = append(entrants, NewRaceWeekendSessionEntrant(
+			entrant.session.ID,
+			&SessionCar{Driver: SessionDriver{GUID: entrant.guid}},
+			&SessionResult{DriverGUID: entrant.guid},
+			nil,
+		))
+	}
+
+	if err := (HeatPositionEntryListSort{}).Sort(raceWeekend, NewRaceWeekendSession(), entrants, nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var order string
+
+	for _, entrant := range entrants {
+		order += entrant.Car.GetGUID()
+	}
+
+	if order != "A1B1A2B2A3L1L2" {
+		t.Logf("Expected heat finishers to be interleaved ahead of the last chance qualifier, got: %s", order)
+		t.Fail()
+	}
+}
+
+func TestRaceWeekendManager_ConvertToHeatRaces(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	rwm := NewRaceWeekendManager(nil, nil, store, nil, nil, nil)
+
+	// newRaceWeekend creates a Race Weekend with 20 entrants and a race straight from the entry list, with a grid of 12.
+	newRaceWeekend := func() (*RaceWeekend, *RaceWeekendSession) {
+		raceWeekend, final := testRaceWeekend(20)
+		final.RaceConfig.MaxClients = 12
+		final.RaceConfig.Sessions = Sessions{SessionTypeRace: &SessionConfig{Name: "Final", Laps: 20}}
+
+		return raceWeekend, final
+	}
+
+	raceWeekend, final := newRaceWeekend()
+
+	if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+		t.Error(err)
+		return
+	}
+
+	splits, err := rwm.ConvertToHeatRaces(raceWeekend.ID.String(), final.ID.String(), 0, 2, 10)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(splits.Heats) != 2 || splits.AdvancePerHeat != 5 || splits.NumLastChanceEntrants() != 10 {
+		t.Logf("Expected 2 heats with 5 advancing from each, got: %d heats with %d advancing", len(splits.Heats), splits.AdvancePerHeat)
+		t.Fail()
+		return
+	}
+
+	raceWeekend, err = store.LoadRaceWeekend(raceWeekend.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	final, err = raceWeekend.FindSessionByID(final.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(raceWeekend.Sessions) != 4 || len(final.ParentIDs) != 3 || final.SortType != heatPositionSortKey || final.HasParent(raceWeekend.ID.String()) {
+		t.Logf("Expected the final to follow 2 heats and a last chance qualifier, got: %d sessions, %d parents", len(raceWeekend.Sessions), len(final.ParentIDs))
+		t.Fail()
+		return
+	}
+
+	lastChance, err := raceWeekend.FindSessionByID(final.ParentIDs[0].String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if lastChance.Name() != "Last Chance Qualifier" || lastChance.SessionInfo().Laps != 10 || len(lastChance.ParentIDs) != 2 {
+		t.Logf("Expected a 10 lap last chance qualifier following both heats, got: %s", lastChance.Name())
+		t.Fail()
+	}
+
+	checkFilter := func(parentID, childID string, start, end, entryListStart int) {
+		filter, err := raceWeekend.GetFilter(parentID, childID)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if filter.ResultStart != start || filter.ResultEnd != end || filter.EntryListStart != entryListStart {
+			t.Logf("Expected results %d to %d in grid position %d, got: %d to %d in grid position %d", start, end, entryListStart, filter.ResultStart, filter.ResultEnd, filter.EntryListStart)
+			t.Fail()
+		}
+	}
+
+	checkFilter(lastChance.ID.String(), final.ID.String(), 1, 2, 11)
+
+	for i, heatID := range final.ParentIDs[1:] {
+		heat, err := raceWeekend.FindSessionByID(heatID.String())
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if heat.Name() != "Heat "+string(rune('1'+i)) || heat.SessionInfo().Laps != 10 || !heat.HasParent(raceWeekend.ID.String()) {
+			t.Logf("Expected a 10 lap heat following the entry list, got: %s", heat.Name())
+			t.Fail()
+		}
+
+		checkFilter(raceWeekend.ID.String(), heat.ID.String(), i*10+1, i*10+10, 1)
+		checkFilter(heat.ID.String(), lastChance.ID.String(), 6, 10, i*5+1)
+		checkFilter(heat.ID.String(), final.ID.String(), 1, 5, i*5+1)
+	}
+
+	t.Run("Promotion from the last chance qualifier", func(t *testing.T) {
+		// complete finishes a session with its entrants in the order of its grid, or in reverse.
+		complete := func(session *RaceWeekendSession, reverse bool) []*RaceWeekendSessionEntrant {
+			entryList, err := session.GetRaceWeekendEntryList(raceWeekend, nil, "")
+
+			if err != nil {
+				t.Error(err)
+				return nil
+			}
+
+			grid := entryList.Sorted()
+
+			if reverse {
+				reverseEntrants(len(grid), grid)
+			}
+
+			session.StartedTime = time.Now()
+			session.CompletedTime = time.Now()
+			session.Results = &SessionResults{Type: SessionTypeRace}
+
+			for _, entrant := range grid {
+				session.Results.Cars = append(session.Results.Cars, entrant.Car)
+				session.Results.Result = append(session.Results.Result, &SessionResult{DriverGUID: entrant.Car.GetGUID(), CarModel: entrant.Car.GetCar()})
+			}
+
+			return grid
+		}
+
+		for _, heatID := range final.ParentIDs[1:] {
+			heat, err := raceWeekend.FindSessionByID(heatID.String())
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			complete(heat, false)
+		}
+
+		// the driver at the back of the last chance qualifier wins it
+		lastChanceFinishers := complete(lastChance, true)
+
+		if len(lastChanceFinishers) != 10 {
+			t.Logf("Expected the drivers who didn't advance from the heats to be in the last chance qualifier, got: %d", len(lastChanceFinishers))
+			t.Fail()
+			return
+		}
+
+		entryList, err := final.GetRaceWeekendEntryList(raceWeekend, nil, "")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		grid := entryList.Sorted()
+
+		if len(grid) != 12 {
+			t.Logf("Expected a full grid of 12 in the final, got: %d", len(grid))
+			t.Fail()
+			return
+		}
+
+		for i, entrant := range grid[10:] {
+			if entrant.Car.GetGUID() != lastChanceFinishers[i].Car.GetGUID() || entrant.SessionID != lastChance.ID {
+				t.Logf("Expected P%d of the last chance qualifier to start P%d in the final, got: %s", i+1, i+11, entrant.Car.GetGUID())
+				t.Fail()
+			}
+		}
+
+		for _, entrant := range grid {
+			for _, eliminated := range lastChanceFinishers[2:] {
+				if entrant.Car.GetGUID() == eliminated.Car.GetGUID() {
+					t.Logf("Expected %s to be eliminated in the last chance qualifier", eliminated.Car.GetGUID())
+					t.Fail()
+				}
+			}
+		}
+	})
+
+	t.Run("Final already run", func(t *testing.T) {
+		raceWeekend, final := newRaceWeekend()
+		final.CompletedTime = time.Now()
+		final.Results = &SessionResults{Type: SessionTypeRace}
+
+		if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if _, err := rwm.ConvertToHeatRaces(raceWeekend.ID.String(), final.ID.String(), 0, 0, 0); err != ErrHeatRacesInvalidSession {
+			t.Logf("Expected a completed final to be refused, got: %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("Everyone fits on the grid", func(t *testing.T) {
+		raceWeekend, final := newRaceWeekend()
+		final.RaceConfig.MaxClients = 24
+
+		if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if _, err := rwm.ConvertToHeatRaces(raceWeekend.ID.String(), final.ID.String(), 0, 0, 0); err != ErrHeatRacesNotNeeded {
+			t.Logf("Expected heats to be refused when everyone fits on the grid, got: %v", err)
+			t.Fail()
+		}
+
+		if raceWeekend, err := store.LoadRaceWeekend(raceWeekend.ID.String()); err != nil || len(raceWeekend.Sessions) != 1 {
+			t.Log("Expected the race weekend to be left unchanged")
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const knockoutStageSortKey = "knockout_stage"
+
+var (
+	ErrKnockoutQualifyingInvalidSession  = errors.New("servermanager: knockout qualifying can only be set up on a qualifying session which has not yet been run")
+	ErrKnockoutQualifyingNoChildren      = errors.New("servermanager: knockout qualifying session must have at least one child session to build a grid for")
+	ErrKnockoutQualifyingInvalidAdvances = errors.New("servermanager: knockout qualifying stages must advance a decreasing number of drivers, each greater than zero")
+)
+
+// KnockoutQualifyingEntryListSort assembles a grid from the stages of a knockout qualifying. Entrants who reached
+// a later stage start ahead of entrants who were eliminated in an earlier stage. Within a stage, entrants keep
+// their existing order, which is set by the filter from that stage.
+type KnockoutQualifyingEntryListSort struct{}
+
+func (KnockoutQualifyingEntryListSort) Sort(rw *RaceWeekend, _ *RaceWeekendSession, entrants []*RaceWeekendSessionEntrant, _ *RaceWeekendSessionToSessionFilter) error {
+	stageDepth := make(map[uuid.UUID]int)
+
+	for _, entrant := range entrants {
+		if _, ok := stageDepth[entrant.SessionID]; ok {
+			continue
+		}
+
+		stage, err := rw.FindSessionByID(entrant.SessionID.String())
+
+		if err != nil {
+			stageDepth[entrant.SessionID] = -1
+			continue
+		}
+
+		stageDepth[entrant.SessionID] = rw.FindTotalNumParents(stage)
+	}
+
+	sort.SliceStable(entrants, func(i, j int) bool {
+		return stageDepth[entrants[i].SessionID] > stageDepth[entrants[j].SessionID]
+	})
+
+	return nil
+}
+
+// ConvertToKnockoutQualifying splits a qualifying session into len(advancing)+1 stages. advancing is the number of
+// drivers that progress from each stage to the next, e.g. []int{15, 10} creates Q1, Q2 and Q3, where the top 15 in
+// Q1 advance to Q2, and the top 10 in Q2 advance to Q3. If perClass is true, the top drivers of each class advance.
+// Drivers who are eliminated from a stage are placed directly onto the grid of the qualifying session's children in
+// their finishing positions. If stageMinutes is greater than zero, it is used as the length of each added stage.
+func (rwm *RaceWeekendManager) ConvertToKnockoutQualifying(raceWeekendID, qualifyingSessionID string, advancing []int, stageMinutes int, perClass bool) error {
+	if len(advancing) == 0 {
+		return ErrKnockoutQualifyingInvalidAdvances
+	}
+
+	for i, numAdvancing := range advancing {
+		if numAdvancing <= 0 || (i > 0 && numAdvancing >= advancing[i-1]) {
+			return ErrKnockoutQualifyingInvalidAdvances
+		}
+	}
+
+	raceWeekend, qualifying, err := rwm.FindSession(raceWeekendID, qualifyingSessionID)
+
+	if err != nil {
+		return err
+	}
+
+	if qualifying.SessionType() != SessionTypeQualifying || qualifying.InProgress() || qualifying.Completed() {
+		return ErrKnockoutQualifyingInvalidSession
+	}
+
+	children := raceWeekend.FindChildren(qualifying.ID.String())
+
+	if len(children) == 0 {
+		return ErrKnockoutQualifyingNoChildren
+	}
+
+	qualifying.SessionInfo().Name = "Q1"
+	stages := []*RaceWeekendSession{qualifying}
+
+	for i := range advancing {
+		previousStage := stages[len(stages)-1]
+
+		stage := copyRaceWeekendSessionConfig(qualifying, fmt.Sprintf("Q%d", i+2), stageMinutes)
+
+		raceWeekend.AddSession(stage, previousStage)
+		raceWeekend.AddFilter(previousStage.ID.String(), stage.ID.String(), &RaceWeekendSessionToSessionFilter{
+			ResultStart:    1,
+			ResultEnd:      advancing[i],
+			EntryListStart: 1,
+			SortType:       "fastest_lap",
+			PerClass:       perClass,
+		})
+
+		stages = append(stages, stage)
+	}
+
+	for _, child := range children {
+		child.RemoveParent(qualifying.ID.String())
+		raceWeekend.RemoveFilter(qualifying.ID.String(), child.ID.String())
+
+		for stageIndex, stage := range stages {
+			filter := &RaceWeekendSessionToSessionFilter{
+				ResultStart:    1,
+				EntryListStart: 1,
+				SortType:       "fastest_lap",
+				PerClass:       perClass,
+			}
+
+			if stageIndex < len(advancing) {
+				// drivers eliminated in this stage
+				filter.ResultStart = advancing[stageIndex] + 1
+				filter.EntryListStart = advancing[stageIndex] + 1
+
+				if stageIndex == 0 {
+					// entrants may still be added to the race weekend, so Q1 takes everyone who didn't advance
+					filter.ToEndOfResults = true
+				} else {
+					filter.ResultEnd = advancing[stageIndex-1]
+				}
+			} else {
+				// drivers who reached the final stage
+				filter.ResultEnd = advancing[len(advancing)-1]
+			}
+
+			child.ParentIDs = append(child.ParentIDs, stage.ID)
+			raceWeekend.AddFilter(stage.ID.String(), child.ID.String(), filter)
+		}
+
+		child.SortType = knockoutStageSortKey
+	}
+
+	return rwm.UpsertRaceWeekend(raceWeekend)
+}
+
+func (rwh *RaceWeekendHandler) knockoutQualifying(w http.ResponseWriter, r *http.Request) {
+	raceWeekendID := chi.URLParam(r, "raceWeekendID")
+
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse knockout qualifying form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var advancing []int
+
+	for _, stage := range strings.Split(r.FormValue("Stages"), ",") {
+		stage = strings.TrimSpace(stage)
+
+		if stage == "" {
+			continue
+		}
+
+		numAdvancing, err := strconv.Atoi(stage)
+
+		if err != nil {
+			AddErrorFlash(w, r, "Knockout stages must be a comma separated list of numbers, e.g. 15, 10")
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
+
+		advancing = append(advancing, numAdvancing)
+	}
+
+	err := rwh.raceWeekendManager.ConvertToKnockoutQualifying(raceWeekendID, chi.URLParam(r, "sessionID"), advancing, formValueAsInt(r.FormValue("StageLength")), r.FormValue("PerClass") == "on")
+
+	switch err {
+	case nil:
+		AddFlash(w, r, fmt.Sprintf("Qualifying has been split into %d knockout stages", len(advancing)+1))
+	case ErrKnockoutQualifyingInvalidSession:
+		AddErrorFlash(w, r, "Knockout qualifying can only be set up on a qualifying session which has not been run")
+	case ErrKnockoutQualifyingNoChildren:
+		AddErrorFlash(w, r, "Please add the sessions that qualifying sets the grid for before setting up knockout qualifying")
+	case ErrKnockoutQualifyingInvalidAdvances:
+		AddErrorFlash(w, r, "Each knockout stage must advance fewer drivers than the stage before it")
+	default:
+		logrus.WithError(err).Errorf("Could not set up knockout qualifying")
+		AddErrorFlash(w, r, "Couldn't set up knockout qualifying")
+	}
+
+	http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+}
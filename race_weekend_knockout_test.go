@@ -0,0 +1,190 @@
+package servermanager
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func knockoutTestEntrants(classIDs ...uuid.UUID) []*RaceWeekendSessionEntrant {
+	var entrants []*RaceWeekendSessionEntrant
+
+	for i, classID := range classIDs {
+		guid := "7656119800000000" + string(rune('0'+i))
+
+		entrants = append(entrants, NewRaceWeekendSessionEntrant(
+			uuid.New(),
+			&SessionCar{Driver: SessionDriver{GUID: guid}},
+			&SessionResult{DriverGUID: guid, ClassID: classID},
+			nil,
+		))
+	}
+
+	return entrants
+}
+
+func TestRaceWeekendSessionToSessionFilter_ToEndOfResults(t *testing.T) {
+	classA, classB := uuid.New(), uuid.New()
+	parent, child := NewRaceWeekendSession(), NewRaceWeekendSession()
+
+	t.Run("Overall", func(t *testing.T) {
+		var entryList RaceWeekendEntryList
+
+		filter := RaceWeekendSessionToSessionFilter{ResultStart: 3, ResultEnd: 3, ToEndOfResults: true, EntryListStart: 1}
+
+		if err := filter.Filter(&RaceWeekend{}, parent, child, knockoutTestEntrants(classA, classB, classA, classB, classA), &entryList); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if len(entryList) != 3 || entryList[0].Car.GetGUID() != "76561198000000002" {
+			t.Logf("Expected the last 3 entrants to be taken, got: %d", len(entryList))
+			t.Fail()
+		}
+	})
+
+	t.Run("Per class", func(t *testing.T) {
+		var entryList RaceWeekendEntryList
+
+		filter := RaceWeekendSessionToSessionFilter{ResultStart: 2, ToEndOfResults: true, EntryListStart: 1, PerClass: true}
+
+		if err := filter.Filter(&RaceWeekend{}, parent, child, knockoutTestEntrants(classA, classB, classA, classB, classA), &entryList); err != nil {
+			t.Error(err)
+			return
+		}
+
+		expected := []string{"76561198000000002", "76561198000000004", "76561198000000003"}
+
+		if len(entryList) != len(expected) {
+			t.Logf("Expected all but the first entrant of each class to be taken, got: %d", len(entryList))
+			t.Fail()
+			return
+		}
+
+		for i, guid := range expected {
+			if entryList[i].Car.GetGUID() != guid {
+				t.Logf("Expected %s at position %d, got: %s", guid, i, entryList[i].Car.GetGUID())
+				t.Fail()
+			}
+		}
+	})
+}
+
+func TestRaceWeekendManager_ConvertToKnockoutQualifying(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	rwm := NewRaceWeekendManager(nil, nil, store, nil, nil, nil)
+
+	newRaceWeekend := func() (*RaceWeekend, *RaceWeekendSession, *RaceWeekendSession) {
+		raceWeekend := NewRaceWeekend()
+
+		qualifying := NewRaceWeekendSession()
+		qualifying.RaceConfig.Sessions = Sessions{SessionTypeQualifying: &SessionConfig{Name: "Qualifying", Time: 15}}
+
+		race := NewRaceWeekendSession()
+		race.RaceConfig.Sessions = Sessions{SessionTypeRace: &SessionConfig{Name: "Race", Laps: 20}}
+
+		raceWeekend.AddSession(qualifying, nil)
+		raceWeekend.AddSession(race, qualifying)
+
+		return raceWeekend, qualifying, race
+	}
+
+	for _, perClass := range []bool{true, false} {
+		raceWeekend, qualifying, race := newRaceWeekend()
+
+		if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err := rwm.ConvertToKnockoutQualifying(raceWeekend.ID.String(), qualifying.ID.String(), []int{15, 10}, 10, perClass); err != nil {
+			t.Error(err)
+			return
+		}
+
+		raceWeekend, err := store.LoadRaceWeekend(raceWeekend.ID.String())
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		race, err = raceWeekend.FindSessionByID(race.ID.String())
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if len(raceWeekend.Sessions) != 4 || len(race.ParentIDs) != 3 || race.SortType != knockoutStageSortKey {
+			t.Logf("Expected Q1, Q2 and Q3 to set the grid for the race, got: %d sessions, %d parents", len(raceWeekend.Sessions), len(race.ParentIDs))
+			t.Fail()
+			continue
+		}
+
+		expected := []struct {
+			name           string
+			start, end     int
+			toEndOfResults bool
+		}{
+			{"Q1", 16, 0, true},
+			{"Q2", 11, 15, false},
+			{"Q3", 1, 10, false},
+		}
+
+		for i, stageID := range race.ParentIDs {
+			stage, err := raceWeekend.FindSessionByID(stageID.String())
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			filter, err := raceWeekend.GetFilter(stageID.String(), race.ID.String())
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if stage.Name() != expected[i].name || filter.ResultStart != expected[i].start || filter.ToEndOfResults != expected[i].toEndOfResults || filter.PerClass != perClass {
+				t.Logf("Expected %s to take %d-%d (to end: %t, per class: %t), got: %s %d-%d (to end: %t, per class: %t)",
+					expected[i].name, expected[i].start, expected[i].end, expected[i].toEndOfResults, perClass,
+					stage.Name(), filter.ResultStart, filter.ResultEnd, filter.ToEndOfResults, filter.PerClass)
+				t.Fail()
+			}
+
+			if !expected[i].toEndOfResults && filter.ResultEnd != expected[i].end {
+				t.Logf("Expected %s to end at %d, got: %d", expected[i].name, expected[i].end, filter.ResultEnd)
+				t.Fail()
+			}
+		}
+	}
+
+	t.Run("Invalid stages", func(t *testing.T) {
+		raceWeekend, qualifying, _ := newRaceWeekend()
+
+		if err := rwm.ConvertToKnockoutQualifying(raceWeekend.ID.String(), qualifying.ID.String(), []int{10, 15}, 0, true); err != ErrKnockoutQualifyingInvalidAdvances {
+			t.Logf("Expected an increasing number of advancing drivers to be refused, got: %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("No children", func(t *testing.T) {
+		raceWeekend, qualifying, race := newRaceWeekend()
+
+		race.RemoveParent(qualifying.ID.String())
+
+		if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err := rwm.ConvertToKnockoutQualifying(raceWeekend.ID.String(), qualifying.ID.String(), []int{15}, 0, true); err != ErrKnockoutQualifyingNoChildren {
+			t.Logf("Expected a qualifying session without children to be refused, got: %v", err)
+			t.Fail()
+		}
+	})
+}
@@ -16,7 +16,6 @@ import (
 
 <<<<<<< HEAD
 	"github.com/JustaPenguin/assetto-server-manager/pkg/udp"
-	"github.com/JustaPenguin/assetto-server-manager/pkg/when"
 
 	"github.com/cj123/ini"
 	"github.com/go-chi/chi"
@@ -43,8 +42,14 @@ type RaceWeekendManager struct {
 	activeRaceWeekend *ActiveRaceWeekend
 	mutex             sync.Mutex
 
-	scheduledSessionTimers         map[string]*when.Timer
-	scheduledSessionReminderTimers map[string]*when.Timer
+	// serverID and serverPool are set when the RaceWeekendManager belongs to one of many servers, allowing
+	// sibling sessions to be started on other servers.
+	serverID   uuid.UUID
+	serverPool RaceWeekendServerPool
+
+	// scheduler is the Scheduler of this RaceWeekendManager's server. The autopilot queues its sessions as jobs of
+	// the Scheduler, so that they are started even if the server is restarted.
+	scheduler *Scheduler
 }
 
 func NewRaceWeekendManager(
@@ -62,9 +67,6 @@ func NewRaceWeekendManager(
 		store:               store,
 		process:             process,
 		acsrClient:          acsrClient,
-
-		scheduledSessionTimers:         make(map[string]*when.Timer),
-		scheduledSessionReminderTimers: make(map[string]*when.Timer),
 =======
 	raceManager *RaceManager
 	store       Store
@@ -565,9 +567,7 @@ func (rwm *RaceWeekendManager) StartSession(raceWeekendID string, raceWeekendSes
 			return ErrRaceWeekendSessionDependencyIncomplete
 		}
 
-		session.StartedTime = time.Now()
-
-		if err := rwm.UpsertRaceWeekend(raceWeekend); err != nil {
+		if err := rwm.markSessionStarted(raceWeekendID, raceWeekendSessionID); err != nil {
 			return err
 		}
 =======
@@ -731,9 +731,13 @@ func (rwm *RaceWeekendManager) UDPCallback(message udp.Message) {
 			return
 		}
 
+		// sessions from this race weekend may be finishing on other servers at the same time.
+		unlockRaceWeekend := lockRaceWeekend(rwm.activeRaceWeekend.RaceWeekendID.String())
+
 		raceWeekend, err := rwm.LoadRaceWeekend(rwm.activeRaceWeekend.RaceWeekendID.String())
 
 		if err != nil {
+			unlockRaceWeekend()
 			logrus.WithError(err).Errorf("Could not load active race weekend")
 			return
 		}
@@ -741,6 +745,7 @@ func (rwm *RaceWeekendManager) UDPCallback(message udp.Message) {
 		session, err := raceWeekend.FindSessionByID(rwm.activeRaceWeekend.SessionID.String())
 
 		if err != nil {
+			unlockRaceWeekend()
 			logrus.WithError(err).Errorf("Could not load active race weekend session")
 			return
 		}
@@ -749,9 +754,13 @@ func (rwm *RaceWeekendManager) UDPCallback(message udp.Message) {
 
 		raceWeekend.EnhanceResults(results)
 
+		session.TyreRuleViolations = raceWeekend.CheckTyreRules(session, results)
+		session.TyreRules.ApplyTyreRulePenalties(results, session.TyreRuleViolations)
+
 		err = saveResults(filename, results)
 
 		if err != nil {
+			unlockRaceWeekend()
 			logrus.WithError(err).Errorf("Could not update session results %s", filename)
 			return
 		}
@@ -759,7 +768,10 @@ func (rwm *RaceWeekendManager) UDPCallback(message udp.Message) {
 		session.Results = results
 
 <<<<<<< HEAD
-		if err := rwm.UpsertRaceWeekend(raceWeekend); err != nil {
+		err = rwm.UpsertRaceWeekend(raceWeekend)
+		unlockRaceWeekend()
+
+		if err != nil {
 =======
 		if err := rwm.store.UpsertRaceWeekend(raceWeekend); err != nil {
 >>>>>>> origin/multiserver2
@@ -776,12 +788,18 @@ func (rwm *RaceWeekendManager) UDPCallback(message udp.Message) {
 			logrus.WithError(err).Error("Could not clear previous locked tyres")
 		}
 
+		if raceWeekend.Autopilot.Enabled {
+			// the autopilot decides which session runs next
+			go rwm.autopilotSessionFinished(raceWeekend.ID.String(), session.ID.String())
+			return
+		}
+
 		// first, look at siblings of this session and see if they were due to be started
 		for _, parent := range session.ParentIDs {
 			siblings := raceWeekend.FindChildren(parent.String())
 
 			for _, sibling := range siblings {
-				if !sibling.Completed() && sibling.StartWhenParentHasFinished {
+				if !sibling.Completed() && !sibling.InProgress() && sibling.StartWhenParentHasFinished {
 					err := rwm.StartSession(raceWeekend.ID.String(), sibling.ID.String(), false)
 
 					if err != nil {
@@ -793,20 +811,35 @@ func (rwm *RaceWeekendManager) UDPCallback(message udp.Message) {
 			}
 		}
 
-		// now we can look and see if any child sessions of this session should be started when it finishes
-		children := raceWeekend.FindChildren(session.ID.String())
+		// now we can look and see if any child sessions of this session should be started when it finishes.
+		// children with other parents that are still running are started when the last of their parents finishes.
+		var children []*RaceWeekendSession
 
-		for _, child := range children {
-			if !child.Completed() && child.StartWhenParentHasFinished {
-				err := rwm.StartSession(raceWeekend.ID.String(), child.ID.String(), false)
+		for _, child := range raceWeekend.FindConcurrentSessions(session.ID.String()) {
+			if child.StartWhenParentHasFinished {
+				children = append(children, child)
+			}
+		}
 
-				if err != nil {
-					logrus.WithError(err).Error("Could not start child session")
-				}
+		if len(children) > 1 && rwm.serverPool != nil {
+			// split sessions, start them all at once on separate servers if there are enough available.
+			err := rwm.StartConcurrentSessions(raceWeekend.ID.String(), children)
 
+			if err == nil {
+				return
+			} else if err != ErrRaceWeekendNotEnoughFreeServers {
+				logrus.WithError(err).Error("Could not start child sessions concurrently")
 				return
 			}
 		}
+
+		if len(children) > 0 {
+			err := rwm.StartSession(raceWeekend.ID.String(), children[0].ID.String(), false)
+
+			if err != nil {
+				logrus.WithError(err).Error("Could not start child session")
+			}
+		}
 	}
 }
 
@@ -883,11 +916,16 @@ func (rwm *RaceWeekendManager) CancelSession(raceWeekendID string, raceWeekendSe
 		return err
 	}
 
+	// the session may be running on another server if it was started alongside its sibling sessions.
+	runningManager := rwm.managerForServer(session.RunningServerID)
+
 	session.StartedTime = time.Time{}
 	session.CompletedTime = time.Time{}
+	session.RunningServerID = uuid.Nil
 	session.Results = nil
+	session.TyreRuleViolations = nil
 
-	if err := rwm.process.Stop(); err != nil {
+	if err := runningManager.process.Stop(); err != nil {
 		return err
 	}
 
@@ -1102,6 +1140,11 @@ type RaceWeekendGridPreview struct {
 	Results map[int]SessionPreviewEntrant
 	Grid    map[int]SessionPreviewEntrant
 	Classes map[string]string
+
+	// ComputedGrid is the grid before grid overrides are applied. GridChanges lists the differences between the
+	// ComputedGrid and the final Grid.
+	ComputedGrid map[int]SessionPreviewEntrant
+	GridChanges  []*RaceWeekendGridChange
 }
 
 type SessionPreviewEntrant struct {
@@ -1113,9 +1156,10 @@ type SessionPreviewEntrant struct {
 
 func NewRaceWeekendGridPreview() *RaceWeekendGridPreview {
 	return &RaceWeekendGridPreview{
-		Results: make(map[int]SessionPreviewEntrant),
-		Grid:    make(map[int]SessionPreviewEntrant),
-		Classes: make(map[string]string),
+		Results:      make(map[int]SessionPreviewEntrant),
+		Grid:         make(map[int]SessionPreviewEntrant),
+		Classes:      make(map[string]string),
+		ComputedGrid: make(map[int]SessionPreviewEntrant),
 	}
 }
 
@@ -1211,6 +1255,12 @@ func (rwm *RaceWeekendManager) PreviewSessionEntryList(raceWeekendID, sessionID,
 	session.SortType = sortType
 	session.NumEntrantsToReverse = reverseGrid
 
+	computedEntryList, err := session.GetComputedRaceWeekendEntryList(raceWeekend, nil, "")
+
+	if err != nil {
+		return nil, err
+	}
+
 	entryList, err := session.GetRaceWeekendEntryList(raceWeekend, nil, "")
 
 	if err != nil {
@@ -1219,41 +1269,48 @@ func (rwm *RaceWeekendManager) PreviewSessionEntryList(raceWeekendID, sessionID,
 
 	preview := NewRaceWeekendGridPreview()
 
-	for i, entrant := range entryList.Sorted() {
-		entrantSession, err := raceWeekend.FindSessionByID(entrant.SessionID.String())
+	addToGrid := func(grid map[int]SessionPreviewEntrant, entryList RaceWeekendEntryList) {
+		for i, entrant := range entryList.Sorted() {
+			entrantSession, err := raceWeekend.FindSessionByID(entrant.SessionID.String())
 
-		if err != nil {
-			continue
-		}
+			if err != nil {
+				continue
+			}
 
-		entrantPositionText := "No Time"
+			entrantPositionText := "No Time"
 
-		if entrantSession.Completed() {
-			for i, result := range entrantSession.Results.Result {
-				if result.DriverGUID == entrant.Car.Driver.GUID {
-					entrantPositionText = fmt.Sprintf("%d%s", i+1, ordinal(int64(i+1)))
-					break
+			if entrantSession.Completed() {
+				for i, result := range entrantSession.Results.Result {
+					if result.DriverGUID == entrant.Car.Driver.GUID {
+						entrantPositionText = fmt.Sprintf("%d%s", i+1, ordinal(int64(i+1)))
+						break
+					}
 				}
 			}
-		}
 
-		class := entrant.ChampionshipClass(raceWeekend)
+			class := entrant.ChampionshipClass(raceWeekend)
 
-		color, ok := preview.Classes[class.Name]
+			color, ok := preview.Classes[class.Name]
 
-		if !ok {
-			color = ChampionshipClassColor(len(preview.Classes))
-			preview.Classes[class.Name] = color
-		}
+			if !ok {
+				color = ChampionshipClassColor(len(preview.Classes))
+				preview.Classes[class.Name] = color
+			}
 
-		preview.Grid[i+1] = SessionPreviewEntrant{
-			Name:       fmt.Sprintf("%s (%s - %s)", entrant.Car.GetName(), entrantSession.Name(), entrantPositionText),
-			Session:    session.Name(),
-			Class:      class.Name,
-			ClassColor: color,
+			grid[i+1] = SessionPreviewEntrant{
+				Name:       fmt.Sprintf("%s (%s - %s)", entrant.Car.GetName(), entrantSession.Name(), entrantPositionText),
+				Session:    session.Name(),
+				Class:      class.Name,
+				ClassColor: color,
+			}
 		}
 	}
 
+	addToGrid(preview.ComputedGrid, computedEntryList)
+	addToGrid(preview.Grid, entryList)
+
+	preview.GridChanges = session.GridChanges(computedEntryList, entryList)
+
 	return preview, nil
 }
 
@@ -1287,100 +1344,8 @@ func (rwm *RaceWeekendManager) ImportRaceWeekend(data string) (string, error) {
 	return raceWeekend.ID.String(), rwm.UpsertRaceWeekend(raceWeekend)
 }
 
-func (rwm *RaceWeekendManager) WatchForScheduledSessions() error {
-	raceWeekends, err := rwm.ListRaceWeekends()
-
-	if err != nil {
-		return err
-	}
-
-	for _, raceWeekend := range raceWeekends {
-		raceWeekend := raceWeekend
-
-		for _, session := range raceWeekend.Sessions {
-			session := session
-
-			if session.ScheduledServerID != serverID {
-				continue
-			}
-
-			if session.ScheduledTime.After(time.Now()) {
-				err := rwm.setupScheduledSessionTimer(raceWeekend, session)
-
-				if err != nil {
-					return err
-				}
-			} else if !session.ScheduledTime.IsZero() {
-				logrus.Infof("The %s Session in the %s Race Weekend was scheduled to run, but the server was offline. Please start the session manually.", session.Name(), raceWeekend.Name)
-			}
-		}
-	}
-
-	return nil
-}
-
-func (rwm *RaceWeekendManager) clearScheduledSessionTimer(session *RaceWeekendSession) {
-	if timer := rwm.scheduledSessionTimers[session.ID.String()]; timer != nil {
-		timer.Stop()
-	}
-}
-
-func (rwm *RaceWeekendManager) setupScheduledSessionTimer(raceWeekend *RaceWeekend, session *RaceWeekendSession) error {
-	rwm.clearScheduledSessionTimer(session)
-
-	var err error
-
-	rwm.scheduledSessionTimers[session.ID.String()], err = when.When(session.ScheduledTime, func() {
-		err := rwm.StartSession(raceWeekend.ID.String(), session.ID.String(), false)
-
-		if err != nil {
-			logrus.WithError(err).Errorf("Could not start scheduled race weekend session")
-		}
-
-		raceWeekend, session, err := rwm.FindSession(raceWeekend.ID.String(), session.ID.String())
-
-		if err != nil {
-			logrus.WithError(err).Error("Could not clear scheduled time on started Race Weekend Session")
-			return
-		}
-
-		session.ScheduledTime = time.Time{}
-
-		if err := rwm.UpsertRaceWeekend(raceWeekend); err != nil {
-			logrus.WithError(err).Error("Could not update race weekend with cleared scheduled time")
-		}
-	})
-
-	if err != nil {
-		return err
-	}
-
-	if rwm.notificationManager.HasNotificationReminders() {
-		for _, timer := range rwm.notificationManager.GetNotificationReminders() {
-			reminderTime := session.ScheduledTime.Add(time.Duration(-timer) * time.Minute)
-
-			if reminderTime.After(time.Now()) {
-				// add reminder
-				thisTimer := timer
-
-				rwm.scheduledSessionReminderTimers[session.ID.String()], err = when.When(reminderTime, func() {
-					err := rwm.notificationManager.SendRaceWeekendReminderMessage(raceWeekend, session, thisTimer)
-
-					if err != nil {
-						logrus.WithError(err).Errorf("Could not send race weekend reminder message")
-					}
-				})
-
-				if err != nil {
-					logrus.WithError(err).Error("Could not set up race weekend reminder timer")
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
+// ScheduleSession stores the scheduled time of a RaceWeekendSession. The session is started by the Scheduler, which
+// must be told about the new schedule.
 func (rwm *RaceWeekendManager) ScheduleSession(raceWeekendID, sessionID string, date time.Time, startWhenParentFinishes bool) error {
 	raceWeekend, session, err := rwm.FindSession(raceWeekendID, sessionID)
 
@@ -1390,7 +1355,11 @@ func (rwm *RaceWeekendManager) ScheduleSession(raceWeekendID, sessionID string,
 
 	session.ScheduledTime = date
 	session.StartWhenParentHasFinished = startWhenParentFinishes
-	session.ScheduledServerID = serverID
+
+	if session.ScheduledServerID == uuid.Nil {
+		// sessions which have been moved to another server to avoid a schedule conflict stay on that server.
+		session.ScheduledServerID = rwm.serverID
+	}
 
 	if config.Lua.Enabled && Premium() {
 		err = raceWeekendEventSchedulePlugin(raceWeekend, session)
@@ -1400,14 +1369,6 @@ func (rwm *RaceWeekendManager) ScheduleSession(raceWeekendID, sessionID string,
 		}
 	}
 
-	if !session.ScheduledTime.IsZero() {
-		err = rwm.setupScheduledSessionTimer(raceWeekend, session)
-
-		if err != nil {
-			return err
-		}
-	}
-
 	return rwm.UpsertRaceWeekend(raceWeekend)
 }
 
@@ -1438,8 +1399,6 @@ func (rwm *RaceWeekendManager) DeScheduleSession(raceWeekendID, sessionID string
 	session.ScheduledTime = time.Time{}
 	session.StartWhenParentHasFinished = false
 
-	rwm.clearScheduledSessionTimer(session)
-
 	return rwm.UpsertRaceWeekend(raceWeekend)
 =======
 	return raceWeekend.ID.String(), rwm.store.UpsertRaceWeekend(raceWeekend)
@@ -119,6 +119,22 @@ var RaceWeekendEntryListSorters = []RaceWeekendEntryListSorterDescription{
 		NeedsChampionship:     true,
 		ShowInManageEntryList: false,
 	},
+	{
+		Name:                  "Knockout Qualifying Stage",
+		Key:                   knockoutStageSortKey,
+		Sorter:                &KnockoutQualifyingEntryListSort{},
+		NeedsParentSession:    false,
+		NeedsChampionship:     false,
+		ShowInManageEntryList: false,
+	},
+	{
+		Name:                  "Heat Finishing Position",
+		Key:                   heatPositionSortKey,
+		Sorter:                &HeatPositionEntryListSort{},
+		NeedsParentSession:    false,
+		NeedsChampionship:     false,
+		ShowInManageEntryList: false,
+	},
 	{
 		Name:                  "Random",
 		Key:                   "random",
@@ -178,7 +194,7 @@ var RaceWeekendEntryListSorters = []RaceWeekendEntryListSorterDescription{
 }
 
 func GetRaceWeekendEntryListSort(key string) RaceWeekendEntryListSorter {
-	for _, sorter := range RaceWeekendEntryListSorters {
+	for _, sorter := range AvailableRaceWeekendEntryListSorters() {
 		if sorter.Key == key {
 <<<<<<< HEAD
 			return PerClassSort(sorter.Sorter)
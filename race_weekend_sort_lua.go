@@ -0,0 +1,147 @@
+package servermanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// luaSortersDirectory contains user-supplied Lua Race Weekend entry list sorters. Each .lua file in the
+	// directory is a sorter, and appears in the sort dropdown with its file name.
+	luaSortersDirectory = "./plugins/sorters"
+
+	luaSorterKeyPrefix    = "lua:"
+	luaSorterFunctionName = "sortEntrants"
+)
+
+// AvailableRaceWeekendEntryListSorters returns the built in RaceWeekendEntryListSorters, followed by any Lua
+// sorters found in the plugins directory.
+func AvailableRaceWeekendEntryListSorters() []RaceWeekendEntryListSorterDescription {
+	sorters := make([]RaceWeekendEntryListSorterDescription, len(RaceWeekendEntryListSorters))
+
+	copy(sorters, RaceWeekendEntryListSorters)
+
+	luaSorters, err := ListLuaRaceWeekendEntryListSorters()
+
+	if err != nil {
+		logrus.WithError(err).Error("Could not list Lua entry list sorters")
+		return sorters
+	}
+
+	return append(sorters, luaSorters...)
+}
+
+// ListLuaRaceWeekendEntryListSorters lists the Lua sorters available in the plugins directory. Lua sorters are
+// only available if Lua plugins are enabled.
+func ListLuaRaceWeekendEntryListSorters() ([]RaceWeekendEntryListSorterDescription, error) {
+	if !config.Lua.Enabled || !Premium() {
+		return nil, nil
+	}
+
+	files, err := ioutil.ReadDir(luaSortersDirectory)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var sorters []RaceWeekendEntryListSorterDescription
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".lua" {
+			continue
+		}
+
+		name := strings.TrimSuffix(file.Name(), ".lua")
+
+		sorters = append(sorters, RaceWeekendEntryListSorterDescription{
+			Name:                  prettifyName(name, true) + " (Lua)",
+			Key:                   luaSorterKeyPrefix + name,
+			Sorter:                &LuaRaceWeekendEntryListSort{FileName: filepath.Join(luaSortersDirectory, file.Name())},
+			NeedsParentSession:    false,
+			NeedsChampionship:     false,
+			ShowInManageEntryList: true,
+		})
+	}
+
+	return sorters, nil
+}
+
+// LuaRaceWeekendEntryListSort sorts entrants using the sortEntrants function of a Lua script. The function is
+// given the entrants, the results of the session they took part in, the championship standings for their class
+// (if the Race Weekend is in a Championship) and the session being sorted, all as JSON. It must return a JSON
+// encoded list of driver GUIDs in the order the entrants should be placed. Entrants missing from the list are
+// placed at the back in their existing order.
+type LuaRaceWeekendEntryListSort struct {
+	FileName string
+}
+
+// luaSortStanding is the simplified championship standing passed to Lua sorters.
+type luaSortStanding struct {
+	GUID   string
+	Name   string
+	Team   string
+	Points float64
+}
+
+func (l *LuaRaceWeekendEntryListSort) Sort(rw *RaceWeekend, session *RaceWeekendSession, entrants []*RaceWeekendSessionEntrant, _ *RaceWeekendSessionToSessionFilter) error {
+	if len(entrants) == 0 {
+		return nil
+	}
+
+	standings := make([]*luaSortStanding, 0)
+
+	if rw.HasLinkedChampionship() && rw.Championship != nil {
+		class := entrants[0].ChampionshipClass(rw)
+
+		for _, standing := range class.Standings(rw.Championship, rw.Championship.Events) {
+			standings = append(standings, &luaSortStanding{
+				GUID:   standing.Car.GetGUID(),
+				Name:   standing.Car.GetName(),
+				Team:   standing.Car.GetTeam(),
+				Points: standing.Points,
+			})
+		}
+	}
+
+	var order []string
+
+	p := NewLuaPlugin()
+	p.Inputs(entrants, session.Results, standings, session).Outputs(&order)
+
+	if err := p.Call(l.FileName, luaSorterFunctionName); err != nil {
+		return err
+	}
+
+	sortEntrantsByGUIDOrder(entrants, order)
+
+	return nil
+}
+
+// sortEntrantsByGUIDOrder reorders entrants to match the given list of GUIDs. Entrants who are not in the list
+// keep their relative order at the back of the grid.
+func sortEntrantsByGUIDOrder(entrants []*RaceWeekendSessionEntrant, order []string) {
+	remaining := make([]*RaceWeekendSessionEntrant, len(entrants))
+	copy(remaining, entrants)
+
+	sorted := make([]*RaceWeekendSessionEntrant, 0, len(entrants))
+
+	for _, guid := range order {
+		for i, entrant := range remaining {
+			if entrant.Car.GetGUID() == guid {
+				sorted = append(sorted, entrant)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+
+	sorted = append(sorted, remaining...)
+
+	copy(entrants, sorted)
+}
@@ -0,0 +1,134 @@
+package servermanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// luaSortTestScript reverses the order of the entrants it is given. The GUIDs are read straight out of the
+// encoded entrants, so the script doesn't need the json plugin.
+const luaSortTestScript = `
+function sortEntrants(encodedEntrants, encodedResults, encodedStandings, encodedSession)
+    local guids = {}
+
+    for guid in string.gmatch(encodedEntrants, '"Guid":"(%w+)"') do
+        table.insert(guids, 1, '"' .. guid .. '"')
+    end
+
+    return "[" .. table.concat(guids, ",") .. "]"
+end
+`
+
+// luaSortTestOrder lists the GUIDs of the entrants in the order they have been sorted into. Sorters don't change the
+// pitboxes of the entrants, so gridOverridesTestOrder can't be used.
+func luaSortTestOrder(entrants []*RaceWeekendSessionEntrant) string {
+	var order string
+
+	for _, entrant := range entrants {
+		order += entrant.Car.GetGUID()
+	}
+
+	return order
+}
+
+func luaSortTestFile(t *testing.T, script string) (string, func()) {
+	dir, err := ioutil.TempDir("", "lua-sorters")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileName := filepath.Join(dir, "sorter.lua")
+
+	if err := ioutil.WriteFile(fileName, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestSortEntrantsByGUIDOrder(t *testing.T) {
+	testCases := []struct {
+		name  string
+		order []string
+		out   string
+	}{
+		{"Full order", []string{"C", "A", "D", "B"}, "CADB"},
+		{"Empty order", nil, "ABCD"},
+		{"Missing entrants keep their order at the back", []string{"D", "B"}, "DBAC"},
+		{"Unknown GUIDs are ignored", []string{"Z", "C", "Y"}, "CABD"},
+		{"Duplicate GUIDs are ignored", []string{"B", "B", "A"}, "BACD"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			entrants := gridOverridesTestEntryList(gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA)
+
+			sortEntrantsByGUIDOrder(entrants, testCase.order)
+
+			if order := luaSortTestOrder(entrants); order != testCase.out {
+				t.Logf("Expected entrants in the order %s, got: %s", testCase.out, order)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestLuaRaceWeekendEntryListSort_Sort(t *testing.T) {
+	t.Run("Sorted by script", func(t *testing.T) {
+		fileName, cleanup := luaSortTestFile(t, luaSortTestScript)
+		defer cleanup()
+
+		sorter := &LuaRaceWeekendEntryListSort{FileName: fileName}
+		entrants := gridOverridesTestEntryList(gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA)
+
+		if err := sorter.Sort(&RaceWeekend{}, NewRaceWeekendSession(), entrants, nil); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if order := luaSortTestOrder(entrants); order != "DCBA" {
+			t.Logf("Expected the script to reverse the entrants, got: %s", order)
+			t.Fail()
+		}
+	})
+
+	t.Run("No entrants", func(t *testing.T) {
+		sorter := &LuaRaceWeekendEntryListSort{FileName: "does-not-exist.lua"}
+
+		if err := sorter.Sort(&RaceWeekend{}, NewRaceWeekendSession(), nil, nil); err != nil {
+			t.Logf("Expected the script not to be run without entrants, got: %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("Script error", func(t *testing.T) {
+		fileName, cleanup := luaSortTestFile(t, `function sortEntrants() error("broken sorter") end`)
+		defer cleanup()
+
+		sorter := &LuaRaceWeekendEntryListSort{FileName: fileName}
+		entrants := gridOverridesTestEntryList(gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA, gridOverridesTestClassA)
+
+		if err := sorter.Sort(&RaceWeekend{}, NewRaceWeekendSession(), entrants, nil); err == nil {
+			t.Log("Expected an error from a broken script")
+			t.Fail()
+		}
+
+		if order := luaSortTestOrder(entrants); order != "ABCD" {
+			t.Logf("Expected a broken script to leave the entrants alone, got: %s", order)
+			t.Fail()
+		}
+	})
+}
+
+func TestAvailableRaceWeekendEntryListSorters(t *testing.T) {
+	// Lua is disabled in the test config, so only the built in sorters are available.
+	if sorters := AvailableRaceWeekendEntryListSorters(); len(sorters) != len(RaceWeekendEntryListSorters) {
+		t.Logf("Expected only the built in sorters when Lua is disabled, got: %d sorters", len(sorters))
+		t.Fail()
+	}
+}
@@ -0,0 +1,477 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"4d63.com/tz"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrRaceWeekendTemplateNeedsName = errors.New("servermanager: race weekend template needs a name")
+
+// A RaceWeekendTemplate is a reusable RaceWeekend layout, e.g. a practice, qualifying, sprint and feature race
+// format. Instantiating a RaceWeekendTemplate creates a new RaceWeekend with the template's sessions and filters,
+// for a given date, track, cars and entry list.
+type RaceWeekendTemplate struct {
+	ID      uuid.UUID
+	Created time.Time
+	Updated time.Time
+	Deleted time.Time
+
+	Name        string
+	Description string
+
+	// RaceWeekend holds the sessions, filters and default entry list of the template. It is never run.
+	RaceWeekend *RaceWeekend
+
+	// SessionStartOffsets are the times (relative to midnight on the date the template is instantiated for) that
+	// each session of the RaceWeekend is scheduled to start, keyed by session ID. Sessions without an offset are
+	// not scheduled.
+	SessionStartOffsets map[uuid.UUID]time.Duration
+}
+
+// NewRaceWeekendTemplate creates a RaceWeekendTemplate from an existing RaceWeekend. Results and progress are
+// removed from the sessions, and any session schedules are stored relative to midnight on the day of the earliest
+// scheduled session.
+func NewRaceWeekendTemplate(raceWeekend *RaceWeekend, name, description string) (*RaceWeekendTemplate, error) {
+	if name == "" {
+		return nil, ErrRaceWeekendTemplateNeedsName
+	}
+
+	templateRaceWeekend, err := raceWeekend.Duplicate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	templateRaceWeekend.Name = name
+	templateRaceWeekend.EntryList = raceWeekend.GetEntryList()
+	templateRaceWeekend.ChampionshipID = uuid.Nil
+	templateRaceWeekend.Championship = nil
+	templateRaceWeekend.Autopilot.Enabled = false
+	templateRaceWeekend.Timeline = nil
+	templateRaceWeekend.PenaltyHistory = nil
+
+	template := &RaceWeekendTemplate{
+		ID:                  uuid.New(),
+		Created:             time.Now(),
+		Name:                name,
+		Description:         description,
+		RaceWeekend:         templateRaceWeekend,
+		SessionStartOffsets: make(map[uuid.UUID]time.Duration),
+	}
+
+	var firstScheduled time.Time
+
+	for _, session := range templateRaceWeekend.Sessions {
+		if !session.ScheduledTime.IsZero() && (firstScheduled.IsZero() || session.ScheduledTime.Before(firstScheduled)) {
+			firstScheduled = session.ScheduledTime
+		}
+	}
+
+	weekendDate := time.Date(firstScheduled.Year(), firstScheduled.Month(), firstScheduled.Day(), 0, 0, 0, 0, firstScheduled.Location())
+
+	for _, session := range templateRaceWeekend.Sessions {
+		if !session.ScheduledTime.IsZero() {
+			template.SessionStartOffsets[session.ID] = session.ScheduledTime.Sub(weekendDate)
+		}
+
+		session.Results = nil
+		session.TyreRuleViolations = nil
+		session.GridOverrides = nil
+		session.StartedTime = time.Time{}
+		session.CompletedTime = time.Time{}
+		session.ScheduledTime = time.Time{}
+		session.Scheduled = time.Time{}
+		session.ScheduledServerID = uuid.Nil
+		session.RunningServerID = uuid.Nil
+		session.StartWhenParentHasFinished = false
+		session.MissedEventCatchUp = MissedEventCatchUp{}
+
+		// championship points are per class, and there is no championship attached to a template.
+		session.Points = make(map[uuid.UUID]*ChampionshipPoints)
+	}
+
+	return template, nil
+}
+
+// RaceWeekendTemplateParameters customise a RaceWeekend created from a RaceWeekendTemplate. Empty parameters
+// use the values from the template.
+type RaceWeekendTemplateParameters struct {
+	Name string
+
+	// Date is the day of the RaceWeekend. Session start offsets are added to midnight on this date.
+	Date time.Time
+
+	Track       string
+	TrackLayout string
+	Cars        []string
+
+	// SessionLengths are the lengths of each session (in laps for lap based races, otherwise minutes), keyed by
+	// the ID of the session in the template.
+	SessionLengths map[uuid.UUID]int
+
+	EntryList EntryList
+}
+
+// Instantiate creates a new RaceWeekend from the RaceWeekendTemplate. All IDs are replaced so that a template can
+// be instantiated any number of times.
+func (t *RaceWeekendTemplate) Instantiate(params RaceWeekendTemplateParameters) (*RaceWeekend, error) {
+	raceWeekend, err := t.RaceWeekend.Duplicate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// a RaceWeekend is remapped the same way as a RaceWeekend in an imported Championship archive.
+	remapper := newChampionshipIDRemapper()
+	remapper.remapRaceWeekend(raceWeekend)
+
+	raceWeekend.Created = time.Now()
+	raceWeekend.Updated = time.Time{}
+	raceWeekend.Name = t.Name
+
+	if params.Name != "" {
+		raceWeekend.Name = params.Name
+	}
+
+	if params.EntryList != nil {
+		raceWeekend.EntryList = params.EntryList
+	}
+
+	if len(params.Cars) > 0 {
+		for _, entrant := range raceWeekend.EntryList {
+			if !carInList(entrant.Model, params.Cars) {
+				// entrants must drive one of the available cars
+				entrant.Model = params.Cars[0]
+				entrant.Skin = ""
+			}
+		}
+	}
+
+	weekendDate := time.Date(params.Date.Year(), params.Date.Month(), params.Date.Day(), 0, 0, 0, 0, params.Date.Location())
+
+	for i, session := range raceWeekend.Sessions {
+		templateSession := t.RaceWeekend.Sessions[i]
+
+		session.Created = time.Now()
+
+		if params.Track != "" {
+			session.RaceConfig.Track = params.Track
+			session.RaceConfig.TrackLayout = params.TrackLayout
+		}
+
+		if len(params.Cars) > 0 {
+			session.RaceConfig.Cars = strings.Join(params.Cars, ";")
+		}
+
+		if length, ok := params.SessionLengths[templateSession.ID]; ok && length > 0 {
+			sessionConfig := session.SessionInfo()
+
+			if session.SessionType() == SessionTypeRace && sessionConfig.Laps > 0 {
+				sessionConfig.Laps = length
+			} else {
+				sessionConfig.Time = length
+			}
+		}
+
+		if offset, ok := t.SessionStartOffsets[templateSession.ID]; ok && !params.Date.IsZero() {
+			session.ScheduledTime = weekendDate.Add(offset)
+		}
+	}
+
+	return raceWeekend, nil
+}
+
+func carInList(car string, cars []string) bool {
+	for _, c := range cars {
+		if c == car {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListRaceWeekendTemplates returns all RaceWeekendTemplates, sorted by name.
+func (rwm *RaceWeekendManager) ListRaceWeekendTemplates() ([]*RaceWeekendTemplate, error) {
+	templates, err := rwm.store.ListRaceWeekendTemplates()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, nil
+}
+
+func (rwm *RaceWeekendManager) LoadRaceWeekendTemplate(id string) (*RaceWeekendTemplate, error) {
+	return rwm.store.LoadRaceWeekendTemplate(id)
+}
+
+func (rwm *RaceWeekendManager) DeleteRaceWeekendTemplate(id string) error {
+	return rwm.store.DeleteRaceWeekendTemplate(id)
+}
+
+// SaveRaceWeekendAsTemplate stores a RaceWeekend as a RaceWeekendTemplate.
+func (rwm *RaceWeekendManager) SaveRaceWeekendAsTemplate(raceWeekendID, name, description string) (*RaceWeekendTemplate, error) {
+	raceWeekend, err := rwm.LoadRaceWeekend(raceWeekendID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := NewRaceWeekendTemplate(raceWeekend, name, description)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return template, rwm.store.UpsertRaceWeekendTemplate(template)
+}
+
+// InstantiateRaceWeekendTemplate creates and saves a RaceWeekend from a RaceWeekendTemplate, storing the scheduled
+// times of its sessions. The sessions must then be scheduled, so that they are checked for conflicts and queued
+// with the Scheduler.
+func (rwm *RaceWeekendManager) InstantiateRaceWeekendTemplate(templateID string, params RaceWeekendTemplateParameters) (*RaceWeekend, error) {
+	template, err := rwm.LoadRaceWeekendTemplate(templateID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	raceWeekend, err := template.Instantiate(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return raceWeekend, rwm.UpsertRaceWeekend(raceWeekend)
+}
+
+type raceWeekendTemplateListTemplateVars struct {
+	BaseTemplateVars
+
+	Templates []*RaceWeekendTemplate
+}
+
+func (rwh *RaceWeekendHandler) listTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := rwh.raceWeekendManager.ListRaceWeekendTemplates()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't list race weekend templates")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rwh.viewRenderer.MustLoadTemplate(w, r, "race-weekend/templates.html", &raceWeekendTemplateListTemplateVars{
+		Templates: templates,
+	})
+}
+
+func (rwh *RaceWeekendHandler) saveAsTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := rwh.raceWeekendManager.SaveRaceWeekendAsTemplate(chi.URLParam(r, "raceWeekendID"), r.FormValue("Name"), r.FormValue("Description"))
+
+	if err == ErrRaceWeekendTemplateNeedsName {
+		AddErrorFlash(w, r, "Please give the template a name")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("couldn't save race weekend as template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, fmt.Sprintf("Race Weekend saved as the template: %s", template.Name))
+	http.Redirect(w, r, "/race-weekend-templates", http.StatusFound)
+}
+
+type raceWeekendTemplateInstantiateTemplateVars struct {
+	BaseTemplateVars
+
+	Template          *RaceWeekendTemplate
+	Tracks            []Track
+	Cars              Cars
+	RaceWeekends      []*RaceWeekend
+	MissedEventPolicy MissedEventPolicy
+}
+
+func (rwh *RaceWeekendHandler) instantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID := chi.URLParam(r, "templateID")
+
+	if r.Method == http.MethodPost {
+		params, err := rwh.templateParametersFromForm(r)
+
+		if err != nil {
+			logrus.WithError(err).Errorf("couldn't read race weekend template parameters")
+			AddErrorFlash(w, r, "Couldn't read the Race Weekend details, please check the form and try again")
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
+
+		raceWeekend, err := rwh.raceWeekendManager.InstantiateRaceWeekendTemplate(templateID, params)
+
+		if err != nil {
+			logrus.WithError(err).Errorf("couldn't create race weekend from template")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		for _, session := range raceWeekend.Sessions {
+			if session.ScheduledTime.IsZero() {
+				continue
+			}
+
+			// sessions go through the same conflict checks as sessions which are scheduled one at a time.
+			conflicts, err := rwh.scheduleSessionFromForm(r, raceWeekend.ID.String(), session.ID.String(), session.ScheduledTime, false)
+
+			switch err {
+			case nil:
+				if len(conflicts) > 0 {
+					AddErrorFlash(w, r, fmt.Sprintf("Warning, %s conflicts with other events on the server: %s", session.Name(), scheduleConflictsDescription(conflicts)))
+				}
+
+				continue
+			case ErrScheduleConflict, ErrNoFreeServer:
+				AddErrorFlash(w, r, fmt.Sprintf("%s was not scheduled, it conflicts with other events on the server: %s", session.Name(), scheduleConflictsDescription(conflicts)))
+			default:
+				logrus.WithError(err).Errorf("couldn't schedule race weekend session: %s", session.Name())
+				AddErrorFlash(w, r, fmt.Sprintf("%s could not be scheduled", session.Name()))
+			}
+
+			if err := rwh.raceWeekendManager.DeScheduleSession(raceWeekend.ID.String(), session.ID.String()); err != nil {
+				logrus.WithError(err).Errorf("couldn't de-schedule race weekend session: %s", session.Name())
+			}
+		}
+
+		AddFlash(w, r, "Race Weekend successfully created from template!")
+		http.Redirect(w, r, "/race-weekend/"+raceWeekend.ID.String(), http.StatusFound)
+		return
+	}
+
+	template, err := rwh.raceWeekendManager.LoadRaceWeekendTemplate(templateID)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load race weekend template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	tracks, err := NewTrackManager().ListTracks()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't list tracks")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	cars, err := ListCars()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't list cars")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	raceWeekends, err := rwh.raceWeekendManager.ListRaceWeekends()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't list race weekends")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rwh.viewRenderer.MustLoadTemplate(w, r, "race-weekend/instantiate-template.html", &raceWeekendTemplateInstantiateTemplateVars{
+		Template:     template,
+		Tracks:       tracks,
+		Cars:         cars,
+		RaceWeekends: raceWeekends,
+	})
+}
+
+func (rwh *RaceWeekendHandler) templateParametersFromForm(r *http.Request) (RaceWeekendTemplateParameters, error) {
+	params := RaceWeekendTemplateParameters{
+		SessionLengths: make(map[uuid.UUID]int),
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return params, err
+	}
+
+	params.Name = r.FormValue("Name")
+	params.Cars = r.Form["Cars"]
+
+	if track := r.FormValue("Track"); track != "" {
+		trackAndLayout := strings.SplitN(track, "/", 2)
+
+		params.Track = trackAndLayout[0]
+
+		if len(trackAndLayout) > 1 {
+			params.TrackLayout = trackAndLayout[1]
+		}
+	}
+
+	if date := r.FormValue("Date"); date != "" {
+		location, err := tz.LoadLocation(r.FormValue("Timezone"))
+
+		if err != nil {
+			logrus.WithError(err).Warnf("could not find location: %s", r.FormValue("Timezone"))
+			location = time.Local
+		}
+
+		params.Date, err = time.ParseInLocation("2006-01-02", date, location)
+
+		if err != nil {
+			return params, err
+		}
+	}
+
+	for i, sessionID := range r.Form["SessionID"] {
+		if i >= len(r.Form["SessionLength"]) {
+			break
+		}
+
+		id, err := uuid.Parse(sessionID)
+
+		if err != nil {
+			return params, err
+		}
+
+		params.SessionLengths[id] = formValueAsInt(r.Form["SessionLength"][i])
+	}
+
+	if entryListFrom := r.FormValue("EntryListFrom"); entryListFrom != "" {
+		raceWeekend, err := rwh.raceWeekendManager.LoadRaceWeekend(entryListFrom)
+
+		if err != nil {
+			return params, err
+		}
+
+		params.EntryList = raceWeekend.GetEntryList()
+	}
+
+	return params, nil
+}
+
+func (rwh *RaceWeekendHandler) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	err := rwh.raceWeekendManager.DeleteRaceWeekendTemplate(chi.URLParam(r, "templateID"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't delete race weekend template")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Race Weekend template successfully deleted!")
+	http.Redirect(w, r, "/race-weekend-templates", http.StatusFound)
+}
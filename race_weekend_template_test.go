@@ -0,0 +1,357 @@
+package servermanager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// templateTestRaceWeekend creates a two driver testRaceWeekend with a completed qualifying session at 10:00 and a
+// race at 14:00. The drivers are in different cars.
+func templateTestRaceWeekend() (*RaceWeekend, *RaceWeekendSession, *RaceWeekendSession) {
+	raceWeekend, qualifying := testRaceWeekend(2)
+	raceWeekend.Name = "Sprint Weekend"
+	raceWeekend.EntryList["CAR_1"].Model = "ks_mclaren_650_gt3"
+
+	day := time.Date(2026, time.May, 9, 0, 0, 0, 0, time.UTC)
+
+	qualifying.RaceConfig.Track = "spa"
+	qualifying.RaceConfig.Sessions[SessionTypeQualifying].Time = 15
+	qualifying.ScheduledTime = day.Add(time.Hour * 10)
+	qualifying.ScheduledServerID = uuid.New()
+	qualifying.StartedTime = day.Add(time.Hour * 10)
+	qualifying.CompletedTime = day.Add(time.Hour*10 + time.Minute*15)
+	qualifying.Results = &SessionResults{Type: SessionTypeQualifying}
+
+	race := testRaceWeekendSession(SessionTypeRace, "Race")
+	race.RaceConfig.Track = "spa"
+	race.RaceConfig.Sessions[SessionTypeRace].Time = 0
+	race.RaceConfig.Sessions[SessionTypeRace].Laps = 20
+	race.ScheduledTime = day.Add(time.Hour * 14)
+	race.GridOverrides = []*RaceWeekendGridOverride{{DriverGUID: "76561198000000000", GridPlaces: 3}}
+
+	raceWeekend.AddSession(race, qualifying)
+	raceWeekend.AddFilter(qualifying.ID.String(), race.ID.String(), &RaceWeekendSessionToSessionFilter{ResultStart: 1, ResultEnd: 10, EntryListStart: 1})
+
+	return raceWeekend, qualifying, race
+}
+
+func TestNewRaceWeekendTemplate(t *testing.T) {
+	raceWeekend, qualifying, race := templateTestRaceWeekend()
+
+	template, err := NewRaceWeekendTemplate(raceWeekend, "Sprint Format", "Qualifying and a sprint race")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if template.RaceWeekend.Name != "Sprint Format" || len(template.RaceWeekend.Sessions) != 2 || len(template.RaceWeekend.EntryList) != 2 {
+		t.Logf("Expected the template to keep the sessions and entry list of the race weekend, got: %d sessions, %d entrants", len(template.RaceWeekend.Sessions), len(template.RaceWeekend.EntryList))
+		t.Fail()
+		return
+	}
+
+	if template.SessionStartOffsets[qualifying.ID] != time.Hour*10 || template.SessionStartOffsets[race.ID] != time.Hour*14 {
+		t.Logf("Expected sessions to start at 10:00 and 14:00, got: %v", template.SessionStartOffsets)
+		t.Fail()
+	}
+
+	for _, session := range template.RaceWeekend.Sessions {
+		if session.Results != nil || !session.StartedTime.IsZero() || !session.CompletedTime.IsZero() || !session.ScheduledTime.IsZero() || session.ScheduledServerID != uuid.Nil || len(session.GridOverrides) > 0 {
+			t.Logf("Expected the progress of %s to be removed from the template", session.Name())
+			t.Fail()
+		}
+	}
+
+	if raceWeekend.Sessions[0].Results == nil || raceWeekend.Sessions[1].ScheduledTime.IsZero() {
+		t.Log("Expected the race weekend to be left unchanged")
+		t.Fail()
+	}
+
+	t.Run("No name", func(t *testing.T) {
+		if _, err := NewRaceWeekendTemplate(raceWeekend, "", ""); err != ErrRaceWeekendTemplateNeedsName {
+			t.Logf("Expected a template without a name to be refused, got: %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestRaceWeekendTemplate_Instantiate(t *testing.T) {
+	raceWeekend, qualifying, race := templateTestRaceWeekend()
+
+	template, err := NewRaceWeekendTemplate(raceWeekend, "Sprint Format", "")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	date := time.Date(2026, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	params := RaceWeekendTemplateParameters{
+		Name:           "Round 3",
+		Date:           date,
+		Track:          "monza",
+		TrackLayout:    "junior",
+		Cars:           []string{"ks_audi_r8_lms"},
+		SessionLengths: map[uuid.UUID]int{qualifying.ID: 20, race.ID: 30},
+	}
+
+	instance, err := template.Instantiate(params)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if instance.Name != "Round 3" || instance.ID == template.RaceWeekend.ID || len(instance.Sessions) != 2 {
+		t.Logf("Expected a new race weekend called Round 3, got: %s", instance.Name)
+		t.Fail()
+		return
+	}
+
+	newQualifying, newRace := instance.Sessions[0], instance.Sessions[1]
+
+	if newQualifying.ID == qualifying.ID || newRace.ID == race.ID {
+		t.Log("Expected the sessions to have new IDs")
+		t.Fail()
+	}
+
+	if len(newQualifying.ParentIDs) != 1 || newQualifying.ParentIDs[0] != instance.ID {
+		t.Log("Expected the qualifying session to follow the new entry list")
+		t.Fail()
+	}
+
+	if len(newRace.ParentIDs) != 1 || newRace.ParentIDs[0] != newQualifying.ID {
+		t.Log("Expected the race to follow the new qualifying session")
+		t.Fail()
+	}
+
+	if _, err := instance.GetFilter(newQualifying.ID.String(), newRace.ID.String()); err != nil {
+		t.Logf("Expected the filter to link the new sessions, got: %v", err)
+		t.Fail()
+	}
+
+	if !newQualifying.ScheduledTime.Equal(date.Add(time.Hour*10)) || !newRace.ScheduledTime.Equal(date.Add(time.Hour*14)) {
+		t.Logf("Expected the sessions to be scheduled on the new date, got: %s and %s", newQualifying.ScheduledTime, newRace.ScheduledTime)
+		t.Fail()
+	}
+
+	if newQualifying.SessionInfo().Time != 20 || newRace.SessionInfo().Laps != 30 || newRace.SessionInfo().Time != 0 {
+		t.Log("Expected the session lengths to be set in minutes for qualifying and laps for the race")
+		t.Fail()
+	}
+
+	for _, session := range instance.Sessions {
+		if session.RaceConfig.Track != "monza" || session.RaceConfig.TrackLayout != "junior" || session.RaceConfig.Cars != "ks_audi_r8_lms" {
+			t.Logf("Expected %s to use the new track and cars, got: %s (%s), %s", session.Name(), session.RaceConfig.Track, session.RaceConfig.TrackLayout, session.RaceConfig.Cars)
+			t.Fail()
+		}
+	}
+
+	for _, entrant := range instance.EntryList {
+		if entrant.Model != "ks_audi_r8_lms" {
+			t.Logf("Expected %s to be moved into an available car, got: %s", entrant.Name, entrant.Model)
+			t.Fail()
+		}
+	}
+
+	if template.RaceWeekend.Sessions[0].ID != qualifying.ID || template.RaceWeekend.Sessions[1].RaceConfig.Track != "spa" {
+		t.Log("Expected the template to be left unchanged")
+		t.Fail()
+	}
+
+	t.Run("Instantiated twice", func(t *testing.T) {
+		other, err := template.Instantiate(RaceWeekendTemplateParameters{})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if other.ID == instance.ID || other.Sessions[0].ID == newQualifying.ID {
+			t.Log("Expected each instance of a template to have its own IDs")
+			t.Fail()
+		}
+
+		if other.Name != "Sprint Format" || !other.Sessions[0].ScheduledTime.IsZero() || other.Sessions[1].SessionInfo().Laps != 20 {
+			t.Log("Expected an instance with no parameters to use the template's values and not be scheduled")
+			t.Fail()
+		}
+	})
+}
+
+func TestRaceWeekendManager_RaceWeekendTemplates(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	rwm := NewRaceWeekendManager(nil, nil, store, nil, nil, nil)
+
+	raceWeekend, _, _ := templateTestRaceWeekend()
+
+	if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+		t.Error(err)
+		return
+	}
+
+	template, err := rwm.SaveRaceWeekendAsTemplate(raceWeekend.ID.String(), "Sprint Format", "")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := rwm.SaveRaceWeekendAsTemplate(raceWeekend.ID.String(), "Another Format", ""); err != nil {
+		t.Error(err)
+		return
+	}
+
+	templates, err := rwm.ListRaceWeekendTemplates()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(templates) != 2 || templates[0].Name != "Another Format" {
+		t.Logf("Expected 2 templates sorted by name, got: %d", len(templates))
+		t.Fail()
+	}
+
+	instance, err := rwm.InstantiateRaceWeekendTemplate(template.ID.String(), RaceWeekendTemplateParameters{Name: "Round 1"})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := store.LoadRaceWeekend(instance.ID.String()); err != nil {
+		t.Logf("Expected the new race weekend to be stored, got: %v", err)
+		t.Fail()
+	}
+
+	if err := rwm.DeleteRaceWeekendTemplate(template.ID.String()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	templates, err = rwm.ListRaceWeekendTemplates()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(templates) != 1 {
+		t.Logf("Expected the deleted template to not be listed, got: %d templates", len(templates))
+		t.Fail()
+	}
+}
+
+func TestRaceWeekendHandler_ScheduleTemplateSessions(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	rwm := NewRaceWeekendManager(nil, nil, store, nil, nil, nil)
+	pool := &testSchedulerServerPool{}
+
+	for i := 0; i < 2; i++ {
+		scheduler := NewScheduler(store, nil, nil, rwm, NewNotificationManager(nil, nil, store))
+		scheduler.serverID = uuid.New()
+		scheduler.serverPool = pool
+
+		// the job queue is never started, so that queued jobs can be checked without starting any sessions.
+		scheduler.processJobsOnce.Do(func() {})
+
+		pool.schedulers = append(pool.schedulers, scheduler)
+	}
+
+	firstServer, secondServer := pool.schedulers[0].serverID, pool.schedulers[1].serverID
+	rwm.serverID = firstServer
+	rwh := NewRaceWeekendHandler(nil, rwm, pool.schedulers[0])
+
+	raceWeekend, _, _ := templateTestRaceWeekend()
+
+	template, err := NewRaceWeekendTemplate(raceWeekend, "Sprint Format", "")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := store.UpsertRaceWeekendTemplate(template); err != nil {
+		t.Error(err)
+		return
+	}
+
+	date := time.Now().Add(time.Hour * 48).Truncate(time.Hour * 24)
+
+	// the first server is busy during qualifying
+	if err := store.UpsertCustomRace(scheduleConflictsTestRace("Existing Race", date.Add(time.Hour*10), firstServer)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	instance, err := rwm.InstantiateRaceWeekendTemplate(template.ID.String(), RaceWeekendTemplateParameters{Date: date})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	form := url.Values{
+		"schedule-conflict-action":   {string(ScheduleConflictAutoAssign)},
+		"missed-event-action":        {string(MissedEventActionStart)},
+		"missed-event-grace-minutes": {"15"},
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/race-weekend-template/"+template.ID.String()+"/instantiate", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	expectedServers := []ServerID{secondServer, firstServer}
+
+	for i, session := range instance.Sessions {
+		if _, err := rwh.scheduleSessionFromForm(r, instance.ID.String(), session.ID.String(), session.ScheduledTime, false); err != nil {
+			t.Error(err)
+			return
+		}
+
+		_, storedSession, err := rwm.FindSession(instance.ID.String(), session.ID.String())
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if storedSession.ScheduledServerID != expectedServers[i] || storedSession.MissedEventPolicy.Action != MissedEventActionStart {
+			t.Logf("Expected %s to be scheduled on server %d with its missed event policy", storedSession.Name(), i+1)
+			t.Fail()
+		}
+
+		jobs, err := store.ListJobs()
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		var jobServers []ServerID
+
+		for _, job := range jobs {
+			if job.EventID == session.ID.String() && job.Type == JobTypeStartEvent && job.Status == JobStatusPending {
+				jobServers = append(jobServers, job.ServerID)
+			}
+		}
+
+		if len(jobServers) != 1 || jobServers[0] != expectedServers[i] {
+			t.Logf("Expected %s to be queued to start on the server it was assigned to, got: %v", storedSession.Name(), jobServers)
+			t.Fail()
+		}
+	}
+}
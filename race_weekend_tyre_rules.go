@@ -0,0 +1,282 @@
+package servermanager
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+// RaceWeekendTyreRules are the tyre compound rules that each driver in a RaceWeekendSession must follow. Compliance
+// is checked using the tyre of each lap in the session results. The Assetto Corsa UDP plugin does not report the tyre
+// a car is using, so rules can't be checked live in RaceControl.
+type RaceWeekendTyreRules struct {
+	// MinimumCompounds is the number of different compounds each driver must use during the session.
+	MinimumCompounds int
+	// CompulsoryCompound must be used for at least one lap by each driver during the session.
+	CompulsoryCompound string
+	// SetsPerWeekend limits the number of sets of each compound (by short name) that each driver may use across the
+	// whole RaceWeekend, up to and including this session. Each stint on a compound counts as a new set.
+	SetsPerWeekend map[string]int
+
+	// PenaltySeconds is added to the result of a driver for each rule that they break.
+	PenaltySeconds int
+	// Disqualify disqualifies drivers who break any rule, rather than applying a time penalty.
+	Disqualify bool
+}
+
+// IsEnabled is true if any tyre rule is set.
+func (r RaceWeekendTyreRules) IsEnabled() bool {
+	return r.MinimumCompounds > 1 || r.CompulsoryCompound != "" || len(r.SetsPerWeekend) > 0
+}
+
+// SetsPerWeekendString formats SetsPerWeekend in the same format as it is entered, e.g. "S:8, M:4"
+func (r RaceWeekendTyreRules) SetsPerWeekendString() string {
+	var sets []string
+
+	for compound, num := range r.SetsPerWeekend {
+		sets = append(sets, fmt.Sprintf("%s:%d", compound, num))
+	}
+
+	sort.Strings(sets)
+
+	return strings.Join(sets, ", ")
+}
+
+// A TyreRuleViolation is a tyre rule that a driver broke during a RaceWeekendSession.
+type TyreRuleViolation struct {
+	DriverGUID string
+	DriverName string
+	Message    string
+}
+
+// tyreStint is a run of consecutive laps by one driver on one compound.
+type tyreStint struct {
+	Compound string
+	NumLaps  int
+}
+
+// tyreStintsForDrivers splits the laps of a session into stints for each driver, ordered by the time of each lap.
+func tyreStintsForDrivers(results *SessionResults) map[string][]*tyreStint {
+	laps := make([]*SessionLap, len(results.Laps))
+	copy(laps, results.Laps)
+
+	sort.SliceStable(laps, func(i, j int) bool {
+		return laps[i].Timestamp < laps[j].Timestamp
+	})
+
+	stints := make(map[string][]*tyreStint)
+
+	for _, lap := range laps {
+		if lap.Tyre == "" {
+			continue
+		}
+
+		driverStints := stints[lap.DriverGUID]
+
+		if len(driverStints) == 0 || driverStints[len(driverStints)-1].Compound != lap.Tyre {
+			driverStints = append(driverStints, &tyreStint{Compound: lap.Tyre})
+		}
+
+		driverStints[len(driverStints)-1].NumLaps++
+		stints[lap.DriverGUID] = driverStints
+	}
+
+	return stints
+}
+
+// CheckTyreRules finds all drivers in the results of a session who broke its tyre rules. Sets used in
+// other completed sessions of the RaceWeekend count towards the weekend set limits.
+func (rw *RaceWeekend) CheckTyreRules(session *RaceWeekendSession, results *SessionResults) []*TyreRuleViolation {
+	rules := session.TyreRules
+
+	if !rules.IsEnabled() || results == nil {
+		return nil
+	}
+
+	// weekendSets is GUID -> Compound -> number of sets used
+	weekendSets := make(map[string]map[string]int)
+
+	countSets := func(sessionResults *SessionResults) {
+		for guid, stints := range tyreStintsForDrivers(sessionResults) {
+			if _, ok := weekendSets[guid]; !ok {
+				weekendSets[guid] = make(map[string]int)
+			}
+
+			for _, stint := range stints {
+				weekendSets[guid][stint.Compound]++
+			}
+		}
+	}
+
+	if len(rules.SetsPerWeekend) > 0 {
+		for _, otherSession := range rw.Sessions {
+			if otherSession.ID != session.ID && otherSession.Completed() {
+				countSets(otherSession.Results)
+			}
+		}
+
+		countSets(results)
+	}
+
+	stints := tyreStintsForDrivers(results)
+
+	var violations []*TyreRuleViolation
+
+	for _, result := range results.Result {
+		driverStints, ok := stints[result.DriverGUID]
+
+		if !ok {
+			// drivers that didn't complete a lap can't have broken a rule
+			continue
+		}
+
+		addViolation := func(format string, args ...interface{}) {
+			violations = append(violations, &TyreRuleViolation{
+				DriverGUID: result.DriverGUID,
+				DriverName: result.DriverName,
+				Message:    fmt.Sprintf(format, args...),
+			})
+		}
+
+		compoundsUsed := make(map[string]bool)
+
+		for _, stint := range driverStints {
+			compoundsUsed[stint.Compound] = true
+		}
+
+		if rules.MinimumCompounds > 1 && len(compoundsUsed) < rules.MinimumCompounds {
+			addViolation("used %d compounds, %d are required", len(compoundsUsed), rules.MinimumCompounds)
+		}
+
+		if rules.CompulsoryCompound != "" && !compoundsUsed[rules.CompulsoryCompound] {
+			addViolation("did not use the compulsory %s compound", rules.CompulsoryCompound)
+		}
+
+		for compound, limit := range rules.SetsPerWeekend {
+			if used := weekendSets[result.DriverGUID][compound]; used > limit {
+				addViolation("used %d sets of %s this weekend, the limit is %d", used, compound, limit)
+			}
+		}
+	}
+
+	return violations
+}
+
+// ApplyTyreRulePenalties penalises each driver in the results who has a violation in the same way as a penalty
+// added from the results page, then re-sorts the results.
+func (r RaceWeekendTyreRules) ApplyTyreRulePenalties(results *SessionResults, violations []*TyreRuleViolation) {
+	if len(violations) == 0 || (!r.Disqualify && r.PenaltySeconds <= 0) {
+		return
+	}
+
+	for _, violation := range violations {
+		for _, result := range results.Result {
+			if result.DriverGUID != violation.DriverGUID {
+				continue
+			}
+
+			if r.Disqualify {
+				result.Disqualified = true
+				result.HasPenalty = false
+				result.LapPenalty = 0
+				continue
+			}
+
+			result.HasPenalty = true
+			result.PenaltyTime += time.Duration(r.PenaltySeconds) * time.Second
+
+			// If penalty time is greater than a lap then add a lap penalty
+			lastLapTime := results.GetLastLapTime(result.DriverGUID, result.CarModel)
+
+			if result.PenaltyTime > lastLapTime {
+				result.LapPenalty = int(result.PenaltyTime / lastLapTime)
+			}
+		}
+	}
+
+	results.sortByPenalties()
+}
+
+// tyreRulesFromForm parses the tyre rules form. Sets per weekend are entered as "S:8, M:4".
+func tyreRulesFromForm(r *http.Request) (RaceWeekendTyreRules, error) {
+	rules := RaceWeekendTyreRules{
+		MinimumCompounds:   formValueAsInt(r.FormValue("MinimumCompounds")),
+		CompulsoryCompound: strings.TrimSpace(r.FormValue("CompulsoryCompound")),
+		SetsPerWeekend:     make(map[string]int),
+		PenaltySeconds:     formValueAsInt(r.FormValue("PenaltySeconds")),
+		Disqualify:         r.FormValue("Disqualify") == "on",
+	}
+
+	for _, set := range strings.Split(r.FormValue("SetsPerWeekend"), ",") {
+		set = strings.TrimSpace(set)
+
+		if set == "" {
+			continue
+		}
+
+		parts := strings.SplitN(set, ":", 2)
+
+		if len(parts) != 2 {
+			return rules, fmt.Errorf("servermanager: invalid tyre set limit: %s", set)
+		}
+
+		num, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+
+		if err != nil {
+			return rules, err
+		}
+
+		rules.SetsPerWeekend[strings.TrimSpace(parts[0])] = num
+	}
+
+	return rules, nil
+}
+
+func (rwm *RaceWeekendManager) UpdateTyreRules(raceWeekendID, sessionID string, rules RaceWeekendTyreRules) error {
+	_, err := rwm.updateRaceWeekend(raceWeekendID, func(raceWeekend *RaceWeekend) error {
+		session, err := raceWeekend.FindSessionByID(sessionID)
+
+		if err != nil {
+			return err
+		}
+
+		session.TyreRules = rules
+
+		return nil
+	})
+
+	return err
+}
+
+func (rwh *RaceWeekendHandler) tyreRules(w http.ResponseWriter, r *http.Request) {
+	raceWeekendID := chi.URLParam(r, "raceWeekendID")
+
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse tyre rules form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rules, err := tyreRulesFromForm(r)
+
+	if err != nil {
+		AddErrorFlash(w, r, "Tyre set limits must be a comma separated list of compound:sets, e.g. S:8, M:4")
+		http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+		return
+	}
+
+	if err := rwh.raceWeekendManager.UpdateTyreRules(raceWeekendID, chi.URLParam(r, "sessionID"), rules); err != nil {
+		logrus.WithError(err).Errorf("Could not update race weekend session tyre rules")
+		AddErrorFlash(w, r, "Couldn't update the tyre rules")
+	} else {
+		AddFlash(w, r, "Tyre rules updated")
+	}
+
+	http.Redirect(w, r, "/race-weekend/"+raceWeekendID, http.StatusFound)
+}
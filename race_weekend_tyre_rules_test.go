@@ -0,0 +1,130 @@
+package servermanager
+
+import (
+	"testing"
+	"time"
+)
+
+func tyreRulesTestResults() *SessionResults {
+	results := &SessionResults{Type: SessionTypeRace}
+
+	drivers := []struct {
+		guid, name string
+		tyres      []string
+	}{
+		{"76561198000000001", "Driver One", []string{"M", "M", "M"}},
+		{"76561198000000002", "Driver Two", []string{"M", "H", "H"}},
+	}
+
+	for i, driver := range drivers {
+		results.Cars = append(results.Cars, &SessionCar{
+			CarID:  i,
+			Model:  "ks_audi_r8_lms",
+			Driver: SessionDriver{GUID: driver.guid, Name: driver.name},
+		})
+
+		for lap, tyre := range driver.tyres {
+			results.Laps = append(results.Laps, &SessionLap{
+				CarID:      i,
+				CarModel:   "ks_audi_r8_lms",
+				DriverGUID: driver.guid,
+				DriverName: driver.name,
+				LapTime:    60000,
+				Timestamp:  (lap+1)*60000 + i*1000,
+				Tyre:       tyre,
+			})
+		}
+
+		// Driver One finishes one second ahead of Driver Two
+		results.Result = append(results.Result, &SessionResult{
+			CarID:      i,
+			CarModel:   "ks_audi_r8_lms",
+			DriverGUID: driver.guid,
+			DriverName: driver.name,
+			TotalTime:  180000 + i*1000,
+		})
+	}
+
+	return results
+}
+
+func TestRaceWeekendTyreRules_ApplyTyreRulePenalties(t *testing.T) {
+	session := NewRaceWeekendSession()
+
+	t.Run("Time penalty", func(t *testing.T) {
+		session.TyreRules = RaceWeekendTyreRules{CompulsoryCompound: "H", PenaltySeconds: 5}
+
+		results := tyreRulesTestResults()
+		raceWeekend := &RaceWeekend{Sessions: []*RaceWeekendSession{session}}
+
+		violations := raceWeekend.CheckTyreRules(session, results)
+
+		if len(violations) != 1 || violations[0].DriverGUID != "76561198000000001" {
+			t.Logf("Expected one violation for Driver One, got: %d", len(violations))
+			t.Fail()
+			return
+		}
+
+		session.TyreRules.ApplyTyreRulePenalties(results, violations)
+
+		if results.Result[0].DriverGUID != "76561198000000002" {
+			t.Logf("Expected Driver Two to be promoted to first, got: %s", results.Result[0].DriverName)
+			t.Fail()
+		}
+
+		penalised := results.Result[1]
+
+		if !penalised.HasPenalty || penalised.PenaltyTime != 5*time.Second || penalised.LapPenalty != 0 {
+			t.Logf("Expected Driver One to have a 5s penalty, got: %t, %s, %d laps", penalised.HasPenalty, penalised.PenaltyTime, penalised.LapPenalty)
+			t.Fail()
+		}
+
+		if penalised.TotalTime != 180000 {
+			t.Logf("Expected the total time of Driver One to be unchanged, got: %d", penalised.TotalTime)
+			t.Fail()
+		}
+	})
+
+	t.Run("Penalty longer than a lap", func(t *testing.T) {
+		session.TyreRules = RaceWeekendTyreRules{MinimumCompounds: 2, PenaltySeconds: 90}
+
+		results := tyreRulesTestResults()
+
+		session.TyreRules.ApplyTyreRulePenalties(results, []*TyreRuleViolation{{DriverGUID: "76561198000000001"}})
+
+		penalised := results.Result[1]
+
+		if penalised.DriverGUID != "76561198000000001" || penalised.LapPenalty != 1 {
+			t.Logf("Expected Driver One to be last with a 1 lap penalty, got: %s with %d laps", penalised.DriverName, penalised.LapPenalty)
+			t.Fail()
+		}
+	})
+
+	t.Run("Disqualification", func(t *testing.T) {
+		session.TyreRules = RaceWeekendTyreRules{CompulsoryCompound: "H", Disqualify: true}
+
+		results := tyreRulesTestResults()
+
+		session.TyreRules.ApplyTyreRulePenalties(results, []*TyreRuleViolation{{DriverGUID: "76561198000000001"}})
+
+		disqualified := results.Result[1]
+
+		if disqualified.DriverGUID != "76561198000000001" || !disqualified.Disqualified || disqualified.HasPenalty {
+			t.Logf("Expected Driver One to be disqualified and last, got: %s (disqualified: %t)", disqualified.DriverName, disqualified.Disqualified)
+			t.Fail()
+		}
+	})
+
+	t.Run("No penalty configured", func(t *testing.T) {
+		session.TyreRules = RaceWeekendTyreRules{CompulsoryCompound: "H"}
+
+		results := tyreRulesTestResults()
+
+		session.TyreRules.ApplyTyreRulePenalties(results, []*TyreRuleViolation{{DriverGUID: "76561198000000001"}})
+
+		if results.Result[0].DriverGUID != "76561198000000001" || results.Result[0].HasPenalty {
+			t.Log("Expected the results to be unchanged when no penalty is configured")
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,533 @@
+package servermanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type RaceWeekendProblemSeverity string
+
+const (
+	// RaceWeekendProblemError is a problem which will stop a session from running correctly.
+	RaceWeekendProblemError RaceWeekendProblemSeverity = "error"
+	// RaceWeekendProblemWarning is a problem which may be intentional, but is worth checking.
+	RaceWeekendProblemWarning RaceWeekendProblemSeverity = "warning"
+)
+
+// A RaceWeekendProblem is an issue found when validating a RaceWeekend.
+type RaceWeekendProblem struct {
+	Severity  RaceWeekendProblemSeverity
+	SessionID uuid.UUID
+	Message   string
+}
+
+// IsError indicates that the RaceWeekendProblem will stop a session from running correctly.
+func (p *RaceWeekendProblem) IsError() bool {
+	return p.Severity == RaceWeekendProblemError
+}
+
+// raceWeekendValidator walks the session graph of a RaceWeekend, formed by session ParentIDs and the Filters
+// between sessions. The Race Weekend entry list is the root of the graph, and has the ID of the RaceWeekend.
+type raceWeekendValidator struct {
+	raceWeekend *RaceWeekend
+
+	projectedSizes map[uuid.UUID]int
+	visiting       map[uuid.UUID]bool
+	cyclic         map[uuid.UUID]bool
+}
+
+func newRaceWeekendValidator(raceWeekend *RaceWeekend) *raceWeekendValidator {
+	return &raceWeekendValidator{
+		raceWeekend:    raceWeekend,
+		projectedSizes: make(map[uuid.UUID]int),
+		visiting:       make(map[uuid.UUID]bool),
+		cyclic:         make(map[uuid.UUID]bool),
+	}
+}
+
+func (v *raceWeekendValidator) numClasses() int {
+	if v.raceWeekend.HasLinkedChampionship() && v.raceWeekend.Championship != nil && len(v.raceWeekend.Championship.Classes) > 0 {
+		return len(v.raceWeekend.Championship.Classes)
+	}
+
+	return 1
+}
+
+// filter returns the filter between parent and child, and whether it is a default filter.
+func (v *raceWeekendValidator) filter(parentID, childID uuid.UUID) (*RaceWeekendSessionToSessionFilter, bool) {
+	filter, err := v.raceWeekend.GetFilter(parentID.String(), childID.String())
+
+	if err == nil {
+		return filter, false
+	}
+
+	filter, err = v.raceWeekend.GetFilterOrUseDefault(parentID.String(), childID.String())
+
+	if err != nil {
+		return nil, true
+	}
+
+	return filter, true
+}
+
+// filterRange returns the zero-indexed, end-exclusive range of the parent's results that a filter takes.
+func (v *raceWeekendValidator) filterRange(filter *RaceWeekendSessionToSessionFilter, parentSize int) (start, end int) {
+	start, end = filter.ResultStart-1, filter.resultEnd(parentSize)
+
+	if start < 0 {
+		start = 0
+	}
+
+	if end > parentSize {
+		end = parentSize
+	}
+
+	if end < start {
+		end = start
+	}
+
+	return start, end
+}
+
+// numEntrantsFromFilter is the projected number of entrants a filter takes from a parent of parentSize.
+func (v *raceWeekendValidator) numEntrantsFromFilter(filter *RaceWeekendSessionToSessionFilter, parentSize int) int {
+	if filter == nil {
+		return 0
+	}
+
+	if filter.ManualDriverSelection {
+		return len(filter.SelectedDriverGUIDs)
+	}
+
+	start, end := v.filterRange(filter, parentSize)
+	num := end - start
+
+	if filter.PerClass {
+		num *= v.numClasses()
+
+		if num > parentSize {
+			num = parentSize
+		}
+	}
+
+	return num
+}
+
+// projectedSize is the number of entrants expected in a session. Completed sessions use their results, otherwise
+// the size is worked out from the filters of the session's parents.
+func (v *raceWeekendValidator) projectedSize(id uuid.UUID) int {
+	if id == v.raceWeekend.ID {
+		return len(v.raceWeekend.GetEntryList())
+	}
+
+	if size, ok := v.projectedSizes[id]; ok {
+		return size
+	}
+
+	if v.visiting[id] {
+		v.cyclic[id] = true
+		return 0
+	}
+
+	session, err := v.raceWeekend.FindSessionByID(id.String())
+
+	if err != nil {
+		return 0
+	}
+
+	if session.Completed() {
+		v.projectedSizes[id] = len(session.Results.Result)
+		return v.projectedSizes[id]
+	}
+
+	v.visiting[id] = true
+
+	size := 0
+
+	for _, parentID := range session.ParentIDs {
+		filter, _ := v.filter(parentID, id)
+		size += v.numEntrantsFromFilter(filter, v.projectedSize(parentID))
+	}
+
+	v.visiting[id] = false
+	v.projectedSizes[id] = size
+
+	return size
+}
+
+// reachesEntryList determines whether a session gets its entrants (through its parents) from the entry list.
+func (v *raceWeekendValidator) reachesEntryList(session *RaceWeekendSession, seen map[uuid.UUID]bool) bool {
+	if seen[session.ID] {
+		return false
+	}
+
+	seen[session.ID] = true
+
+	for _, parentID := range session.ParentIDs {
+		if parentID == v.raceWeekend.ID {
+			return true
+		}
+
+		parent, err := v.raceWeekend.FindSessionByID(parentID.String())
+
+		if err != nil {
+			continue
+		}
+
+		if v.reachesEntryList(parent, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// depth is the number of sessions between a session and the entry list, along the longest path through its parents.
+func (v *raceWeekendValidator) depth(session *RaceWeekendSession, seen map[uuid.UUID]bool) int {
+	if seen[session.ID] {
+		return 0
+	}
+
+	seen[session.ID] = true
+	defer delete(seen, session.ID)
+
+	depth := 0
+
+	for _, parentID := range session.ParentIDs {
+		parent, err := v.raceWeekend.FindSessionByID(parentID.String())
+
+		if err != nil {
+			continue
+		}
+
+		if parentDepth := v.depth(parent, seen) + 1; parentDepth > depth {
+			depth = parentDepth
+		}
+	}
+
+	return depth
+}
+
+func (v *raceWeekendValidator) validate() []*RaceWeekendProblem {
+	var problems []*RaceWeekendProblem
+
+	addProblem := func(severity RaceWeekendProblemSeverity, sessionID uuid.UUID, format string, args ...interface{}) {
+		problems = append(problems, &RaceWeekendProblem{
+			Severity:  severity,
+			SessionID: sessionID,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, session := range v.raceWeekend.Sessions {
+		v.projectedSize(session.ID)
+	}
+
+	for _, session := range v.raceWeekend.Sessions {
+		for _, parentID := range session.ParentIDs {
+			if _, err := v.raceWeekend.FindSessionByID(parentID.String()); err != nil {
+				addProblem(RaceWeekendProblemError, session.ID, "%s depends on a session which no longer exists", session.Name())
+			}
+		}
+
+		if v.cyclic[session.ID] {
+			addProblem(RaceWeekendProblemError, session.ID, "%s depends on itself through its parent sessions", session.Name())
+		} else if !v.reachesEntryList(session, make(map[uuid.UUID]bool)) {
+			addProblem(RaceWeekendProblemError, session.ID, "%s is not connected to the entry list, so it will have no entrants", session.Name())
+		}
+
+		if session.Completed() || session.InProgress() {
+			continue
+		}
+
+		projectedSize := v.projectedSize(session.ID)
+		gridSize := RaceWeekendSessionGridSize(session)
+
+		if projectedSize == 0 {
+			addProblem(RaceWeekendProblemWarning, session.ID, "%s is not expected to have any entrants", session.Name())
+		} else if gridSize > 0 && projectedSize > gridSize {
+			addProblem(RaceWeekendProblemError, session.ID, "%s is expected to have %d entrants, but its grid only has space for %d", session.Name(), projectedSize, gridSize)
+		}
+	}
+
+	parentIDs := []uuid.UUID{v.raceWeekend.ID}
+
+	for _, session := range v.raceWeekend.Sessions {
+		parentIDs = append(parentIDs, session.ID)
+	}
+
+	for _, parentID := range parentIDs {
+		problems = append(problems, v.validateSplits(parentID)...)
+	}
+
+	return problems
+}
+
+// validateSplits checks that the children of a session take distinct ranges of its results, and that together they
+// take all of its results.
+func (v *raceWeekendValidator) validateSplits(parentID uuid.UUID) []*RaceWeekendProblem {
+	children := v.raceWeekend.FindChildren(parentID.String())
+
+	if len(children) < 2 {
+		// a single child may intentionally take only some entrants, e.g. the top 10 into a shootout.
+		return nil
+	}
+
+	parentName := "The entry list"
+
+	if parentID != v.raceWeekend.ID {
+		parent, err := v.raceWeekend.FindSessionByID(parentID.String())
+
+		if err != nil {
+			return nil
+		}
+
+		parentName = parent.Name()
+	}
+
+	type split struct {
+		child      *RaceWeekendSession
+		filter     *RaceWeekendSessionToSessionFilter
+		start, end int
+	}
+
+	var splits []split
+	numPerClass := 0
+
+	for _, child := range children {
+		filter, _ := v.filter(parentID, child.ID)
+
+		if filter == nil || filter.ManualDriverSelection {
+			// manually selected drivers can't be checked for overlaps
+			return nil
+		}
+
+		if filter.PerClass {
+			numPerClass++
+		}
+
+		splits = append(splits, split{child: child, filter: filter})
+	}
+
+	parentSize := v.projectedSize(parentID)
+
+	if numPerClass == len(splits) {
+		// per class splits take their range from each class, so check them against the size of a class.
+		parentSize = (parentSize + v.numClasses() - 1) / v.numClasses()
+	} else if numPerClass > 0 {
+		// a mix of per class and overall splits can't be compared.
+		return nil
+	}
+
+	for i := range splits {
+		splits[i].start, splits[i].end = v.filterRange(splits[i].filter, parentSize)
+	}
+
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].start < splits[j].start
+	})
+
+	var problems []*RaceWeekendProblem
+
+	covered := 0
+
+	// previous is the split of the results which reaches furthest so far.
+	var previous *split
+
+	for i := range splits {
+		s := &splits[i]
+
+		if s.start > covered {
+			problems = append(problems, &RaceWeekendProblem{
+				Severity:  RaceWeekendProblemWarning,
+				SessionID: s.child.ID,
+				Message:   fmt.Sprintf("Positions %d to %d of %s don't go into any session", covered+1, s.start, parentName),
+			})
+		}
+
+		if s.end > covered {
+			covered = s.end
+		}
+
+		if s.start == 0 && s.end >= parentSize {
+			// children which take all entrants (e.g. practice and qualifying both from the entry list) aren't splits.
+			continue
+		}
+
+		if previous != nil && s.start < previous.end {
+			problems = append(problems, &RaceWeekendProblem{
+				Severity:  RaceWeekendProblemWarning,
+				SessionID: s.child.ID,
+				Message:   fmt.Sprintf("%s and %s both take positions %d to %d of %s", previous.child.Name(), s.child.Name(), s.start+1, minInt(s.end, previous.end), parentName),
+			})
+		}
+
+		if previous == nil || s.end > previous.end {
+			previous = s
+		}
+	}
+
+	if covered < parentSize {
+		problems = append(problems, &RaceWeekendProblem{
+			Severity:  RaceWeekendProblemWarning,
+			SessionID: parentID,
+			Message:   fmt.Sprintf("Positions %d to %d of %s don't go into any session", covered+1, parentSize, parentName),
+		})
+	}
+
+	return problems
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// Validate checks the session graph of a RaceWeekend for sessions which can't be run, filters which overlap or
+// leave gaps, and grids which won't fit on the track.
+func (rw *RaceWeekend) Validate() []*RaceWeekendProblem {
+	return newRaceWeekendValidator(rw).validate()
+}
+
+// RaceWeekendGraph is the session graph of a RaceWeekend, for use in a planner. The entry list is a node with the
+// ID of the RaceWeekend.
+type RaceWeekendGraph struct {
+	RaceWeekendID uuid.UUID
+	Name          string
+
+	Nodes    []*RaceWeekendGraphNode
+	Edges    []*RaceWeekendGraphEdge
+	Problems []*RaceWeekendProblem
+}
+
+type RaceWeekendGraphNode struct {
+	ID          uuid.UUID
+	Name        string
+	SessionType SessionType
+	IsEntryList bool
+
+	InProgress    bool
+	Completed     bool
+	ScheduledTime time.Time
+	SortType      string
+
+	ProjectedGridSize int
+	GridSize          int
+}
+
+type RaceWeekendGraphEdge struct {
+	ParentID uuid.UUID
+	ChildID  uuid.UUID
+
+	// IsDefault indicates that no filter has been configured, so all entrants go from the parent to the child.
+	IsDefault bool
+
+	ResultStart           int
+	ResultEnd             int
+	EntryListStart        int
+	NumEntrantsToReverse  int
+	SortType              string
+	PerClass              bool
+	ManualDriverSelection bool
+
+	ProjectedEntrants int
+}
+
+// Graph returns the session graph of a RaceWeekend, along with any problems found validating it.
+func (rw *RaceWeekend) Graph() *RaceWeekendGraph {
+	v := newRaceWeekendValidator(rw)
+
+	graph := &RaceWeekendGraph{
+		RaceWeekendID: rw.ID,
+		Name:          rw.Name,
+		Problems:      v.validate(),
+	}
+
+	graph.Nodes = append(graph.Nodes, &RaceWeekendGraphNode{
+		ID:                rw.ID,
+		Name:              "Entry List",
+		IsEntryList:       true,
+		Completed:         true,
+		ProjectedGridSize: v.projectedSize(rw.ID),
+	})
+
+	sessions := make([]*RaceWeekendSession, len(rw.Sessions))
+	depths := make(map[uuid.UUID]int)
+
+	for i, session := range rw.Sessions {
+		sessions[i] = session
+		depths[session.ID] = v.depth(session, make(map[uuid.UUID]bool))
+	}
+
+	// each session is listed after the sessions it depends on, so that the graph can be drawn in the order it runs.
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return depths[sessions[i].ID] < depths[sessions[j].ID]
+	})
+
+	for _, session := range sessions {
+		graph.Nodes = append(graph.Nodes, &RaceWeekendGraphNode{
+			ID:                session.ID,
+			Name:              session.Name(),
+			SessionType:       session.SessionType(),
+			InProgress:        session.InProgress(),
+			Completed:         session.Completed(),
+			ScheduledTime:     session.ScheduledTime,
+			SortType:          session.SortType,
+			ProjectedGridSize: v.projectedSize(session.ID),
+			GridSize:          RaceWeekendSessionGridSize(session),
+		})
+
+		for _, parentID := range session.ParentIDs {
+			filter, isDefault := v.filter(parentID, session.ID)
+
+			if filter == nil {
+				continue
+			}
+
+			graph.Edges = append(graph.Edges, &RaceWeekendGraphEdge{
+				ParentID:              parentID,
+				ChildID:               session.ID,
+				IsDefault:             isDefault,
+				ResultStart:           filter.ResultStart,
+				ResultEnd:             filter.ResultEnd,
+				EntryListStart:        filter.EntryListStart,
+				NumEntrantsToReverse:  filter.NumEntrantsToReverse,
+				SortType:              filter.SortType,
+				PerClass:              filter.PerClass,
+				ManualDriverSelection: filter.ManualDriverSelection,
+				ProjectedEntrants:     v.numEntrantsFromFilter(filter, v.projectedSize(parentID)),
+			})
+		}
+	}
+
+	return graph
+}
+
+func (rwh *RaceWeekendHandler) graphAPI(w http.ResponseWriter, r *http.Request) {
+	raceWeekend, err := rwh.raceWeekendManager.LoadRaceWeekend(chi.URLParam(r, "raceWeekendID"))
+
+	if err == ErrRaceWeekendNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Errorf("couldn't load race weekend")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(raceWeekend.Graph())
+}
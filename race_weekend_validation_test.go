@@ -0,0 +1,296 @@
+package servermanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// testRaceWeekend creates a Race Weekend with numEntrants entrants, "Driver 1" (GUID 76561198000000000) onwards, and a
+// qualifying session from the entry list.
+func testRaceWeekend(numEntrants int) (*RaceWeekend, *RaceWeekendSession) {
+	raceWeekend := NewRaceWeekend()
+	raceWeekend.Name = "Test Weekend"
+	raceWeekend.EntryList = make(EntryList)
+
+	for i := 0; i < numEntrants; i++ {
+		entrant := NewEntrant()
+		entrant.Name = fmt.Sprintf("Driver %d", i+1)
+		entrant.GUID = fmt.Sprintf("7656119800000%04d", i)
+		entrant.Model = "ks_audi_r8_lms"
+
+		raceWeekend.EntryList.AddInPitBox(entrant, i)
+	}
+
+	qualifying := testRaceWeekendSession(SessionTypeQualifying, "Qualifying")
+	qualifying.ParentIDs = []uuid.UUID{raceWeekend.ID}
+
+	raceWeekend.AddSession(qualifying, nil)
+
+	return raceWeekend, qualifying
+}
+
+func testRaceWeekendSession(sessionType SessionType, name string) *RaceWeekendSession {
+	session := NewRaceWeekendSession()
+	session.RaceConfig.Sessions = Sessions{sessionType: &SessionConfig{Name: name, Time: 20}}
+
+	return session
+}
+
+// validationTestSplit adds two races which take the given ranges of the qualifying results.
+func validationTestSplit(start1, end1, start2, end2 int) func(*RaceWeekend, *RaceWeekendSession) {
+	return func(raceWeekend *RaceWeekend, qualifying *RaceWeekendSession) {
+		race1, race2 := testRaceWeekendSession(SessionTypeRace, "Race 1"), testRaceWeekendSession(SessionTypeRace, "Race 2")
+
+		raceWeekend.AddSession(race1, qualifying)
+		raceWeekend.AddSession(race2, qualifying)
+
+		raceWeekend.AddFilter(qualifying.ID.String(), race1.ID.String(), &RaceWeekendSessionToSessionFilter{ResultStart: start1, ResultEnd: end1, EntryListStart: 1})
+		raceWeekend.AddFilter(qualifying.ID.String(), race2.ID.String(), &RaceWeekendSessionToSessionFilter{ResultStart: start2, ResultEnd: end2, EntryListStart: 1})
+	}
+}
+
+func TestRaceWeekend_Validate(t *testing.T) {
+	type problem struct {
+		severity RaceWeekendProblemSeverity
+		message  string
+	}
+
+	testCases := []struct {
+		name     string
+		setup    func(*RaceWeekend, *RaceWeekendSession)
+		problems []problem
+	}{
+		{
+			name: "Valid",
+			setup: func(raceWeekend *RaceWeekend, qualifying *RaceWeekendSession) {
+				raceWeekend.AddSession(testRaceWeekendSession(SessionTypeRace, "Race"), qualifying)
+			},
+		},
+		{
+			name: "Grid too small",
+			setup: func(raceWeekend *RaceWeekend, qualifying *RaceWeekendSession) {
+				race := testRaceWeekendSession(SessionTypeRace, "Race")
+				race.RaceConfig.MaxClients = 16
+
+				raceWeekend.AddSession(race, qualifying)
+			},
+			problems: []problem{
+				{RaceWeekendProblemError, "Race is expected to have 20 entrants, but its grid only has space for 16"},
+			},
+		},
+		{
+			name: "Missing parent",
+			setup: func(raceWeekend *RaceWeekend, qualifying *RaceWeekendSession) {
+				race := testRaceWeekendSession(SessionTypeRace, "Race")
+				race.ParentIDs = []uuid.UUID{uuid.New()}
+
+				raceWeekend.AddSession(race, nil)
+			},
+			problems: []problem{
+				{RaceWeekendProblemError, "Race depends on a session which no longer exists"},
+				{RaceWeekendProblemError, "Race is not connected to the entry list, so it will have no entrants"},
+				{RaceWeekendProblemWarning, "Race is not expected to have any entrants"},
+			},
+		},
+		{
+			name: "Cycle",
+			setup: func(raceWeekend *RaceWeekend, qualifying *RaceWeekendSession) {
+				race1, race2 := testRaceWeekendSession(SessionTypeRace, "Race 1"), testRaceWeekendSession(SessionTypeRace, "Race 2")
+
+				raceWeekend.AddSession(race1, race2)
+				raceWeekend.AddSession(race2, race1)
+			},
+			problems: []problem{
+				{RaceWeekendProblemError, "Race 1 depends on itself through its parent sessions"},
+				{RaceWeekendProblemWarning, "Race 1 is not expected to have any entrants"},
+				{RaceWeekendProblemError, "Race 2 is not connected to the entry list, so it will have no entrants"},
+				{RaceWeekendProblemWarning, "Race 2 is not expected to have any entrants"},
+			},
+		},
+		{
+			name:  "Split",
+			setup: validationTestSplit(1, 10, 11, 20),
+		},
+		{
+			name:  "Overlapping split",
+			setup: validationTestSplit(1, 12, 10, 20),
+			problems: []problem{
+				{RaceWeekendProblemWarning, "Race 1 and Race 2 both take positions 10 to 12 of Qualifying"},
+			},
+		},
+		{
+			name:  "Gap in split",
+			setup: validationTestSplit(1, 10, 13, 20),
+			problems: []problem{
+				{RaceWeekendProblemWarning, "Positions 11 to 12 of Qualifying don't go into any session"},
+			},
+		},
+		{
+			name:  "Split leaves out the back of the results",
+			setup: validationTestSplit(1, 10, 11, 15),
+			problems: []problem{
+				{RaceWeekendProblemWarning, "Positions 16 to 20 of Qualifying don't go into any session"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			raceWeekend, qualifying := testRaceWeekend(20)
+			testCase.setup(raceWeekend, qualifying)
+
+			problems := raceWeekend.Validate()
+
+			if len(problems) != len(testCase.problems) {
+				for _, p := range problems {
+					t.Logf("%s: %s", p.Severity, p.Message)
+				}
+
+				t.Logf("Expected %d problems, got: %d", len(testCase.problems), len(problems))
+				t.Fail()
+				return
+			}
+
+			for i, expected := range testCase.problems {
+				if problems[i].Severity != expected.severity || problems[i].Message != expected.message {
+					t.Logf("Expected %s: %s, got: %s: %s", expected.severity, expected.message, problems[i].Severity, problems[i].Message)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestRaceWeekend_Graph(t *testing.T) {
+	raceWeekend, qualifying := testRaceWeekend(20)
+	validationTestSplit(1, 10, 11, 15)(raceWeekend, qualifying)
+
+	graph := raceWeekend.Graph()
+
+	if len(graph.Nodes) != 4 || len(graph.Edges) != 3 || len(graph.Problems) != 1 {
+		t.Logf("Expected 4 nodes, 3 edges and 1 problem, got: %d, %d and %d", len(graph.Nodes), len(graph.Edges), len(graph.Problems))
+		t.Fail()
+		return
+	}
+
+	if entryList := graph.Nodes[0]; !entryList.IsEntryList || entryList.ID != raceWeekend.ID || entryList.ProjectedGridSize != 20 {
+		t.Log("Expected the first node to be the entry list, with 20 entrants")
+		t.Fail()
+	}
+
+	expected := []struct {
+		isDefault bool
+		entrants  int
+	}{
+		{true, 20},
+		{false, 10},
+		{false, 5},
+	}
+
+	for i, edge := range graph.Edges {
+		if edge.IsDefault != expected[i].isDefault || edge.ProjectedEntrants != expected[i].entrants {
+			t.Logf("Expected edge %d to take %d entrants (default: %t), got: %d (default: %t)", i, expected[i].entrants, expected[i].isDefault, edge.ProjectedEntrants, edge.IsDefault)
+			t.Fail()
+		}
+	}
+
+	if graph.Nodes[3].ProjectedGridSize != 5 || graph.Nodes[3].SessionType != SessionTypeRace {
+		t.Logf("Expected Race 2 to have 5 entrants, got: %d", graph.Nodes[3].ProjectedGridSize)
+		t.Fail()
+	}
+
+	t.Run("Sessions follow their parents", func(t *testing.T) {
+		raceWeekend, qualifying := testRaceWeekend(20)
+
+		race, final := testRaceWeekendSession(SessionTypeRace, "Race"), testRaceWeekendSession(SessionTypeRace, "Final")
+		race.ParentIDs = []uuid.UUID{qualifying.ID}
+		final.ParentIDs = []uuid.UUID{race.ID}
+
+		// the sessions are stored in the reverse of the order they run in
+		raceWeekend.Sessions = []*RaceWeekendSession{final, race, qualifying}
+
+		graph := raceWeekend.Graph()
+
+		var order []string
+
+		for _, node := range graph.Nodes {
+			order = append(order, node.Name)
+		}
+
+		if strings.Join(order, ", ") != "Entry List, Qualifying, Race, Final" {
+			t.Logf("Expected each session to follow the sessions it depends on, got: %s", strings.Join(order, ", "))
+			t.Fail()
+		}
+
+		if len(graph.Edges) != 3 || graph.Edges[0].ChildID != qualifying.ID || graph.Edges[2].ChildID != final.ID {
+			t.Log("Expected the edges to be in the order of the sessions")
+			t.Fail()
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		raceWeekend, qualifying := testRaceWeekend(20)
+
+		race1, race2 := testRaceWeekendSession(SessionTypeRace, "Race 1"), testRaceWeekendSession(SessionTypeRace, "Race 2")
+
+		raceWeekend.AddSession(race1, race2)
+		raceWeekend.AddSession(race2, race1)
+		race1.ParentIDs = append(race1.ParentIDs, qualifying.ID)
+
+		if graph := raceWeekend.Graph(); len(graph.Nodes) != 4 || graph.Nodes[1].ID != qualifying.ID {
+			t.Log("Expected a graph with a cycle to list the sessions outside of the cycle first")
+			t.Fail()
+		}
+	})
+}
+
+func TestRaceWeekendHandler_GraphAPI(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	rwh := NewRaceWeekendHandler(nil, NewRaceWeekendManager(nil, nil, store, nil, nil, nil), nil)
+
+	raceWeekend, qualifying := testRaceWeekend(20)
+	validationTestSplit(1, 10, 11, 20)(raceWeekend, qualifying)
+
+	if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+		t.Error(err)
+		return
+	}
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("raceWeekendID", raceWeekend.ID.String())
+
+	r := httptest.NewRequest(http.MethodGet, "/api/race-weekend/"+raceWeekend.ID.String()+"/graph", nil)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+
+	w := httptest.NewRecorder()
+
+	rwh.graphAPI(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Logf("Expected a JSON response, got: %d (%s)", w.Code, w.Header().Get("Content-Type"))
+		t.Fail()
+		return
+	}
+
+	var graph RaceWeekendGraph
+
+	if err := json.NewDecoder(w.Body).Decode(&graph); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if graph.RaceWeekendID != raceWeekend.ID || graph.Name != "Test Weekend" || len(graph.Nodes) != 4 || len(graph.Problems) != 0 {
+		t.Logf("Expected the graph of the race weekend, got: %s with %d nodes and %d problems", graph.Name, len(graph.Nodes), len(graph.Problems))
+		t.Fail()
+	}
+}
@@ -15,6 +15,8 @@ type Resolver struct {
 	discordManager        *DiscordManager
 	notificationManager   *NotificationManager
 	scheduledRacesManager *ScheduledRacesManager
+	teamManager           *TeamManager
+	seasonManager         *SeasonManager
 
 	viewRenderer *Renderer
 
@@ -29,6 +31,8 @@ type Resolver struct {
 	resultsHandler        *ResultsHandler
 	scheduledRacesHandler *ScheduledRacesHandler
 	contentUploadHandler  *ContentUploadHandler
+	teamsHandler          *TeamsHandler
+	seasonsHandler        *SeasonsHandler
 }
 
 func NewResolver(templateLoader TemplateLoader, reloadTemplates bool, store Store) (*Resolver, error) {
@@ -80,6 +84,9 @@ func (r *Resolver) resolveMultiServerManager() *MultiServerManager {
 		r.resolveAccountHandler(),
 	)
 
+	// the servers depend on the DiscordManager for notifications, so it can only be given the servers afterwards.
+	r.resolveDiscordManager().multiServerManager = r.multiServerManager
+
 	return r.multiServerManager
 }
 
@@ -203,6 +210,46 @@ func (r *Resolver) resolveContentUploadHandler() *ContentUploadHandler {
 	return r.contentUploadHandler
 }
 
+func (r *Resolver) resolveTeamManager() *TeamManager {
+	if r.teamManager != nil {
+		return r.teamManager
+	}
+
+	r.teamManager = NewTeamManager(r.ResolveStore())
+
+	return r.teamManager
+}
+
+func (r *Resolver) resolveTeamsHandler() *TeamsHandler {
+	if r.teamsHandler != nil {
+		return r.teamsHandler
+	}
+
+	r.teamsHandler = NewTeamsHandler(r.resolveBaseHandler(), r.resolveTeamManager())
+
+	return r.teamsHandler
+}
+
+func (r *Resolver) resolveSeasonManager() *SeasonManager {
+	if r.seasonManager != nil {
+		return r.seasonManager
+	}
+
+	r.seasonManager = NewSeasonManager(r.ResolveStore())
+
+	return r.seasonManager
+}
+
+func (r *Resolver) resolveSeasonsHandler() *SeasonsHandler {
+	if r.seasonsHandler != nil {
+		return r.seasonsHandler
+	}
+
+	r.seasonsHandler = NewSeasonsHandler(r.resolveBaseHandler(), r.resolveSeasonManager())
+
+	return r.seasonsHandler
+}
+
 func (r *Resolver) resolveDiscordManager() *DiscordManager {
 	if r.discordManager != nil {
 		return r.discordManager
@@ -236,6 +283,8 @@ func (r *Resolver) ResolveRouter(fs http.FileSystem) http.Handler {
 		r.resolveResultsHandler(),
 		r.resolveContentUploadHandler(),
 		r.resolveScheduledRacesHandler(),
+		r.resolveTeamsHandler(),
+		r.resolveSeasonsHandler(),
 	)
 }
 
@@ -38,6 +38,7 @@ type SessionResults struct {
 	SessionFile    string           `json:"SessionFile"`
 	ChampionshipID string           `json:"ChampionshipID"`
 	RaceWeekendID  string           `json:"RaceWeekendID"`
+	PitStops       []*PitStop       `json:"PitStops,omitempty"`
 }
 
 var ErrSessionCarNotFound = errors.New("servermanager: session car not found")
@@ -780,6 +781,17 @@ func (s *SessionResults) GetTeamName(driverGUID string) string {
 	return ""
 }
 
+// GetTeamURL returns a link to the page of the Team that the driver was in, if the Team is known to Server Manager.
+func (s *SessionResults) GetTeamURL(driverGUID string) string {
+	for _, car := range s.Cars {
+		if car.Driver.GUID == driverGUID && car.Driver.TeamID != uuid.Nil {
+			return "/team/" + car.Driver.TeamID.String()
+		}
+	}
+
+	return ""
+}
+
 func (s *SessionResults) GetNumLaps(driverGUID, model string) int {
 	carID := s.FindCarIDForGUIDAndModel(driverGUID, model)
 
@@ -1121,6 +1133,7 @@ type SessionDriver struct {
 	Name      string    `json:"Name"`
 	Nation    string    `json:"Nation"`
 	Team      string    `json:"Team"`
+	TeamID    uuid.UUID `json:"TeamID"`
 	ClassID   uuid.UUID `json:"ClassID"`
 }
 
@@ -0,0 +1,359 @@
+package servermanager
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultResultsNotificationStandingsCount is the number of championship standings shown in results
+	// notifications if the server options don't specify one.
+	defaultResultsNotificationStandingsCount = 5
+
+	resultsNotificationPodiumSize    = 3
+	resultsNotificationIncidentCount = 3
+)
+
+// ResultsSummaryDriver is a driver's finishing position in a session.
+type ResultsSummaryDriver struct {
+	Position   int
+	DriverName string
+	CarName    string
+	Time       string
+}
+
+// ResultsSummaryLap is the fastest lap of a session.
+type ResultsSummaryLap struct {
+	DriverName string
+	CarName    string
+	LapTime    string
+}
+
+// ResultsSummaryStanding is a driver's position in the championship standings after a session, and how many
+// places they have moved since before the session.
+type ResultsSummaryStanding struct {
+	Position   int
+	DriverName string
+	Points     float64
+	Movement   int
+	New        bool
+}
+
+// MovementArrow shows whether the driver has moved up or down the standings, e.g. "▲2".
+func (s ResultsSummaryStanding) MovementArrow() string {
+	switch {
+	case s.New:
+		return "(new)"
+	case s.Movement > 0:
+		return fmt.Sprintf("▲%d", s.Movement)
+	case s.Movement < 0:
+		return fmt.Sprintf("▼%d", -s.Movement)
+	default:
+		return "–"
+	}
+}
+
+// ResultsSummaryIncident is a disqualification, penalty or collision in a session. Collisions have an
+// OtherDriverName.
+type ResultsSummaryIncident struct {
+	DriverName      string
+	OtherDriverName string
+	Disqualified    bool
+	LapPenalty      int
+	Penalty         string
+	ImpactSpeed     int
+
+	language string
+}
+
+// String describes the incident in the language of the notification it is in, or English if it is not in one.
+func (i ResultsSummaryIncident) String() string {
+	var messageID string
+
+	switch {
+	case i.Disqualified:
+		messageID = "ResultsIncidentDisqualified"
+	case i.LapPenalty > 0:
+		messageID = "ResultsIncidentLapPenalty"
+	case i.Penalty != "":
+		messageID = "ResultsIncidentTimePenalty"
+	default:
+		messageID = "ResultsIncidentCollision"
+	}
+
+	lang := i.language
+
+	if lang == "" {
+		lang = defaultLanguage.String()
+	}
+
+	return getLocaliser().Localise(lang, messageID, i)
+}
+
+// ResultsSummaryClassStandings are the top championship standings of a class.
+type ResultsSummaryClassStandings struct {
+	ClassName string
+	Standings []ResultsSummaryStanding
+}
+
+// ResultsSummary is the data that results notifications are written from, see NotificationTemplateData.
+type ResultsSummary struct {
+	SessionType string
+	Track       string
+	EventName   string
+	SessionFile string
+	Date        time.Time
+
+	Podium     []ResultsSummaryDriver
+	FastestLap *ResultsSummaryLap
+	Incidents  []ResultsSummaryIncident
+
+	ChampionshipName string
+	Standings        []ResultsSummaryClassStandings
+}
+
+// NewResultsSummary summarises the podium, fastest lap and notable incidents of a session.
+func NewResultsSummary(results *SessionResults) *ResultsSummary {
+	summary := &ResultsSummary{
+		SessionType: results.Type.String(),
+		Track:       trackSummary(results.TrackName, results.TrackConfig),
+		SessionFile: results.SessionFile,
+		Date:        results.Date,
+	}
+
+	for i, result := range results.Result {
+		if len(summary.Podium) == resultsNotificationPodiumSize {
+			break
+		}
+
+		if result.DriverGUID == "" || result.Disqualified {
+			continue
+		}
+
+		driver := ResultsSummaryDriver{
+			Position:   i + 1,
+			DriverName: driverName(result.DriverName),
+			CarName:    prettifyName(result.CarModel, true),
+		}
+
+		if results.Type == SessionTypeRace && result.TotalTime > 0 {
+			driver.Time = formatDuration(results.GetTime(result.TotalTime, result.DriverGUID, result.CarModel, true), true)
+		} else if result.BestLap > 0 {
+			driver.Time = formatDuration(time.Duration(result.BestLap)*time.Millisecond, true)
+		}
+
+		summary.Podium = append(summary.Podium, driver)
+	}
+
+	if lap := results.FastestLap(); lap != nil && lap.Cuts == 0 {
+		summary.FastestLap = &ResultsSummaryLap{
+			DriverName: driverName(lap.DriverName),
+			CarName:    prettifyName(lap.CarModel, true),
+			LapTime:    formatDuration(lap.GetLapTime(), true),
+		}
+	}
+
+	summary.Incidents = resultsIncidents(results)
+
+	return summary
+}
+
+// resultsIncidents lists disqualifications, penalties and the hardest collisions between cars in a session.
+func resultsIncidents(results *SessionResults) []ResultsSummaryIncident {
+	var incidents []ResultsSummaryIncident
+
+	for _, result := range results.Result {
+		if result.DriverGUID == "" {
+			continue
+		}
+
+		if result.Disqualified {
+			incidents = append(incidents, ResultsSummaryIncident{
+				DriverName:   driverName(result.DriverName),
+				Disqualified: true,
+			})
+		} else if result.HasPenalty {
+			incident := ResultsSummaryIncident{
+				DriverName: driverName(result.DriverName),
+				LapPenalty: result.LapPenalty,
+			}
+
+			if result.LapPenalty == 0 {
+				incident.Penalty = result.PenaltyTime.String()
+			}
+
+			incidents = append(incidents, incident)
+		}
+	}
+
+	var collisions []*SessionEvent
+
+	for _, event := range results.Events {
+		if event.Type == "COLLISION_WITH_CAR" && event.Driver != nil && event.OtherDriver != nil {
+			collisions = append(collisions, event)
+		}
+	}
+
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].ImpactSpeed > collisions[j].ImpactSpeed
+	})
+
+	for i, collision := range collisions {
+		if i == resultsNotificationIncidentCount {
+			break
+		}
+
+		incidents = append(incidents, ResultsSummaryIncident{
+			DriverName:      driverName(collision.Driver.Name),
+			OtherDriverName: driverName(collision.OtherDriver.Name),
+			ImpactSpeed:     int(math.Round(collision.ImpactSpeed)),
+		})
+	}
+
+	return incidents
+}
+
+// AddChampionshipStandings adds the top standingsCount drivers of each class in the championship after the session,
+// with their movement from the standings before the session.
+func (s *ResultsSummary) AddChampionshipStandings(before, after *Championship, standingsCount int) {
+	s.ChampionshipName = after.Name
+
+	if standingsCount <= 0 {
+		standingsCount = defaultResultsNotificationStandingsCount
+	}
+
+	previousPositions := make(map[string]map[string]int)
+
+	for _, class := range before.Classes {
+		previousPositions[class.ID.String()] = make(map[string]int)
+
+		for i, standing := range class.Standings(before, before.Events) {
+			previousPositions[class.ID.String()][standing.Car.Driver.GUID] = i + 1
+		}
+	}
+
+	for _, class := range after.Classes {
+		classStandings := ResultsSummaryClassStandings{}
+
+		if len(after.Classes) > 1 {
+			classStandings.ClassName = class.Name
+		}
+
+		for i, standing := range class.Standings(after, after.Events) {
+			if i == standingsCount {
+				break
+			}
+
+			summaryStanding := ResultsSummaryStanding{
+				Position:   i + 1,
+				DriverName: driverName(standing.Car.Driver.Name),
+				Points:     standing.Points,
+			}
+
+			if previousPosition, ok := previousPositions[class.ID.String()][standing.Car.Driver.GUID]; ok {
+				summaryStanding.Movement = previousPosition - summaryStanding.Position
+			} else {
+				summaryStanding.New = true
+			}
+
+			classStandings.Standings = append(classStandings.Standings, summaryStanding)
+		}
+
+		if len(classStandings.Standings) > 0 {
+			s.Standings = append(s.Standings, classStandings)
+		}
+	}
+}
+
+// inLanguage returns a copy of the summary whose incidents are described in the given language.
+func (s *ResultsSummary) inLanguage(lang string) *ResultsSummary {
+	summary := *s
+	summary.Incidents = make([]ResultsSummaryIncident, len(s.Incidents))
+
+	for i, incident := range s.Incidents {
+		incident.language = lang
+		summary.Incidents[i] = incident
+	}
+
+	return &summary
+}
+
+// pendingResultsSummary is the state of the server when a session ends, before the ChampionshipManager and
+// RaceWeekendManager have saved the results of the session.
+type pendingResultsSummary struct {
+	filename  string
+	eventName string
+
+	// championship is the championship that the session is part of, as it was before the session. It is nil if the
+	// session is not part of a championship.
+	championship *Championship
+}
+
+// newPendingResultsSummary records the championship standings before the results of a session are saved, so that
+// the movement of each driver can be shown. Race weekends count towards the standings of their championship.
+func newPendingResultsSummary(store Store, raceEvent RaceEvent, filename string) *pendingResultsSummary {
+	pending := &pendingResultsSummary{
+		filename:  filename,
+		eventName: raceEvent.EventName(),
+	}
+
+	var championshipID uuid.UUID
+
+	switch event := raceEvent.(type) {
+	case *ActiveChampionship:
+		championshipID = event.ChampionshipID
+	case *ActiveRaceWeekend:
+		championshipID = event.ChampionshipID
+	}
+
+	if championshipID == uuid.Nil {
+		return pending
+	}
+
+	championship, err := loadChampionship(store, championshipID.String())
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not load championship for results notification")
+		return pending
+	}
+
+	pending.championship = championship
+
+	return pending
+}
+
+// build summarises the results of the session once they have been saved, including any penalties applied by the
+// RaceWeekendManager and ChampionshipManager.
+func (p *pendingResultsSummary) build(store Store) (*ResultsSummary, error) {
+	results, err := LoadResult(p.filename, LoadResultWithoutPluginFire)
+
+	if err != nil {
+		return nil, err
+	}
+
+	serverOpts, err := store.LoadServerOptions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	summary := NewResultsSummary(results)
+	summary.EventName = p.eventName
+
+	if p.championship != nil {
+		championship, err := loadChampionship(store, p.championship.ID.String())
+
+		if err != nil {
+			logrus.WithError(err).Errorf("Could not load championship for results notification")
+		} else {
+			summary.AddChampionshipStandings(p.championship, championship, serverOpts.ResultsNotificationStandingsCount)
+		}
+	}
+
+	return summary, nil
+}
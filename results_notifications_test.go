@@ -0,0 +1,205 @@
+package servermanager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testResultsSummary = &ResultsSummary{
+	SessionType: "Race",
+	Track:       "Barbagello",
+	EventName:   "Test Championship",
+	Podium: []ResultsSummaryDriver{
+		{Position: 1, DriverName: "Driver One", CarName: "Ferrari 458 GT2", Time: "20:01.123"},
+		{Position: 2, DriverName: "Driver Two", CarName: "Ferrari 458 GT2", Time: "20:02.456"},
+		{Position: 3, DriverName: "Driver Three", CarName: "Ferrari 458 GT2", Time: "20:05.789"},
+	},
+	FastestLap: &ResultsSummaryLap{DriverName: "Driver Two", CarName: "Ferrari 458 GT2", LapTime: "01:02.345"},
+	Incidents: []ResultsSummaryIncident{
+		{DriverName: "Driver Four", Penalty: "5s"},
+		{DriverName: "Driver One", OtherDriverName: "Driver Three", ImpactSpeed: 54},
+	},
+	Standings: []ResultsSummaryClassStandings{
+		{
+			Standings: []ResultsSummaryStanding{
+				{Position: 1, DriverName: "Driver Two", Points: 43, Movement: 1},
+				{Position: 2, DriverName: "Driver One", Points: 40, Movement: -1},
+				{Position: 3, DriverName: "Driver Four", Points: 12},
+				{Position: 4, DriverName: "Driver Three", Points: 10, New: true},
+			},
+		},
+	},
+}
+
+func TestResultsNotification(t *testing.T) {
+	t.Run("Default template", func(t *testing.T) {
+		title, msg, err := resultsNotification(&GlobalServerConfig{}, testResultsSummary)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if title != "Race results - Barbagello" {
+			t.Logf("Unexpected results title: %s", title)
+			t.Fail()
+		}
+
+		for _, expected := range []string{
+			"Test Championship - Race at Barbagello",
+			"P1: Driver One (Ferrari 458 GT2) 20:01.123",
+			"Fastest lap: Driver Two (Ferrari 458 GT2) 01:02.345",
+			"- Driver Four received a 5s penalty",
+			"- Driver One and Driver Three collided at 54 km/h",
+			"1. Driver Two - 43 pts ▲1",
+			"2. Driver One - 40 pts ▼1",
+			"3. Driver Four - 12 pts –",
+			"4. Driver Three - 10 pts (new)",
+		} {
+			if !strings.Contains(msg, expected) {
+				t.Logf("Expected results message to contain %q, got: %s", expected, msg)
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("Custom template", func(t *testing.T) {
+		serverOpts := &GlobalServerConfig{}
+		serverOpts.NotificationTemplates.Set(NotificationResultsPosted, "Results\n{{ range .Podium }}{{ .DriverName }};{{ end }}{{ range .Incidents }}{{ . }};{{ end }}")
+
+		_, msg, err := resultsNotification(serverOpts, testResultsSummary)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if msg != "Driver One;Driver Two;Driver Three;Driver Four received a 5s penalty;Driver One and Driver Three collided at 54 km/h;" {
+			t.Logf("Unexpected results message: %s", msg)
+			t.Fail()
+		}
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		serverOpts := &GlobalServerConfig{}
+		serverOpts.NotificationTemplates.Set(NotificationResultsPosted, "{{ range .Podium }}")
+
+		title, _, err := resultsNotification(serverOpts, testResultsSummary)
+
+		if err != nil || title != "Race results - Barbagello" {
+			t.Logf("Expected an invalid template to fall back to the default template, got title: %q, err: %v", title, err)
+			t.Fail()
+		}
+	})
+}
+
+func TestResultsNotification_Language(t *testing.T) {
+	if err := InitLocalisation(NewFilesystemLocalisationLoader("cmd/server-manager/localisation")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer InitLocalisation(nil)
+
+	serverOpts := &GlobalServerConfig{NotificationLanguage: "es"}
+	serverOpts.NotificationTemplates.Set(NotificationResultsPosted, "Results\n{{ range .Incidents }}{{ . }};{{ end }}")
+
+	_, msg, err := resultsNotification(serverOpts, testResultsSummary)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if msg != "Driver Four recibió una penalización de 5s;Driver One y Driver Three chocaron a 54 km/h;" {
+		t.Logf("Expected the incidents to be written in the notification language, got: %s", msg)
+		t.Fail()
+	}
+
+	if testResultsSummary.Incidents[0].String() != "Driver Four received a 5s penalty" {
+		t.Log("Expected rendering a notification to leave the summary's incidents in English")
+		t.Fail()
+	}
+}
+
+func TestResultsSummary_AddChampionshipStandingsRaceWeekend(t *testing.T) {
+	championship := seasonTestChampionship("Weekend Cup", "1", "2", "3")
+	class := championship.Classes[0]
+
+	// the race weekend's race is finished in a different order to the championship's first race.
+	weekendResults := seasonTestChampionship("Weekend Cup", "3", "1", "2").Events[0].Sessions[SessionTypeRace].Results
+
+	for _, car := range weekendResults.Cars {
+		car.Driver.ClassID = class.ID
+	}
+
+	for _, lap := range weekendResults.Laps {
+		lap.ClassID = class.ID
+	}
+
+	for _, result := range weekendResults.Result {
+		result.ClassID = class.ID
+	}
+
+	raceWeekendEvent := func(results *SessionResults) *ChampionshipEvent {
+		session := NewRaceWeekendSession()
+		session.RaceConfig.Sessions = Sessions{SessionTypeRace: &SessionConfig{Name: "Race", Laps: 10}}
+		session.Points = map[uuid.UUID]*ChampionshipPoints{class.ID: &class.Points}
+
+		if results != nil {
+			session.CompletedTime = time.Now()
+			session.Results = results
+		}
+
+		raceWeekend := NewRaceWeekend()
+		raceWeekend.AddSession(session, nil)
+
+		event := NewChampionshipEvent()
+		event.RaceWeekendID = raceWeekend.ID
+		event.RaceWeekend = raceWeekend
+
+		return event
+	}
+
+	before := *championship
+	before.Events = append([]*ChampionshipEvent{}, championship.Events[0], raceWeekendEvent(nil))
+
+	after := *championship
+	after.Events = append([]*ChampionshipEvent{}, championship.Events[0], raceWeekendEvent(weekendResults))
+
+	summary := &ResultsSummary{}
+	summary.AddChampionshipStandings(&before, &after, 0)
+
+	if summary.ChampionshipName != "Weekend Cup" || len(summary.Standings) != 1 {
+		t.Logf("Expected the standings of the race weekend's championship, got: %s with %d classes", summary.ChampionshipName, len(summary.Standings))
+		t.Fail()
+		return
+	}
+
+	expected := []struct {
+		name     string
+		movement int
+	}{
+		{"Driver 1", 0},
+		{"Driver 3", 1},
+		{"Driver 2", -1},
+	}
+
+	standings := summary.Standings[0].Standings
+
+	if len(standings) != len(expected) {
+		t.Logf("Expected %d standings, got: %d", len(expected), len(standings))
+		t.Fail()
+		return
+	}
+
+	for i, standing := range standings {
+		if standing.DriverName != expected[i].name || standing.Movement != expected[i].movement || standing.New {
+			t.Logf("Expected P%d to be %s (%d), got: %s (%d)", i+1, expected[i].name, expected[i].movement, standing.DriverName, standing.Movement)
+			t.Fail()
+		}
+	}
+}
@@ -54,6 +54,8 @@ func Router(
 	resultsHandler *ResultsHandler,
 	contentUploadHandler *ContentUploadHandler,
 	scheduledRacesHandler *ScheduledRacesHandler,
+	teamsHandler *TeamsHandler,
+	seasonsHandler *SeasonsHandler,
 ) http.Handler {
 	r := chi.NewRouter()
 
@@ -66,6 +68,9 @@ func Router(
 	r.HandleFunc("/logout", accountHandler.logout)
 	r.Handle("/metrics", prometheusMonitoringHandler())
 
+	// signed calendar feeds, available to calendar apps without logging in
+	r.Get("/calendar/{kind}/{id}/{signature}", scheduledRacesHandler.calendarFeed)
+
 	if Debug {
 		r.Mount("/debug/", middleware.Profiler())
 	}
@@ -93,6 +98,16 @@ func Router(
 		r.Get("/calendar", scheduledRacesHandler.calendar)
 		r.Get("/calendar.json", scheduledRacesHandler.calendarJSON)
 
+		// teams
+		r.Get("/teams", teamsHandler.list)
+		r.Get("/team/{teamID}", teamsHandler.view)
+
+		// seasons
+		r.Get("/seasons", seasonsHandler.list)
+		r.Get("/season/{seasonID}", seasonsHandler.view)
+		r.Get("/season/{seasonID}/ics", seasonsHandler.icalFeed)
+		r.Get("/api/season/{seasonID}", seasonsHandler.api)
+
 		// account management
 		r.HandleFunc("/accounts/new-password", accountHandler.newPassword)
 		r.HandleFunc("/accounts/update", accountHandler.update)
@@ -121,6 +136,19 @@ func Router(
 		// results
 		r.Post("/results/{fileName}/edit", resultsHandler.edit)
 
+		// teams
+		r.Get("/teams/new", teamsHandler.createOrEdit)
+		r.Post("/teams/new/submit", teamsHandler.submit)
+		r.Get("/team/{teamID}/edit", teamsHandler.createOrEdit)
+		r.Post("/team/{teamID}/roster", teamsHandler.addDriver)
+		r.Get("/team/{teamID}/championship/{championshipID}/driver/{driverGUID}/remove", teamsHandler.removeDriver)
+		r.Post("/team/{teamID}/championship/{championshipID}/driver/{driverGUID}/transfer", teamsHandler.transferDriver)
+
+		// seasons
+		r.Get("/seasons/new", seasonsHandler.createOrEdit)
+		r.Post("/seasons/new/submit", seasonsHandler.submit)
+		r.Get("/season/{seasonID}/edit", seasonsHandler.createOrEdit)
+
 		// endpoints
 		r.Post("/api/track/upload", contentUploadHandler.upload(ContentTypeTrack))
 		r.Post("/api/car/upload", contentUploadHandler.upload(ContentTypeCar))
@@ -140,6 +168,8 @@ func Router(
 		r.Post("/car/{name}/skin/delete", carsHandler.deleteSkin)
 		r.Get("/weather/delete/{key}", weatherHandler.delete)
 		r.Get("/setups/delete/{car}/{track}/{setup}", carSetupDeleteHandler)
+		r.Get("/team/{teamID}/delete", teamsHandler.delete)
+		r.Get("/season/{seasonID}/delete", seasonsHandler.delete)
 
 	})
 
@@ -29,6 +29,90 @@ type ScheduledEvent interface {
 	GetRecurrenceRule() (*rrule.RRule, error)
 	SetRecurrenceRule(input string) error
 	ClearRecurrenceRule()
+	GetRecurrenceSet() (*rrule.Set, error)
+	OccurrenceFor(start time.Time) time.Time
+	OccurrenceStart(occurrence time.Time) time.Time
+	FindRecurrenceOverrideForStart(start time.Time) *RecurrenceOverride
+	SkipOccurrence(occurrence time.Time)
+	AddOccurrence(occurrence time.Time)
+	OverrideOccurrence(override *RecurrenceOverride)
+	EndRecurrence(until time.Time) error
+	SetScheduledTime(scheduled time.Time)
+	GetScheduledServerID() ServerID
+	SetScheduledServerID(serverID ServerID)
+
+	GetMissedEventPolicy() MissedEventPolicy
+	SetMissedEventPolicy(policy MissedEventPolicy)
+	RecordMissedEvent(missedEvent *MissedEvent)
+	LastMissedEvent() *MissedEvent
+}
+
+// ScheduledEventBase holds the schedule of an event which can be run by the Scheduler.
+type ScheduledEventBase struct {
+	Scheduled         time.Time
+	ScheduledInitial  time.Time
+	Recurrence        string
+	ScheduledServerID ServerID
+
+	// RecurrenceExceptions are occurrences removed from the series (EXDATE), e.g. for a holiday.
+	RecurrenceExceptions []time.Time
+	// RecurrenceAdditions are extra occurrences added to the series (RDATE).
+	RecurrenceAdditions []time.Time
+	// RecurrenceOverrides change the start time or track of single occurrences.
+	RecurrenceOverrides []*RecurrenceOverride
+
+	MissedEventCatchUp
+}
+
+func (seb *ScheduledEventBase) GetScheduledTime() time.Time {
+	return seb.Scheduled
+}
+
+func (seb *ScheduledEventBase) SetScheduledTime(scheduled time.Time) {
+	seb.Scheduled = scheduled
+}
+
+func (seb *ScheduledEventBase) GetScheduledServerID() ServerID {
+	return seb.ScheduledServerID
+}
+
+func (seb *ScheduledEventBase) SetScheduledServerID(serverID ServerID) {
+	seb.ScheduledServerID = serverID
+}
+
+func (seb *ScheduledEventBase) HasRecurrenceRule() bool {
+	return seb.Recurrence != ""
+}
+
+func (seb *ScheduledEventBase) GetRecurrenceRule() (*rrule.RRule, error) {
+	rule, err := rrule.StrToRRule(seb.Recurrence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rule.DTStart(seb.ScheduledInitial)
+
+	return rule, nil
+}
+
+func (seb *ScheduledEventBase) SetRecurrenceRule(input string) error {
+	rule, err := rrule.StrToRRule(input)
+
+	if err != nil {
+		return err
+	}
+
+	seb.Recurrence = rule.String()
+
+	return nil
+}
+
+func (seb *ScheduledEventBase) ClearRecurrenceRule() {
+	seb.Recurrence = ""
+	seb.RecurrenceExceptions = nil
+	seb.RecurrenceAdditions = nil
+	seb.RecurrenceOverrides = nil
 }
 
 func BuildICalEvent(event ScheduledEvent) *components.Event {
@@ -156,6 +240,21 @@ func NewScheduledRacesManager(store Store) *ScheduledRacesManager {
 }
 
 func (srm *ScheduledRacesManager) getScheduledRaces() ([]ScheduledEvent, error) {
+	return srm.listEvents(func(event ScheduledEvent) bool {
+		return !event.GetScheduledTime().IsZero()
+	})
+}
+
+// getSkippedRaces returns events which were skipped because their start was missed while the server was offline.
+func (srm *ScheduledRacesManager) getSkippedRaces() ([]ScheduledEvent, error) {
+	return srm.listEvents(func(event ScheduledEvent) bool {
+		missedEvent := event.LastMissedEvent()
+
+		return event.GetScheduledTime().IsZero() && missedEvent != nil && missedEvent.Outcome == MissedEventSkipped
+	})
+}
+
+func (srm *ScheduledRacesManager) listEvents(include func(event ScheduledEvent) bool) ([]ScheduledEvent, error) {
 	customRaces, err := srm.store.ListCustomRaces()
 
 	if err != nil {
@@ -165,7 +264,7 @@ func (srm *ScheduledRacesManager) getScheduledRaces() ([]ScheduledEvent, error)
 	var scheduled []ScheduledEvent
 
 	for _, race := range customRaces {
-		if race.Scheduled.IsZero() {
+		if !include(race) {
 			continue
 		}
 
@@ -180,7 +279,7 @@ func (srm *ScheduledRacesManager) getScheduledRaces() ([]ScheduledEvent, error)
 
 	for _, championship := range championships {
 		for _, event := range championship.Events {
-			if event.Scheduled.IsZero() {
+			if !include(event) {
 				continue
 			}
 
@@ -202,6 +301,17 @@ func (srm *ScheduledRacesManager) buildScheduledRaces(w io.Writer) error {
 	cal := components.NewCalendar()
 
 	for _, event := range scheduled {
+		if customRace, ok := event.(*CustomRace); ok && customRace.HasRecurrenceRule() {
+			icalEvents, err := BuildRecurringICalEvents(customRace)
+
+			if err != nil {
+				return err
+			}
+
+			cal.Events = append(cal.Events, icalEvents...)
+			continue
+		}
+
 		icalEvent := BuildICalEvent(event)
 
 		cal.Events = append(cal.Events, icalEvent)
@@ -249,6 +359,12 @@ func (srm *ScheduledRacesManager) buildCalendar(start time.Time, end time.Time)
 		return nil, err
 	}
 
+	skipped, err := srm.getSkippedRaces()
+
+	if err != nil {
+		return nil, err
+	}
+
 	var calendarObjects []CalendarObject
 
 	if len(scheduled) == 0 {
@@ -274,37 +390,13 @@ func (srm *ScheduledRacesManager) buildCalendar(start time.Time, end time.Time)
 		})
 	}
 
-	var recurring []ScheduledEvent
-
-	for _, scheduledEvent := range scheduled {
-		if scheduledEvent.HasRecurrenceRule() {
-			customRace, ok := scheduledEvent.(*CustomRace)
-
-			if !ok {
-				continue
-			}
-
-			rule, err := customRace.GetRecurrenceRule()
-
-			if err != nil {
-				return nil, err
-			}
+	scheduled, err = expandRecurringEvents(scheduled, start, end)
 
-			for _, startTime := range rule.Between(start, end, true) {
-				newEvent := *customRace
-				newEvent.Scheduled = startTime
-				newEvent.UUID = uuid.New()
-
-				if customRace.GetScheduledTime() == newEvent.GetScheduledTime() {
-					continue
-				}
-
-				recurring = append(recurring, &newEvent)
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	scheduled = append(scheduled, recurring...)
+	scheduled = append(scheduled, skipped...)
 
 	return BuildCalObject(scheduled, calendarObjects)
 }
@@ -314,7 +406,18 @@ func BuildCalObject(scheduled []ScheduledEvent, calendarObjects []CalendarObject
 
 		var prevSessionTime time.Duration
 		start := scheduledEvent.GetScheduledTime()
-		end := scheduledEvent.GetScheduledTime()
+
+		// events which were missed while the server was offline show what the scheduler did about it.
+		missedEvent := scheduledEvent.LastMissedEvent()
+
+		if missedEvent != nil && start.IsZero() {
+			start = missedEvent.ScheduledTime
+		} else if missedEvent != nil && !missedEvent.RescheduledTime.Equal(start) && !missedEvent.ScheduledTime.Equal(start) {
+			// the missed event was for an earlier start
+			missedEvent = nil
+		}
+
+		end := start
 
 		var sessionTypes []SessionType
 
@@ -397,14 +500,34 @@ func BuildCalObject(scheduled []ScheduledEvent, calendarObjects []CalendarObject
 
 			textColor = "#303030"
 
+			title := GenerateSummary(scheduledEvent.GetRaceSetup(), session.Name) + " " + scheduledEvent.GetSummary()
+			description := carList(scheduledEvent.GetRaceSetup().Cars) + ": " + scheduledEvent.ReadOnlyEntryList().Entrants()
+
+			if override := scheduledEvent.FindRecurrenceOverrideForStart(scheduledEvent.GetScheduledTime()); override != nil {
+				classNames = append(classNames, "calendar-recurrence-override")
+				description = override.Description() + " " + description
+			}
+
+			if missedEvent != nil {
+				classNames = append(classNames, "calendar-missed")
+				description = missedEvent.Message + " " + description
+
+				if missedEvent.Outcome == MissedEventSkipped {
+					title = "(Skipped) " + title
+					backgroundColor = "#d3d3d3"
+				} else if missedEvent.Outcome == MissedEventRescheduled {
+					title = "(Rescheduled) " + title
+				}
+			}
+
 			calendarObjects = append(calendarObjects, CalendarObject{
 				ID:               scheduledEvent.GetID().String() + session.Name,
 				GroupID:          scheduledEvent.GetID().String(),
 				AllDay:           false,
 				Start:            start,
 				End:              end,
-				Title:            GenerateSummary(scheduledEvent.GetRaceSetup(), session.Name) + " " + scheduledEvent.GetSummary(),
-				Description:      carList(scheduledEvent.GetRaceSetup().Cars) + ": " + scheduledEvent.ReadOnlyEntryList().Entrants(),
+				Title:            title,
+				Description:      description,
 				URL:              pageURL,
 				SignUpURL:        signUpURL,
 				ClassNames:       classNames,
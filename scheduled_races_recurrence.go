@@ -0,0 +1,844 @@
+package servermanager
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/heindl/caldav-go/icalendar/components"
+	"github.com/heindl/caldav-go/icalendar/values"
+	"github.com/sirupsen/logrus"
+	"github.com/teambition/rrule-go"
+)
+
+// maxRecurrenceLookahead limits how many occurrences are checked when looking for the next occurrence of a series.
+const maxRecurrenceLookahead = 100
+
+var ErrEventHasNoRecurrence = errors.New("servermanager: event has no recurrence rule")
+
+// RecurrenceScope is how much of a series is changed when editing an occurrence of a recurring event.
+type RecurrenceScope string
+
+const (
+	RecurrenceScopeThisOccurrence    RecurrenceScope = "this"
+	RecurrenceScopeFutureOccurrences RecurrenceScope = "future"
+)
+
+// A RecurrenceOverride changes a single occurrence of a recurring event.
+type RecurrenceOverride struct {
+	// OccurrenceTime is when the occurrence would start according to the recurrence rule. It identifies the
+	// occurrence (the RECURRENCE-ID in iCal).
+	OccurrenceTime time.Time
+
+	// Scheduled moves the occurrence. If zero, the occurrence starts at its OccurrenceTime.
+	Scheduled time.Time
+
+	// Track and TrackLayout run the occurrence at a different track. If Track is empty, the event's track is used.
+	Track       string
+	TrackLayout string
+}
+
+// StartTime is when the overridden occurrence starts.
+func (ro *RecurrenceOverride) StartTime() time.Time {
+	if ro.Scheduled.IsZero() {
+		return ro.OccurrenceTime
+	}
+
+	return ro.Scheduled
+}
+
+// Apply changes the race setup of the occurrence.
+func (ro *RecurrenceOverride) Apply(raceSetup *CurrentRaceConfig) {
+	if ro.Track == "" {
+		return
+	}
+
+	raceSetup.Track = ro.Track
+	raceSetup.TrackLayout = ro.TrackLayout
+}
+
+// Description explains the override, e.g. for the calendar.
+func (ro *RecurrenceOverride) Description() string {
+	description := "This occurrence has been changed"
+
+	if !ro.Scheduled.IsZero() && !ro.Scheduled.Equal(ro.OccurrenceTime) {
+		description += fmt.Sprintf(", it was due to start at %s", ro.OccurrenceTime.Format(time.RFC1123))
+	}
+
+	if ro.Track != "" {
+		description += fmt.Sprintf(", it is being held at %s", trackSummary(ro.Track, ro.TrackLayout))
+	}
+
+	return description + "."
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, other := range times {
+		if other.Equal(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeTime(times []time.Time, t time.Time) []time.Time {
+	var out []time.Time
+
+	for _, other := range times {
+		if !other.Equal(t) {
+			out = append(out, other)
+		}
+	}
+
+	return out
+}
+
+// GetRecurrenceSet is the recurrence rule along with its additions (RDATE) and exceptions (EXDATE).
+func (seb *ScheduledEventBase) GetRecurrenceSet() (*rrule.Set, error) {
+	rule, err := seb.GetRecurrenceRule()
+
+	if err != nil {
+		return nil, err
+	}
+
+	set := &rrule.Set{}
+	set.RRule(rule)
+
+	for _, addition := range seb.RecurrenceAdditions {
+		set.RDate(addition)
+	}
+
+	for _, exception := range seb.RecurrenceExceptions {
+		set.ExDate(exception)
+	}
+
+	return set, nil
+}
+
+// FindRecurrenceOverride finds the override for an occurrence, or nil if the occurrence is unchanged.
+func (seb *ScheduledEventBase) FindRecurrenceOverride(occurrence time.Time) *RecurrenceOverride {
+	for _, override := range seb.RecurrenceOverrides {
+		if override.OccurrenceTime.Equal(occurrence) {
+			return override
+		}
+	}
+
+	return nil
+}
+
+// FindRecurrenceOverrideForStart finds the override for the occurrence which starts at start.
+func (seb *ScheduledEventBase) FindRecurrenceOverrideForStart(start time.Time) *RecurrenceOverride {
+	for _, override := range seb.RecurrenceOverrides {
+		if override.StartTime().Equal(start) {
+			return override
+		}
+	}
+
+	return nil
+}
+
+// OccurrenceFor finds the occurrence which starts at start, taking moved occurrences into account.
+func (seb *ScheduledEventBase) OccurrenceFor(start time.Time) time.Time {
+	if override := seb.FindRecurrenceOverrideForStart(start); override != nil {
+		return override.OccurrenceTime
+	}
+
+	return start
+}
+
+// OccurrenceStart is when an occurrence starts, taking moved occurrences into account.
+func (seb *ScheduledEventBase) OccurrenceStart(occurrence time.Time) time.Time {
+	if override := seb.FindRecurrenceOverride(occurrence); override != nil {
+		return override.StartTime()
+	}
+
+	return occurrence
+}
+
+// UpcomingOccurrences lists the next occurrences of the series, by the time the recurrence rule gives them.
+func (seb *ScheduledEventBase) UpcomingOccurrences(n int) []time.Time {
+	if !seb.HasRecurrenceRule() {
+		return nil
+	}
+
+	set, err := seb.GetRecurrenceSet()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not get recurrence set")
+		return nil
+	}
+
+	var occurrences []time.Time
+
+	for occurrence := set.After(time.Now(), true); !occurrence.IsZero() && len(occurrences) < n; occurrence = set.After(occurrence, false) {
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences
+}
+
+// SkipOccurrence removes an occurrence from the series.
+func (seb *ScheduledEventBase) SkipOccurrence(occurrence time.Time) {
+	seb.removeRecurrenceOverride(occurrence)
+
+	if containsTime(seb.RecurrenceAdditions, occurrence) {
+		seb.RecurrenceAdditions = removeTime(seb.RecurrenceAdditions, occurrence)
+		return
+	}
+
+	if !containsTime(seb.RecurrenceExceptions, occurrence) {
+		seb.RecurrenceExceptions = append(seb.RecurrenceExceptions, occurrence)
+	}
+}
+
+// AddOccurrence adds an extra occurrence to the series.
+func (seb *ScheduledEventBase) AddOccurrence(occurrence time.Time) {
+	if containsTime(seb.RecurrenceExceptions, occurrence) {
+		seb.RecurrenceExceptions = removeTime(seb.RecurrenceExceptions, occurrence)
+		return
+	}
+
+	if !containsTime(seb.RecurrenceAdditions, occurrence) {
+		seb.RecurrenceAdditions = append(seb.RecurrenceAdditions, occurrence)
+	}
+}
+
+// OverrideOccurrence changes a single occurrence of the series, replacing any existing override for it.
+func (seb *ScheduledEventBase) OverrideOccurrence(override *RecurrenceOverride) {
+	seb.removeRecurrenceOverride(override.OccurrenceTime)
+
+	seb.RecurrenceOverrides = append(seb.RecurrenceOverrides, override)
+}
+
+func (seb *ScheduledEventBase) removeRecurrenceOverride(occurrence time.Time) {
+	var overrides []*RecurrenceOverride
+
+	for _, override := range seb.RecurrenceOverrides {
+		if !override.OccurrenceTime.Equal(occurrence) {
+			overrides = append(overrides, override)
+		}
+	}
+
+	seb.RecurrenceOverrides = overrides
+}
+
+// EndRecurrence ends the series at until. Occurrences added or changed after until are removed.
+func (seb *ScheduledEventBase) EndRecurrence(until time.Time) error {
+	if !seb.HasRecurrenceRule() {
+		return ErrEventHasNoRecurrence
+	}
+
+	option, err := rrule.StrToROption(seb.Recurrence)
+
+	if err != nil {
+		return err
+	}
+
+	option.Until = until.UTC()
+	option.Count = 0
+
+	rule, err := rrule.NewRRule(*option)
+
+	if err != nil {
+		return err
+	}
+
+	seb.Recurrence = rule.String()
+
+	var additions []time.Time
+
+	for _, addition := range seb.RecurrenceAdditions {
+		if !addition.After(until) {
+			additions = append(additions, addition)
+		}
+	}
+
+	seb.RecurrenceAdditions = additions
+
+	var overrides []*RecurrenceOverride
+
+	for _, override := range seb.RecurrenceOverrides {
+		if !override.OccurrenceTime.After(until) {
+			overrides = append(overrides, override)
+		}
+	}
+
+	seb.RecurrenceOverrides = overrides
+
+	return nil
+}
+
+// remainingRecurrence is the recurrence rule of the series from occurrence onwards. Series which end after a number
+// of occurrences have the occurrences before occurrence taken off their count.
+func (seb *ScheduledEventBase) remainingRecurrence(occurrence time.Time) (string, error) {
+	option, err := rrule.StrToROption(seb.Recurrence)
+
+	if err != nil {
+		return "", err
+	}
+
+	if option.Count == 0 {
+		return seb.Recurrence, nil
+	}
+
+	rule, err := seb.GetRecurrenceRule()
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, ruleOccurrence := range rule.All() {
+		if ruleOccurrence.Before(occurrence) {
+			option.Count--
+		}
+	}
+
+	if option.Count < 1 {
+		// occurrence was added to the series after the rule ended, so it is the only remaining occurrence.
+		option.Count = 1
+	}
+
+	remaining, err := rrule.NewRRule(*option)
+
+	if err != nil {
+		return "", err
+	}
+
+	return remaining.String(), nil
+}
+
+// shiftRecurrence moves the exceptions, additions and overrides of a series from occurrence onwards by offset, for
+// a series which has been split with its start time moved. Earlier entries belong to the original series and are removed.
+func (seb *ScheduledEventBase) shiftRecurrence(occurrence time.Time, offset time.Duration) {
+	shift := func(times []time.Time) []time.Time {
+		var out []time.Time
+
+		for _, t := range times {
+			if !t.Before(occurrence) {
+				out = append(out, t.Add(offset))
+			}
+		}
+
+		return out
+	}
+
+	seb.RecurrenceExceptions = shift(seb.RecurrenceExceptions)
+	seb.RecurrenceAdditions = shift(seb.RecurrenceAdditions)
+
+	var overrides []*RecurrenceOverride
+
+	for _, override := range seb.RecurrenceOverrides {
+		if override.OccurrenceTime.Before(occurrence) {
+			continue
+		}
+
+		override.OccurrenceTime = override.OccurrenceTime.Add(offset)
+		overrides = append(overrides, override)
+	}
+
+	seb.RecurrenceOverrides = overrides
+}
+
+func (rws *RaceWeekendSession) GetRecurrenceSet() (*rrule.Set, error) {
+	return nil, nil
+}
+
+func (rws *RaceWeekendSession) FindRecurrenceOverrideForStart(start time.Time) *RecurrenceOverride {
+	return nil
+}
+
+func (rws *RaceWeekendSession) OccurrenceFor(start time.Time) time.Time {
+	return start
+}
+
+func (rws *RaceWeekendSession) OccurrenceStart(occurrence time.Time) time.Time {
+	return occurrence
+}
+
+func (rws *RaceWeekendSession) SkipOccurrence(occurrence time.Time) {
+	// no-op
+}
+
+func (rws *RaceWeekendSession) AddOccurrence(occurrence time.Time) {
+	// no-op
+}
+
+func (rws *RaceWeekendSession) OverrideOccurrence(override *RecurrenceOverride) {
+	// no-op
+}
+
+func (rws *RaceWeekendSession) EndRecurrence(until time.Time) error {
+	return ErrEventHasNoRecurrence
+}
+
+func (cr *CustomRace) Duplicate() (*CustomRace, error) {
+	buf := new(bytes.Buffer)
+
+	var newCustomRace CustomRace
+
+	if err := gob.NewEncoder(buf).Encode(cr); err != nil {
+		return nil, err
+	}
+
+	if err := gob.NewDecoder(buf).Decode(&newCustomRace); err != nil {
+		return nil, err
+	}
+
+	return &newCustomRace, nil
+}
+
+// occurrenceEvent is a copy of a recurring CustomRace for a single occurrence, for display in calendars.
+func (cr *CustomRace) occurrenceEvent(occurrence time.Time) *CustomRace {
+	newEvent := *cr
+	newEvent.Scheduled = cr.OccurrenceStart(occurrence)
+	newEvent.UUID = uuid.New()
+
+	if override := cr.FindRecurrenceOverride(occurrence); override != nil {
+		override.Apply(&newEvent.RaceConfig)
+	}
+
+	return &newEvent
+}
+
+// expandRecurringEvents adds the occurrences of recurring custom races which start between start and end, with their
+// overrides applied. Skipped occurrences are not included.
+func expandRecurringEvents(scheduled []ScheduledEvent, start, end time.Time) ([]ScheduledEvent, error) {
+	var expanded []ScheduledEvent
+
+	for _, scheduledEvent := range scheduled {
+		customRace, ok := scheduledEvent.(*CustomRace)
+
+		if !ok || !customRace.HasRecurrenceRule() {
+			expanded = append(expanded, scheduledEvent)
+			continue
+		}
+
+		set, err := customRace.GetRecurrenceSet()
+
+		if err != nil {
+			return nil, err
+		}
+
+		current := customRace.OccurrenceFor(customRace.GetScheduledTime())
+
+		// the event itself is the current occurrence, which may be at a different track.
+		if override := customRace.FindRecurrenceOverride(current); override != nil && override.Track != "" {
+			currentEvent := *customRace
+			override.Apply(&currentEvent.RaceConfig)
+
+			expanded = append(expanded, &currentEvent)
+		} else {
+			expanded = append(expanded, customRace)
+		}
+
+		for _, occurrence := range set.Between(start, end, true) {
+			if occurrence.Equal(current) || customRace.FindRecurrenceOverride(occurrence) != nil {
+				continue
+			}
+
+			expanded = append(expanded, customRace.occurrenceEvent(occurrence))
+		}
+
+		// overrides can move occurrences into (or out of) the range.
+		for _, override := range customRace.RecurrenceOverrides {
+			startTime := override.StartTime()
+
+			if override.OccurrenceTime.Equal(current) || startTime.Before(start) || startTime.After(end) {
+				continue
+			}
+
+			if len(set.Between(override.OccurrenceTime, override.OccurrenceTime, true)) == 0 {
+				// the occurrence has been skipped, or the series has ended
+				continue
+			}
+
+			expanded = append(expanded, customRace.occurrenceEvent(override.OccurrenceTime))
+		}
+	}
+
+	return expanded, nil
+}
+
+// iCalRecurrenceRule converts a stored recurrence rule to an iCal RRULE.
+func iCalRecurrenceRule(recurrence string) (*values.RecurrenceRule, error) {
+	option, err := rrule.StrToROption(recurrence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// DTSTART is given by the event itself
+	option.Dtstart = time.Time{}
+
+	rule := new(values.RecurrenceRule)
+
+	if err := rule.DecodeICalValue(option.String()); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// BuildRecurringICalEvents builds the iCal events of a recurring custom race from its current occurrence onwards: the
+// series with its RRULE, EXDATEs and RDATEs, followed by an event for each changed occurrence.
+func BuildRecurringICalEvents(customRace *CustomRace) ([]*components.Event, error) {
+	current := customRace.OccurrenceFor(customRace.GetScheduledTime())
+
+	series := *customRace
+	series.Scheduled = current
+
+	icalEvent := BuildICalEvent(&series)
+
+	rule, err := iCalRecurrenceRule(customRace.Recurrence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	icalEvent.AddRecurrenceRules(rule)
+
+	for _, exception := range customRace.RecurrenceExceptions {
+		icalEvent.AddRecurrenceExceptions(values.NewDateTime(exception.UTC()))
+	}
+
+	if len(customRace.RecurrenceAdditions) > 0 {
+		var additions []*values.DateTime
+
+		for _, addition := range customRace.RecurrenceAdditions {
+			additions = append(additions, values.NewDateTime(addition.UTC()))
+		}
+
+		icalEvent.RecurrenceDateTimes = values.NewRecurrenceDateTimes(additions...)
+	}
+
+	icalEvents := []*components.Event{icalEvent}
+
+	for _, override := range customRace.RecurrenceOverrides {
+		if override.OccurrenceTime.Before(current) {
+			continue
+		}
+
+		overrideEvent := BuildICalEvent(customRace.occurrenceEvent(override.OccurrenceTime))
+		overrideEvent.UID = icalEvent.UID
+		overrideEvent.RecurrenceId = values.NewDateTime(override.OccurrenceTime.UTC())
+
+		icalEvents = append(icalEvents, overrideEvent)
+	}
+
+	return icalEvents, nil
+}
+
+// nextOccurrenceStart finds when the next occurrence of a recurring event starts, after now.
+func (s *Scheduler) nextOccurrenceStart(event ScheduledEvent) (time.Time, error) {
+	set, err := event.GetRecurrenceSet()
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	occurrence := set.After(now, true)
+
+	for i := 0; i < maxRecurrenceLookahead && !occurrence.IsZero(); i++ {
+		if start := event.OccurrenceStart(occurrence); start.After(now) {
+			return start, nil
+		}
+
+		occurrence = set.After(occurrence, false)
+	}
+
+	return time.Time{}, nil
+}
+
+// rescheduleRecurringEvent moves the timer of a recurring event to its next occurrence after its series has changed.
+func (s *Scheduler) rescheduleRecurringEvent(event ScheduledEvent) error {
+	nextStart, err := s.nextOccurrenceStart(event)
+
+	if err != nil {
+		return err
+	}
+
+	if nextStart.IsZero() {
+		// the series has no more occurrences
+		s.cancelJobs(event)
+
+		return s.clearScheduledTime(event)
+	}
+
+	if !nextStart.Equal(event.GetScheduledTime()) {
+		event.SetScheduledTime(nextStart)
+
+		err := s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+			storedEvent.SetScheduledTime(nextStart)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Schedule(event, nextStart)
+}
+
+// updateRecurrence changes the series of a recurring event, persists it and reschedules the event.
+func (s *Scheduler) updateRecurrence(event ScheduledEvent, fn func(event ScheduledEvent) error) error {
+	if !event.HasRecurrenceRule() {
+		return ErrEventHasNoRecurrence
+	}
+
+	if err := fn(event); err != nil {
+		return err
+	}
+
+	var fnErr error
+
+	err := s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+		if storedEvent != event {
+			fnErr = fn(storedEvent)
+		}
+	})
+
+	if err != nil {
+		return err
+	} else if fnErr != nil {
+		return fnErr
+	}
+
+	return s.rescheduleRecurringEvent(event)
+}
+
+// SkipOccurrence removes a single occurrence of a recurring event, e.g. for a holiday.
+func (s *Scheduler) SkipOccurrence(event ScheduledEvent, occurrence time.Time) error {
+	return s.updateRecurrence(event, func(event ScheduledEvent) error {
+		event.SkipOccurrence(occurrence)
+		return nil
+	})
+}
+
+// AddOccurrence adds an extra occurrence to a recurring event.
+func (s *Scheduler) AddOccurrence(event ScheduledEvent, occurrence time.Time) error {
+	return s.updateRecurrence(event, func(event ScheduledEvent) error {
+		event.AddOccurrence(occurrence)
+		return nil
+	})
+}
+
+// EditOccurrence changes the start time or track of a single occurrence of a recurring event.
+func (s *Scheduler) EditOccurrence(event ScheduledEvent, override *RecurrenceOverride) error {
+	return s.updateRecurrence(event, func(event ScheduledEvent) error {
+		event.OverrideOccurrence(override)
+		return nil
+	})
+}
+
+// EndRecurrence ends the series of a recurring event at until.
+func (s *Scheduler) EndRecurrence(event ScheduledEvent, until time.Time) error {
+	return s.updateRecurrence(event, func(event ScheduledEvent) error {
+		return event.EndRecurrence(until)
+	})
+}
+
+// EditFutureOccurrences changes an occurrence of a recurring custom race and all occurrences after it. The series is
+// split in two: the existing race ends before the occurrence, and a copy of the race with the changes continues the
+// series.
+func (s *Scheduler) EditFutureOccurrences(customRace *CustomRace, override *RecurrenceOverride) (*CustomRace, error) {
+	if !customRace.HasRecurrenceRule() {
+		return nil, ErrEventHasNoRecurrence
+	}
+
+	future, err := customRace.Duplicate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	start := override.StartTime()
+
+	future.Recurrence, err = customRace.remainingRecurrence(override.OccurrenceTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	future.UUID = uuid.New()
+	future.Created = time.Now()
+	future.Updated = time.Time{}
+	future.ScheduledInitial = start
+	future.Scheduled = start
+	future.MissedEvents = nil
+	future.shiftRecurrence(override.OccurrenceTime, start.Sub(override.OccurrenceTime))
+	// the changes are now part of the series, rather than an override of its first occurrence
+	future.removeRecurrenceOverride(start)
+	override.Apply(&future.RaceConfig)
+
+	// the original series is ended first, so that the two series never both have the occurrence.
+	if err := s.EndRecurrence(customRace, override.OccurrenceTime.Add(-time.Second)); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpsertCustomRace(future); err != nil {
+		return nil, err
+	}
+
+	return future, s.rescheduleRecurringEvent(future)
+}
+
+func scheduleTimeFromForm(r *http.Request, dateField, timeField string) (time.Time, error) {
+	location, err := time.LoadLocation(r.FormValue("event-schedule-timezone"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("could not find location: %s", r.FormValue("event-schedule-timezone"))
+		location = time.Local
+	}
+
+	return time.ParseInLocation("2006-01-02-15:04", r.FormValue(dateField)+"-"+r.FormValue(timeField), location)
+}
+
+// editOccurrence skips or changes an occurrence of a recurring custom race, or all occurrences after it.
+func (crh *CustomRaceHandler) editOccurrence(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse edit occurrence form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	customRace, err := crh.raceManager.raceStore.FindCustomRaceByID(chi.URLParam(r, "uuid"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't find custom race")
+		http.NotFound(w, r)
+		return
+	}
+
+	occurrence, err := time.Parse(time.RFC3339, r.FormValue("occurrence"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't parse occurrence")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	override := &RecurrenceOverride{
+		OccurrenceTime: occurrence,
+		Track:          r.FormValue("occurrence-track"),
+		TrackLayout:    r.FormValue("occurrence-track-layout"),
+	}
+
+	if r.FormValue("occurrence-date") != "" {
+		override.Scheduled, err = scheduleTimeFromForm(r, "occurrence-date", "occurrence-time")
+
+		if err != nil {
+			logrus.WithError(err).Errorf("couldn't parse occurrence date")
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case r.FormValue("action") == "skip" && RecurrenceScope(r.FormValue("scope")) == RecurrenceScopeFutureOccurrences:
+		err = crh.scheduler.EndRecurrence(customRace, occurrence.Add(-time.Second))
+
+		if err == nil {
+			AddFlash(w, r, fmt.Sprintf("The series now ends before %s", occurrence.Format(time.RFC1123)))
+		}
+	case r.FormValue("action") == "skip":
+		err = crh.scheduler.SkipOccurrence(customRace, occurrence)
+
+		if err == nil {
+			AddFlash(w, r, fmt.Sprintf("The occurrence at %s will be skipped", occurrence.Format(time.RFC1123)))
+		}
+	case RecurrenceScope(r.FormValue("scope")) == RecurrenceScopeFutureOccurrences:
+		_, err = crh.scheduler.EditFutureOccurrences(customRace, override)
+
+		if err == nil {
+			AddFlash(w, r, fmt.Sprintf("The series has been changed from %s onwards", occurrence.Format(time.RFC1123)))
+		}
+	default:
+		err = crh.scheduler.EditOccurrence(customRace, override)
+
+		if err == nil {
+			AddFlash(w, r, fmt.Sprintf("The occurrence at %s has been changed", occurrence.Format(time.RFC1123)))
+		}
+	}
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't edit occurrence")
+		AddErrorFlash(w, r, "Couldn't change the occurrence")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+// addOccurrence adds an extra occurrence to a recurring custom race.
+func (crh *CustomRaceHandler) addOccurrence(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse add occurrence form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	customRace, err := crh.raceManager.raceStore.FindCustomRaceByID(chi.URLParam(r, "uuid"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't find custom race")
+		http.NotFound(w, r)
+		return
+	}
+
+	occurrence, err := scheduleTimeFromForm(r, "occurrence-date", "occurrence-time")
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't parse occurrence date")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := crh.scheduler.AddOccurrence(customRace, occurrence); err != nil {
+		logrus.WithError(err).Errorf("couldn't add occurrence")
+		AddErrorFlash(w, r, "Couldn't add the occurrence")
+	} else {
+		AddFlash(w, r, fmt.Sprintf("An extra occurrence has been added at %s", occurrence.Format(time.RFC1123)))
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+// endSeries ends the series of a recurring custom race at a date.
+func (crh *CustomRaceHandler) endSeries(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		logrus.WithError(err).Errorf("couldn't parse end series form")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	customRace, err := crh.raceManager.raceStore.FindCustomRaceByID(chi.URLParam(r, "uuid"))
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't find custom race")
+		http.NotFound(w, r)
+		return
+	}
+
+	until, err := scheduleTimeFromForm(r, "series-end-date", "series-end-time")
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't parse series end date")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := crh.scheduler.EndRecurrence(customRace, until); err != nil {
+		logrus.WithError(err).Errorf("couldn't end series")
+		AddErrorFlash(w, r, "Couldn't end the series")
+	} else {
+		AddFlash(w, r, fmt.Sprintf("The series will end at %s", until.Format(time.RFC1123)))
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
@@ -0,0 +1,150 @@
+package servermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestScheduler_EditFutureOccurrences(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	s := NewScheduler(store, nil, nil, nil, NewNotificationManager(nil, nil, store))
+
+	start := time.Now().Add(time.Hour * 24).Truncate(time.Minute)
+
+	customRace := &CustomRace{UUID: uuid.New(), Name: "Daily Race", HasCustomName: true}
+	customRace.RaceConfig.Track = "old_track"
+	customRace.ScheduledInitial = start
+	customRace.Scheduled = start
+
+	if err := customRace.SetRecurrenceRule("FREQ=DAILY;COUNT=5"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := store.UpsertCustomRace(customRace); err != nil {
+		t.Error(err)
+		return
+	}
+
+	set, err := customRace.GetRecurrenceSet()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	occurrences := set.All()
+
+	if len(occurrences) != 5 {
+		t.Logf("Expected the series to have 5 occurrences, got: %d", len(occurrences))
+		t.Fail()
+		return
+	}
+
+	// the third occurrence, and all occurrences after it, move an hour later to a new track
+	override := &RecurrenceOverride{
+		OccurrenceTime: occurrences[2],
+		Scheduled:      occurrences[2].Add(time.Hour),
+		Track:          "new_track",
+	}
+
+	future, err := s.EditFutureOccurrences(customRace, override)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	original, err := store.FindCustomRaceByID(customRace.UUID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	originalSet, err := original.GetRecurrenceSet()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if originalOccurrences := originalSet.All(); len(originalOccurrences) != 2 || !originalOccurrences[1].Equal(occurrences[1]) {
+		t.Logf("Expected the original series to end after its second occurrence, got: %v", originalOccurrences)
+		t.Fail()
+	}
+
+	storedFuture, err := store.FindCustomRaceByID(future.UUID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	futureSet, err := storedFuture.GetRecurrenceSet()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	futureOccurrences := futureSet.All()
+
+	if len(futureOccurrences) != 3 {
+		t.Logf("Expected the new series to have the 3 remaining occurrences, got: %d", len(futureOccurrences))
+		t.Fail()
+	} else if !futureOccurrences[0].Equal(override.StartTime()) {
+		t.Logf("Expected the new series to start at %s, got: %s", override.StartTime(), futureOccurrences[0])
+		t.Fail()
+	}
+
+	if storedFuture.RaceConfig.Track != "new_track" || original.RaceConfig.Track != "old_track" {
+		t.Logf("Expected only the new series to use the new track, got: %s and %s", original.RaceConfig.Track, storedFuture.RaceConfig.Track)
+		t.Fail()
+	}
+
+	if !storedFuture.GetScheduledTime().Equal(override.StartTime()) {
+		t.Logf("Expected the new series to be scheduled for %s, got: %s", override.StartTime(), storedFuture.GetScheduledTime())
+		t.Fail()
+	}
+}
+
+func TestScheduledEventBase_RemainingRecurrence(t *testing.T) {
+	start := time.Date(2030, 1, 7, 20, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		recurrence string
+		occurrence time.Time
+		expected   string
+	}{
+		{"FREQ=DAILY;COUNT=10", start.Add(time.Hour * 24 * 4), "FREQ=DAILY;COUNT=6"},
+		{"FREQ=DAILY;COUNT=10", start, "FREQ=DAILY;COUNT=10"},
+		{"FREQ=DAILY;COUNT=3", start.Add(time.Hour * 24 * 30), "FREQ=DAILY;COUNT=1"},
+		{"FREQ=DAILY", start.Add(time.Hour * 24 * 4), "FREQ=DAILY"},
+	}
+
+	for _, testCase := range testCases {
+		seb := &ScheduledEventBase{ScheduledInitial: start}
+
+		if err := seb.SetRecurrenceRule(testCase.recurrence); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		remaining, err := seb.remainingRecurrence(testCase.occurrence)
+
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if remaining != testCase.expected {
+			t.Logf("Expected the remaining recurrence of %s to be %s, got: %s", testCase.recurrence, testCase.expected, remaining)
+			t.Fail()
+		}
+	}
+}
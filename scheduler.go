@@ -2,6 +2,8 @@ package servermanager
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -14,8 +16,15 @@ type Scheduler struct {
 	notificationManager *NotificationManager
 	store               Store
 
-	timers         map[string]*time.Timer
-	reminderTimers map[string]*time.Timer
+	// serverID and serverPool are set when the Scheduler belongs to one of many servers. Each Scheduler only
+	// starts the events which are scheduled on its own server.
+	serverID   ServerID
+	serverPool SchedulerServerPool
+
+	// scheduled starts, reminders and recurrences are run by a job queue which is persisted in the store.
+	jobsMutex       sync.Mutex
+	wakeJobs        chan struct{}
+	processJobsOnce sync.Once
 }
 
 func NewScheduler(store Store, raceManager *RaceManager, championshipManager *ChampionshipManager, raceWeekendManager *RaceWeekendManager, notificationManager *NotificationManager) *Scheduler {
@@ -26,14 +35,17 @@ func NewScheduler(store Store, raceManager *RaceManager, championshipManager *Ch
 		championshipManager: championshipManager,
 		raceWeekendManager:  raceWeekendManager,
 
-		timers:         make(map[string]*time.Timer),
-		reminderTimers: make(map[string]*time.Timer),
+		wakeJobs: make(chan struct{}, 1),
 	}
 }
 
 var ErrInvalidScheduleTime = errors.New("servermanager: invalid schedule time")
 
 func (s *Scheduler) Init() error {
+	if err := s.recoverJobs(); err != nil {
+		return err
+	}
+
 	// load all custom races, championships, race weekends. if scheduled is after now, then schedule it
 	customRaces, err := s.store.ListCustomRaces()
 
@@ -69,6 +81,8 @@ func (s *Scheduler) Init() error {
 		}
 	}
 
+	s.processJobs()
+
 	return nil
 }
 
@@ -78,33 +92,22 @@ func (s *Scheduler) scheduleExistingEvent(event ScheduledEvent) {
 		return
 	}
 
+	if !s.runsEvent(event) {
+		// this event is run by the Scheduler of another server
+		return
+	}
+
 	if event.GetScheduledTime().After(time.Now()) {
 		err := s.Schedule(event, event.GetScheduledTime())
 
 		if err != nil {
 			logrus.WithError(err).Error("Could not schedule event (%s)", event.EventName())
 		}
-	} else if event.HasRecurrenceRule() {
-		if !event.GetScheduledTime().IsZero() {
-			logrus.Infof("Looks like the server was offline whilst a recurring scheduled event was meant to start!"+
-				" Start time: %s. The schedule has been cleared, and the next recurrence time has been set."+
-				" Start the event manually if you wish to run it.", event.GetScheduledTime().String())
-		}
-
-		err := s.Schedule(event, s.findNextRecurrence(event, event.GetScheduledTime()))
-
-		if err != nil {
-			logrus.WithError(err).Errorf("Couldn't schedule next recurring event (%s)", event.EventName())
-		}
 	} else {
-		logrus.Infof("Looks like the server was offline whilst a scheduled event was meant to start!"+
-			" Start time: %s. The schedule has been cleared. Start the event manually if you wish to run it.", event.GetScheduledTime().String())
-
-		err := s.clearScheduledTime(event)
-
-		if err != nil {
-			logrus.WithError(err).Error("Could not clear event scheduled time")
-		}
+		// the server was offline whilst the event was meant to start. its queued jobs are cancelled so that the
+		// missed event policy decides whether it is started.
+		s.cancelJobs(event)
+		s.catchUpMissedEvent(event)
 	}
 }
 
@@ -113,66 +116,58 @@ func (s *Scheduler) Schedule(event ScheduledEvent, startTime time.Time) error {
 		return ErrInvalidScheduleTime
 	}
 
-	if timer := s.timers[event.GetID().String()]; timer != nil {
-		timer.Stop()
-	}
-
-	if reminderTimer := s.reminderTimers[event.GetID().String()]; reminderTimer != nil {
-		reminderTimer.Stop()
-	}
-
-	s.timers[event.GetID().String()] = time.AfterFunc(time.Until(startTime), func() {
-		err := s.startEvent(event)
+	if !s.runsEvent(event) {
+		if scheduler := s.schedulerForServer(s.serverForEvent(event)); scheduler != nil {
+			// the event has moved to another server, so it must only be run there.
+			s.cancelJobs(event)
 
-		if err != nil {
-			logrus.WithError(err).Errorf("Could not start scheduled event")
-			return
+			return scheduler.Schedule(event, startTime)
 		}
+	}
 
-		if event.HasRecurrenceRule() {
-			nextRecurrence := s.findNextRecurrence(event, startTime)
+	conflicts, err := s.FindConflicts(event, startTime, s.serverID)
 
-			if !nextRecurrence.IsZero() {
-				err = s.Schedule(event, nextRecurrence)
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not check event: %s for schedule conflicts", event.EventName())
+	}
 
-				if err != nil {
-					logrus.WithError(err).Errorf("Could not set next recurrence timer")
-				}
-			}
-		}
-	})
+	for _, conflict := range conflicts {
+		logrus.Warnf("Event: %s is scheduled for %s, but %s", event.EventName(), startTime.String(), conflict.String())
+	}
 
-	serverOptions, err := s.store.LoadServerOptions()
+	s.cancelJobs(event)
 
-	if err != nil {
+	if err := s.enqueueJob(s.newJob(JobTypeStartEvent, event, startTime, startTime)); err != nil {
 		return err
 	}
 
-	if serverOptions.NotificationReminderTimer > 0 {
-		err := s.notificationManager.SendRaceScheduledMessage(event, startTime)
-
-		if err != nil {
+	if customRace, ok := event.(*CustomRace); ok {
+		if err := s.notificationManager.SendRaceScheduledMessage(customRace, startTime); err != nil {
 			logrus.WithError(err).Errorf("Could not send race scheduled message")
 		}
+	}
+
+	for _, reminder := range s.notificationManager.GetNotificationReminders() {
+		reminderTime := startTime.Add(-time.Duration(reminder) * time.Minute)
 
-		duration := time.Until(startTime.Add(time.Duration(-serverOptions.NotificationReminderTimer)))
+		if reminderTime.Before(time.Now()) {
+			continue
+		}
 
-		s.reminderTimers[event.GetID().String()] = time.AfterFunc(duration, func() {
-			err := s.notificationManager.SendRaceReminderMessage(event)
+		job := s.newJob(JobTypeReminder, event, startTime, reminderTime)
+		job.IdempotencyKey += fmt.Sprintf(":%d", reminder)
+		job.ReminderMinutes = reminder
 
-			if err != nil {
-				logrus.WithError(err).Errorf("Could not send race reminder message")
-			}
-		})
+		if err := s.enqueueJob(job); err != nil {
+			logrus.WithError(err).Errorf("Could not queue race reminder message")
+		}
 	}
 
 	return nil
 }
 
 func (s *Scheduler) DeSchedule(event ScheduledEvent) error {
-	if timer := s.timers[event.GetID().String()]; timer != nil {
-		timer.Stop()
-	}
+	s.cancelJobs(event)
 
 	event.ClearRecurrenceRule()
 
@@ -180,17 +175,22 @@ func (s *Scheduler) DeSchedule(event ScheduledEvent) error {
 }
 
 func (s *Scheduler) findNextRecurrence(event ScheduledEvent, start time.Time) time.Time {
-	rule, err := event.GetRecurrenceRule()
+	set, err := event.GetRecurrenceSet()
 
 	if err != nil {
 		logrus.WithError(err).Errorf("Couldn't get recurrence rule for event: %s", event.GetID())
 		return time.Time{}
 	}
 
-	next := rule.After(start, false)
+	// start may be a moved occurrence, so the series continues from the occurrence it replaced.
+	next := set.After(event.OccurrenceFor(start), false)
+
+	if next.IsZero() {
+		return time.Time{}
+	}
 
-	if next.After(time.Now()) {
-		return next
+	if nextStart := event.OccurrenceStart(next); nextStart.After(time.Now()) {
+		return nextStart
 	}
 
 	return time.Time{}
@@ -198,7 +198,8 @@ func (s *Scheduler) findNextRecurrence(event ScheduledEvent, start time.Time) ti
 
 var ErrUnknownScheduledEvent = errors.New("servermanager: unknown scheduled event")
 
-func (s *Scheduler) startEvent(event ScheduledEvent) error {
+// startEvent starts an event which was scheduled to start at scheduledTime.
+func (s *Scheduler) startEvent(event ScheduledEvent, scheduledTime time.Time) error {
 	switch e := event.(type) {
 	case *RaceWeekendSession:
 		raceWeekend, _, err := s.raceWeekendManager.FindRaceWeekendForSession(event.GetID().String())
@@ -207,6 +208,12 @@ func (s *Scheduler) startEvent(event ScheduledEvent) error {
 			return err
 		}
 
+		if raceWeekend.Autopilot.Enabled && raceWeekend.Autopilot.QueuedSessionID == e.ID {
+			// sessions queued by the autopilot are watched by it, and it decides what happens if they fail to start.
+			s.raceWeekendManager.autopilotStartSession(raceWeekend.ID.String(), e.ID.String())
+			return nil
+		}
+
 		err = s.raceWeekendManager.StartSession(raceWeekend.ID.String(), event.GetID().String())
 
 		if err != nil {
@@ -225,7 +232,17 @@ func (s *Scheduler) startEvent(event ScheduledEvent) error {
 			return err
 		}
 	case *CustomRace:
-		err := s.raceManager.StartCustomRace(e.GetID().String(), false)
+		customRace, err := s.store.FindCustomRaceByID(e.GetID().String())
+
+		if err != nil {
+			return err
+		}
+
+		if override := customRace.FindRecurrenceOverrideForStart(scheduledTime); override != nil && override.Track != "" {
+			err = s.raceManager.StartCustomRaceWithOverride(customRace, override)
+		} else {
+			err = s.raceManager.StartCustomRace(e.GetID().String(), false)
+		}
 
 		if err != nil {
 			return err
@@ -234,10 +251,17 @@ func (s *Scheduler) startEvent(event ScheduledEvent) error {
 		return ErrUnknownScheduledEvent
 	}
 
-	return s.clearScheduledTime(event)
+	return nil
 }
 
 func (s *Scheduler) clearScheduledTime(event ScheduledEvent) error {
+	return s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+		storedEvent.SetScheduledTime(time.Time{})
+	})
+}
+
+// updateScheduledEvent loads the stored copy of an event, applies fn to it and persists it.
+func (s *Scheduler) updateScheduledEvent(event ScheduledEvent, fn func(storedEvent ScheduledEvent)) error {
 	switch e := event.(type) {
 	case *RaceWeekendSession:
 		raceWeekend, raceWeekendSession, err := s.raceWeekendManager.FindRaceWeekendForSession(event.GetID().String())
@@ -246,7 +270,7 @@ func (s *Scheduler) clearScheduledTime(event ScheduledEvent) error {
 			return err
 		}
 
-		raceWeekendSession.Scheduled = time.Time{}
+		fn(raceWeekendSession)
 
 		return s.store.UpsertRaceWeekend(raceWeekend)
 	case *ChampionshipEvent:
@@ -256,12 +280,11 @@ func (s *Scheduler) clearScheduledTime(event ScheduledEvent) error {
 			return err
 		}
 
-		championshipEvent.Scheduled = time.Time{}
+		fn(championshipEvent)
 
 		return s.championshipManager.UpsertChampionship(championship)
 	case *CustomRace:
-
-		e.Scheduled = time.Time{}
+		fn(e)
 
 		return s.store.UpsertCustomRace(e)
 	default:
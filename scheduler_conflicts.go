@@ -0,0 +1,407 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// typicalAverageSpeed is the average lap speed in m/s (~150km/h) used to estimate lap times from a track's length.
+	typicalAverageSpeed = 150 / 3.6
+	// defaultLapTime is used for lap-based sessions when the track's length is unknown.
+	defaultLapTime = time.Minute * 2
+	// scheduleConflictMargin is kept clear between events on the same server, for loading and the results screen.
+	scheduleConflictMargin = time.Minute * 5
+)
+
+var (
+	ErrScheduleConflict = errors.New("servermanager: scheduled event conflicts with another event on the same server")
+	ErrNoFreeServer     = errors.New("servermanager: no server is free at the scheduled time")
+)
+
+// ScheduleConflictAction is what happens when an event is scheduled to overlap another event on the same server.
+type ScheduleConflictAction string
+
+const (
+	// ScheduleConflictWarn schedules the event anyway, returning the conflicts so that they can be shown.
+	ScheduleConflictWarn ScheduleConflictAction = ""
+	// ScheduleConflictRefuse does not schedule the event.
+	ScheduleConflictRefuse ScheduleConflictAction = "refuse"
+	// ScheduleConflictAutoAssign moves the event to a server which is free at the scheduled time.
+	ScheduleConflictAutoAssign ScheduleConflictAction = "auto-assign"
+)
+
+// SchedulerServerPool gives a Scheduler access to the Schedulers of the other servers, so that events can be
+// moved between them.
+type SchedulerServerPool interface {
+	Schedulers() []*Scheduler
+}
+
+// A ScheduleConflict is an event which overlaps the time an event is being scheduled for.
+type ScheduleConflict struct {
+	ServerID  ServerID
+	EventName string
+	Start     time.Time
+	End       time.Time
+
+	// Running is set when the conflicting event has already been started.
+	Running bool
+}
+
+func (sc *ScheduleConflict) String() string {
+	if sc.Running {
+		return fmt.Sprintf("%s is running until around %s", sc.EventName, sc.End.Format(time.RFC1123))
+	}
+
+	return fmt.Sprintf("%s is scheduled from %s to around %s", sc.EventName, sc.Start.Format(time.RFC1123), sc.End.Format(time.RFC1123))
+}
+
+// scheduleConflictsDescription lists conflicts for a flash message.
+func scheduleConflictsDescription(conflicts []*ScheduleConflict) string {
+	var descriptions []string
+
+	for _, conflict := range conflicts {
+		descriptions = append(descriptions, conflict.String())
+	}
+
+	return strings.Join(descriptions, "; ")
+}
+
+var trackLengthRegex = regexp.MustCompile(`^\s*([\d.,]+)\s*([a-zA-Z]*)\.?\s*$`)
+
+// trackLengthUnits are the number of metres in each unit that a track length may be given in.
+var trackLengthUnits = map[string]float64{
+	"m":          1,
+	"meters":     1,
+	"metres":     1,
+	"km":         1000,
+	"kms":        1000,
+	"kilometers": 1000,
+	"kilometres": 1000,
+	"mi":         1609.344,
+	"mile":       1609.344,
+	"miles":      1609.344,
+}
+
+// parseTrackLength reads a track length in metres from a track's ui_track.json length, which is free text,
+// e.g. "5793m", "5.8 km", "2.5 miles" or "3,602". Lengths in an unknown unit are not parsed.
+func parseTrackLength(length string) (float64, bool) {
+	matches := trackLengthRegex.FindStringSubmatch(length)
+
+	if matches == nil {
+		return 0, false
+	}
+
+	number := matches[1]
+	unit := strings.ToLower(matches[2])
+
+	multiplier, ok := trackLengthUnits[unit]
+
+	if unit != "" && !ok {
+		return 0, false
+	}
+
+	if multiplier > 1 {
+		// long units are often written with a decimal comma, e.g. "5,8 km"
+		number = strings.Replace(number, ",", ".", 1)
+	} else {
+		number = strings.Replace(number, ",", "", -1)
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+
+	if unit == "" {
+		// lengths without a unit are in metres, unless they are too short to be a track, then they are in km.
+		if value < 100 {
+			multiplier = 1000
+		} else {
+			multiplier = 1
+		}
+	}
+
+	return value * multiplier, true
+}
+
+// EstimateLapTime estimates a typical lap time for a track from its length.
+func EstimateLapTime(track, layout string) time.Duration {
+	info := trackInfo(track, layout)
+
+	if info == nil {
+		return defaultLapTime
+	}
+
+	metres, ok := parseTrackLength(info.Length)
+
+	if !ok {
+		return defaultLapTime
+	}
+
+	return time.Duration(metres / typicalAverageSpeed * float64(time.Second))
+}
+
+// EstimateEventDuration estimates how long an event takes to run all of its sessions. Timed sessions use their
+// time (plus an extra lap for races if RaceExtraLap is set), lap-based sessions use an estimated lap time.
+func EstimateEventDuration(raceSetup CurrentRaceConfig) time.Duration {
+	var duration time.Duration
+
+	lapTime := EstimateLapTime(raceSetup.Track, raceSetup.TrackLayout)
+
+	for sessionType, session := range raceSetup.Sessions {
+		duration += time.Duration(session.WaitTime) * time.Second
+
+		if session.Time > 0 {
+			duration += time.Duration(session.Time) * time.Minute
+
+			if sessionType == SessionTypeRace && raceSetup.RaceExtraLap == 1 {
+				duration += lapTime
+			}
+		} else {
+			duration += time.Duration(session.Laps) * lapTime
+		}
+
+		duration += time.Duration(raceSetup.ResultScreenTime) * time.Second
+	}
+
+	return duration
+}
+
+// defaultServerID is the server that runs events which have not been assigned to a server.
+func (s *Scheduler) defaultServerID() ServerID {
+	if s.serverPool != nil {
+		if schedulers := s.serverPool.Schedulers(); len(schedulers) > 0 {
+			return schedulers[0].serverID
+		}
+	}
+
+	return s.serverID
+}
+
+// serverForEvent is the server that an event will be run on.
+func (s *Scheduler) serverForEvent(event ScheduledEvent) ServerID {
+	if serverID := event.GetScheduledServerID(); serverID != uuid.Nil {
+		return serverID
+	}
+
+	return s.defaultServerID()
+}
+
+// schedulerForServer finds the Scheduler of a server, or nil if the server is unknown.
+func (s *Scheduler) schedulerForServer(serverID ServerID) *Scheduler {
+	if serverID == s.serverID {
+		return s
+	}
+
+	if s.serverPool == nil {
+		return nil
+	}
+
+	for _, scheduler := range s.serverPool.Schedulers() {
+		if scheduler.serverID == serverID {
+			return scheduler
+		}
+	}
+
+	return nil
+}
+
+// listScheduledEvents returns every event with a scheduled start time, on all servers.
+func (s *Scheduler) listScheduledEvents() ([]ScheduledEvent, error) {
+	var events []ScheduledEvent
+
+	customRaces, err := s.store.ListCustomRaces()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, customRace := range customRaces {
+		if !customRace.GetScheduledTime().IsZero() {
+			events = append(events, customRace)
+		}
+	}
+
+	championships, err := s.store.ListChampionships()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, championship := range championships {
+		for _, event := range championship.Events {
+			if !event.GetScheduledTime().IsZero() {
+				events = append(events, event)
+			}
+		}
+	}
+
+	raceWeekends, err := s.store.ListRaceWeekends()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raceWeekend := range raceWeekends {
+		for _, session := range raceWeekend.Sessions {
+			if !session.GetScheduledTime().IsZero() {
+				events = append(events, session)
+			}
+		}
+	}
+
+	return events, nil
+}
+
+func overlaps(start, end, otherStart, otherEnd time.Time) bool {
+	return start.Before(otherEnd.Add(scheduleConflictMargin)) && otherStart.Before(end.Add(scheduleConflictMargin))
+}
+
+// FindConflicts finds the events on a server which would overlap an event starting at startTime. Championship
+// events which are still running are included, using their start time and estimated duration.
+func (s *Scheduler) FindConflicts(event ScheduledEvent, startTime time.Time, serverID ServerID) ([]*ScheduleConflict, error) {
+	var conflicts []*ScheduleConflict
+
+	end := startTime.Add(EstimateEventDuration(event.GetRaceSetup()))
+
+	events, err := s.listScheduledEvents()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, otherEvent := range events {
+		if otherEvent.GetID() == event.GetID() || s.serverForEvent(otherEvent) != serverID {
+			continue
+		}
+
+		otherStart := otherEvent.GetScheduledTime()
+		otherEnd := otherStart.Add(EstimateEventDuration(otherEvent.GetRaceSetup()))
+
+		if overlaps(startTime, end, otherStart, otherEnd) {
+			conflicts = append(conflicts, &ScheduleConflict{
+				ServerID:  serverID,
+				EventName: otherEvent.EventName(),
+				Start:     otherStart,
+				End:       otherEnd,
+			})
+		}
+	}
+
+	championships, err := s.store.ListChampionships()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, championship := range championships {
+		for _, championshipEvent := range championship.Events {
+			if !championshipEvent.InProgress() || championshipEvent.GetID() == event.GetID() || s.serverForEvent(championshipEvent) != serverID {
+				continue
+			}
+
+			runningEnd := championshipEvent.StartedTime.Add(EstimateEventDuration(championshipEvent.GetRaceSetup()))
+
+			if overlaps(startTime, end, championshipEvent.StartedTime, runningEnd) {
+				conflicts = append(conflicts, &ScheduleConflict{
+					ServerID:  serverID,
+					EventName: championshipEvent.EventName(),
+					Start:     championshipEvent.StartedTime,
+					End:       runningEnd,
+					Running:   true,
+				})
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// FindFreeServer finds a server with no events which would overlap an event starting at startTime.
+func (s *Scheduler) FindFreeServer(event ScheduledEvent, startTime time.Time) (ServerID, error) {
+	schedulers := []*Scheduler{s}
+
+	if s.serverPool != nil {
+		schedulers = s.serverPool.Schedulers()
+	}
+
+	for _, scheduler := range schedulers {
+		conflicts, err := s.FindConflicts(event, startTime, scheduler.serverID)
+
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		if len(conflicts) == 0 {
+			return scheduler.serverID, nil
+		}
+	}
+
+	return uuid.Nil, ErrNoFreeServer
+}
+
+// ResolveScheduleConflicts checks an event which is about to be scheduled for conflicts on its server, handling them
+// with the given action. The event is assigned to the server it will run on. Any conflicts which remain are returned,
+// along with ErrScheduleConflict if the event must not be scheduled.
+func (s *Scheduler) ResolveScheduleConflicts(event ScheduledEvent, startTime time.Time, action ScheduleConflictAction) ([]*ScheduleConflict, error) {
+	serverID := s.serverForEvent(event)
+
+	conflicts, err := s.FindConflicts(event, startTime, serverID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conflicts) > 0 {
+		switch action {
+		case ScheduleConflictRefuse:
+			return conflicts, ErrScheduleConflict
+		case ScheduleConflictAutoAssign:
+			serverID, err = s.FindFreeServer(event, startTime)
+
+			if err != nil {
+				return conflicts, err
+			}
+
+			logrus.Infof("Event: %s conflicts with %d other event(s), assigning it to server: %s", event.EventName(), len(conflicts), serverID.String())
+
+			conflicts = nil
+		}
+	}
+
+	if serverID != event.GetScheduledServerID() {
+		event.SetScheduledServerID(serverID)
+
+		err := s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+			storedEvent.SetScheduledServerID(serverID)
+		})
+
+		if err != nil {
+			return conflicts, err
+		}
+	}
+
+	return conflicts, nil
+}
+
+// runsEvent determines if an event should be started by this Scheduler, rather than the Scheduler of another server.
+func (s *Scheduler) runsEvent(event ScheduledEvent) bool {
+	return s.serverForEvent(event) == s.serverID
+}
+
+func (rws *RaceWeekendSession) GetScheduledServerID() ServerID {
+	return rws.ScheduledServerID
+}
+
+func (rws *RaceWeekendSession) SetScheduledServerID(serverID ServerID) {
+	rws.ScheduledServerID = serverID
+}
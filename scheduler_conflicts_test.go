@@ -0,0 +1,168 @@
+package servermanager
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseTrackLength(t *testing.T) {
+	testCases := []struct {
+		length string
+		metres float64
+		ok     bool
+	}{
+		{"5793m", 5793, true},
+		{"5793 m", 5793, true},
+		{"5793 metres", 5793, true},
+		{"3,602", 3602, true},
+		{"5.8 km", 5800, true},
+		{"5,8km", 5800, true},
+		{"5.8", 5800, true},
+		{"2.5 miles", 4023.36, true},
+		{"2.5mi", 4023.36, true},
+		{"1 Mile", 1609.344, true},
+		{"12000 ft", 0, false},
+		{"5 laps", 0, false},
+		{"unknown", 0, false},
+		{"", 0, false},
+		{"0m", 0, false},
+	}
+
+	for _, testCase := range testCases {
+		metres, ok := parseTrackLength(testCase.length)
+
+		if ok != testCase.ok || math.Abs(metres-testCase.metres) > 0.01 {
+			t.Logf("Expected %q to be parsed as %.2fm (%t), got: %.2fm (%t)", testCase.length, testCase.metres, testCase.ok, metres, ok)
+			t.Fail()
+		}
+	}
+}
+
+type testSchedulerServerPool struct {
+	schedulers []*Scheduler
+}
+
+func (p *testSchedulerServerPool) Schedulers() []*Scheduler {
+	return p.schedulers
+}
+
+func scheduleConflictsTestRace(name string, start time.Time, serverID ServerID) *CustomRace {
+	customRace := &CustomRace{UUID: uuid.New(), Name: name, HasCustomName: true}
+	customRace.RaceConfig.Sessions = Sessions{
+		SessionTypeRace: &SessionConfig{Name: "Race", Time: 30},
+	}
+	customRace.SetScheduledTime(start)
+	customRace.SetScheduledServerID(serverID)
+
+	return customRace
+}
+
+func TestScheduler_ResolveScheduleConflicts(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	pool := &testSchedulerServerPool{}
+
+	for i := 0; i < 2; i++ {
+		scheduler := NewScheduler(store, nil, nil, nil, nil)
+		scheduler.serverID = uuid.New()
+		scheduler.serverPool = pool
+
+		pool.schedulers = append(pool.schedulers, scheduler)
+	}
+
+	scheduler, firstServer, secondServer := pool.schedulers[0], pool.schedulers[0].serverID, pool.schedulers[1].serverID
+
+	start := time.Now().Add(time.Hour * 24).Truncate(time.Minute)
+
+	if err := store.UpsertCustomRace(scheduleConflictsTestRace("Existing Race", start, firstServer)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	t.Run("No conflict", func(t *testing.T) {
+		customRace := scheduleConflictsTestRace("Later Race", time.Time{}, firstServer)
+
+		conflicts, err := scheduler.ResolveScheduleConflicts(customRace, start.Add(time.Hour*2), ScheduleConflictRefuse)
+
+		if err != nil || len(conflicts) != 0 {
+			t.Logf("Expected no conflicts for a race after the existing race, got: %d (%v)", len(conflicts), err)
+			t.Fail()
+		}
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		customRace := scheduleConflictsTestRace("Overlapping Race", time.Time{}, firstServer)
+
+		conflicts, err := scheduler.ResolveScheduleConflicts(customRace, start.Add(time.Minute*10), ScheduleConflictWarn)
+
+		if err != nil || len(conflicts) != 1 || conflicts[0].EventName != "Existing Race" {
+			t.Logf("Expected the existing race to be returned as a conflict, got: %d (%v)", len(conflicts), err)
+			t.Fail()
+		}
+
+		if customRace.GetScheduledServerID() != firstServer {
+			t.Log("Expected the race to stay on its server")
+			t.Fail()
+		}
+	})
+
+	t.Run("Refuse", func(t *testing.T) {
+		customRace := scheduleConflictsTestRace("Overlapping Race", time.Time{}, firstServer)
+
+		conflicts, err := scheduler.ResolveScheduleConflicts(customRace, start.Add(time.Minute*10), ScheduleConflictRefuse)
+
+		if err != ErrScheduleConflict || len(conflicts) != 1 {
+			t.Logf("Expected the race to be refused, got: %d conflicts (%v)", len(conflicts), err)
+			t.Fail()
+		}
+	})
+
+	t.Run("Auto assign", func(t *testing.T) {
+		customRace := scheduleConflictsTestRace("Moved Race", time.Time{}, firstServer)
+
+		if err := store.UpsertCustomRace(customRace); err != nil {
+			t.Error(err)
+			return
+		}
+
+		conflicts, err := scheduler.ResolveScheduleConflicts(customRace, start.Add(time.Minute*10), ScheduleConflictAutoAssign)
+
+		if err != nil || len(conflicts) != 0 {
+			t.Logf("Expected the race to be moved without conflicts, got: %d (%v)", len(conflicts), err)
+			t.Fail()
+		}
+
+		storedRace, err := store.FindCustomRaceByID(customRace.UUID.String())
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if customRace.GetScheduledServerID() != secondServer || storedRace.GetScheduledServerID() != secondServer {
+			t.Log("Expected the race to be assigned to the free server and persisted")
+			t.Fail()
+		}
+
+		// the moved race is scheduled, so both servers are now busy
+		storedRace.SetScheduledTime(start.Add(time.Minute * 10))
+
+		if err := store.UpsertCustomRace(storedRace); err != nil {
+			t.Error(err)
+			return
+		}
+
+		otherRace := scheduleConflictsTestRace("Third Race", time.Time{}, firstServer)
+
+		_, err = scheduler.ResolveScheduleConflicts(otherRace, start.Add(time.Minute*5), ScheduleConflictAutoAssign)
+
+		if err != ErrNoFreeServer {
+			t.Logf("Expected no server to be free, got: %v", err)
+			t.Fail()
+		}
+	})
+}
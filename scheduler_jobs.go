@@ -0,0 +1,639 @@
+package servermanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// jobPollInterval is the longest the job queue waits before checking for due jobs. The queue compares jobs to
+	// the wall clock each time it wakes, so a change to the system clock delays jobs by at most this long.
+	jobPollInterval = time.Second * 30
+	// jobRetryDelay is the delay before a failed job is first retried. It doubles with each attempt.
+	jobRetryDelay = time.Minute
+	// jobRetention is how long finished jobs are kept for before they are removed from the Store.
+	jobRetention = time.Hour * 24 * 7
+
+	defaultJobMaxAttempts = 3
+)
+
+var (
+	ErrJobNotFound     = errors.New("servermanager: job not found")
+	ErrJobNotRetryable = errors.New("servermanager: only failed jobs can be retried")
+)
+
+// JobType is the work that a Job does when it runs.
+type JobType string
+
+const (
+	// JobTypeStartEvent starts a scheduled event.
+	JobTypeStartEvent JobType = "start-event"
+	// JobTypePostEvent runs after a scheduled event has started, clearing its schedule.
+	JobTypePostEvent JobType = "post-event"
+	// JobTypeRecurrence schedules the next occurrence of a recurring event.
+	JobTypeRecurrence JobType = "recurrence"
+	// JobTypeReminder sends a reminder notification before a scheduled event starts.
+	JobTypeReminder JobType = "reminder"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// ScheduledEventKind identifies the type of ScheduledEvent that a Job is for, so it can be loaded from the Store.
+type ScheduledEventKind string
+
+const (
+	ScheduledEventKindCustomRace         ScheduledEventKind = "custom-race"
+	ScheduledEventKindChampionshipEvent  ScheduledEventKind = "championship-event"
+	ScheduledEventKindRaceWeekendSession ScheduledEventKind = "race-weekend-session"
+)
+
+func scheduledEventKind(event ScheduledEvent) ScheduledEventKind {
+	switch event.(type) {
+	case *ChampionshipEvent:
+		return ScheduledEventKindChampionshipEvent
+	case *RaceWeekendSession:
+		return ScheduledEventKindRaceWeekendSession
+	default:
+		return ScheduledEventKindCustomRace
+	}
+}
+
+// A Job is a unit of Scheduler work which is persisted in the Store, so that it survives restarts. Jobs are run at
+// least once: a job which was running when the server stopped is run again, so each job checks the stored event
+// before it does anything that can't be repeated.
+type Job struct {
+	ID uuid.UUID
+
+	// IdempotencyKey identifies the work a job does. A job is not queued if an unfinished or successful job with
+	// the same key exists.
+	IdempotencyKey string
+
+	Type     JobType
+	ServerID ServerID
+
+	EventKind ScheduledEventKind
+	EventID   string
+	EventName string
+
+	// Occurrence is the start time of the event that the job is for.
+	Occurrence time.Time
+	// ReminderMinutes is how long before the event starts that a reminder is sent.
+	ReminderMinutes int
+
+	RunAt       time.Time
+	Status      JobStatus
+	Attempts    int
+	MaxAttempts int
+	Error       string
+
+	Created  time.Time
+	Updated  time.Time
+	Started  time.Time
+	Finished time.Time
+}
+
+func (j *Job) Description() string {
+	switch j.Type {
+	case JobTypeStartEvent:
+		return fmt.Sprintf("Start %s", j.EventName)
+	case JobTypePostEvent:
+		return fmt.Sprintf("Clear the schedule of %s", j.EventName)
+	case JobTypeRecurrence:
+		return fmt.Sprintf("Schedule the next recurrence of %s", j.EventName)
+	case JobTypeReminder:
+		return fmt.Sprintf("Send a %d minute reminder for %s", j.ReminderMinutes, j.EventName)
+	default:
+		return j.EventName
+	}
+}
+
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+func (j *Job) CanRetry() bool {
+	return j.Status == JobStatusFailed
+}
+
+// newJob creates a job for an event occurrence on this Scheduler's server.
+func (s *Scheduler) newJob(jobType JobType, event ScheduledEvent, occurrence, runAt time.Time) *Job {
+	return &Job{
+		ID:             uuid.New(),
+		IdempotencyKey: fmt.Sprintf("%s:%s:%d", jobType, event.GetID().String(), occurrence.Unix()),
+		Type:           jobType,
+		ServerID:       s.serverID,
+		EventKind:      scheduledEventKind(event),
+		EventID:        event.GetID().String(),
+		EventName:      event.EventName(),
+		Occurrence:     occurrence,
+		RunAt:          runAt,
+		Status:         JobStatusPending,
+		MaxAttempts:    defaultJobMaxAttempts,
+		Created:        time.Now(),
+	}
+}
+
+// ListJobs returns the jobs of this Scheduler's server, newest first.
+func (s *Scheduler) ListJobs() ([]*Job, error) {
+	jobs, err := s.store.ListJobs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var serverJobs []*Job
+
+	for _, job := range jobs {
+		if job.ServerID == s.serverID {
+			serverJobs = append(serverJobs, job)
+		}
+	}
+
+	sort.Slice(serverJobs, func(i, j int) bool {
+		return serverJobs[i].RunAt.After(serverJobs[j].RunAt)
+	})
+
+	return serverJobs, nil
+}
+
+// enqueueJob persists a job, unless a job with the same idempotency key is waiting to run, running or has succeeded.
+func (s *Scheduler) enqueueJob(job *Job) error {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	jobs, err := s.ListJobs()
+
+	if err != nil {
+		return err
+	}
+
+	for _, existingJob := range jobs {
+		if existingJob.IdempotencyKey == job.IdempotencyKey && existingJob.Status != JobStatusFailed && existingJob.Status != JobStatusCancelled {
+			logrus.Debugf("Job: %s is already queued", job.IdempotencyKey)
+			return nil
+		}
+	}
+
+	if err := s.store.UpsertJob(job); err != nil {
+		return err
+	}
+
+	s.processJobs()
+
+	return nil
+}
+
+// cancelJobs cancels the start and reminder jobs of an event which have not yet run.
+func (s *Scheduler) cancelJobs(event ScheduledEvent) {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	jobs, err := s.ListJobs()
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not list jobs to cancel for event: %s", event.EventName())
+		return
+	}
+
+	for _, job := range jobs {
+		if job.EventID != event.GetID().String() || job.Status != JobStatusPending || (job.Type != JobTypeStartEvent && job.Type != JobTypeReminder) {
+			continue
+		}
+
+		job.Status = JobStatusCancelled
+		job.Finished = time.Now()
+
+		if err := s.store.UpsertJob(job); err != nil {
+			logrus.WithError(err).Errorf("Could not cancel job: %s", job.IdempotencyKey)
+		}
+	}
+}
+
+// RetryJob queues a failed job to run again straight away.
+func (s *Scheduler) RetryJob(id string) error {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	job, err := s.store.LoadJob(id)
+
+	if err != nil {
+		return err
+	}
+
+	if !job.CanRetry() {
+		return ErrJobNotRetryable
+	}
+
+	job.Status = JobStatusPending
+	job.RunAt = time.Now()
+	job.Attempts = 0
+	job.Error = ""
+	job.Finished = time.Time{}
+
+	if err := s.store.UpsertJob(job); err != nil {
+		return err
+	}
+
+	s.processJobs()
+
+	return nil
+}
+
+// StartNow starts a scheduled event straight away on the server it is scheduled on, instead of waiting for its
+// scheduled time. The event is cleared from the schedule (or moved to its next recurrence) once it has started.
+func (s *Scheduler) StartNow(event ScheduledEvent) error {
+	if !s.runsEvent(event) {
+		if scheduler := s.schedulerForServer(s.serverForEvent(event)); scheduler != nil {
+			return scheduler.StartNow(event)
+		}
+	}
+
+	s.cancelJobs(event)
+
+	return s.enqueueJob(s.newJob(JobTypeStartEvent, event, event.GetScheduledTime(), time.Now()))
+}
+
+// recoverJobs requeues jobs which were running when the server stopped.
+func (s *Scheduler) recoverJobs() error {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	jobs, err := s.ListJobs()
+
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.Status != JobStatusRunning {
+			continue
+		}
+
+		logrus.Infof("Job: %s was interrupted, it will be run again", job.Description())
+
+		job.Status = JobStatusPending
+		job.Error = "interrupted by a restart"
+
+		if err := s.store.UpsertJob(job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// processJobs starts the job queue if it is not running, or wakes it up to check for newly due jobs.
+func (s *Scheduler) processJobs() {
+	s.processJobsOnce.Do(func() {
+		go s.runJobQueue()
+	})
+
+	select {
+	case s.wakeJobs <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) runJobQueue() {
+	for {
+		nextRun := s.runDueJobs()
+		wait := jobPollInterval
+
+		if !nextRun.IsZero() && time.Until(nextRun) < wait {
+			wait = time.Until(nextRun)
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-s.wakeJobs:
+			timer.Stop()
+		}
+	}
+}
+
+// runDueJobs runs the pending jobs which are due, oldest first. It returns when the next pending job is due.
+func (s *Scheduler) runDueJobs() time.Time {
+	jobs, err := s.ListJobs()
+
+	if err != nil {
+		logrus.WithError(err).Error("Could not list scheduled jobs")
+		return time.Time{}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].RunAt.Before(jobs[j].RunAt)
+	})
+
+	var nextRun time.Time
+
+	for _, job := range jobs {
+		if job.IsFinished() && time.Since(job.Finished) > jobRetention {
+			if err := s.store.DeleteJob(job.ID.String()); err != nil {
+				logrus.WithError(err).Errorf("Could not remove old job: %s", job.IdempotencyKey)
+			}
+
+			continue
+		}
+
+		if job.Status != JobStatusPending {
+			continue
+		}
+
+		if job.RunAt.After(time.Now()) {
+			if nextRun.IsZero() || job.RunAt.Before(nextRun) {
+				nextRun = job.RunAt
+			}
+
+			continue
+		}
+
+		if retryAt := s.runJob(job); !retryAt.IsZero() && (nextRun.IsZero() || retryAt.Before(nextRun)) {
+			nextRun = retryAt
+		}
+	}
+
+	return nextRun
+}
+
+// runJob runs a job and stores its outcome. If the job failed and can be tried again, the time of the retry is
+// returned.
+func (s *Scheduler) runJob(job *Job) time.Time {
+	job.Status = JobStatusRunning
+	job.Attempts++
+	job.Started = time.Now()
+
+	if err := s.updateJob(job); err != nil {
+		logrus.WithError(err).Errorf("Could not mark job: %s as running", job.IdempotencyKey)
+		return time.Time{}
+	}
+
+	logrus.Infof("Running job: %s (attempt %d of %d)", job.Description(), job.Attempts, job.MaxAttempts)
+
+	var err error
+
+	switch job.Type {
+	case JobTypeStartEvent:
+		err = s.runStartEventJob(job)
+	case JobTypePostEvent:
+		err = s.runPostEventJob(job)
+	case JobTypeRecurrence:
+		err = s.runRecurrenceJob(job)
+	case JobTypeReminder:
+		err = s.runReminderJob(job)
+	default:
+		err = fmt.Errorf("servermanager: unknown job type: %s", job.Type)
+	}
+
+	var retryAt time.Time
+
+	if err == nil {
+		job.Status = JobStatusSucceeded
+		job.Error = ""
+		job.Finished = time.Now()
+	} else if job.Attempts < job.MaxAttempts {
+		logrus.WithError(err).Errorf("Job: %s failed, it will be retried", job.Description())
+
+		retryAt = time.Now().Add(jobRetryDelay * time.Duration(1<<uint(job.Attempts-1)))
+
+		job.Status = JobStatusPending
+		job.Error = err.Error()
+		job.RunAt = retryAt
+	} else {
+		logrus.WithError(err).Errorf("Job: %s failed", job.Description())
+
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+		job.Finished = time.Now()
+	}
+
+	if err := s.updateJob(job); err != nil {
+		logrus.WithError(err).Errorf("Could not store outcome of job: %s", job.IdempotencyKey)
+	}
+
+	return retryAt
+}
+
+func (s *Scheduler) updateJob(job *Job) error {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	return s.store.UpsertJob(job)
+}
+
+// loadScheduledEvent loads the stored copy of the event that a job is for.
+func (s *Scheduler) loadScheduledEvent(kind ScheduledEventKind, id string) (ScheduledEvent, error) {
+	switch kind {
+	case ScheduledEventKindChampionshipEvent:
+		championship, event, err := s.championshipManager.FindChampionshipForEvent(id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		event.championship = championship
+
+		return event, nil
+	case ScheduledEventKindRaceWeekendSession:
+		raceWeekend, session, err := s.raceWeekendManager.FindRaceWeekendForSession(id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		session.raceWeekend = raceWeekend
+
+		return session, nil
+	case ScheduledEventKindCustomRace:
+		return s.store.FindCustomRaceByID(id)
+	default:
+		return nil, ErrUnknownScheduledEvent
+	}
+}
+
+func (s *Scheduler) runStartEventJob(job *Job) error {
+	event, err := s.loadScheduledEvent(job.EventKind, job.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	scheduledTime := event.GetScheduledTime()
+
+	if scheduledTime.IsZero() {
+		// the event has been de-scheduled, or was already started by an earlier attempt of this job.
+		logrus.Infof("Event: %s is no longer scheduled, not starting it", event.EventName())
+		return nil
+	}
+
+	// the schedule is cleared before the event is started, so that if the server stops whilst the event is starting,
+	// the job does not start the event a second time once it is resumed.
+	if err := s.clearScheduledTime(event); err != nil {
+		return err
+	}
+
+	if err := s.startEvent(event, scheduledTime); err != nil {
+		// the event didn't start, so its schedule is put back for the job to be retried.
+		restoreErr := s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+			storedEvent.SetScheduledTime(scheduledTime)
+		})
+
+		if restoreErr != nil {
+			logrus.WithError(restoreErr).Errorf("Could not restore scheduled time of event: %s", event.EventName())
+		}
+
+		return err
+	}
+
+	return s.enqueueJob(s.newJob(JobTypePostEvent, event, job.Occurrence, time.Now()))
+}
+
+func (s *Scheduler) runPostEventJob(job *Job) error {
+	event, err := s.loadScheduledEvent(job.EventKind, job.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	// the schedule is left alone if the event has since been scheduled for a later time.
+	if scheduled := event.GetScheduledTime(); !scheduled.IsZero() && !scheduled.After(job.Occurrence) {
+		if err := s.clearScheduledTime(event); err != nil {
+			return err
+		}
+	}
+
+	if !event.HasRecurrenceRule() {
+		return nil
+	}
+
+	return s.enqueueJob(s.newJob(JobTypeRecurrence, event, job.Occurrence, time.Now()))
+}
+
+func (s *Scheduler) runRecurrenceJob(job *Job) error {
+	event, err := s.loadScheduledEvent(job.EventKind, job.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	if !event.HasRecurrenceRule() {
+		return nil
+	}
+
+	nextRecurrence := s.findNextRecurrence(event, job.Occurrence)
+
+	if nextRecurrence.IsZero() {
+		logrus.Infof("Event: %s has no more recurrences", event.EventName())
+		return nil
+	}
+
+	if !nextRecurrence.Equal(event.GetScheduledTime()) {
+		event.SetScheduledTime(nextRecurrence)
+
+		err := s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+			storedEvent.SetScheduledTime(nextRecurrence)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Schedule(event, nextRecurrence)
+}
+
+func (s *Scheduler) runReminderJob(job *Job) error {
+	if time.Now().After(job.Occurrence) {
+		logrus.Infof("Event: %s has already started, not sending reminder", job.EventName)
+		return nil
+	}
+
+	event, err := s.loadScheduledEvent(job.EventKind, job.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	if !event.GetScheduledTime().Equal(job.Occurrence) {
+		logrus.Infof("Event: %s has been rescheduled, not sending reminder", event.EventName())
+		return nil
+	}
+
+	switch e := event.(type) {
+	case *CustomRace:
+		return s.notificationManager.SendRaceReminderMessage(e, job.ReminderMinutes)
+	case *ChampionshipEvent:
+		return s.notificationManager.SendChampionshipReminderMessage(e.championship, e, job.ReminderMinutes)
+	case *RaceWeekendSession:
+		return s.notificationManager.SendRaceWeekendReminderMessage(e.raceWeekend, e, job.ReminderMinutes)
+	default:
+		return ErrUnknownScheduledEvent
+	}
+}
+
+type JobsHandler struct {
+	*BaseHandler
+
+	scheduler *Scheduler
+}
+
+func NewJobsHandler(baseHandler *BaseHandler, scheduler *Scheduler) *JobsHandler {
+	return &JobsHandler{
+		BaseHandler: baseHandler,
+		scheduler:   scheduler,
+	}
+}
+
+type jobsTemplateVars struct {
+	BaseTemplateVars
+
+	Jobs []*Job
+}
+
+func (jh *JobsHandler) list(w http.ResponseWriter, r *http.Request) {
+	jobs, err := jh.scheduler.ListJobs()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't list scheduled jobs")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	jh.viewRenderer.MustLoadTemplate(w, r, "server/jobs.html", &jobsTemplateVars{
+		BaseTemplateVars: BaseTemplateVars{
+			WideContainer: true,
+		},
+		Jobs: jobs,
+	})
+}
+
+func (jh *JobsHandler) retry(w http.ResponseWriter, r *http.Request) {
+	err := jh.scheduler.RetryJob(chi.URLParam(r, "jobID"))
+
+	if err == ErrJobNotFound || err == ErrJobNotRetryable {
+		AddErrorFlash(w, r, "Only failed jobs can be retried")
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't retry scheduled job")
+		AddErrorFlash(w, r, "Couldn't retry the job")
+	} else {
+		AddFlash(w, r, "The job will be retried shortly")
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
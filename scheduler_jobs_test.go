@@ -0,0 +1,306 @@
+package servermanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newSchedulerJobsTestStore() (Store, func()) {
+	dir := filepath.Join(os.TempDir(), "asm-scheduler-jobs-store")
+	sharedDir := filepath.Join(os.TempDir(), "asm-scheduler-jobs-store-shared")
+
+	return NewJSONStore(dir, sharedDir), func() {
+		os.RemoveAll(dir)
+		os.RemoveAll(sharedDir)
+	}
+}
+
+func TestScheduler_RunJobRetries(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	s := NewScheduler(store, nil, nil, nil, nil)
+
+	// the custom race is never stored, so each attempt of the job fails to load it.
+	customRace := &CustomRace{UUID: uuid.New(), Name: "Missing Race", HasCustomName: true}
+
+	job := s.newJob(JobTypeStartEvent, customRace, time.Now(), time.Now())
+
+	if err := store.UpsertJob(job); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for attempt := 1; attempt <= defaultJobMaxAttempts; attempt++ {
+		before := time.Now()
+		retryAt := s.runJob(job)
+
+		storedJob, err := store.LoadJob(job.ID.String())
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if storedJob.Attempts != attempt {
+			t.Logf("Expected job to have been attempted %d times, got: %d", attempt, storedJob.Attempts)
+			t.Fail()
+		}
+
+		if storedJob.Error == "" {
+			t.Log("Expected the error of the failed attempt to be stored on the job")
+			t.Fail()
+		}
+
+		if attempt < defaultJobMaxAttempts {
+			expectedDelay := jobRetryDelay * time.Duration(1<<uint(attempt-1))
+
+			if storedJob.Status != JobStatusPending {
+				t.Logf("Expected job to be pending a retry after attempt %d, got: %s", attempt, storedJob.Status)
+				t.Fail()
+			}
+
+			if retryAt.Before(before.Add(expectedDelay)) || !storedJob.RunAt.Equal(retryAt) {
+				t.Logf("Expected attempt %d to be retried after %s, retry at: %s, run at: %s", attempt, expectedDelay, retryAt, storedJob.RunAt)
+				t.Fail()
+			}
+		} else {
+			if storedJob.Status != JobStatusFailed || !storedJob.CanRetry() {
+				t.Logf("Expected job to have failed after %d attempts, got: %s", attempt, storedJob.Status)
+				t.Fail()
+			}
+
+			if !retryAt.IsZero() {
+				t.Log("Expected a failed job to not be retried automatically")
+				t.Fail()
+			}
+		}
+
+		job = storedJob
+	}
+}
+
+func TestScheduler_RecoverJobs(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	s := NewScheduler(store, nil, nil, nil, nil)
+
+	customRace := &CustomRace{UUID: uuid.New(), Name: "Interrupted Race", HasCustomName: true}
+
+	runningJob := s.newJob(JobTypeStartEvent, customRace, time.Now(), time.Now())
+	runningJob.Status = JobStatusRunning
+	runningJob.Attempts = 1
+
+	succeededJob := s.newJob(JobTypeReminder, customRace, time.Now(), time.Now())
+	succeededJob.Status = JobStatusSucceeded
+
+	for _, job := range []*Job{runningJob, succeededJob} {
+		if err := store.UpsertJob(job); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if err := s.recoverJobs(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	recoveredJob, err := store.LoadJob(runningJob.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if recoveredJob.Status != JobStatusPending || recoveredJob.Attempts != 1 {
+		t.Logf("Expected the interrupted job to be pending with its attempt kept, got: %s (%d attempts)", recoveredJob.Status, recoveredJob.Attempts)
+		t.Fail()
+	}
+
+	finishedJob, err := store.LoadJob(succeededJob.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if finishedJob.Status != JobStatusSucceeded {
+		t.Logf("Expected a finished job to be left alone, got: %s", finishedJob.Status)
+		t.Fail()
+	}
+}
+
+func TestScheduler_RunStartEventJobIsIdempotent(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	// the scheduler has no RaceManager, so the test fails with a panic if the job tries to start the race.
+	s := NewScheduler(store, nil, nil, nil, nil)
+
+	occurrence := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	customRace := &CustomRace{UUID: uuid.New(), Name: "Started Race", HasCustomName: true}
+
+	// an earlier attempt of the job cleared the schedule before starting the race, then the server stopped.
+	if err := store.UpsertCustomRace(customRace); err != nil {
+		t.Error(err)
+		return
+	}
+
+	job := s.newJob(JobTypeStartEvent, customRace, occurrence, occurrence)
+	job.Status = JobStatusRunning
+	job.Attempts = 1
+
+	if err := store.UpsertJob(job); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := s.recoverJobs(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	job, err := store.LoadJob(job.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s.runJob(job)
+
+	job, err = store.LoadJob(job.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if job.Status != JobStatusSucceeded {
+		t.Logf("Expected the resumed job to succeed without starting the race again, got: %s (%s)", job.Status, job.Error)
+		t.Fail()
+	}
+
+	jobs, err := s.ListJobs()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, otherJob := range jobs {
+		if otherJob.Type == JobTypePostEvent {
+			t.Log("Expected no post event job to be queued for a race which wasn't started")
+			t.Fail()
+		}
+	}
+}
+
+func TestRaceWeekendManager_AutopilotQueuesStartJob(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	rwm := NewRaceWeekendManager(nil, nil, store, nil, &dummyNotificationManager{}, nil)
+	rwm.serverID = uuid.New()
+
+	scheduler := NewScheduler(store, nil, nil, rwm, NewNotificationManager(nil, nil, store))
+	scheduler.serverID = rwm.serverID
+
+	// the job queue is never started, so that queued jobs can be checked without starting any sessions.
+	scheduler.processJobsOnce.Do(func() {})
+
+	rwm.scheduler = scheduler
+
+	raceWeekend, qualifying := testRaceWeekend(20)
+
+	if err := store.UpsertRaceWeekend(raceWeekend); err != nil {
+		t.Error(err)
+		return
+	}
+
+	pendingStartJobs := func() []*Job {
+		jobs, err := scheduler.ListJobs()
+
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+
+		var pending []*Job
+
+		for _, job := range jobs {
+			if job.Type == JobTypeStartEvent && job.Status == JobStatusPending {
+				pending = append(pending, job)
+			}
+		}
+
+		return pending
+	}
+
+	before := time.Now()
+
+	if err := rwm.SetAutopilot(raceWeekend.ID.String(), RaceWeekendAutopilot{Enabled: true, GapMinutes: 5}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	jobs := pendingStartJobs()
+
+	if len(jobs) != 1 || jobs[0].EventID != qualifying.ID.String() || jobs[0].RunAt.Before(before.Add(time.Minute*5)) {
+		t.Logf("Expected a start job for qualifying in 5 minutes, got: %d jobs", len(jobs))
+		t.Fail()
+		return
+	}
+
+	_, session, err := rwm.FindSession(raceWeekend.ID.String(), qualifying.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !session.ScheduledTime.Equal(jobs[0].RunAt) || session.ScheduledServerID != rwm.serverID {
+		t.Log("Expected the queued session to be scheduled on the autopilot's server for when its job runs")
+		t.Fail()
+	}
+
+	// resuming the autopilot after a restart keeps the stored job.
+	if err := rwm.ResumeAutopilot(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if resumedJobs := pendingStartJobs(); len(resumedJobs) != 1 || resumedJobs[0].ID != jobs[0].ID {
+		t.Logf("Expected the queued job to be kept when the autopilot is resumed, got: %d jobs", len(resumedJobs))
+		t.Fail()
+	}
+
+	if err := rwm.SetAutopilot(raceWeekend.ID.String(), RaceWeekendAutopilot{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(pendingStartJobs()) != 0 {
+		t.Log("Expected the queued job to be cancelled when the autopilot is disabled")
+		t.Fail()
+	}
+
+	_, session, err = rwm.FindSession(raceWeekend.ID.String(), qualifying.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !session.ScheduledTime.IsZero() {
+		t.Log("Expected the queued session to no longer be scheduled once the autopilot is disabled")
+		t.Fail()
+	}
+}
@@ -0,0 +1,181 @@
+package servermanager
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MissedEventAction is what the Scheduler does with an event whose start time passed while the server was offline.
+type MissedEventAction string
+
+const (
+	// MissedEventActionSkip clears the schedule (or moves to the next recurrence) and sends a notification.
+	MissedEventActionSkip MissedEventAction = ""
+	// MissedEventActionStart starts the event as soon as the server is back, if it is within the grace period.
+	MissedEventActionStart MissedEventAction = "start"
+	// MissedEventActionReschedule reschedules the event to a number of minutes after the server is back.
+	MissedEventActionReschedule MissedEventAction = "reschedule"
+)
+
+// A MissedEventPolicy configures how a ScheduledEvent catches up if it was missed.
+type MissedEventPolicy struct {
+	Action MissedEventAction
+
+	// GraceMinutes is how late an event can be started with MissedEventActionStart. Events missed by longer than
+	// this are skipped.
+	GraceMinutes int
+	// RescheduleMinutes is how long after the server is back that an event is rescheduled to with
+	// MissedEventActionReschedule.
+	RescheduleMinutes int
+}
+
+func (p MissedEventPolicy) GracePeriod() time.Duration {
+	return time.Duration(p.GraceMinutes) * time.Minute
+}
+
+func (p MissedEventPolicy) RescheduleDelay() time.Duration {
+	return time.Duration(p.RescheduleMinutes) * time.Minute
+}
+
+type MissedEventOutcome string
+
+const (
+	MissedEventStarted     MissedEventOutcome = "started"
+	MissedEventRescheduled MissedEventOutcome = "rescheduled"
+	MissedEventSkipped     MissedEventOutcome = "skipped"
+)
+
+// A MissedEvent records a scheduled start that was missed and what the Scheduler decided to do about it.
+type MissedEvent struct {
+	Time            time.Time
+	ScheduledTime   time.Time
+	Outcome         MissedEventOutcome
+	RescheduledTime time.Time
+	Message         string
+}
+
+// MissedEventCatchUp is the missed event policy and history of a ScheduledEvent.
+type MissedEventCatchUp struct {
+	MissedEventPolicy MissedEventPolicy
+	MissedEvents      []*MissedEvent
+}
+
+func (m *MissedEventCatchUp) GetMissedEventPolicy() MissedEventPolicy {
+	return m.MissedEventPolicy
+}
+
+func (m *MissedEventCatchUp) SetMissedEventPolicy(policy MissedEventPolicy) {
+	m.MissedEventPolicy = policy
+}
+
+func (m *MissedEventCatchUp) RecordMissedEvent(missedEvent *MissedEvent) {
+	m.MissedEvents = append(m.MissedEvents, missedEvent)
+}
+
+func (m *MissedEventCatchUp) LastMissedEvent() *MissedEvent {
+	if len(m.MissedEvents) == 0 {
+		return nil
+	}
+
+	return m.MissedEvents[len(m.MissedEvents)-1]
+}
+
+func (rws *RaceWeekendSession) SetScheduledTime(scheduled time.Time) {
+	rws.ScheduledTime = scheduled
+}
+
+// catchUpMissedEvent decides what to do with an event whose start time passed while the server was offline, using
+// the event's MissedEventPolicy. The decision is stored with the event so it can be shown on the calendar, and a
+// notification is sent.
+func (s *Scheduler) catchUpMissedEvent(event ScheduledEvent) {
+	policy := event.GetMissedEventPolicy()
+	now := time.Now()
+
+	missedEvent := &MissedEvent{
+		Time:          now,
+		ScheduledTime: event.GetScheduledTime(),
+	}
+
+	var nextStart time.Time
+
+	switch {
+	case policy.Action == MissedEventActionStart && now.Sub(missedEvent.ScheduledTime) <= policy.GracePeriod():
+		missedEvent.Outcome = MissedEventStarted
+		missedEvent.Message = fmt.Sprintf("%s was missed while the server was offline. It is being started now, within its %d minute grace period.", event.EventName(), policy.GraceMinutes)
+
+		nextStart = now
+	case policy.Action == MissedEventActionReschedule:
+		missedEvent.Outcome = MissedEventRescheduled
+		missedEvent.RescheduledTime = now.Add(policy.RescheduleDelay())
+		missedEvent.Message = fmt.Sprintf("%s was missed while the server was offline. It has been rescheduled to %s.", event.EventName(), missedEvent.RescheduledTime.Format(time.RFC1123))
+
+		nextStart = missedEvent.RescheduledTime
+	default:
+		missedEvent.Outcome = MissedEventSkipped
+		missedEvent.Message = fmt.Sprintf("%s was missed while the server was offline and has been skipped. Start the event manually if you wish to run it.", event.EventName())
+
+		if policy.Action == MissedEventActionStart {
+			missedEvent.Message = fmt.Sprintf("%s was missed while the server was offline by more than its %d minute grace period, and has been skipped.", event.EventName(), policy.GraceMinutes)
+		}
+
+		if event.HasRecurrenceRule() {
+			nextStart = s.findNextRecurrence(event, missedEvent.ScheduledTime)
+
+			if !nextStart.IsZero() {
+				missedEvent.Message += fmt.Sprintf(" The next recurrence is at %s.", nextStart.Format(time.RFC1123))
+			}
+		}
+	}
+
+	logrus.Infof("Missed scheduled event: %s (scheduled for: %s)", missedEvent.Message, missedEvent.ScheduledTime.String())
+
+	// the decision is stored before any timers are set up, so that an event which is started straight away can
+	// clear its own schedule without it being overwritten.
+	err := s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+		switch missedEvent.Outcome {
+		case MissedEventRescheduled:
+			storedEvent.SetScheduledTime(missedEvent.RescheduledTime)
+		case MissedEventSkipped:
+			if !event.HasRecurrenceRule() {
+				storedEvent.SetScheduledTime(time.Time{})
+			}
+		}
+
+		storedEvent.RecordMissedEvent(missedEvent)
+	})
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Could not record missed event decision for: %s", event.EventName())
+	}
+
+	if !nextStart.IsZero() {
+		if err := s.Schedule(event, nextStart); err != nil {
+			logrus.WithError(err).Errorf("Could not schedule missed event: %s", event.EventName())
+		}
+	}
+
+	if err := s.notificationManager.SendMessage("Missed Scheduled Event", missedEvent.Message); err != nil {
+		logrus.WithError(err).Errorf("Could not send missed event notification")
+	}
+}
+
+// SetMissedEventPolicy updates and persists the MissedEventPolicy for an event.
+func (s *Scheduler) SetMissedEventPolicy(event ScheduledEvent, policy MissedEventPolicy) error {
+	event.SetMissedEventPolicy(policy)
+
+	return s.updateScheduledEvent(event, func(storedEvent ScheduledEvent) {
+		storedEvent.SetMissedEventPolicy(policy)
+	})
+}
+
+// missedEventPolicyFromForm reads a MissedEventPolicy from the "missed-event-policy" template fields.
+func missedEventPolicyFromForm(r *http.Request) MissedEventPolicy {
+	return MissedEventPolicy{
+		Action:            MissedEventAction(r.FormValue("missed-event-action")),
+		GraceMinutes:      formValueAsInt(r.FormValue("missed-event-grace-minutes")),
+		RescheduleMinutes: formValueAsInt(r.FormValue("missed-event-reschedule-minutes")),
+	}
+}
@@ -0,0 +1,184 @@
+package servermanager
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestScheduler_CatchUpMissedEvent(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	s := NewScheduler(store, nil, nil, nil, NewNotificationManager(nil, nil, store))
+
+	// the job queue is never started, so that queued jobs can be checked without starting any events.
+	s.processJobsOnce.Do(func() {})
+
+	pendingStartJob := func(event ScheduledEvent) *Job {
+		jobs, err := s.ListJobs()
+
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+
+		for _, job := range jobs {
+			if job.EventID == event.GetID().String() && job.Type == JobTypeStartEvent && job.Status == JobStatusPending {
+				return job
+			}
+		}
+
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		policy     MissedEventPolicy
+		missedBy   time.Duration
+		recurrence string
+
+		outcome     MissedEventOutcome
+		startsAfter time.Duration
+		scheduled   bool
+	}{
+		{
+			name:        "Start within grace period",
+			policy:      MissedEventPolicy{Action: MissedEventActionStart, GraceMinutes: 30},
+			missedBy:    time.Minute * 10,
+			outcome:     MissedEventStarted,
+			startsAfter: 0,
+			scheduled:   true,
+		},
+		{
+			name:     "Start outside grace period",
+			policy:   MissedEventPolicy{Action: MissedEventActionStart, GraceMinutes: 30},
+			missedBy: time.Hour,
+			outcome:  MissedEventSkipped,
+		},
+		{
+			name:        "Reschedule",
+			policy:      MissedEventPolicy{Action: MissedEventActionReschedule, RescheduleMinutes: 15},
+			missedBy:    time.Hour,
+			outcome:     MissedEventRescheduled,
+			startsAfter: time.Minute * 15,
+			scheduled:   true,
+		},
+		{
+			name:     "Skip",
+			policy:   MissedEventPolicy{},
+			missedBy: time.Minute,
+			outcome:  MissedEventSkipped,
+		},
+		{
+			name:        "Skip to next recurrence",
+			policy:      MissedEventPolicy{},
+			missedBy:    time.Hour,
+			recurrence:  "FREQ=DAILY;COUNT=3",
+			outcome:     MissedEventSkipped,
+			startsAfter: time.Hour * 23,
+			scheduled:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			scheduled := time.Now().Add(-testCase.missedBy).Truncate(time.Second)
+
+			customRace := &CustomRace{UUID: uuid.New(), Name: testCase.name, HasCustomName: true}
+			customRace.ScheduledInitial = scheduled
+			customRace.Scheduled = scheduled
+			customRace.SetMissedEventPolicy(testCase.policy)
+
+			if testCase.recurrence != "" {
+				if err := customRace.SetRecurrenceRule(testCase.recurrence); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+
+			if err := store.UpsertCustomRace(customRace); err != nil {
+				t.Error(err)
+				return
+			}
+
+			before := time.Now()
+
+			s.catchUpMissedEvent(customRace)
+
+			storedRace, err := store.FindCustomRaceByID(customRace.UUID.String())
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			missedEvent := storedRace.LastMissedEvent()
+
+			if missedEvent == nil || missedEvent.Outcome != testCase.outcome || !missedEvent.ScheduledTime.Equal(scheduled) {
+				t.Logf("Expected the missed event to be recorded as %s, got: %+v", testCase.outcome, missedEvent)
+				t.Fail()
+				return
+			}
+
+			job := pendingStartJob(customRace)
+
+			if !testCase.scheduled {
+				if job != nil {
+					t.Logf("Expected the event not to be started, but it is queued for: %s", job.RunAt)
+					t.Fail()
+				}
+
+				if testCase.recurrence == "" && !storedRace.GetScheduledTime().IsZero() {
+					t.Log("Expected the schedule of a skipped event to be cleared")
+					t.Fail()
+				}
+
+				return
+			}
+
+			if job == nil {
+				t.Log("Expected the event to be queued to start")
+				t.Fail()
+				return
+			}
+
+			if job.RunAt.Before(before.Add(testCase.startsAfter)) || job.RunAt.After(time.Now().Add(testCase.startsAfter+time.Hour)) {
+				t.Logf("Expected the event to start %s from now, got: %s", testCase.startsAfter, job.RunAt)
+				t.Fail()
+			}
+
+			if testCase.outcome == MissedEventRescheduled && !storedRace.GetScheduledTime().Equal(missedEvent.RescheduledTime) {
+				t.Logf("Expected the rescheduled time to be stored, got: %s", storedRace.GetScheduledTime())
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestMissedEventPolicyFromForm(t *testing.T) {
+	form := url.Values{
+		"missed-event-action":             {string(MissedEventActionReschedule)},
+		"missed-event-grace-minutes":      {"20"},
+		"missed-event-reschedule-minutes": {"45"},
+	}
+
+	r := httptest.NewRequest("POST", "/race-weekend/id/session/id/schedule", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	policy := missedEventPolicyFromForm(r)
+
+	if policy.Action != MissedEventActionReschedule || policy.GraceMinutes != 20 || policy.RescheduleMinutes != 45 {
+		t.Logf("Expected the missed event policy to be read from the form, got: %+v", policy)
+		t.Fail()
+	}
+
+	if policy.RescheduleDelay() != time.Minute*45 || policy.GracePeriod() != time.Minute*20 {
+		t.Log("Expected the policy durations to be in minutes")
+		t.Fail()
+	}
+}
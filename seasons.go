@@ -0,0 +1,518 @@
+package servermanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/heindl/caldav-go/icalendar"
+	"github.com/heindl/caldav-go/icalendar/components"
+	"github.com/sirupsen/logrus"
+)
+
+func NewSeason(name string) *Season {
+	return &Season{
+		ID:      uuid.New(),
+		Created: time.Now(),
+		Name:    name,
+	}
+}
+
+// A Season groups multiple Championships together, with an overall title decided by the combined points
+// of each Championship.
+type Season struct {
+	ID      uuid.UUID
+	Created time.Time
+	Updated time.Time
+	Deleted time.Time
+
+	Name        string
+	Description string
+
+	Championships []*SeasonChampionship
+}
+
+// A SeasonChampionship is a Championship which makes up part of a Season. The points scored in the Championship
+// are multiplied by the Weighting when calculating the Season standings.
+type SeasonChampionship struct {
+	ChampionshipID uuid.UUID
+	Weighting      float64
+}
+
+func (s *Season) WeightingForChampionship(championshipID uuid.UUID) float64 {
+	for _, seasonChampionship := range s.Championships {
+		if seasonChampionship.ChampionshipID == championshipID {
+			return seasonChampionship.Weighting
+		}
+	}
+
+	return 0
+}
+
+// SeasonDriverStanding is a driver's combined points across all Championships in a Season.
+type SeasonDriverStanding struct {
+	DriverGUID string
+	DriverName string
+	Team       string
+
+	// ChampionshipPoints are the weighted points scored in each Championship, keyed by Championship ID.
+	ChampionshipPoints map[uuid.UUID]float64
+	Points             float64
+}
+
+// SeasonTeamStanding is a Team's combined points across all Championships in a Season.
+type SeasonTeamStanding struct {
+	// TeamID is uuid.Nil for teams which are only known by name.
+	TeamID uuid.UUID
+	Team   string
+
+	// ChampionshipPoints are the weighted points scored in each Championship, keyed by Championship ID.
+	ChampionshipPoints map[uuid.UUID]float64
+	Points             float64
+}
+
+type SeasonStandings struct {
+	Drivers []*SeasonDriverStanding
+	Teams   []*SeasonTeamStanding
+}
+
+// Standings combines the driver and team standings of each Championship in the Season. Points from each class of a
+// Championship are added together, then multiplied by the Championship's weighting.
+func (s *Season) Standings(championships []*Championship) *SeasonStandings {
+	drivers := make(map[string]*SeasonDriverStanding)
+	teams := make(map[string]*SeasonTeamStanding)
+
+	for _, championship := range championships {
+		weighting := s.WeightingForChampionship(championship.ID)
+
+		for _, class := range championship.Classes {
+			for _, standing := range class.Standings(championship, championship.Events) {
+				guid := standing.Car.Driver.GUID
+
+				if _, ok := drivers[guid]; !ok {
+					drivers[guid] = &SeasonDriverStanding{
+						DriverGUID:         guid,
+						DriverName:         standing.Car.Driver.Name,
+						ChampionshipPoints: make(map[uuid.UUID]float64),
+					}
+				}
+
+				drivers[guid].Team = standing.Car.Driver.Team
+				drivers[guid].ChampionshipPoints[championship.ID] += standing.Points * weighting
+				drivers[guid].Points += standing.Points * weighting
+			}
+
+			for _, standing := range class.TeamStandings(championship, championship.Events) {
+				key := strings.ToLower(standing.Key())
+
+				if _, ok := teams[key]; !ok {
+					teams[key] = &SeasonTeamStanding{
+						TeamID:             standing.TeamID,
+						Team:               standing.Team,
+						ChampionshipPoints: make(map[uuid.UUID]float64),
+					}
+				}
+
+				teams[key].ChampionshipPoints[championship.ID] += standing.Points * weighting
+				teams[key].Points += standing.Points * weighting
+			}
+		}
+	}
+
+	out := &SeasonStandings{}
+
+	for _, driver := range drivers {
+		out.Drivers = append(out.Drivers, driver)
+	}
+
+	for _, team := range teams {
+		if team.Team == "" {
+			continue
+		}
+
+		out.Teams = append(out.Teams, team)
+	}
+
+	sort.Slice(out.Drivers, func(i, j int) bool {
+		if out.Drivers[i].Points == out.Drivers[j].Points {
+			return out.Drivers[i].DriverName < out.Drivers[j].DriverName
+		}
+
+		return out.Drivers[i].Points > out.Drivers[j].Points
+	})
+
+	sort.Slice(out.Teams, func(i, j int) bool {
+		if out.Teams[i].Points == out.Teams[j].Points {
+			return out.Teams[i].Team < out.Teams[j].Team
+		}
+
+		return out.Teams[i].Points > out.Teams[j].Points
+	})
+
+	return out
+}
+
+type SeasonManager struct {
+	store Store
+}
+
+func NewSeasonManager(store Store) *SeasonManager {
+	return &SeasonManager{
+		store: store,
+	}
+}
+
+func (sm *SeasonManager) ListSeasons() ([]*Season, error) {
+	seasons, err := sm.store.ListSeasons()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].Updated.After(seasons[j].Updated)
+	})
+
+	return seasons, nil
+}
+
+func (sm *SeasonManager) LoadSeason(id string) (*Season, error) {
+	return sm.store.LoadSeason(id)
+}
+
+func (sm *SeasonManager) DeleteSeason(id string) error {
+	return sm.store.DeleteSeason(id)
+}
+
+// LoadSeasonChampionships loads all of the Championships in a Season, in the order they were added to the Season.
+// Championships which have since been deleted are skipped.
+func (sm *SeasonManager) LoadSeasonChampionships(season *Season) ([]*Championship, error) {
+	var championships []*Championship
+
+	for _, seasonChampionship := range season.Championships {
+		championship, err := loadChampionship(sm.store, seasonChampionship.ChampionshipID.String())
+
+		if err == ErrChampionshipNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !championship.Deleted.IsZero() {
+			continue
+		}
+
+		championships = append(championships, championship)
+	}
+
+	return championships, nil
+}
+
+func (sm *SeasonManager) HandleCreateSeason(r *http.Request) (season *Season, edited bool, err error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, false, err
+	}
+
+	name := strings.TrimSpace(r.FormValue("Name"))
+
+	if name == "" {
+		return nil, false, ValidationError("Please enter a name for the season.")
+	}
+
+	if seasonID := r.FormValue("Editing"); seasonID != "" {
+		season, err = sm.store.LoadSeason(seasonID)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		edited = true
+	} else {
+		season = NewSeason(name)
+	}
+
+	season.Name = name
+	season.Description = r.FormValue("Description")
+	season.Championships = nil
+
+	for i, championshipID := range r.Form["ChampionshipID"] {
+		id, err := uuid.Parse(championshipID)
+
+		if err != nil {
+			continue
+		}
+
+		weighting := 1.0
+
+		if i < len(r.Form["Weighting"]) {
+			weighting = formValueAsFloat(r.Form["Weighting"][i])
+		}
+
+		if weighting <= 0 {
+			// a championship with no weighting does not count towards the season
+			continue
+		}
+
+		season.Championships = append(season.Championships, &SeasonChampionship{
+			ChampionshipID: id,
+			Weighting:      weighting,
+		})
+	}
+
+	return season, edited, sm.store.UpsertSeason(season)
+}
+
+func (sm *SeasonManager) BuildICalFeed(seasonID string, w io.Writer) error {
+	season, err := sm.store.LoadSeason(seasonID)
+
+	if err != nil {
+		return err
+	}
+
+	championships, err := sm.LoadSeasonChampionships(season)
+
+	if err != nil {
+		return err
+	}
+
+	cal := components.NewCalendar()
+
+	for _, championship := range championships {
+		for _, event := range championship.Events {
+			if event.Scheduled.IsZero() {
+				continue
+			}
+
+			event.championship = championship
+
+			cal.Events = append(cal.Events, BuildICalEvent(event))
+		}
+	}
+
+	str, err := icalendar.Marshal(cal)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(w, str)
+
+	return err
+}
+
+type SeasonsHandler struct {
+	*BaseHandler
+
+	seasonManager *SeasonManager
+}
+
+func NewSeasonsHandler(baseHandler *BaseHandler, seasonManager *SeasonManager) *SeasonsHandler {
+	return &SeasonsHandler{
+		BaseHandler:   baseHandler,
+		seasonManager: seasonManager,
+	}
+}
+
+type seasonListTemplateVars struct {
+	BaseTemplateVars
+
+	Seasons []*Season
+}
+
+func (sh *SeasonsHandler) list(w http.ResponseWriter, r *http.Request) {
+	seasons, err := sh.seasonManager.ListSeasons()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't list seasons")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	sh.viewRenderer.MustLoadTemplate(w, r, "seasons/index.html", &seasonListTemplateVars{
+		Seasons: seasons,
+	})
+}
+
+type seasonViewTemplateVars struct {
+	BaseTemplateVars
+
+	Season        *Season
+	Championships []*Championship
+	Standings     *SeasonStandings
+}
+
+// loadSeasonAndStandings loads the Season from the request URL and computes its standings. If an error occurs,
+// it is written to w and ok is false.
+func (sh *SeasonsHandler) loadSeasonAndStandings(w http.ResponseWriter, r *http.Request) (season *Season, championships []*Championship, standings *SeasonStandings, ok bool) {
+	season, err := sh.seasonManager.LoadSeason(chi.URLParam(r, "seasonID"))
+
+	if err == ErrSeasonNotFound {
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't load season")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, nil, false
+	}
+
+	championships, err = sh.seasonManager.LoadSeasonChampionships(season)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't load championships for season: %s", season.ID)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, nil, false
+	}
+
+	return season, championships, season.Standings(championships), true
+}
+
+func (sh *SeasonsHandler) view(w http.ResponseWriter, r *http.Request) {
+	season, championships, standings, ok := sh.loadSeasonAndStandings(w, r)
+
+	if !ok {
+		return
+	}
+
+	sh.viewRenderer.MustLoadTemplate(w, r, "seasons/view.html", &seasonViewTemplateVars{
+		Season:        season,
+		Championships: championships,
+		Standings:     standings,
+	})
+}
+
+type seasonAPIChampionship struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Weighting float64   `json:"weighting"`
+	URL       string    `json:"url"`
+	Progress  float64   `json:"progress"`
+}
+
+type seasonAPIResponse struct {
+	ID            uuid.UUID                `json:"id"`
+	Name          string                   `json:"name"`
+	Description   string                   `json:"description"`
+	Championships []*seasonAPIChampionship `json:"championships"`
+	Standings     *SeasonStandings         `json:"standings"`
+}
+
+func (sh *SeasonsHandler) api(w http.ResponseWriter, r *http.Request) {
+	season, championships, standings, ok := sh.loadSeasonAndStandings(w, r)
+
+	if !ok {
+		return
+	}
+
+	response := &seasonAPIResponse{
+		ID:          season.ID,
+		Name:        season.Name,
+		Description: season.Description,
+		Standings:   standings,
+	}
+
+	for _, championship := range championships {
+		response.Championships = append(response.Championships, &seasonAPIChampionship{
+			ID:        championship.ID,
+			Name:      championship.Name,
+			Weighting: season.WeightingForChampionship(championship.ID),
+			URL:       championship.GetURL(),
+			Progress:  championship.Progress(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(response)
+}
+
+func (sh *SeasonsHandler) icalFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Add("Content-Disposition", "inline; filename=season.ics")
+
+	err := sh.seasonManager.BuildICalFeed(chi.URLParam(r, "seasonID"), w)
+
+	if err != nil {
+		logrus.WithError(err).Error("could not build season calendar feed")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+type seasonEditTemplateVars struct {
+	BaseTemplateVars
+
+	Season        *Season
+	IsEditing     bool
+	Championships []*Championship
+}
+
+func (sh *SeasonsHandler) createOrEdit(w http.ResponseWriter, r *http.Request) {
+	championships, err := sh.seasonManager.store.ListChampionships()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't list championships")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	vars := &seasonEditTemplateVars{
+		Season:        &Season{},
+		Championships: championships,
+	}
+
+	if seasonID := chi.URLParam(r, "seasonID"); seasonID != "" {
+		season, err := sh.seasonManager.LoadSeason(seasonID)
+
+		if err != nil {
+			logrus.WithError(err).Error("couldn't load season")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		vars.Season = season
+		vars.IsEditing = true
+	}
+
+	sh.viewRenderer.MustLoadTemplate(w, r, "seasons/new.html", vars)
+}
+
+func (sh *SeasonsHandler) submit(w http.ResponseWriter, r *http.Request) {
+	season, edited, err := sh.seasonManager.HandleCreateSeason(r)
+
+	if validationError, ok := err.(ValidationError); ok {
+		AddErrorFlash(w, r, string(validationError))
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't create season")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if edited {
+		AddFlash(w, r, "Season successfully edited!")
+	} else {
+		AddFlash(w, r, "Season successfully created!")
+	}
+
+	http.Redirect(w, r, "/season/"+season.ID.String(), http.StatusFound)
+}
+
+func (sh *SeasonsHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := sh.seasonManager.DeleteSeason(chi.URLParam(r, "seasonID")); err != nil {
+		logrus.WithError(err).Error("couldn't delete season")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Season successfully deleted!")
+	http.Redirect(w, r, "/seasons", http.StatusFound)
+}
@@ -0,0 +1,199 @@
+package servermanager
+
+import (
+	"math"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seasonTestChampionship creates a Championship with a single race, finished by the drivers in the order given.
+// Each driver is in their own team, and their results are made from their championship entrant.
+func seasonTestChampionship(name string, finishingOrder ...string) *Championship {
+	championship := NewChampionship(name)
+
+	class := NewChampionshipClass("GT3")
+	championship.AddClass(class)
+
+	results := &SessionResults{
+		Type:           SessionTypeRace,
+		ChampionshipID: championship.ID.String(),
+	}
+
+	for i, driver := range finishingOrder {
+		entrant := NewEntrant()
+		entrant.Name = "Driver " + driver
+		entrant.GUID = "7656119800000000" + driver
+		entrant.Team = "Team " + driver
+		entrant.Model = "ks_audi_r8_lms"
+		class.Entrants.AddToBackOfGrid(entrant)
+
+		car, result := entrant.AsSessionCar(), entrant.AsSessionResult()
+		car.Driver.ClassID = class.ID
+		result.ClassID = class.ID
+		result.BestLap = 100000 + i*1000
+		result.TotalTime = 1000000 + i*1000
+
+		results.Cars = append(results.Cars, car)
+		results.Result = append(results.Result, result)
+		results.Laps = append(results.Laps, &SessionLap{
+			CarID:      car.CarID,
+			CarModel:   car.Model,
+			DriverGUID: entrant.GUID,
+			DriverName: entrant.Name,
+			LapTime:    result.BestLap,
+			Timestamp:  result.BestLap,
+			ClassID:    class.ID,
+		})
+	}
+
+	event := NewChampionshipEvent()
+	event.RaceSetup = ConfigIniDefault().CurrentRaceConfig
+	event.CompletedTime = time.Now()
+	event.Sessions[SessionTypeRace] = &ChampionshipSession{
+		StartedTime:   time.Now().Add(-time.Hour),
+		CompletedTime: time.Now(),
+		Results:       results,
+	}
+
+	championship.Events = append(championship.Events, event)
+
+	return championship
+}
+
+func TestSeason_Standings(t *testing.T) {
+	sprint := seasonTestChampionship("Sprint Cup", "1", "2", "3")
+	endurance := seasonTestChampionship("Endurance Cup", "3", "2", "1")
+
+	season := NewSeason("Season")
+	season.Championships = []*SeasonChampionship{
+		{ChampionshipID: sprint.ID, Weighting: 1},
+		{ChampionshipID: endurance.ID, Weighting: 2},
+	}
+
+	standings := season.Standings([]*Championship{sprint, endurance})
+
+	expectedDriverPoints := make(map[string]float64)
+	expectedTeamPoints := make(map[string]float64)
+
+	for _, championship := range []*Championship{sprint, endurance} {
+		weighting := season.WeightingForChampionship(championship.ID)
+
+		for _, standing := range championship.Classes[0].Standings(championship, championship.Events) {
+			expectedDriverPoints[standing.Car.Driver.GUID] += standing.Points * weighting
+		}
+
+		for _, standing := range championship.Classes[0].TeamStandings(championship, championship.Events) {
+			expectedTeamPoints[standing.Team] += standing.Points * weighting
+		}
+	}
+
+	if len(standings.Drivers) != 3 || len(standings.Teams) != 3 {
+		t.Logf("Expected 3 drivers and 3 teams in the season standings, got: %d and %d", len(standings.Drivers), len(standings.Teams))
+		t.Fail()
+		return
+	}
+
+	for i, driver := range standings.Drivers {
+		if math.Abs(driver.Points-expectedDriverPoints[driver.DriverGUID]) > 0.001 {
+			t.Logf("Expected %s to have %.2f weighted points, got: %.2f", driver.DriverName, expectedDriverPoints[driver.DriverGUID], driver.Points)
+			t.Fail()
+		}
+
+		if driver.ChampionshipPoints[sprint.ID]+driver.ChampionshipPoints[endurance.ID] != driver.Points {
+			t.Logf("Expected the championship points of %s to add up to their season points", driver.DriverName)
+			t.Fail()
+		}
+
+		if i > 0 && standings.Drivers[i-1].Points < driver.Points {
+			t.Log("Expected the drivers to be sorted by season points")
+			t.Fail()
+		}
+	}
+
+	// the endurance cup is worth double, so its winner wins the season
+	if standings.Drivers[0].DriverName != "Driver 3" {
+		t.Logf("Expected the endurance cup winner to lead the season, got: %s", standings.Drivers[0].DriverName)
+		t.Fail()
+	}
+
+	for _, team := range standings.Teams {
+		if math.Abs(team.Points-expectedTeamPoints[team.Team]) > 0.001 {
+			t.Logf("Expected %s to have %.2f weighted points, got: %.2f", team.Team, expectedTeamPoints[team.Team], team.Points)
+			t.Fail()
+		}
+	}
+
+	t.Run("Championship not in season", func(t *testing.T) {
+		other := seasonTestChampionship("Other Cup", "4")
+
+		standings := season.Standings([]*Championship{other})
+
+		if len(standings.Drivers) != 1 || standings.Drivers[0].Points != 0 {
+			t.Log("Expected a championship which is not part of the season to score no points")
+			t.Fail()
+		}
+	})
+}
+
+func TestSeasonManager_HandleCreateSeason(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	sm := NewSeasonManager(store)
+
+	counted, defaultWeighting, excluded := uuid.New(), uuid.New(), uuid.New()
+
+	form := url.Values{
+		"Name":           {"Umbrella Season"},
+		"ChampionshipID": {counted.String(), excluded.String(), "not-a-uuid", defaultWeighting.String()},
+		"Weighting":      {"1.5", "0", "2"},
+	}
+
+	r := httptest.NewRequest("POST", "/seasons/new/submit", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	season, edited, err := sm.HandleCreateSeason(r)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if edited || season.Name != "Umbrella Season" {
+		t.Logf("Expected a new season to be created, got: %s (edited: %t)", season.Name, edited)
+		t.Fail()
+	}
+
+	if len(season.Championships) != 2 || season.WeightingForChampionship(counted) != 1.5 || season.WeightingForChampionship(defaultWeighting) != 1 {
+		t.Logf("Expected 2 weighted championships, got: %d", len(season.Championships))
+		t.Fail()
+	}
+
+	if season.WeightingForChampionship(excluded) != 0 {
+		t.Log("Expected a championship with no weighting to be left out of the season")
+		t.Fail()
+	}
+
+	if _, err := sm.LoadSeason(season.ID.String()); err != nil {
+		t.Logf("Expected the season to be stored, got: %v", err)
+		t.Fail()
+	}
+
+	t.Run("No name", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/seasons/new/submit", strings.NewReader(url.Values{"Name": {" "}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		if _, _, err := sm.HandleCreateSeason(r); err == nil {
+			t.Log("Expected a season without a name to be refused")
+			t.Fail()
+		} else if _, ok := err.(ValidationError); !ok {
+			t.Logf("Expected a validation error, got: %v", err)
+			t.Fail()
+		}
+	})
+}
@@ -10,6 +10,14 @@ import (
 type SteamLoginHandler struct{}
 
 func (slh *SteamLoginHandler) redirectToSteamLogin(backURLFunc func(r *http.Request) string) http.HandlerFunc {
+	return slh.loginWithSteam(func(w http.ResponseWriter, r *http.Request, steamID string) {
+		http.Redirect(w, r, backURLFunc(r)+"?steamGUID="+steamID, http.StatusFound)
+	})
+}
+
+// loginWithSteam runs the Steam OpenID flow and calls onLogin with the validated Steam ID. Unlike redirectToSteamLogin,
+// the Steam ID is never passed through the query string, so it is safe to use for authentication.
+func (slh *SteamLoginHandler) loginWithSteam(onLogin func(w http.ResponseWriter, r *http.Request, steamID string)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		opID := steam_go.NewOpenId(r)
 		switch opID.Mode() {
@@ -28,7 +36,7 @@ func (slh *SteamLoginHandler) redirectToSteamLogin(backURLFunc func(r *http.Requ
 				return
 			}
 
-			http.Redirect(w, r, backURLFunc(r)+"?steamGUID="+steamID, http.StatusFound)
+			onLogin(w, r, steamID)
 		}
 	}
 }
@@ -49,6 +49,35 @@ type Store interface {
 	LoadRaceWeekend(id string) (*RaceWeekend, error)
 	DeleteRaceWeekend(id string) error
 
+	// Teams
+	ListTeams() ([]*Team, error)
+	UpsertTeam(team *Team) error
+	LoadTeam(id string) (*Team, error)
+	DeleteTeam(id string) error
+
+	// Seasons
+	ListSeasons() ([]*Season, error)
+	UpsertSeason(season *Season) error
+	LoadSeason(id string) (*Season, error)
+	DeleteSeason(id string) error
+
+	// Race Weekend Templates
+	ListRaceWeekendTemplates() ([]*RaceWeekendTemplate, error)
+	UpsertRaceWeekendTemplate(template *RaceWeekendTemplate) error
+	LoadRaceWeekendTemplate(id string) (*RaceWeekendTemplate, error)
+	DeleteRaceWeekendTemplate(id string) error
+
+	// Scheduler Jobs
+	ListJobs() ([]*Job, error)
+	UpsertJob(job *Job) error
+	LoadJob(id string) (*Job, error)
+	DeleteJob(id string) error
+
+	// Driver Notification Preferences
+	ListDriverNotificationPreferences() ([]*DriverNotificationPreferences, error)
+	UpsertDriverNotificationPreferences(prefs *DriverNotificationPreferences) error
+	LoadDriverNotificationPreferences(guid string) (*DriverNotificationPreferences, error)
+
 	// Deprecated: Use the XXXServer methods below.
 	//UpsertServerOptions(so *GlobalServerConfig) error
 
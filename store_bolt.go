@@ -24,9 +24,11 @@ var (
 	accountsBucketName      = []byte("accounts")
 	frameLinksBucketName    = []byte("frameLinks")
 	raceWeekendsBucketName  = []byte("raceWeekends")
-	serversBucketName  = []byte("servers")
+	serversBucketName       = []byte("servers")
 
-	serverOptionsKey = []byte("serverOptions")
+	teamsBucketName   = []byte("teams")
+	seasonsBucketName = []byte("seasons")
+	serverOptionsKey  = []byte("serverOptions")
 )
 
 func (rs *BoltStore) customRaceBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
@@ -806,7 +808,6 @@ func (rs *BoltStore) DeleteRaceWeekend(id string) error {
 	return rs.UpsertRaceWeekend(raceWeekend)
 }
 
-
 func (rs *BoltStore) serversBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
 	if !tx.Writable() {
 		bkt := tx.Bucket(serversBucketName)
@@ -914,3 +915,533 @@ func (rs *BoltStore) UpsertServer(server *Server) error {
 		return b.Put([]byte(server.ID.String()), data)
 	})
 }
+
+func (rs *BoltStore) teamsBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	if !tx.Writable() {
+		bkt := tx.Bucket(teamsBucketName)
+
+		if bkt == nil {
+			return nil, bbolt.ErrBucketNotFound
+		}
+
+		return bkt, nil
+	}
+
+	return tx.CreateBucketIfNotExists(teamsBucketName)
+}
+
+func (rs *BoltStore) UpsertTeam(team *Team) error {
+	team.Updated = time.Now()
+
+	return rs.db.Update(func(tx *bbolt.Tx) error {
+		b, err := rs.teamsBucket(tx)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := rs.encode(team)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(team.ID.String()), data)
+	})
+}
+
+func (rs *BoltStore) ListTeams() ([]*Team, error) {
+	var teams []*Team
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.teamsBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var team *Team
+
+			err := rs.decode(v, &team)
+
+			if err != nil {
+				return err
+			}
+
+			if !team.Deleted.IsZero() {
+				return nil
+			}
+
+			teams = append(teams, team)
+
+			return nil
+		})
+	})
+
+	return teams, err
+}
+
+var ErrTeamNotFound = errors.New("servermanager: team not found")
+
+func (rs *BoltStore) LoadTeam(id string) (*Team, error) {
+	var team *Team
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.teamsBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return ErrTeamNotFound
+		} else if err != nil {
+			return err
+		}
+
+		data := b.Get([]byte(id))
+
+		if data == nil {
+			return ErrTeamNotFound
+		}
+
+		return rs.decode(data, &team)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (rs *BoltStore) DeleteTeam(id string) error {
+	team, err := rs.LoadTeam(id)
+
+	if err != nil {
+		return err
+	}
+
+	team.Deleted = time.Now()
+
+	return rs.UpsertTeam(team)
+}
+
+func (rs *BoltStore) seasonsBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	if !tx.Writable() {
+		bkt := tx.Bucket(seasonsBucketName)
+
+		if bkt == nil {
+			return nil, bbolt.ErrBucketNotFound
+		}
+
+		return bkt, nil
+	}
+
+	return tx.CreateBucketIfNotExists(seasonsBucketName)
+}
+
+func (rs *BoltStore) UpsertSeason(season *Season) error {
+	season.Updated = time.Now()
+
+	return rs.db.Update(func(tx *bbolt.Tx) error {
+		b, err := rs.seasonsBucket(tx)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := rs.encode(season)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(season.ID.String()), data)
+	})
+}
+
+func (rs *BoltStore) ListSeasons() ([]*Season, error) {
+	var seasons []*Season
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.seasonsBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var season *Season
+
+			err := rs.decode(v, &season)
+
+			if err != nil {
+				return err
+			}
+
+			if !season.Deleted.IsZero() {
+				return nil
+			}
+
+			seasons = append(seasons, season)
+
+			return nil
+		})
+	})
+
+	return seasons, err
+}
+
+var ErrSeasonNotFound = errors.New("servermanager: season not found")
+
+func (rs *BoltStore) LoadSeason(id string) (*Season, error) {
+	var season *Season
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.seasonsBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return ErrSeasonNotFound
+		} else if err != nil {
+			return err
+		}
+
+		data := b.Get([]byte(id))
+
+		if data == nil {
+			return ErrSeasonNotFound
+		}
+
+		return rs.decode(data, &season)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return season, nil
+}
+
+func (rs *BoltStore) DeleteSeason(id string) error {
+	season, err := rs.LoadSeason(id)
+
+	if err != nil {
+		return err
+	}
+
+	season.Deleted = time.Now()
+
+	return rs.UpsertSeason(season)
+}
+
+func (rs *BoltStore) raceWeekendTemplatesBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	if !tx.Writable() {
+		bkt := tx.Bucket(raceWeekendTemplatesBucketName)
+
+		if bkt == nil {
+			return nil, bbolt.ErrBucketNotFound
+		}
+
+		return bkt, nil
+	}
+
+	return tx.CreateBucketIfNotExists(raceWeekendTemplatesBucketName)
+}
+
+func (rs *BoltStore) UpsertRaceWeekendTemplate(template *RaceWeekendTemplate) error {
+	template.Updated = time.Now()
+
+	return rs.db.Update(func(tx *bbolt.Tx) error {
+		b, err := rs.raceWeekendTemplatesBucket(tx)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := rs.encode(template)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(template.ID.String()), data)
+	})
+}
+
+func (rs *BoltStore) ListRaceWeekendTemplates() ([]*RaceWeekendTemplate, error) {
+	var raceWeekendTemplates []*RaceWeekendTemplate
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.raceWeekendTemplatesBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var template *RaceWeekendTemplate
+
+			err := rs.decode(v, &template)
+
+			if err != nil {
+				return err
+			}
+
+			if !template.Deleted.IsZero() {
+				return nil
+			}
+
+			raceWeekendTemplates = append(raceWeekendTemplates, template)
+
+			return nil
+		})
+	})
+
+	return raceWeekendTemplates, err
+}
+
+var ErrRaceWeekendTemplateNotFound = errors.New("servermanager: race weekend template not found")
+
+func (rs *BoltStore) LoadRaceWeekendTemplate(id string) (*RaceWeekendTemplate, error) {
+	var template *RaceWeekendTemplate
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.raceWeekendTemplatesBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return ErrRaceWeekendTemplateNotFound
+		} else if err != nil {
+			return err
+		}
+
+		data := b.Get([]byte(id))
+
+		if data == nil {
+			return ErrRaceWeekendTemplateNotFound
+		}
+
+		return rs.decode(data, &template)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (rs *BoltStore) DeleteRaceWeekendTemplate(id string) error {
+	template, err := rs.LoadRaceWeekendTemplate(id)
+
+	if err != nil {
+		return err
+	}
+
+	template.Deleted = time.Now()
+
+	return rs.UpsertRaceWeekendTemplate(template)
+}
+
+var jobsBucketName = []byte("jobs")
+
+func (rs *BoltStore) jobsBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	if !tx.Writable() {
+		bkt := tx.Bucket(jobsBucketName)
+
+		if bkt == nil {
+			return nil, bbolt.ErrBucketNotFound
+		}
+
+		return bkt, nil
+	}
+
+	return tx.CreateBucketIfNotExists(jobsBucketName)
+}
+
+func (rs *BoltStore) ListJobs() ([]*Job, error) {
+	var jobs []*Job
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.jobsBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var job *Job
+
+			err := rs.decode(v, &job)
+
+			if err != nil {
+				return err
+			}
+
+			jobs = append(jobs, job)
+
+			return nil
+		})
+	})
+
+	return jobs, err
+}
+
+func (rs *BoltStore) UpsertJob(job *Job) error {
+	job.Updated = time.Now()
+
+	return rs.db.Update(func(tx *bbolt.Tx) error {
+		b, err := rs.jobsBucket(tx)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := rs.encode(job)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(job.ID.String()), data)
+	})
+}
+
+func (rs *BoltStore) LoadJob(id string) (*Job, error) {
+	var job *Job
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.jobsBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return ErrJobNotFound
+		} else if err != nil {
+			return err
+		}
+
+		data := b.Get([]byte(id))
+
+		if data == nil {
+			return ErrJobNotFound
+		}
+
+		return rs.decode(data, &job)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (rs *BoltStore) DeleteJob(id string) error {
+	return rs.db.Update(func(tx *bbolt.Tx) error {
+		b, err := rs.jobsBucket(tx)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+var driverNotificationPreferencesBucketName = []byte("driver_notification_preferences")
+
+func (rs *BoltStore) driverNotificationPreferencesBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	if !tx.Writable() {
+		bkt := tx.Bucket(driverNotificationPreferencesBucketName)
+
+		if bkt == nil {
+			return nil, bbolt.ErrBucketNotFound
+		}
+
+		return bkt, nil
+	}
+
+	return tx.CreateBucketIfNotExists(driverNotificationPreferencesBucketName)
+}
+
+func (rs *BoltStore) ListDriverNotificationPreferences() ([]*DriverNotificationPreferences, error) {
+	var allPrefs []*DriverNotificationPreferences
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.driverNotificationPreferencesBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var prefs *DriverNotificationPreferences
+
+			err := rs.decode(v, &prefs)
+
+			if err != nil {
+				return err
+			}
+
+			allPrefs = append(allPrefs, prefs)
+
+			return nil
+		})
+	})
+
+	return allPrefs, err
+}
+
+func (rs *BoltStore) UpsertDriverNotificationPreferences(prefs *DriverNotificationPreferences) error {
+	prefs.Updated = time.Now()
+
+	return rs.db.Update(func(tx *bbolt.Tx) error {
+		b, err := rs.driverNotificationPreferencesBucket(tx)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := rs.encode(prefs)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(prefs.GUID), data)
+	})
+}
+
+func (rs *BoltStore) LoadDriverNotificationPreferences(guid string) (*DriverNotificationPreferences, error) {
+	var prefs *DriverNotificationPreferences
+
+	err := rs.db.View(func(tx *bbolt.Tx) error {
+		b, err := rs.driverNotificationPreferencesBucket(tx)
+
+		if err == bbolt.ErrBucketNotFound {
+			return ErrDriverNotificationPreferencesNotFound
+		} else if err != nil {
+			return err
+		}
+
+		data := b.Get([]byte(guid))
+
+		if data == nil {
+			return ErrDriverNotificationPreferencesNotFound
+		}
+
+		return rs.decode(data, &prefs)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
@@ -19,13 +19,18 @@ const (
 	frameLinksFile    = "frame_links.json"
 	serverMetaDir     = "meta"
 	auditFile         = "audit.json"
+	jobsDir           = "jobs"
+	driverNotifsDir   = "driver_notifications"
 
 	// shared data
-	championshipsDir = "championships"
-	raceWeekendsDir  = "race_weekends"
-	customRacesDir   = "custom_races"
-	serversDir       = "servers"
-	entrantsFile     = "entrants.json"
+	championshipsDir        = "championships"
+	raceWeekendsDir         = "race_weekends"
+	customRacesDir          = "custom_races"
+	serversDir              = "servers"
+	entrantsFile            = "entrants.json"
+	teamsDir                = "teams"
+	seasonsDir              = "seasons"
+	raceWeekendTemplatesDir = "race_weekend_templates"
 )
 
 func NewJSONStore(dir string, sharedDir string) Store {
@@ -516,3 +521,262 @@ func (rs *JSONStore) DeleteServer(uuid string) error {
 func (rs *JSONStore) UpsertServer(server *Server) error {
 	return rs.encodeFile(rs.shared, filepath.Join(serversDir, server.ID.String()+".json"), server)
 }
+
+func (rs *JSONStore) ListTeams() ([]*Team, error) {
+	files, err := rs.listFiles(filepath.Join(rs.shared, teamsDir))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var teams []*Team
+
+	for _, file := range files {
+		team, err := rs.LoadTeam(file)
+
+		if err != nil || !team.Deleted.IsZero() {
+			continue
+		}
+
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+func (rs *JSONStore) UpsertTeam(team *Team) error {
+	team.Updated = time.Now()
+
+	return rs.encodeFile(rs.shared, filepath.Join(teamsDir, team.ID.String()+".json"), team)
+}
+
+func (rs *JSONStore) LoadTeam(id string) (*Team, error) {
+	var team *Team
+
+	err := rs.decodeFile(rs.shared, filepath.Join(teamsDir, id+".json"), &team)
+
+	if os.IsNotExist(err) {
+		return nil, ErrTeamNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (rs *JSONStore) DeleteTeam(id string) error {
+	team, err := rs.LoadTeam(id)
+
+	if err != nil {
+		return err
+	}
+
+	team.Deleted = time.Now()
+
+	return rs.UpsertTeam(team)
+}
+
+func (rs *JSONStore) ListSeasons() ([]*Season, error) {
+	files, err := rs.listFiles(filepath.Join(rs.shared, seasonsDir))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var seasons []*Season
+
+	for _, file := range files {
+		season, err := rs.LoadSeason(file)
+
+		if err != nil || !season.Deleted.IsZero() {
+			continue
+		}
+
+		seasons = append(seasons, season)
+	}
+
+	return seasons, nil
+}
+
+func (rs *JSONStore) UpsertSeason(season *Season) error {
+	season.Updated = time.Now()
+
+	return rs.encodeFile(rs.shared, filepath.Join(seasonsDir, season.ID.String()+".json"), season)
+}
+
+func (rs *JSONStore) LoadSeason(id string) (*Season, error) {
+	var season *Season
+
+	err := rs.decodeFile(rs.shared, filepath.Join(seasonsDir, id+".json"), &season)
+
+	if os.IsNotExist(err) {
+		return nil, ErrSeasonNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return season, nil
+}
+
+func (rs *JSONStore) DeleteSeason(id string) error {
+	season, err := rs.LoadSeason(id)
+
+	if err != nil {
+		return err
+	}
+
+	season.Deleted = time.Now()
+
+	return rs.UpsertSeason(season)
+}
+
+func (rs *JSONStore) ListRaceWeekendTemplates() ([]*RaceWeekendTemplate, error) {
+	files, err := rs.listFiles(filepath.Join(rs.shared, raceWeekendTemplatesDir))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var raceWeekendTemplates []*RaceWeekendTemplate
+
+	for _, file := range files {
+		template, err := rs.LoadRaceWeekendTemplate(file)
+
+		if err != nil || !template.Deleted.IsZero() {
+			continue
+		}
+
+		raceWeekendTemplates = append(raceWeekendTemplates, template)
+	}
+
+	return raceWeekendTemplates, nil
+}
+
+func (rs *JSONStore) UpsertRaceWeekendTemplate(template *RaceWeekendTemplate) error {
+	template.Updated = time.Now()
+
+	return rs.encodeFile(rs.shared, filepath.Join(raceWeekendTemplatesDir, template.ID.String()+".json"), template)
+}
+
+func (rs *JSONStore) LoadRaceWeekendTemplate(id string) (*RaceWeekendTemplate, error) {
+	var template *RaceWeekendTemplate
+
+	err := rs.decodeFile(rs.shared, filepath.Join(raceWeekendTemplatesDir, id+".json"), &template)
+
+	if os.IsNotExist(err) {
+		return nil, ErrRaceWeekendTemplateNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (rs *JSONStore) DeleteRaceWeekendTemplate(id string) error {
+	template, err := rs.LoadRaceWeekendTemplate(id)
+
+	if err != nil {
+		return err
+	}
+
+	template.Deleted = time.Now()
+
+	return rs.UpsertRaceWeekendTemplate(template)
+}
+
+func (rs *JSONStore) ListJobs() ([]*Job, error) {
+	files, err := rs.listFiles(filepath.Join(rs.base, jobsDir))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+
+	for _, file := range files {
+		job, err := rs.LoadJob(file)
+
+		if err != nil {
+			continue
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+func (rs *JSONStore) UpsertJob(job *Job) error {
+	job.Updated = time.Now()
+
+	return rs.encodeFile(rs.base, filepath.Join(jobsDir, job.ID.String()+".json"), job)
+}
+
+func (rs *JSONStore) LoadJob(id string) (*Job, error) {
+	var job *Job
+
+	err := rs.decodeFile(rs.base, filepath.Join(jobsDir, id+".json"), &job)
+
+	if os.IsNotExist(err) {
+		return nil, ErrJobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (rs *JSONStore) DeleteJob(id string) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	err := os.Remove(filepath.Join(rs.base, jobsDir, id+".json"))
+
+	if os.IsNotExist(err) {
+		return ErrJobNotFound
+	}
+
+	return err
+}
+
+func (rs *JSONStore) ListDriverNotificationPreferences() ([]*DriverNotificationPreferences, error) {
+	files, err := rs.listFiles(filepath.Join(rs.base, driverNotifsDir))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var allPrefs []*DriverNotificationPreferences
+
+	for _, file := range files {
+		prefs, err := rs.LoadDriverNotificationPreferences(file)
+
+		if err != nil {
+			continue
+		}
+
+		allPrefs = append(allPrefs, prefs)
+	}
+
+	return allPrefs, nil
+}
+
+func (rs *JSONStore) UpsertDriverNotificationPreferences(prefs *DriverNotificationPreferences) error {
+	prefs.Updated = time.Now()
+
+	return rs.encodeFile(rs.base, filepath.Join(driverNotifsDir, prefs.GUID+".json"), prefs)
+}
+
+func (rs *JSONStore) LoadDriverNotificationPreferences(guid string) (*DriverNotificationPreferences, error) {
+	var prefs *DriverNotificationPreferences
+
+	err := rs.decodeFile(rs.base, filepath.Join(driverNotifsDir, guid+".json"), &prefs)
+
+	if os.IsNotExist(err) {
+		return nil, ErrDriverNotificationPreferencesNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
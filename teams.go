@@ -0,0 +1,736 @@
+package servermanager
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDriverAlreadyInTeam = errors.New("servermanager: driver is already in a team for this championship, transfer them instead")
+	ErrDriverNotInTeam     = errors.New("servermanager: driver is not in a team for this championship")
+)
+
+func NewTeam(name string) *Team {
+	return &Team{
+		ID:      uuid.New(),
+		Created: time.Now(),
+		Name:    name,
+		Rosters: make(map[uuid.UUID][]*TeamRosterEntry),
+	}
+}
+
+// A Team is a group of drivers who score points together in one or more Championships.
+type Team struct {
+	ID      uuid.UUID
+	Created time.Time
+	Updated time.Time
+	Deleted time.Time
+
+	Name string
+
+	// LogoURL is an absolute or relative URL to an image of the team logo.
+	LogoURL string
+
+	PrimaryColour   string
+	SecondaryColour string
+
+	// Rosters is a map of Championship ID to the drivers that have driven for this Team in that Championship.
+	Rosters map[uuid.UUID][]*TeamRosterEntry
+
+	// Transfers is the history of drivers moving into or out of this Team.
+	Transfers []*TeamTransfer
+}
+
+// A TeamRosterEntry is a driver's stint with a Team in a Championship. A driver's points count towards the Team for
+// any events that finished between Joined and Left. A zero Joined time means 'since the start of the championship',
+// and a zero Left time means that the driver is still in the Team.
+type TeamRosterEntry struct {
+	DriverGUID string
+	DriverName string
+
+	Joined time.Time
+	Left   time.Time
+}
+
+func (tre *TeamRosterEntry) IsCurrent() bool {
+	return tre.Left.IsZero()
+}
+
+// ActiveAt indicates whether the roster entry covers the given time. Events with no time (i.e. those which have not
+// yet finished) are considered to be covered by current roster entries only.
+func (tre *TeamRosterEntry) ActiveAt(t time.Time) bool {
+	if t.IsZero() {
+		return tre.IsCurrent()
+	}
+
+	return !t.Before(tre.Joined) && (tre.Left.IsZero() || t.Before(tre.Left))
+}
+
+// A TeamTransfer records a driver moving between teams in a Championship.
+type TeamTransfer struct {
+	ChampionshipID uuid.UUID
+	DriverGUID     string
+	DriverName     string
+
+	FromTeamID   uuid.UUID
+	FromTeamName string
+	ToTeamID     uuid.UUID
+	ToTeamName   string
+
+	// TransferPoints indicates that all of the points the driver had scored in the Championship were moved
+	// to their new team.
+	TransferPoints bool
+
+	Time time.Time
+}
+
+func (t *Team) Roster(championshipID uuid.UUID) []*TeamRosterEntry {
+	return t.Rosters[championshipID]
+}
+
+// CurrentDrivers returns the roster entries of drivers who are currently in the Team for a given Championship.
+func (t *Team) CurrentDrivers(championshipID uuid.UUID) []*TeamRosterEntry {
+	var out []*TeamRosterEntry
+
+	for _, entry := range t.Rosters[championshipID] {
+		if entry.IsCurrent() {
+			out = append(out, entry)
+		}
+	}
+
+	return out
+}
+
+// HasDriverAt indicates whether the driver was in this Team for the given Championship at time t.
+func (t *Team) HasDriverAt(championshipID uuid.UUID, driverGUID string, at time.Time) bool {
+	for _, entry := range t.Rosters[championshipID] {
+		if entry.DriverGUID == driverGUID && entry.ActiveAt(at) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Team) addDriver(championshipID uuid.UUID, driverGUID, driverName string, joined time.Time) {
+	if t.Rosters == nil {
+		t.Rosters = make(map[uuid.UUID][]*TeamRosterEntry)
+	}
+
+	t.Rosters[championshipID] = append(t.Rosters[championshipID], &TeamRosterEntry{
+		DriverGUID: driverGUID,
+		DriverName: driverName,
+		Joined:     joined,
+	})
+}
+
+func (t *Team) removeDriver(championshipID uuid.UUID, driverGUID string, left time.Time) {
+	for _, entry := range t.Rosters[championshipID] {
+		if entry.DriverGUID == driverGUID && entry.IsCurrent() {
+			entry.Left = left
+		}
+	}
+}
+
+// removeDriverHistory removes all record of a driver from a Team's roster in a Championship, meaning that they
+// no longer contribute any points to the Team.
+func (t *Team) removeDriverHistory(championshipID uuid.UUID, driverGUID string) {
+	var roster []*TeamRosterEntry
+
+	for _, entry := range t.Rosters[championshipID] {
+		if entry.DriverGUID != driverGUID {
+			roster = append(roster, entry)
+		}
+	}
+
+	t.Rosters[championshipID] = roster
+}
+
+// ChampionshipTransfers returns the transfer history of the Team in a given Championship.
+func (t *Team) ChampionshipTransfers(championshipID uuid.UUID) []*TeamTransfer {
+	var out []*TeamTransfer
+
+	for _, transfer := range t.Transfers {
+		if transfer.ChampionshipID == championshipID {
+			out = append(out, transfer)
+		}
+	}
+
+	return out
+}
+
+// TeamsForChampionship loads all Teams which have a roster for the given Championship.
+func TeamsForChampionship(store Store, championshipID uuid.UUID) ([]*Team, error) {
+	teams, err := store.ListTeams()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Team
+
+	for _, team := range teams {
+		if _, ok := team.Rosters[championshipID]; ok {
+			out = append(out, team)
+		}
+	}
+
+	return out, nil
+}
+
+type TeamManager struct {
+	store Store
+}
+
+func NewTeamManager(store Store) *TeamManager {
+	return &TeamManager{
+		store: store,
+	}
+}
+
+func (tm *TeamManager) ListTeams() ([]*Team, error) {
+	teams, err := tm.store.ListTeams()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(teams, func(i, j int) bool {
+		return strings.ToLower(teams[i].Name) < strings.ToLower(teams[j].Name)
+	})
+
+	return teams, nil
+}
+
+func (tm *TeamManager) LoadTeam(id string) (*Team, error) {
+	return tm.store.LoadTeam(id)
+}
+
+func (tm *TeamManager) DeleteTeam(id string) error {
+	return tm.store.DeleteTeam(id)
+}
+
+func (tm *TeamManager) HandleCreateTeam(r *http.Request) (team *Team, edited bool, err error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, false, err
+	}
+
+	name := strings.TrimSpace(r.FormValue("Name"))
+
+	if name == "" {
+		return nil, false, ValidationError("Please enter a team name.")
+	}
+
+	if teamID := r.FormValue("Editing"); teamID != "" {
+		team, err = tm.store.LoadTeam(teamID)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		edited = true
+	} else {
+		team = NewTeam(name)
+	}
+
+	previousName := team.Name
+
+	team.Name = name
+	team.LogoURL = strings.TrimSpace(r.FormValue("LogoURL"))
+	team.PrimaryColour = r.FormValue("PrimaryColour")
+	team.SecondaryColour = r.FormValue("SecondaryColour")
+
+	if err := tm.store.UpsertTeam(team); err != nil {
+		return nil, edited, err
+	}
+
+	if edited && previousName != team.Name {
+		// keep the team name on the entry lists in sync, so that it is shown in game and in results files.
+		for championshipID := range team.Rosters {
+			if err := tm.syncEntrantTeamNames(championshipID); err != nil {
+				return nil, edited, err
+			}
+		}
+	}
+
+	return team, edited, nil
+}
+
+// findDriverTeam finds the Team a driver is currently in for a Championship.
+func (tm *TeamManager) findDriverTeam(championshipID uuid.UUID, driverGUID string) (*Team, error) {
+	teams, err := TeamsForChampionship(tm.store, championshipID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, team := range teams {
+		for _, entry := range team.CurrentDrivers(championshipID) {
+			if entry.DriverGUID == driverGUID {
+				return team, nil
+			}
+		}
+	}
+
+	return nil, ErrDriverNotInTeam
+}
+
+// AddDriverToTeam adds a driver to a Team's roster in a Championship. The driver must not currently be in another
+// Team for that Championship.
+func (tm *TeamManager) AddDriverToTeam(teamID, championshipID, driverGUID string) error {
+	team, err := tm.store.LoadTeam(teamID)
+
+	if err != nil {
+		return err
+	}
+
+	championship, err := tm.store.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	_, entrant := findEntrantInChampionship(championship, driverGUID)
+
+	if entrant == nil {
+		return ErrDriverNotInChampionship
+	}
+
+	if _, err := tm.findDriverTeam(championship.ID, driverGUID); err == nil {
+		return ErrDriverAlreadyInTeam
+	} else if err != ErrDriverNotInTeam {
+		return err
+	}
+
+	joined := time.Now()
+
+	if championship.NumCompletedEvents() == 0 {
+		joined = time.Time{}
+	}
+
+	team.addDriver(championship.ID, entrant.GUID, entrant.Name, joined)
+
+	if err := tm.store.UpsertTeam(team); err != nil {
+		return err
+	}
+
+	return tm.syncEntrantTeamNames(championship.ID)
+}
+
+// RemoveDriverFromTeam ends a driver's stint with a Team. Points they have already scored remain with the Team.
+func (tm *TeamManager) RemoveDriverFromTeam(teamID, championshipID, driverGUID string) error {
+	team, err := tm.store.LoadTeam(teamID)
+
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	team.removeDriver(id, driverGUID, time.Now())
+
+	if err := tm.store.UpsertTeam(team); err != nil {
+		return err
+	}
+
+	return tm.syncEntrantTeamNames(id)
+}
+
+// TransferDriver moves a driver into a new Team for a Championship. If transferPoints is true, all points the driver
+// has previously scored in the Championship are moved to the new Team, otherwise they stay with the old Team.
+func (tm *TeamManager) TransferDriver(championshipID, driverGUID, toTeamID string, transferPoints bool) error {
+	championship, err := tm.store.LoadChampionship(championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	_, entrant := findEntrantInChampionship(championship, driverGUID)
+
+	if entrant == nil {
+		return ErrDriverNotInChampionship
+	}
+
+	fromTeam, err := tm.findDriverTeam(championship.ID, driverGUID)
+
+	if err != nil {
+		return err
+	}
+
+	toTeam, err := tm.store.LoadTeam(toTeamID)
+
+	if err != nil {
+		return err
+	}
+
+	if fromTeam.ID == toTeam.ID {
+		return nil
+	}
+
+	now := time.Now()
+
+	if transferPoints {
+		fromTeam.removeDriverHistory(championship.ID, driverGUID)
+		toTeam.addDriver(championship.ID, entrant.GUID, entrant.Name, time.Time{})
+	} else {
+		fromTeam.removeDriver(championship.ID, driverGUID, now)
+		toTeam.addDriver(championship.ID, entrant.GUID, entrant.Name, now)
+	}
+
+	transfer := &TeamTransfer{
+		ChampionshipID: championship.ID,
+		DriverGUID:     entrant.GUID,
+		DriverName:     entrant.Name,
+		FromTeamID:     fromTeam.ID,
+		FromTeamName:   fromTeam.Name,
+		ToTeamID:       toTeam.ID,
+		ToTeamName:     toTeam.Name,
+		TransferPoints: transferPoints,
+		Time:           now,
+	}
+
+	fromTeam.Transfers = append(fromTeam.Transfers, transfer)
+	toTeam.Transfers = append(toTeam.Transfers, transfer)
+
+	if err := tm.store.UpsertTeam(fromTeam); err != nil {
+		return err
+	}
+
+	if err := tm.store.UpsertTeam(toTeam); err != nil {
+		return err
+	}
+
+	logrus.Infof("Driver %s (%s) transferred from team %s to %s", entrant.Name, entrant.GUID, fromTeam.Name, toTeam.Name)
+
+	return tm.syncEntrantTeamNames(championship.ID)
+}
+
+// syncEntrantTeamNames sets the Team name of each Entrant in a Championship to that of their current Team.
+func (tm *TeamManager) syncEntrantTeamNames(championshipID uuid.UUID) error {
+	championship, err := tm.store.LoadChampionship(championshipID.String())
+
+	if err != nil {
+		return err
+	}
+
+	teams, err := TeamsForChampionship(tm.store, championshipID)
+
+	if err != nil {
+		return err
+	}
+
+	championship.teams = teams
+
+	// a team's standing is keyed by its ID once it has a roster, so its penalties must be too.
+	keyTeamPenaltiesByTeamID(championship, teams)
+
+	for _, class := range championship.Classes {
+		for _, entrant := range class.Entrants {
+			if entrant.GUID == "" {
+				continue
+			}
+
+			if team := championship.FindTeamForDriver(entrant.GUID, time.Time{}); team != nil {
+				entrant.Team = team.Name
+			}
+		}
+	}
+
+	return tm.store.UpsertChampionship(championship)
+}
+
+// keyTeamPenaltiesByTeamID moves team penalties which are keyed by the name of a Team with a roster in the
+// Championship to the Team's ID, which is the key of its standing. It reports whether any penalties were moved.
+func keyTeamPenaltiesByTeamID(championship *Championship, teams []*Team) bool {
+	changed := false
+
+	for _, class := range championship.Classes {
+		for _, team := range teams {
+			penalty, ok := class.TeamPenalties[team.Name]
+
+			if !ok {
+				continue
+			}
+
+			class.TeamPenalties[team.ID.String()] += penalty
+			delete(class.TeamPenalties, team.Name)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// TeamChampionshipSummary is a Team's involvement in a single Championship.
+type TeamChampionshipSummary struct {
+	Championship *Championship
+	Roster       []*TeamRosterEntry
+	Transfers    []*TeamTransfer
+	Standings    []*TeamClassStanding
+}
+
+// TeamClassStanding is the position of a Team within a Championship Class.
+type TeamClassStanding struct {
+	Class    *ChampionshipClass
+	Position int
+	Standing *TeamStanding
+}
+
+// BuildTeamSummaries returns the standings and rosters for a Team across all of its Championships.
+func (tm *TeamManager) BuildTeamSummaries(team *Team) ([]*TeamChampionshipSummary, error) {
+	var out []*TeamChampionshipSummary
+
+	for championshipID, roster := range team.Rosters {
+		championship, err := loadChampionship(tm.store, championshipID.String())
+
+		if err == ErrChampionshipNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !championship.Deleted.IsZero() {
+			continue
+		}
+
+		summary := &TeamChampionshipSummary{
+			Championship: championship,
+			Roster:       roster,
+			Transfers:    team.ChampionshipTransfers(championship.ID),
+		}
+
+		for _, class := range championship.Classes {
+			for pos, standing := range class.TeamStandings(championship, championship.Events) {
+				if standing.TeamID == team.ID {
+					summary.Standings = append(summary.Standings, &TeamClassStanding{
+						Class:    class,
+						Position: pos + 1,
+						Standing: standing,
+					})
+				}
+			}
+		}
+
+		out = append(out, summary)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Championship.Updated.After(out[j].Championship.Updated)
+	})
+
+	return out, nil
+}
+
+type TeamsHandler struct {
+	*BaseHandler
+
+	teamManager *TeamManager
+}
+
+func NewTeamsHandler(baseHandler *BaseHandler, teamManager *TeamManager) *TeamsHandler {
+	return &TeamsHandler{
+		BaseHandler: baseHandler,
+		teamManager: teamManager,
+	}
+}
+
+type teamListTemplateVars struct {
+	BaseTemplateVars
+
+	Teams []*Team
+}
+
+func (th *TeamsHandler) list(w http.ResponseWriter, r *http.Request) {
+	teams, err := th.teamManager.ListTeams()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't list teams")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	th.viewRenderer.MustLoadTemplate(w, r, "teams/index.html", &teamListTemplateVars{
+		Teams: teams,
+	})
+}
+
+type teamViewTemplateVars struct {
+	BaseTemplateVars
+
+	Team             *Team
+	Teams            []*Team
+	Championships    []*TeamChampionshipSummary
+	AllChampionships []*Championship
+}
+
+func (th *TeamsHandler) view(w http.ResponseWriter, r *http.Request) {
+	team, err := th.teamManager.LoadTeam(chi.URLParam(r, "teamID"))
+
+	if err == ErrTeamNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't load team")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	summaries, err := th.teamManager.BuildTeamSummaries(team)
+
+	if err != nil {
+		logrus.WithError(err).Errorf("couldn't build summaries for team: %s", team.ID)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	teams, err := th.teamManager.ListTeams()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't list teams")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	allChampionships, err := th.teamManager.store.ListChampionships()
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't list championships")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	th.viewRenderer.MustLoadTemplate(w, r, "teams/view.html", &teamViewTemplateVars{
+		Team:             team,
+		Teams:            teams,
+		Championships:    summaries,
+		AllChampionships: allChampionships,
+	})
+}
+
+type teamEditTemplateVars struct {
+	BaseTemplateVars
+
+	Team      *Team
+	IsEditing bool
+}
+
+func (th *TeamsHandler) createOrEdit(w http.ResponseWriter, r *http.Request) {
+	vars := &teamEditTemplateVars{
+		Team: &Team{},
+	}
+
+	if teamID := chi.URLParam(r, "teamID"); teamID != "" {
+		team, err := th.teamManager.LoadTeam(teamID)
+
+		if err != nil {
+			logrus.WithError(err).Error("couldn't load team")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		vars.Team = team
+		vars.IsEditing = true
+	}
+
+	th.viewRenderer.MustLoadTemplate(w, r, "teams/new.html", vars)
+}
+
+func (th *TeamsHandler) submit(w http.ResponseWriter, r *http.Request) {
+	team, edited, err := th.teamManager.HandleCreateTeam(r)
+
+	if validationError, ok := err.(ValidationError); ok {
+		AddErrorFlash(w, r, string(validationError))
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't create team")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if edited {
+		AddFlash(w, r, "Team successfully edited!")
+	} else {
+		AddFlash(w, r, "Team successfully created!")
+	}
+
+	http.Redirect(w, r, "/team/"+team.ID.String(), http.StatusFound)
+}
+
+func (th *TeamsHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := th.teamManager.DeleteTeam(chi.URLParam(r, "teamID")); err != nil {
+		logrus.WithError(err).Error("couldn't delete team")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Team successfully deleted!")
+	http.Redirect(w, r, "/teams", http.StatusFound)
+}
+
+func (th *TeamsHandler) addDriver(w http.ResponseWriter, r *http.Request) {
+	// entrants are submitted in the form championshipID/driverGUID
+	entrantParts := strings.SplitN(r.FormValue("Entrant"), "/", 2)
+
+	if len(entrantParts) != 2 {
+		AddErrorFlash(w, r, "Please select a driver to add to the team.")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	}
+
+	err := th.teamManager.AddDriverToTeam(chi.URLParam(r, "teamID"), entrantParts[0], entrantParts[1])
+
+	if err == ErrDriverAlreadyInTeam || err == ErrDriverNotInChampionship {
+		AddErrorFlash(w, r, "Could not add the driver to the team. Make sure they are an entrant in the championship, and are not already in another team.")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't add driver to team")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Driver successfully added to the team.")
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (th *TeamsHandler) removeDriver(w http.ResponseWriter, r *http.Request) {
+	err := th.teamManager.RemoveDriverFromTeam(chi.URLParam(r, "teamID"), chi.URLParam(r, "championshipID"), chi.URLParam(r, "driverGUID"))
+
+	if err != nil {
+		logrus.WithError(err).Error("couldn't remove driver from team")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Driver successfully removed from the team.")
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (th *TeamsHandler) transferDriver(w http.ResponseWriter, r *http.Request) {
+	err := th.teamManager.TransferDriver(chi.URLParam(r, "championshipID"), chi.URLParam(r, "driverGUID"), r.FormValue("ToTeamID"), r.FormValue("TransferPoints") == "on")
+
+	if err == ErrDriverNotInTeam || err == ErrDriverNotInChampionship {
+		AddErrorFlash(w, r, "Could not transfer the driver. Make sure they are an entrant in the championship and are currently in a team.")
+		http.Redirect(w, r, r.Referer(), http.StatusFound)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("couldn't transfer driver")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	AddFlash(w, r, "Driver successfully transferred.")
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
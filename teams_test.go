@@ -0,0 +1,179 @@
+package servermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTeam_HasDriverAt(t *testing.T) {
+	championshipID := uuid.New()
+	transferTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	before := transferTime.Add(-time.Hour * 24)
+	after := transferTime.Add(time.Hour * 24)
+
+	oldTeam := NewTeam("Old Team")
+	newTeam := NewTeam("New Team")
+
+	oldTeam.addDriver(championshipID, "1234", "Driver", time.Time{})
+
+	oldTeam.removeDriver(championshipID, "1234", transferTime)
+	newTeam.addDriver(championshipID, "1234", "Driver", transferTime)
+
+	if !oldTeam.HasDriverAt(championshipID, "1234", before) {
+		t.Log("Expected driver to be in the old team before the transfer")
+		t.Fail()
+	}
+
+	if oldTeam.HasDriverAt(championshipID, "1234", after) {
+		t.Log("Expected driver to not be in the old team after the transfer")
+		t.Fail()
+	}
+
+	if newTeam.HasDriverAt(championshipID, "1234", before) {
+		t.Log("Expected driver to not be in the new team before the transfer")
+		t.Fail()
+	}
+
+	if !newTeam.HasDriverAt(championshipID, "1234", after) {
+		t.Log("Expected driver to be in the new team after the transfer")
+		t.Fail()
+	}
+
+	if !newTeam.HasDriverAt(championshipID, "1234", time.Time{}) || oldTeam.HasDriverAt(championshipID, "1234", time.Time{}) {
+		t.Log("Expected driver to currently be in the new team only")
+		t.Fail()
+	}
+
+	if oldTeam.HasDriverAt(uuid.New(), "1234", before) {
+		t.Log("Expected driver to not be in a team for another championship")
+		t.Fail()
+	}
+
+	// transferring points removes the driver from the old team entirely
+	oldTeam.removeDriverHistory(championshipID, "1234")
+
+	if oldTeam.HasDriverAt(championshipID, "1234", before) {
+		t.Log("Expected driver history to be removed from the old team")
+		t.Fail()
+	}
+}
+
+func TestMoveTeamPenaltiesToTeamIDs(t *testing.T) {
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	championship := NewChampionship("Team Penalties")
+	class := NewChampionshipClass("GT3")
+	class.TeamPenalties = map[string]int{
+		"Roster Team":       5,
+		"Results Team":      3,
+		uuid.New().String(): 2,
+	}
+	championship.AddClass(class)
+
+	rosterTeam := NewTeam("Roster Team")
+	rosterTeam.addDriver(championship.ID, "1234", "Driver", time.Time{})
+
+	if err := store.UpsertTeam(rosterTeam); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := store.UpsertChampionship(championship); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := moveTeamPenaltiesToTeamIDs(store); err != nil {
+		t.Error(err)
+		return
+	}
+
+	migrated, err := store.LoadChampionship(championship.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	penalties := migrated.Classes[0].TeamPenalties
+
+	if penalties[rosterTeam.ID.String()] != 5 {
+		t.Logf("Expected the roster team penalty to be keyed by team ID, got: %v", penalties)
+		t.Fail()
+	}
+
+	if _, ok := penalties["Roster Team"]; ok {
+		t.Log("Expected the roster team name key to be removed")
+		t.Fail()
+	}
+
+	if penalties["Results Team"] != 3 || len(penalties) != 3 {
+		t.Logf("Expected teams without a roster to keep their name key, got: %v", penalties)
+		t.Fail()
+	}
+}
+
+func TestTeamManager_AddDriverToTeamMovesTeamPenalties(t *testing.T) {
+	// a fresh team store, as found when upgrading from a version without teams.
+	store, cleanup := newSchedulerJobsTestStore()
+	defer cleanup()
+
+	tm := NewTeamManager(store)
+
+	championship := NewChampionship("Team Penalties")
+	class := NewChampionshipClass("GT3")
+	class.TeamPenalties = map[string]int{"Roster Team": 5, "Other Team": 3}
+	championship.AddClass(class)
+
+	entrant := NewEntrant()
+	entrant.Name = "Driver"
+	entrant.GUID = "1234"
+	entrant.Team = "Roster Team"
+	class.Entrants.AddToBackOfGrid(entrant)
+
+	if err := store.UpsertChampionship(championship); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// no team has a roster yet, so the migration leaves the penalties keyed by name.
+	if err := moveTeamPenaltiesToTeamIDs(store); err != nil {
+		t.Error(err)
+		return
+	}
+
+	team := NewTeam("Roster Team")
+
+	if err := store.UpsertTeam(team); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := tm.AddDriverToTeam(team.ID.String(), championship.ID.String(), "1234"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	updated, err := store.LoadChampionship(championship.ID.String())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	updatedClass := updated.Classes[0]
+
+	if updatedClass.PenaltyForTeam(team.ID.String()) != 5 || updatedClass.PenaltyForTeam("Roster Team") != 0 {
+		t.Logf("Expected the penalty to follow the team's standing to its ID, got: %v", updatedClass.TeamPenalties)
+		t.Fail()
+	}
+
+	if updatedClass.PenaltyForTeam("Other Team") != 3 {
+		t.Logf("Expected teams without a roster to keep their penalty, got: %v", updatedClass.TeamPenalties)
+		t.Fail()
+	}
+}